@@ -178,6 +178,43 @@ func (p PayloadAuthKeyboardInteractiveBackendError) Equals(other Payload) bool {
 	return p.Username == p2.Username && p.Reason == p2.Reason
 }
 
+// PayloadAuthBanned is a payload for a message that indicates that the source IP address has been banned after too
+// many failed authentication attempts.
+type PayloadAuthBanned struct {
+	Username string `json:"username" yaml:"username"`
+	Reason   string `json:"reason" yaml:"reason"`
+}
+
+// Equals compares two PayloadAuthBanned payloads.
+func (p PayloadAuthBanned) Equals(other Payload) bool {
+	p2, ok := other.(PayloadAuthBanned)
+	if !ok {
+		return false
+	}
+	return p.Username == p2.Username && p.Reason == p2.Reason
+}
+
+// PayloadAuthorization is a payload for a message that indicates the decision of the authorization provider.
+type PayloadAuthorization struct {
+	Username              string `json:"username" yaml:"username"`
+	AuthenticatedUsername string `json:"authenticatedUsername" yaml:"authenticatedUsername"`
+	Allowed               bool   `json:"allowed" yaml:"allowed"`
+	// Rule is the name of the authorization rule that made the decision. It is empty if no named rule matched.
+	Rule string `json:"rule,omitempty" yaml:"rule,omitempty"`
+}
+
+// Equals compares two PayloadAuthorization payloads.
+func (p PayloadAuthorization) Equals(other Payload) bool {
+	p2, ok := other.(PayloadAuthorization)
+	if !ok {
+		return false
+	}
+	return p.Username == p2.Username &&
+		p.AuthenticatedUsername == p2.AuthenticatedUsername &&
+		p.Allowed == p2.Allowed &&
+		p.Rule == p2.Rule
+}
+
 // PayloadHandshakeFailed is a payload for a failed handshake.
 type PayloadHandshakeFailed struct {
 	Reason string `json:"reason" yaml:"reason"`
@@ -110,3 +110,21 @@ func (p PayloadRequestStreamLocal) Equals(other Payload) bool {
 	}
 	return p.Path == p2.Path
 }
+
+// PayloadClosedByPolicy is the payload for a message that indicates that a channel or the whole connection was closed
+// because of a policy, such as an idle timeout.
+type PayloadClosedByPolicy struct {
+	// Reason is the name of the policy that caused the close, for example "idle".
+	Reason string `json:"reason" yaml:"reason"`
+	// Connection is true if the whole connection was closed, not just the channel.
+	Connection bool `json:"connection" yaml:"connection"`
+}
+
+// Equals compares two PayloadClosedByPolicy payloads.
+func (p PayloadClosedByPolicy) Equals(other Payload) bool {
+	p2, ok := other.(PayloadClosedByPolicy)
+	if !ok {
+		return false
+	}
+	return p.Reason == p2.Reason && p.Connection == p2.Connection
+}
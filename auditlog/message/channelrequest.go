@@ -120,6 +120,17 @@ func (p PayloadChannelRequestX11) Equals(other Payload) bool {
 		p.Screen == p2.Screen
 }
 
+// PayloadChannelRequestAuthAgent is a payload signaling a request to forward the SSH agent of the client.
+type PayloadChannelRequestAuthAgent struct {
+	RequestID uint64 `json:"requestId" yaml:"requestId"`
+}
+
+// Equals compares two PayloadChannelRequestAuthAgent payloads.
+func (p PayloadChannelRequestAuthAgent) Equals(other Payload) bool {
+	p2, ok := other.(PayloadChannelRequestAuthAgent)
+	return ok && p.RequestID == p2.RequestID
+}
+
 // PayloadChannelRequestShell is a payload signaling a request for a shell.
 type PayloadChannelRequestShell struct {
 	RequestID uint64 `json:"requestId" yaml:"requestId"`
@@ -29,4 +29,36 @@ func (p PayloadGlobalRequestDecodeFailed) Equals(other Payload) bool {
 		return false
 	}
 	return p.RequestID == p2.RequestID && p.RequestType == p2.RequestType && p.Reason == p2.Reason
-}
\ No newline at end of file
+}
+
+// PayloadGlobalRequestFailed is a payload that signals that a global request, such as a reverse forwarding request,
+// has been rejected.
+type PayloadGlobalRequestFailed struct {
+	RequestType string `json:"requestType" yaml:"requestType"`
+	Reason      string `json:"reason" yaml:"reason"`
+}
+
+// Equals compares two PayloadGlobalRequestFailed payloads.
+func (p PayloadGlobalRequestFailed) Equals(other Payload) bool {
+	p2, ok := other.(PayloadGlobalRequestFailed)
+	if !ok {
+		return false
+	}
+	return p.RequestType == p2.RequestType && p.Reason == p2.Reason
+}
+
+// PayloadPolicyDryRun is a payload that signals that a request would have been rejected by the security settings, but
+// was let through because they are in dry run mode.
+type PayloadPolicyDryRun struct {
+	Rule   string `json:"rule" yaml:"rule"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// Equals compares two PayloadPolicyDryRun payloads.
+func (p PayloadPolicyDryRun) Equals(other Payload) bool {
+	p2, ok := other.(PayloadPolicyDryRun)
+	if !ok {
+		return false
+	}
+	return p.Rule == p2.Rule && p.Reason == p2.Reason
+}
@@ -23,6 +23,8 @@ const (
 	TypeAuthKeyboardInteractiveAnswer       Type = 109 // TypeAuthKeyboardInteractiveAnswer is a message that indicates a response to a keyboard-interactive challenge.
 	TypeAuthKeyboardInteractiveFailed       Type = 110 // TypeAuthKeyboardInteractiveFailed indicates that a keyboard-interactive authentication process has failed.
 	TypeAuthKeyboardInteractiveBackendError Type = 111 // TypeAuthKeyboardInteractiveBackendError indicates an error in the authentication backend during a keyboard-interactive authentication.
+	TypeAuthBanned                          Type = 112 // TypeAuthBanned indicates that the source IP address has been temporarily banned due to too many failed authentication attempts.
+	TypeAuthorization                       Type = 113 // TypeAuthorization indicates the decision of the authorization provider after a successful authentication.
 
 	TypeHandshakeFailed             Type = 198 // TypeHandshakeFailed indicates that the handshake has failed.
 	TypeHandshakeSuccessful         Type = 199 // TypeHandshakeSuccessful indicates that the handshake and authentication was successful.
@@ -32,6 +34,8 @@ const (
 	TypeRequestCancelReverseForward Type = 203 // TypeRequestReverseForward describes a request from the client to stop forwarding a remote port
 	TypeRequestStreamLocal          Type = 204 // TypeRequestStreamLocal describes a request from the client to forward a remote socket
 	TypeRequestCancelStreamLocal    Type = 205 // TypeRequestCancelStreamLocal describes a request from the client to stop forwarding a remote socket
+	TypeGlobalRequestFailed         Type = 206 // TypeGlobalRequestFailed describes that a global request, such as a reverse forwarding request, has been rejected
+	TypePolicyDryRun                Type = 207 // TypePolicyDryRun describes that a request would have been rejected by the security settings in dry run mode
 
 	TypeNewChannel                   Type = 300 // TypeNewChannel describes a message that indicates a new channel request.
 	TypeNewChannelSuccessful         Type = 301 // TypeNewChannelSuccessful describes a message when the new channel request was successful.
@@ -41,6 +45,7 @@ const (
 	TypeNewReverseX11ForwardChannel  Type = 305 // TypeNewReverseX11ForwardChannel describes a message when the server opens a new channel due to an incoming connection on the forwarded X11 port
 	TypeNewForwardStreamLocalChannel Type = 306 // TypeDirectStreamLocalChannel describes a message when the client requests to open a new channel due to an incoming connection towards a forwarded port
 	TypeNewReverseStreamLocalChannel Type = 307 // TypeNewReverseStreamLocalChannel describes a message when the server opens a new channel due to an incoming connection on a forwarded unix socket
+	TypeNewReverseAuthAgentChannel   Type = 308 // TypeNewReverseAuthAgentChannel describes a message when the server opens a new channel to the SSH agent of the client due to an incoming connection on the forwarded agent socket
 
 	TypeChannelRequestUnknownType  Type = 400 // TypeChannelRequestUnknownType describes an in-channel request from the user that is not supported.
 	TypeChannelRequestDecodeFailed Type = 401 // TypeChannelRequestDecodeFailed describes an in-channel request from the user that is supported but the payload could not be decoded.
@@ -54,12 +59,14 @@ const (
 	TypeChannelRequestSubsystem Type = 407 // TypeChannelRequestSubsystem describes an in-channel request to start a well-known subsystem (e.g. SFTP).
 	TypeChannelRequestWindow    Type = 408 // TypeChannelRequestWindow describes an in-channel request to resize the current interactive terminal.
 
-	TypeChannelRequestX11 Type = 409 // TypeChannelRequestX11 describes an in-channel request to start forwarding remote X11 connections to the client
+	TypeChannelRequestX11       Type = 409 // TypeChannelRequestX11 describes an in-channel request to start forwarding remote X11 connections to the client
+	TypeChannelRequestAuthAgent Type = 410 // TypeChannelRequestAuthAgent describes an in-channel request to forward the SSH agent of the client into the container
 
-	TypeWriteClose Type = 496 // TypeWriteClose indicates that the channel was closed for writing from the server side.
-	TypeClose      Type = 497 // TypeClose indicates that the channel was closed.
-	TypeExitSignal Type = 498 // TypeExitSignal describes the signal that caused a program to terminate abnormally.
-	TypeExit       Type = 499 // TypeExit describes a message that is sent when the program exited. The payload contains the exit status.
+	TypeClosedByPolicy Type = 495 // TypeClosedByPolicy indicates that the channel or the whole connection was closed because of a policy, such as an idle timeout.
+	TypeWriteClose     Type = 496 // TypeWriteClose indicates that the channel was closed for writing from the server side.
+	TypeClose          Type = 497 // TypeClose indicates that the channel was closed.
+	TypeExitSignal     Type = 498 // TypeExitSignal describes the signal that caused a program to terminate abnormally.
+	TypeExit           Type = 499 // TypeExit describes a message that is sent when the program exited. The payload contains the exit status.
 
 	TypeIO            Type = 500 // TypeIO describes the testdata transferred to and from the currently running program on the terminal.
 	TypeRequestFailed Type = 501 // TypeRequestFailed describes that a request has failed.
@@ -83,6 +90,8 @@ var typeToID = map[Type]string{
 	TypeAuthKeyboardInteractiveAnswer:       "auth_keyboard_interactive_answer",
 	TypeAuthKeyboardInteractiveFailed:       "auth_keyboard_interactive_failed",
 	TypeAuthKeyboardInteractiveBackendError: "auth_keyboard_interactive_backend_error",
+	TypeAuthBanned:                          "auth_banned",
+	TypeAuthorization:                       "auth_authorization",
 
 	TypeGlobalRequestUnknown:         "global_request_unknown",
 	TypeGlobalRequestDecodeFailed:    "global_request_decode_failed",
@@ -90,6 +99,8 @@ var typeToID = map[Type]string{
 	TypeRequestCancelReverseForward:  "cancel_forward_tcpip",
 	TypeRequestStreamLocal:           "forward_streamlocal",
 	TypeRequestCancelStreamLocal:     "cancel_forward_streamlocal",
+	TypeGlobalRequestFailed:          "global_request_failed",
+	TypePolicyDryRun:                 "policy_dry_run",
 	TypeNewChannel:                   "new_channel",
 	TypeNewChannelSuccessful:         "new_channel_successful",
 	TypeNewChannelFailed:             "new_channel_failed",
@@ -98,6 +109,7 @@ var typeToID = map[Type]string{
 	TypeNewReverseX11ForwardChannel:  "new_channel_x11",
 	TypeNewForwardStreamLocalChannel: "new_channel_direct_streamlocal",
 	TypeNewReverseStreamLocalChannel: "new_channel_forwarded_streamlocal",
+	TypeNewReverseAuthAgentChannel:   "new_channel_auth_agent",
 
 	TypeChannelRequestUnknownType:  "channel_request_unknown",
 	TypeChannelRequestDecodeFailed: "channel_request_decode_failed",
@@ -109,6 +121,8 @@ var typeToID = map[Type]string{
 	TypeChannelRequestSubsystem:    "subsystem",
 	TypeChannelRequestWindow:       "window",
 	TypeChannelRequestX11:          "x11-req",
+	TypeChannelRequestAuthAgent:    "auth-agent-req",
+	TypeClosedByPolicy:             "closed_by_policy",
 	TypeWriteClose:                 "close_write",
 	TypeClose:                      "close",
 	TypeExit:                       "exit",
@@ -136,6 +150,8 @@ var typeToName = map[Type]string{
 	TypeAuthKeyboardInteractiveAnswer:       "Keyboard-interactive authentication answer",
 	TypeAuthKeyboardInteractiveFailed:       "Keyboard-interactive authentication failed",
 	TypeAuthKeyboardInteractiveBackendError: "Keyboard-interactive authentication backend error",
+	TypeAuthBanned:                          "Source address banned",
+	TypeAuthorization:                       "Authorization decision",
 
 	TypeGlobalRequestUnknown:         "Unknown global request",
 	TypeGlobalRequestDecodeFailed:    "Failed to decode global request",
@@ -143,6 +159,8 @@ var typeToName = map[Type]string{
 	TypeRequestCancelReverseForward:  "Request to stop reverse port forwarding",
 	TypeRequestStreamLocal:           "Request reverse socket forwarding",
 	TypeRequestCancelStreamLocal:     "Request to stop reverse socket forwarding",
+	TypeGlobalRequestFailed:          "Global request failed",
+	TypePolicyDryRun:                 "Policy dry run rejection",
 	TypeNewChannel:                   "New channel request",
 	TypeNewChannelSuccessful:         "New channel successful",
 	TypeNewChannelFailed:             "New channel failed",
@@ -151,6 +169,7 @@ var typeToName = map[Type]string{
 	TypeNewReverseX11ForwardChannel:  "New server-to-client X11 forwarding channel",
 	TypeNewForwardStreamLocalChannel: "New client-to-server unix socket forwarding channel",
 	TypeNewReverseStreamLocalChannel: "New server-to-client unix socket forwarding channel",
+	TypeNewReverseAuthAgentChannel:   "New server-to-client SSH agent channel",
 
 	TypeChannelRequestUnknownType:  "Unknown channel request",
 	TypeChannelRequestDecodeFailed: "Failed to decode channel request",
@@ -162,6 +181,8 @@ var typeToName = map[Type]string{
 	TypeChannelRequestSubsystem:    "Request subsystem",
 	TypeChannelRequestWindow:       "Change window size",
 	TypeChannelRequestX11:          "Request X11 forwarding",
+	TypeChannelRequestAuthAgent:    "Request SSH agent forwarding",
+	TypeClosedByPolicy:             "Closed by policy",
 	TypeWriteClose:                 "Close channel for writing",
 	TypeClose:                      "Close channel",
 	TypeExit:                       "Program exited",
@@ -183,6 +204,8 @@ var messageTypeToPayload = map[Type]Payload{
 	TypeAuthKeyboardInteractiveAnswer:       PayloadAuthKeyboardInteractiveAnswer{},
 	TypeAuthKeyboardInteractiveFailed:       PayloadAuthKeyboardInteractiveFailed{},
 	TypeAuthKeyboardInteractiveBackendError: PayloadAuthKeyboardInteractiveBackendError{},
+	TypeAuthBanned:                          PayloadAuthBanned{},
+	TypeAuthorization:                       PayloadAuthorization{},
 	TypeHandshakeFailed:                     PayloadHandshakeFailed{},
 	TypeHandshakeSuccessful:                 PayloadHandshakeSuccessful{},
 
@@ -197,6 +220,8 @@ var messageTypeToPayload = map[Type]Payload{
 	TypeRequestCancelReverseForward:  PayloadRequestReverseForward{},
 	TypeRequestStreamLocal:           PayloadRequestStreamLocal{},
 	TypeRequestCancelStreamLocal:     PayloadRequestStreamLocal{},
+	TypeGlobalRequestFailed:          PayloadGlobalRequestFailed{},
+	TypePolicyDryRun:                 PayloadPolicyDryRun{},
 	TypeNewChannel:                   PayloadNewChannel{},
 	TypeNewChannelSuccessful:         PayloadNewChannelSuccessful{},
 	TypeNewChannelFailed:             PayloadNewChannelFailed{},
@@ -205,6 +230,7 @@ var messageTypeToPayload = map[Type]Payload{
 	TypeNewReverseX11ForwardChannel:  PayloadNewReverseX11ForwardChannel{},
 	TypeNewForwardStreamLocalChannel: PayloadRequestStreamLocal{},
 	TypeNewReverseStreamLocalChannel: PayloadRequestStreamLocal{},
+	TypeNewReverseAuthAgentChannel:   nil,
 
 	TypeChannelRequestUnknownType:  PayloadChannelRequestUnknownType{},
 	TypeChannelRequestDecodeFailed: PayloadChannelRequestDecodeFailed{},
@@ -216,10 +242,12 @@ var messageTypeToPayload = map[Type]Payload{
 	TypeChannelRequestSubsystem:    PayloadChannelRequestSubsystem{},
 	TypeChannelRequestWindow:       PayloadChannelRequestWindow{},
 	TypeChannelRequestX11:          PayloadChannelRequestX11{},
+	TypeChannelRequestAuthAgent:    PayloadChannelRequestAuthAgent{},
 	TypeIO:                         PayloadIO{},
 	TypeRequestFailed:              PayloadRequestFailed{},
 	TypeExit:                       PayloadExit{},
 	TypeExitSignal:                 PayloadExitSignal{},
+	TypeClosedByPolicy:             PayloadClosedByPolicy{},
 
 	TypeClose:      nil,
 	TypeWriteClose: nil,
@@ -1,7 +1,8 @@
 package auth
 
 import (
-    "go.containerssh.io/libcontainerssh/metadata"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/metadata"
 )
 
 // PasswordAuthRequest is an authentication request for password authentication.
@@ -26,6 +27,60 @@ type PublicKeyAuthRequest struct {
 	PublicKey `json:",inline"`
 }
 
+// KeyboardInteractiveAuthRequest is an authentication request for a round of keyboard-interactive authentication. The
+// first round of an authentication contains no answers. If the auth server responds with a challenge, the questions
+// of the challenge are shown to the user and the answers are sent in the next round along with the state of the
+// challenge.
+//
+// swagger:model KeyboardInteractiveAuthRequest
+type KeyboardInteractiveAuthRequest struct {
+	metadata.ConnectionAuthPendingMetadata `json:",inline"`
+
+	// Round is the number of the round within the authentication, starting at 1.
+	//
+	// required: true
+	Round int `json:"round"`
+
+	// State is the state the auth server returned in the challenge of the previous round.
+	State string `json:"state,omitempty"`
+
+	// Answers contains the answers of the user to the questions of the previous round, keyed by the question ID.
+	Answers map[string]string `json:"answers,omitempty"`
+}
+
+// KeyboardInteractiveChallenge is a set of questions the auth server asks the user in a keyboard-interactive
+// authentication.
+//
+// swagger:model KeyboardInteractiveChallenge
+type KeyboardInteractiveChallenge struct {
+	// Instruction is the text shown to the user before the questions.
+	Instruction string `json:"instruction,omitempty"`
+
+	// Questions are the questions the user has to answer. The challenge may contain no questions, in which case
+	// only the instruction is shown and the next round starts immediately.
+	Questions []KeyboardInteractiveQuestion `json:"questions,omitempty"`
+
+	// State is an opaque value sent back to the auth server in the next round, for example to identify the pending
+	// authentication.
+	State string `json:"state,omitempty"`
+}
+
+// KeyboardInteractiveQuestion is a single question of a KeyboardInteractiveChallenge.
+//
+// swagger:model KeyboardInteractiveQuestion
+type KeyboardInteractiveQuestion struct {
+	// ID identifies the answer in the next round. If empty, the index of the question is used.
+	ID string `json:"id,omitempty"`
+
+	// Question is the text of the question.
+	//
+	// required: true
+	Question string `json:"question"`
+
+	// Echo indicates that the answer of the user should be shown as it is typed.
+	Echo bool `json:"echo"`
+}
+
 // AuthorizationRequest is the authorization request used after some
 // authentication methods (e.g. kerberos) to determine whether users are
 // allowed to access the service
@@ -46,6 +101,25 @@ type ResponseBody struct {
 	// required: true
 	// in: body
 	Success bool `json:"success"`
+
+	// Cache controls how long ContainerSSH caches the response if caching is enabled. Only public key authentication
+	// responses are cached.
+	//
+	// required: false
+	// in: body
+	Cache *config.CacheControl `json:"cache,omitempty"`
+}
+
+// KeyboardInteractiveResponseBody is a response to a round of keyboard-interactive authentication.
+//
+// swagger:model KeyboardInteractiveAuthResponseBody
+type KeyboardInteractiveResponseBody struct {
+	ResponseBody `json:",inline"`
+
+	// Challenge contains the questions of the next round. If it is set and the authentication is not successful,
+	// the questions are shown to the user and the answers are sent to the auth server in the next round. Otherwise,
+	// the authentication is complete.
+	Challenge *KeyboardInteractiveChallenge `json:"challenge,omitempty"`
 }
 
 // Response is the full HTTP authentication response.
@@ -57,3 +131,13 @@ type Response struct {
 	// in: body
 	ResponseBody
 }
+
+// KeyboardInteractiveResponse is the full HTTP response to a round of keyboard-interactive authentication.
+//
+// swagger:response KeyboardInteractiveAuthResponse
+type KeyboardInteractiveResponse struct {
+	// The response body
+	//
+	// in: body
+	KeyboardInteractiveResponseBody
+}
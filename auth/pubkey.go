@@ -1,9 +1,80 @@
 package auth
 
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
 // PublicKey contains the details of a public key provided during authentication.
 type PublicKey struct {
 	// PublicKey is the key in the authorized key format.
 	//
 	// required: true
 	PublicKey string `json:"publicKey"`
+
+	// Certificate contains the decoded fields of the OpenSSH certificate if the client presented one. The PublicKey
+	// field contains the entire certificate in this case.
+	//
+	// required: false
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key in the format used by OpenSSH. If the key cannot be parsed, the
+// hash of the key string is returned instead, so different keys never have the same fingerprint.
+func (p PublicKey) Fingerprint() string {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(p.PublicKey))
+	if err != nil {
+		sum := sha256.Sum256([]byte(p.PublicKey))
+		return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	}
+	return ssh.FingerprintSHA256(key)
+}
+
+// Certificate contains the decoded fields of an OpenSSH certificate.
+//
+// swagger:model PublicKeyCertificate
+type Certificate struct {
+	// PublicKey is the certified public key in the authorized key format.
+	//
+	// required: true
+	PublicKey string `json:"publicKey"`
+	// Type is the certificate type. It is either "user" or "host".
+	//
+	// required: true
+	Type string `json:"type"`
+	// Serial is the serial number of the certificate assigned by the certificate authority.
+	//
+	// required: true
+	Serial uint64 `json:"serial"`
+	// KeyID is the free-form identifier of the certificate assigned by the certificate authority.
+	//
+	// required: true
+	KeyID string `json:"keyId"`
+	// Principals is the list of usernames or hostnames the certificate is valid for.
+	//
+	// required: false
+	Principals []string `json:"principals,omitempty"`
+	// ValidAfter is the time the certificate becomes valid.
+	//
+	// required: true
+	ValidAfter time.Time `json:"validAfter"`
+	// ValidBefore is the time the certificate expires. If it is not set the certificate does not expire.
+	//
+	// required: false
+	ValidBefore *time.Time `json:"validBefore,omitempty"`
+	// CriticalOptions contains the critical options of the certificate, such as force-command.
+	//
+	// required: false
+	CriticalOptions map[string]string `json:"criticalOptions,omitempty"`
+	// Extensions contains the extensions of the certificate, such as permit-pty.
+	//
+	// required: false
+	Extensions map[string]string `json:"extensions,omitempty"`
+	// SignatureKey is the public key of the certificate authority in the authorized key format.
+	//
+	// required: true
+	SignatureKey string `json:"signatureKey"`
 }
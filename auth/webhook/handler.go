@@ -9,3 +9,9 @@ import (
 type AuthRequestHandler interface {
 	auth.Handler
 }
+
+// KeyboardInteractiveRequestHandler can optionally be implemented by an AuthRequestHandler to ask the user questions
+// when keyboard-interactive authentication is configured with the webhook method.
+type KeyboardInteractiveRequestHandler interface {
+	auth.KeyboardInteractiveHandler
+}
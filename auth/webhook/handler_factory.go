@@ -3,11 +3,14 @@ package webhook
 import (
 	"net/http"
 
+	"go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/internal/auth"
 	"go.containerssh.io/libcontainerssh/log"
 )
 
-// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler.
-func NewHandler(h AuthRequestHandler, logger log.Logger) http.Handler {
-	return auth.NewHandler(h, logger)
+// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler. If a signature
+// secret is configured, requests without a valid signature, with an expired timestamp, or with a nonce that has already
+// been used are rejected.
+func NewHandler(h AuthRequestHandler, signature config.HTTPSignatureConfig, logger log.Logger) http.Handler {
+	return auth.NewHandler(h, signature, logger)
 }
@@ -96,6 +96,48 @@ func (a *authHandler) OnPubKey(meta metadata.ConnectionAuthPendingMetadata, publ
 	return false, meta.AuthFailed(), nil
 }
 
+// swagger:operation POST /keyboard-interactive Authentication authKeyboardInteractive
+//
+// # Keyboard-interactive authentication
+//
+// Called for each round of a keyboard-interactive authentication. Respond with a challenge to ask the user further
+// questions.
+//
+// ---
+// parameters:
+//   - name: request
+//     in: body
+//     description: The authentication request
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/KeyboardInteractiveAuthRequest"
+//
+// responses:
+//
+//	"200":
+//	  "$ref": "#/responses/KeyboardInteractiveAuthResponse"
+func (a *authHandler) OnKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	round int,
+	_ string,
+	answers map[string]string,
+) (bool, *publicAuth.KeyboardInteractiveChallenge, metadata.ConnectionAuthenticatedMetadata, error) {
+	if meta.Username != "foo" && meta.Username != "busybox" {
+		return false, nil, meta.AuthFailed(), nil
+	}
+	if round == 1 {
+		return false, &publicAuth.KeyboardInteractiveChallenge{
+			Questions: []publicAuth.KeyboardInteractiveQuestion{
+				{ID: "password", Question: "Password: "},
+			},
+		}, meta.AuthFailed(), nil
+	}
+	if answers["password"] == "bar" {
+		return true, nil, meta.Authenticated(meta.Username), nil
+	}
+	return false, nil, meta.AuthFailed(), nil
+}
+
 // swagger:operation POST /authz Authentication authz
 //
 // # Authorization
@@ -169,6 +211,8 @@ func (h *handler) ServeHTTP(writer goHttp.ResponseWriter, request *goHttp.Reques
 	case "/password":
 		fallthrough
 	case "/pubkey":
+		fallthrough
+	case "/keyboard-interactive":
 		h.auth.ServeHTTP(writer, request)
 	case "/config":
 		h.config.ServeHTTP(writer, request)
@@ -186,8 +230,11 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	authHTTPHandler := auth.NewHandler(&authHandler{}, logger)
-	configHTTPHandler, err := configWebhook.NewHandler(&configHandler{}, logger)
+	signature := config.HTTPSignatureConfig{
+		Secret: os.Getenv("CONTAINERSSH_WEBHOOK_SECRET"),
+	}
+	authHTTPHandler := auth.NewHandler(&authHandler{}, signature, logger)
+	configHTTPHandler, err := configWebhook.NewHandler(&configHandler{}, signature, logger)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,23 @@
+package config
+
+// AdminConfig is the configuration for the admin HTTP API, which lists the live connections and lets administrators
+// terminate them. The API requires TLS with client certificate authentication.
+type AdminConfig struct {
+	HTTPServerConfiguration `json:",inline" yaml:",inline" default:"{\"listen\":\"127.0.0.1:9200\"}"`
+
+	Enable bool `json:"enable" yaml:"enable" comment:"Enable the admin API." default:"false"`
+}
+
+// Validate validates the admin API configuration.
+func (c AdminConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Cert == "" || c.Key == "" {
+		return newError("cert", "the admin API requires a TLS certificate and key")
+	}
+	if c.ClientCACert == "" {
+		return newError("clientcacert", "the admin API requires a client CA certificate to authenticate clients")
+	}
+	return c.HTTPServerConfiguration.Validate()
+}
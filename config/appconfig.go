@@ -33,10 +33,19 @@ type AppConfig struct {
 	Audit AuditLogConfig `json:"audit" yaml:"audit"`
 	// Health contains the configuration for the health check service.
 	Health HealthConfig `json:"health" yaml:"health"`
+	// RateLimit contains the configuration for connection and authentication rate limiting.
+	RateLimit RateLimitConfig `json:"ratelimit" yaml:"ratelimit"`
+	// Restart contains the configuration for the graceful restart with listener handoff.
+	Restart RestartConfig `json:"restart" yaml:"restart"`
+	// Admin contains the configuration for the admin HTTP API.
+	Admin AdminConfig `json:"admin" yaml:"admin"`
 
 	// Security contains the security restrictions on what can be executed. This option can be changed from the config
 	// server.
 	Security SecurityConfig `json:"security" yaml:"security"`
+	// SFTP contains the configuration for the built-in SFTP server. This option can be changed from the config
+	// server.
+	SFTP SFTPConfig `json:"sftp" yaml:"sftp"`
 	// Backend defines which backend to use. This option can be changed from the config server.
 	Backend Backend `json:"backend" yaml:"backend" default:"docker"`
 	// Docker contains the configuration for the docker backend. This option can be changed from the config server.
@@ -119,13 +128,32 @@ func (cfg *AppConfig) Validate(dynamic bool) error {
 	queue.add("geoip", &cfg.GeoIP)
 	queue.add("audit", &cfg.Audit)
 	queue.add("health", &cfg.Health)
+	queue.add("ratelimit", &cfg.RateLimit)
+	queue.add("restart", &cfg.Restart)
+	queue.add("admin", &cfg.Admin)
 
 	if cfg.ConfigServer.URL != "" && !dynamic {
 		return queue.Validate()
 	}
 	queue.add("security", &cfg.Security)
+	queue.add("sftp", &cfg.SFTP)
 	queue.add("backend", &cfg.Backend)
-	switch cfg.Backend {
+	// Each backend configuration is validated once, no matter how many listeners use it.
+	backends := map[Backend]struct{}{cfg.Backend: {}}
+	for _, listener := range cfg.SSH.Listeners {
+		if listener.Backend != "" {
+			backends[listener.Backend] = struct{}{}
+		}
+	}
+	for backend := range backends {
+		cfg.addBackendValidation(queue, backend)
+	}
+
+	return queue.Validate()
+}
+
+func (cfg *AppConfig) addBackendValidation(queue *validationQueue, backend Backend) {
+	switch backend {
 	case BackendDocker:
 		queue.add("docker", &cfg.Docker)
 	case BackendKubernetes:
@@ -133,8 +161,6 @@ func (cfg *AppConfig) Validate(dynamic bool) error {
 	case BackendSSHProxy:
 		queue.add("sshproxy", &cfg.SSHProxy)
 	}
-
-	return queue.Validate()
 }
 
 type validatable interface {
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"time"
+
+	"go.containerssh.io/libcontainerssh/internal/ldap"
+	"golang.org/x/crypto/ssh"
 )
 
 // AuthConfig is the configuration of the authentication client.
@@ -33,6 +39,13 @@ type AuthConfig struct {
 	// do not verify the provided SSH username.
 	Authz AuthzConfig `json:"authz" yaml:"authz"`
 
+	// AuthMethods lists the sequences of authentication methods a user must complete, similar to the
+	// AuthenticationMethods option of OpenSSH. For example, [["publickey", "keyboard-interactive"]] requires a public
+	// key followed by a keyboard-interactive authentication. The client receives a partial success after each method
+	// of a sequence except the last one. If empty, a single successful authentication method is sufficient. GSSAPI
+	// cannot be part of a sequence and is disabled when sequences are configured.
+	AuthMethods [][]SSHAuthMethod `json:"authMethods" yaml:"authMethods"`
+
 	// AuthTimeout is the timeout for the overall authentication call (e.g. verifying a password). If the server
 	// responds with a non-200 response the call will be retried until this timeout is reached. This timeout
 	// should be increased to ~180s for OAuth2 login.
@@ -56,6 +69,7 @@ type legacyAuthConfig struct {
 	KeyboardInteractiveAuth KeyboardInteractiveAuthConfig `json:"keyboardInteractive" yaml:"keyboardInteractive"`
 	GSSAPIAuth              GSSAPIAuthConfig              `json:"gssapi" yaml:"gssapi"`
 	Authz                   AuthzConfig                   `json:"authz" yaml:"authz"`
+	AuthMethods             [][]SSHAuthMethod             `json:"authMethods" yaml:"authMethods"`
 
 	HTTPClientConfiguration `json:",inline" yaml:",inline"`
 	AuthTimeout             time.Duration `json:"authTimeout" yaml:"authTimeout" default:"60s"`
@@ -69,6 +83,7 @@ func (l *legacyAuthConfig) Set(c *AuthConfig) {
 	c.KeyboardInteractiveAuth = l.KeyboardInteractiveAuth
 	c.GSSAPIAuth = l.GSSAPIAuth
 	c.Authz = l.Authz
+	c.AuthMethods = l.AuthMethods
 	c.HTTPClientConfiguration = l.HTTPClientConfiguration
 	c.Password = l.Password
 	c.PubKey = l.PubKey
@@ -80,6 +95,7 @@ type newAuthConfig struct {
 	KeyboardInteractiveAuth KeyboardInteractiveAuthConfig `json:"keyboardInteractive" yaml:"keyboardInteractive"`
 	GSSAPIAuth              GSSAPIAuthConfig              `json:"gssapi" yaml:"gssapi"`
 	Authz                   AuthzConfig                   `json:"authz" yaml:"authz"`
+	AuthMethods             [][]SSHAuthMethod             `json:"authMethods" yaml:"authMethods"`
 
 	HTTPClientConfiguration `json:",inline" yaml:",inline"`
 	AuthTimeout             time.Duration `json:"authTimeout" yaml:"authTimeout"`
@@ -93,6 +109,7 @@ func (n *newAuthConfig) Set(c *AuthConfig) {
 	c.KeyboardInteractiveAuth = n.KeyboardInteractiveAuth
 	c.GSSAPIAuth = n.GSSAPIAuth
 	c.Authz = n.Authz
+	c.AuthMethods = n.AuthMethods
 	c.HTTPClientConfiguration = n.HTTPClientConfiguration
 	c.Password = n.Password
 	c.PubKey = n.PubKey
@@ -130,7 +147,7 @@ func (c *AuthConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 func (c *AuthConfig) Validate() error {
 	if //goland:noinspection GoDeprecation
 	c.PasswordAuth.Method == PasswordAuthMethodDisabled &&
-		c.PublicKeyAuth.Method == PubKeyAuthMethodDisabled &&
+		c.PublicKeyAuth.Method == PubKeyAuthMethodDisabled && !c.PublicKeyAuth.Certificates.Enabled() &&
 		c.KeyboardInteractiveAuth.Method == KeyboardInteractiveAuthMethodDisabled &&
 		c.GSSAPIAuth.Method == GSSAPIAuthMethodDisabled &&
 		(((c.Password == nil || !*c.Password) && (c.PubKey == nil || !*c.PubKey)) && c.URL == "") {
@@ -148,7 +165,7 @@ func (c *AuthConfig) Validate() error {
 			return wrap(err, "password")
 		}
 	}
-	if c.PublicKeyAuth.Method != PubKeyAuthMethodDisabled {
+	if c.PublicKeyAuth.Method != PubKeyAuthMethodDisabled || c.PublicKeyAuth.Certificates.Enabled() {
 		//goland:noinspection GoDeprecation
 		if c.URL != "" && c.PubKey != nil && *c.PubKey {
 			return newError(
@@ -175,6 +192,9 @@ func (c *AuthConfig) Validate() error {
 			return wrap(err, "authz")
 		}
 	}
+	if err := c.validateAuthMethods(); err != nil {
+		return err
+	}
 	//goland:noinspection GoDeprecation
 	if ((c.Password != nil && *c.Password) || (c.PubKey != nil && *c.PubKey)) && c.URL != "" {
 		//goland:noinspection GoDeprecation
@@ -184,6 +204,53 @@ func (c *AuthConfig) Validate() error {
 	return nil
 }
 
+// validateAuthMethods checks that the authentication method sequences only contain configured methods.
+func (c *AuthConfig) validateAuthMethods() error {
+	for i, sequence := range c.AuthMethods {
+		field := fmt.Sprintf("authMethods[%d]", i)
+		if len(sequence) == 0 {
+			return newError(field, "the authentication method sequence cannot be empty")
+		}
+		for j, method := range sequence {
+			methodField := fmt.Sprintf("%s[%d]", field, j)
+			if err := method.Validate(); err != nil {
+				return wrap(err, methodField)
+			}
+			if method == SSHAuthMethodGSSAPI {
+				return newError(methodField, "GSSAPI cannot be used in an authentication method sequence")
+			}
+			if !c.methodConfigured(method) {
+				return newError(methodField, "the %s authentication method is not configured", method)
+			}
+			for _, previousMethod := range sequence[:j] {
+				if previousMethod == method {
+					return newError(methodField, "the %s method appears more than once in the sequence", method)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// methodConfigured returns true if an authenticator is configured for the SSH authentication method.
+func (c *AuthConfig) methodConfigured(method SSHAuthMethod) bool {
+	//goland:noinspection GoDeprecation
+	switch method {
+	case SSHAuthMethodPassword:
+		return c.PasswordAuth.Method != PasswordAuthMethodDisabled ||
+			(c.URL != "" && c.Password != nil && *c.Password)
+	case SSHAuthMethodPublicKey:
+		return c.PublicKeyAuth.Method != PubKeyAuthMethodDisabled || c.PublicKeyAuth.Certificates.Enabled() ||
+			(c.URL != "" && c.PubKey != nil && *c.PubKey)
+	case SSHAuthMethodKeyboardInteractive:
+		return c.KeyboardInteractiveAuth.Method != KeyboardInteractiveAuthMethodDisabled
+	case SSHAuthMethodGSSAPI:
+		return c.GSSAPIAuth.Method != GSSAPIAuthMethodDisabled
+	default:
+		return false
+	}
+}
+
 // region AuthMethod
 
 // AuthMethod is a listing of all authentication methods. These methods are not guaranteed to support any particular
@@ -192,7 +259,7 @@ type AuthMethod string
 
 // Validate checks if the provided method is valid or not.
 func (m AuthMethod) Validate() error {
-	if m == "webhook" || m == "oauth2" || m == "kerberos" {
+	if m == "webhook" || m == "oauth2" || m == "kerberos" || m == "authorizedkeys" || m == "ldap" || m == "totp" {
 		return nil
 	}
 	return fmt.Errorf("invalid value for method: %s", m)
@@ -210,6 +277,15 @@ const AuthMethodOAuth2 AuthMethod = "oauth2"
 // AuthMethodKerberos authenticates using the Kerberos method.
 const AuthMethodKerberos AuthMethod = "kerberos"
 
+// AuthMethodAuthorizedKeys authenticates using OpenSSH authorized_keys files.
+const AuthMethodAuthorizedKeys AuthMethod = "authorizedkeys"
+
+// AuthMethodLDAP authenticates against an LDAP directory.
+const AuthMethodLDAP AuthMethod = "ldap"
+
+// AuthMethodTOTP authenticates by asking for a time-based one-time password using the keyboard-interactive facility.
+const AuthMethodTOTP AuthMethod = "totp"
+
 // endregion
 
 // region PasswordAuth
@@ -224,6 +300,9 @@ type PasswordAuthConfig struct {
 
 	// Kerberos configures the Kerberos authenticator for password authentication.
 	Kerberos AuthKerberosClientConfig `json:"kerberos" yaml:"kerberos"`
+
+	// LDAP configures the LDAP authenticator for password authentication.
+	LDAP AuthLDAPConfig `json:"ldap" yaml:"ldap"`
 }
 
 // Validate checks the password configuration structure for misconfiguration.
@@ -238,6 +317,8 @@ func (c PasswordAuthConfig) Validate() error {
 		return c.Webhook.Validate()
 	case PasswordAuthMethodKerberos:
 		return c.Kerberos.Validate()
+	case PasswordAuthMethodLDAP:
+		return wrap(c.LDAP.Validate(), "ldap")
 	default:
 		return fmt.Errorf("BUG: unsupported password authenticator: %s", c.Method)
 	}
@@ -248,7 +329,8 @@ type PasswordAuthMethod string
 
 // Validate checks if the provided method is valid or not.
 func (m PasswordAuthMethod) Validate() error {
-	if m == PasswordAuthMethodDisabled || m == PasswordAuthMethodWebhook || m == PasswordAuthMethodKerberos {
+	if m == PasswordAuthMethodDisabled || m == PasswordAuthMethodWebhook || m == PasswordAuthMethodKerberos ||
+		m == PasswordAuthMethodLDAP {
 		return nil
 	}
 	return fmt.Errorf("invalid value for method: %s", m)
@@ -263,6 +345,9 @@ const PasswordAuthMethodWebhook PasswordAuthMethod = PasswordAuthMethod(AuthMeth
 // PasswordAuthMethodKerberos authenticates passwords using Kerberos.
 const PasswordAuthMethodKerberos PasswordAuthMethod = PasswordAuthMethod(AuthMethodKerberos)
 
+// PasswordAuthMethodLDAP authenticates passwords by binding to an LDAP directory as the user.
+const PasswordAuthMethodLDAP PasswordAuthMethod = PasswordAuthMethod(AuthMethodLDAP)
+
 // endregion
 
 // region PubKeyAuth
@@ -274,17 +359,37 @@ type PublicKeyAuthConfig struct {
 
 	// Webhook configures the webhook authenticator for public key authentication.
 	Webhook AuthWebhookClientConfig `json:"webhook" yaml:"webhook"`
+
+	// AuthorizedKeys configures the authenticator reading OpenSSH authorized_keys files.
+	AuthorizedKeys AuthAuthorizedKeysConfig `json:"authorizedKeys" yaml:"authorizedKeys"`
+
+	// LDAP configures the authenticator reading the public keys of the users from an LDAP directory.
+	LDAP AuthLDAPConfig `json:"ldap" yaml:"ldap"`
+
+	// Certificates configures the validation of OpenSSH user certificates. Certificates signed by a trusted CA are
+	// validated natively, all other keys are passed to the configured method.
+	Certificates AuthUserCertificateConfig `json:"certificates" yaml:"certificates"`
 }
 
 func (c PublicKeyAuthConfig) Validate() error {
 	if err := c.Method.Validate(); err != nil {
 		return fmt.Errorf("invalid public key authentication configuration (%w)", err)
 	}
+	if err := c.Certificates.Validate(); err != nil {
+		return wrap(err, "certificates")
+	}
 	switch c.Method {
 	case PubKeyAuthMethodDisabled:
 		return nil
 	case PubKeyAuthMethodWebhook:
 		return c.Webhook.Validate()
+	case PubKeyAuthMethodAuthorizedKeys:
+		return wrap(c.AuthorizedKeys.Validate(), "authorizedKeys")
+	case PubKeyAuthMethodLDAP:
+		if c.LDAP.PublicKeyAttribute == "" {
+			return wrap(newError("publicKeyAttribute", "the public key attribute cannot be empty"), "ldap")
+		}
+		return wrap(c.LDAP.Validate(), "ldap")
 	default:
 		return fmt.Errorf("BUG: unsupported public key authenticator: %s", c.Method)
 	}
@@ -295,7 +400,8 @@ type PublicKeyAuthMethod string
 
 // Validate checks if the provided method is valid or not.
 func (m PublicKeyAuthMethod) Validate() error {
-	if m == PubKeyAuthMethodDisabled || m == PubKeyAuthMethodWebhook {
+	if m == PubKeyAuthMethodDisabled || m == PubKeyAuthMethodWebhook || m == PubKeyAuthMethodAuthorizedKeys ||
+		m == PubKeyAuthMethodLDAP {
 		return nil
 	}
 	return fmt.Errorf("invalid value for method: %s", m)
@@ -307,6 +413,12 @@ const PubKeyAuthMethodDisabled PublicKeyAuthMethod = PublicKeyAuthMethod(AuthMet
 // PubKeyAuthMethodWebhook authenticates using an HTTP webhook.
 const PubKeyAuthMethodWebhook PublicKeyAuthMethod = PublicKeyAuthMethod(AuthMethodWebhook)
 
+// PubKeyAuthMethodAuthorizedKeys authenticates using OpenSSH authorized_keys files on the local filesystem.
+const PubKeyAuthMethodAuthorizedKeys PublicKeyAuthMethod = PublicKeyAuthMethod(AuthMethodAuthorizedKeys)
+
+// PubKeyAuthMethodLDAP authenticates using the public keys stored in an LDAP directory.
+const PubKeyAuthMethodLDAP PublicKeyAuthMethod = PublicKeyAuthMethod(AuthMethodLDAP)
+
 // endregion
 
 // region Keyboard-interactive
@@ -318,6 +430,12 @@ type KeyboardInteractiveAuthConfig struct {
 
 	// Webhook configures the oAuth2 authenticator for keyboard-interactive authentication.
 	OAuth2 AuthOAuth2ClientConfig `json:"oauth2" yaml:"oauth2"`
+
+	// TOTP configures the time-based one-time password authenticator for keyboard-interactive authentication.
+	TOTP AuthTOTPConfig `json:"totp" yaml:"totp"`
+
+	// Webhook configures the authenticator relaying the questions of an auth server to the user.
+	Webhook AuthKeyboardInteractiveWebhookConfig `json:"webhook" yaml:"webhook"`
 }
 
 func (c KeyboardInteractiveAuthConfig) Validate() error {
@@ -329,6 +447,10 @@ func (c KeyboardInteractiveAuthConfig) Validate() error {
 		return nil
 	case KeyboardInteractiveAuthMethodOAuth2:
 		return wrap(c.OAuth2.Validate(), "oauth2")
+	case KeyboardInteractiveAuthMethodTOTP:
+		return wrap(c.TOTP.Validate(), "totp")
+	case KeyboardInteractiveAuthMethodWebhook:
+		return wrap(c.Webhook.Validate(), "webhook")
 	default:
 		return newError("method", "BUG: unsupported keyboard-interactive authentication method: %s", c.Method)
 	}
@@ -339,7 +461,8 @@ type KeyboardInteractiveAuthMethod string
 
 // Validate checks if the provided method is valid or not.
 func (m KeyboardInteractiveAuthMethod) Validate() error {
-	if m == KeyboardInteractiveAuthMethodDisabled || m == KeyboardInteractiveAuthMethodOAuth2 {
+	if m == KeyboardInteractiveAuthMethodDisabled || m == KeyboardInteractiveAuthMethodOAuth2 ||
+		m == KeyboardInteractiveAuthMethodTOTP || m == KeyboardInteractiveAuthMethodWebhook {
 		return nil
 	}
 	return fmt.Errorf("invalid value for method for keyboard-interactive authentication: %s", m)
@@ -351,6 +474,31 @@ const KeyboardInteractiveAuthMethodDisabled KeyboardInteractiveAuthMethod = Keyb
 // KeyboardInteractiveAuthMethodOAuth2 authenticates using oAuth2/OIDC.
 const KeyboardInteractiveAuthMethodOAuth2 KeyboardInteractiveAuthMethod = KeyboardInteractiveAuthMethod(AuthMethodOAuth2)
 
+// KeyboardInteractiveAuthMethodTOTP authenticates by asking for a time-based one-time password (RFC 6238).
+const KeyboardInteractiveAuthMethodTOTP KeyboardInteractiveAuthMethod = KeyboardInteractiveAuthMethod(AuthMethodTOTP)
+
+// KeyboardInteractiveAuthMethodWebhook authenticates by relaying the questions of an HTTP auth server to the user.
+const KeyboardInteractiveAuthMethodWebhook KeyboardInteractiveAuthMethod = KeyboardInteractiveAuthMethod(AuthMethodWebhook)
+
+// AuthKeyboardInteractiveWebhookConfig configures the keyboard-interactive webhook authenticator. The auth server
+// receives a request on the /keyboard-interactive path for each round and may respond with further questions until
+// the authentication succeeds or fails.
+type AuthKeyboardInteractiveWebhookConfig struct {
+	AuthWebhookClientConfig `json:",inline" yaml:",inline"`
+
+	// MaxRounds is the maximum number of rounds of questions in a single authentication. The authentication fails if
+	// the auth server sends more.
+	MaxRounds int `json:"maxRounds" yaml:"maxRounds" default:"10"`
+}
+
+// Validate validates the keyboard-interactive webhook configuration.
+func (c *AuthKeyboardInteractiveWebhookConfig) Validate() error {
+	if c.MaxRounds < 1 {
+		return newError("maxRounds", "the maximum number of rounds must be at least 1")
+	}
+	return c.AuthWebhookClientConfig.Validate()
+}
+
 // endregion
 
 // region GSSAPI
@@ -405,6 +553,13 @@ type AuthWebhookClientConfig struct {
 	// AuthTimeout is the timeout for the overall authentication call (e.g. verifying a password). If the server
 	// responds with a non-200 response the call will be retried until this timeout is reached.
 	AuthTimeout time.Duration `json:"authTimeout" yaml:"authTimeout" default:"60s"`
+
+	// Retry configures how failed requests are retried within the AuthTimeout.
+	Retry WebhookRetryConfig `json:"retry" yaml:"retry"`
+	// CircuitBreaker configures failing authentication requests immediately while the server is unhealthy.
+	CircuitBreaker CircuitBreakerConfig `json:"circuitBreaker" yaml:"circuitBreaker"`
+	// Cache configures caching public key authentication responses.
+	Cache WebhookCacheConfig `json:"cache" yaml:"cache"`
 }
 
 // Validate validates the authentication client configuration.
@@ -412,6 +567,15 @@ func (c *AuthWebhookClientConfig) Validate() error {
 	if c.AuthTimeout < 100*time.Millisecond {
 		return newError("timeout", "auth timeout value %s is too low, must be at least 100ms", c.AuthTimeout.String())
 	}
+	if err := c.Retry.Validate(); err != nil {
+		return wrap(err, "retry")
+	}
+	if err := c.CircuitBreaker.Validate(); err != nil {
+		return wrap(err, "circuitBreaker")
+	}
+	if err := c.Cache.Validate(); err != nil {
+		return wrap(err, "cache")
+	}
 	if err := c.HTTPClientConfiguration.Validate(); err != nil {
 		return err
 	}
@@ -420,6 +584,325 @@ func (c *AuthWebhookClientConfig) Validate() error {
 
 // endregion
 
+// region Authorized keys
+
+// AuthAuthorizedKeysConfig configures the authenticator that reads public keys from OpenSSH authorized_keys files.
+type AuthAuthorizedKeysConfig struct {
+	// Path is the path template of the authorized_keys file for each user. The %u token is replaced with the
+	// username, %% is replaced with a literal %. For example: /etc/containerssh/authorized_keys/%u
+	Path string `json:"path" yaml:"path" default:"/etc/containerssh/authorized_keys/%u"`
+
+	// PermitUserEnvironment applies the environment="NAME=value" options of the authorized_keys entries to the
+	// environment of the connection. This mirrors the PermitUserEnvironment option of OpenSSH and is disabled by
+	// default, because the environment can change the behavior of the programs started in the container.
+	PermitUserEnvironment bool `json:"permitUserEnvironment" yaml:"permitUserEnvironment" default:"false"`
+}
+
+// Validate checks the authorized keys configuration.
+func (c *AuthAuthorizedKeysConfig) Validate() error {
+	if c.Path == "" {
+		return newError("path", "the authorized_keys path template cannot be empty")
+	}
+	unescaped := strings.ReplaceAll(c.Path, "%%", "")
+	if !strings.Contains(unescaped, "%u") {
+		return newError("path", "the authorized_keys path template must contain the %%u token for the username")
+	}
+	stripped := strings.ReplaceAll(unescaped, "%u", "")
+	if strings.Contains(stripped, "%") {
+		return newError("path", "the authorized_keys path template contains an unsupported token: %s", c.Path)
+	}
+	return nil
+}
+
+// endregion
+
+// region TOTP
+
+// AuthTOTPConfig configures the authenticator asking the user for a time-based one-time password as described in
+// RFC 6238. The secret of the user is taken from the metadata of the connection first, for example as returned by the
+// auth webhook in an earlier step of an authentication method sequence, then from the secrets file.
+type AuthTOTPConfig struct {
+	// SecretsFile is the path of a file containing the base32-encoded secrets of the users, one username:secret pair
+	// per line. Empty lines and lines starting with # are ignored. The file is read again when it changes.
+	SecretsFile string `json:"secretsFile" yaml:"secretsFile"`
+	// SecretMetadataKey is the metadata key holding the base32-encoded secret of the user. The auth webhook should
+	// mark the value as sensitive. Leave empty to only use the secrets file.
+	SecretMetadataKey string `json:"secretMetadataKey" yaml:"secretMetadataKey" default:"TOTP_SECRET"`
+
+	// Algorithm is the HMAC hash algorithm of the one-time passwords.
+	Algorithm TOTPAlgorithm `json:"algorithm" yaml:"algorithm" default:"sha1"`
+	// Digits is the number of digits of the one-time passwords.
+	Digits int `json:"digits" yaml:"digits" default:"6"`
+	// Period is the length of a time step. A new one-time password is valid in each time step.
+	Period time.Duration `json:"period" yaml:"period" default:"30s"`
+	// Drift is the number of time steps before and after the current one in which the one-time password is also
+	// accepted, to allow for clock differences between the server and the device of the user.
+	Drift uint `json:"drift" yaml:"drift" default:"1"`
+
+	// Instruction is the text displayed to the user before asking for the one-time password.
+	Instruction string `json:"instruction" yaml:"instruction"`
+	// Question is the prompt for the one-time password.
+	Question string `json:"question" yaml:"question" default:"One-time password: "`
+}
+
+// Validate checks the TOTP configuration.
+func (c *AuthTOTPConfig) Validate() error {
+	if c.SecretsFile == "" && c.SecretMetadataKey == "" {
+		return newError("secretsFile", "either the secrets file or the secret metadata key must be set")
+	}
+	if err := c.Algorithm.Validate(); err != nil {
+		return wrap(err, "algorithm")
+	}
+	if c.Digits < 6 || c.Digits > 10 {
+		return newError("digits", "the number of digits must be between 6 and 10: %d", c.Digits)
+	}
+	if c.Period < time.Second {
+		return newError("period", "the period must be at least one second: %s", c.Period)
+	}
+	if c.Drift > 10 {
+		return newError("drift", "the drift cannot be more than 10 time steps: %d", c.Drift)
+	}
+	if c.Question == "" {
+		return newError("question", "the question cannot be empty")
+	}
+	return nil
+}
+
+// TOTPAlgorithm is the HMAC hash algorithm used for generating time-based one-time passwords.
+type TOTPAlgorithm string
+
+// TOTPAlgorithmSHA1 uses HMAC-SHA1. This is the default and the only algorithm most authenticator apps support.
+const TOTPAlgorithmSHA1 TOTPAlgorithm = "sha1"
+
+// TOTPAlgorithmSHA256 uses HMAC-SHA256.
+const TOTPAlgorithmSHA256 TOTPAlgorithm = "sha256"
+
+// TOTPAlgorithmSHA512 uses HMAC-SHA512.
+const TOTPAlgorithmSHA512 TOTPAlgorithm = "sha512"
+
+// Validate checks if the algorithm is supported.
+func (a TOTPAlgorithm) Validate() error {
+	switch a {
+	case TOTPAlgorithmSHA1, TOTPAlgorithmSHA256, TOTPAlgorithmSHA512:
+		return nil
+	default:
+		return fmt.Errorf("invalid TOTP algorithm: %s", a)
+	}
+}
+
+// endregion
+
+// region LDAP
+
+// AuthLDAPConfig configures the authenticator that verifies users against an LDAP directory, such as OpenLDAP or
+// Active Directory. The user is looked up with a search, then the password is verified by binding as the user, or the
+// public key is compared to the public key attribute of the user.
+type AuthLDAPConfig struct {
+	// URL is the address of the LDAP server in the form of ldap://host:port or ldaps://host:port.
+	URL string `json:"url" yaml:"url"`
+	// StartTLS upgrades an ldap:// connection to TLS with the StartTLS operation before sending any credentials.
+	StartTLS bool `json:"startTLS" yaml:"startTLS" default:"false"`
+	// CACert is the PEM-encoded CA certificate, or file containing a PEM-encoded CA certificate used to verify the
+	// LDAP server certificate. If empty, the system certificate pool is used.
+	CACert string `json:"cacert" yaml:"cacert"`
+	// TLSVersion is the minimum TLS version to use for ldaps:// and StartTLS connections.
+	TLSVersion TLSVersion `json:"tlsVersion" yaml:"tlsVersion" default:"1.2"`
+
+	// BindDN is the DN of the service account used to search for users. If empty, the search is anonymous.
+	BindDN string `json:"bindDN" yaml:"bindDN"`
+	// BindPassword is the password of the service account.
+	BindPassword string `json:"bindPassword" yaml:"bindPassword"`
+
+	// BaseDN is the DN the user search starts from. The whole subtree is searched.
+	BaseDN string `json:"baseDN" yaml:"baseDN"`
+	// UserFilter is the search filter template to find the user. The %u token is replaced with the escaped username,
+	// %% is replaced with a literal %. The search must return exactly one entry.
+	UserFilter string `json:"userFilter" yaml:"userFilter" default:"(uid=%u)"`
+
+	// PublicKeyAttribute is the attribute holding the OpenSSH public keys of the user for public key authentication.
+	PublicKeyAttribute string `json:"publicKeyAttribute" yaml:"publicKeyAttribute" default:"sshPublicKey"`
+
+	// GroupAttribute is the attribute of the user holding the group memberships, for example memberOf. Leave empty to
+	// not read the group memberships.
+	GroupAttribute string `json:"groupAttribute" yaml:"groupAttribute" default:"memberOf"`
+	// GroupMetadataKey is the metadata key the group names of the user are stored under, separated by commas. If a
+	// group is given as a DN, the value of its first RDN is used as the name, for example admins for
+	// cn=admins,ou=groups,dc=example,dc=com.
+	GroupMetadataKey string `json:"groupMetadataKey" yaml:"groupMetadataKey" default:"LDAP_GROUPS"`
+	// Metadata maps further attributes of the user to metadata keys. Multiple values are separated by commas.
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+
+	// Timeout is the timeout for connecting to the LDAP server and for each operation.
+	Timeout time.Duration `json:"timeout" yaml:"timeout" default:"10s"`
+}
+
+// Validate checks the LDAP configuration.
+func (c *AuthLDAPConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return wrap(err, "url")
+	}
+	switch u.Scheme {
+	case "ldap":
+	case "ldaps":
+		if c.StartTLS {
+			return newError("startTLS", "StartTLS cannot be used with an ldaps:// URL")
+		}
+	default:
+		return newError("url", "the LDAP URL must start with ldap:// or ldaps://: %s", c.URL)
+	}
+	if u.Hostname() == "" {
+		return newError("url", "the LDAP URL must contain a host name: %s", c.URL)
+	}
+	if err := c.TLSVersion.Validate(); err != nil {
+		return wrap(err, "tlsVersion")
+	}
+	if _, err := c.CACertPool(); err != nil {
+		return wrap(err, "cacert")
+	}
+	if c.BindDN != "" && c.BindPassword == "" {
+		return newError("bindPassword", "the bind password cannot be empty if a bind DN is set")
+	}
+	if c.BaseDN == "" {
+		return newError("baseDN", "the base DN cannot be empty")
+	}
+	if err := c.validateUserFilter(); err != nil {
+		return wrap(err, "userFilter")
+	}
+	if c.GroupAttribute != "" && c.GroupMetadataKey == "" {
+		return newError("groupMetadataKey", "the group metadata key cannot be empty if a group attribute is set")
+	}
+	if c.Timeout <= 0 {
+		return newError("timeout", "the timeout must be positive")
+	}
+	return nil
+}
+
+func (c *AuthLDAPConfig) validateUserFilter() error {
+	unescaped := strings.ReplaceAll(c.UserFilter, "%%", "")
+	if !strings.Contains(unescaped, "%u") {
+		return fmt.Errorf("the user filter must contain the %%u token for the username")
+	}
+	if strings.Contains(strings.ReplaceAll(unescaped, "%u", ""), "%") {
+		return fmt.Errorf("the user filter contains an unsupported token: %s", c.UserFilter)
+	}
+	if _, err := ldap.CompileFilter(c.UserFilterFor("user")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UserFilterFor returns the user filter with the tokens replaced for the given username.
+func (c *AuthLDAPConfig) UserFilterFor(username string) string {
+	result := strings.Builder{}
+	tpl := c.UserFilter
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] == '%' && i+1 < len(tpl) {
+			switch tpl[i+1] {
+			case 'u':
+				result.WriteString(ldap.EscapeFilter(username))
+				i++
+				continue
+			case '%':
+				result.WriteByte('%')
+				i++
+				continue
+			}
+		}
+		result.WriteByte(tpl[i])
+	}
+	return result.String()
+}
+
+// CACertPool returns the certificate pool to verify the LDAP server with. It returns nil if the system pool should be
+// used.
+func (c *AuthLDAPConfig) CACertPool() (*x509.CertPool, error) {
+	if strings.TrimSpace(c.CACert) == "" {
+		return nil, nil
+	}
+	caCert, err := loadPEM(c.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA certificate (%w)", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("invalid CA certificate provided")
+	}
+	return pool, nil
+}
+
+// endregion
+
+// region User certificates
+
+// AuthUserCertificateConfig configures the validation of OpenSSH user certificates against trusted certificate
+// authorities.
+type AuthUserCertificateConfig struct {
+	// TrustedCAKeys is a list of certificate authority public keys in the authorized_keys format, or files to load
+	// them from. Certificates signed by these keys are accepted if they are valid for the username. Leave empty to
+	// disable certificate validation.
+	TrustedCAKeys []string `json:"trustedCAKeys" yaml:"trustedCAKeys"`
+
+	// RevokedKeys is the path to a file containing revoked keys and certificates. The file can either be an OpenSSH
+	// key revocation list (KRL) as generated by ssh-keygen -k, or a list of public keys. The file is reloaded when it
+	// changes. Revoked keys are rejected even if they are not certificates. If the file cannot be parsed, all
+	// certificate authentications fail. The signature section of a KRL is not verified, the file is trusted as
+	// configured and should be protected with file permissions.
+	RevokedKeys string `json:"revokedKeys" yaml:"revokedKeys"`
+}
+
+// Enabled returns true if at least one trusted CA key is configured.
+func (c AuthUserCertificateConfig) Enabled() bool {
+	return len(c.TrustedCAKeys) > 0
+}
+
+// Validate checks the user certificate configuration.
+func (c AuthUserCertificateConfig) Validate() error {
+	for i, key := range c.TrustedCAKeys {
+		if strings.TrimSpace(key) == "" {
+			return newError(fmt.Sprintf("trustedCAKeys[%d]", i), "the trusted CA key cannot be empty")
+		}
+	}
+	if c.RevokedKeys != "" && !c.Enabled() {
+		return newError("revokedKeys", "revokedKeys requires at least one trusted CA key")
+	}
+	return nil
+}
+
+// LoadTrustedCAKeys loads the trusted CA keys, reading them from files where needed.
+func (c AuthUserCertificateConfig) LoadTrustedCAKeys() ([]ssh.PublicKey, error) {
+	var result []ssh.PublicKey
+	for _, key := range c.TrustedCAKeys {
+		data := []byte(key)
+		if _, _, _, _, err := ssh.ParseAuthorizedKey(data); err != nil {
+			// We are deliberately loading a dynamic file here.
+			data, err = os.ReadFile(key) //nolint:gosec
+			if err != nil {
+				return nil, fmt.Errorf("failed to load trusted CA key %s (%w)", key, err)
+			}
+		}
+		found := false
+		for rest := data; len(rest) > 0; {
+			var (
+				caKey ssh.PublicKey
+				err   error
+			)
+			caKey, _, _, rest, err = ssh.ParseAuthorizedKey(rest)
+			if err != nil {
+				break
+			}
+			result = append(result, caKey)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no valid public keys found in trusted CA key %s", key)
+		}
+	}
+	return result, nil
+}
+
+// endregion
+
 // region oAuth2
 
 // AuthOAuth2ClientConfig is the configuration for OAuth2-based authentication.
@@ -673,6 +1156,12 @@ type AuthzConfig struct {
 	Method AuthzMethod `json:"method" yaml:"method" default:""`
 
 	Webhook AuthWebhookClientConfig `json:"webhook" yaml:"webhook"`
+
+	// Rules are the rules of the rules authorization method. The rules are evaluated in order, the first matching
+	// rule decides if the user is allowed to log in.
+	Rules []AuthzRule `json:"rules" yaml:"rules"`
+	// DefaultAction is the decision of the rules authorization method if no rule matches.
+	DefaultAction AuthzAction `json:"defaultAction" yaml:"defaultAction" default:"deny"`
 }
 
 // Validate validates the authorization configuration.
@@ -685,6 +1174,16 @@ func (k *AuthzConfig) Validate() error {
 		return nil
 	case AuthzMethodWebhook:
 		return wrap(k.Webhook.Validate(), "webhook")
+	case AuthzMethodRules:
+		if err := k.DefaultAction.Validate(); err != nil {
+			return wrap(err, "defaultAction")
+		}
+		for i, rule := range k.Rules {
+			if err := rule.Validate(); err != nil {
+				return wrap(err, fmt.Sprintf("rules[%d]", i))
+			}
+		}
+		return nil
 	default:
 		return newError("method", "BUG: invalid value for method for authorization: %s", k.Method)
 	}
@@ -695,7 +1194,7 @@ type AuthzMethod string
 
 // Validate checks if the provided method is valid or not.
 func (m AuthzMethod) Validate() error {
-	if m == AuthzMethodDisabled || m == AuthzMethodWebhook {
+	if m == AuthzMethodDisabled || m == AuthzMethodWebhook || m == AuthzMethodRules {
 		return nil
 	}
 	return fmt.Errorf("invalid value for method for authorization: %s", m)
@@ -707,6 +1206,116 @@ const AuthzMethodDisabled AuthzMethod = AuthzMethod(AuthMethodDisabled)
 // AuthzMethodWebhook authorizes users using HTTP webhooks.
 const AuthzMethodWebhook AuthzMethod = AuthzMethod(AuthMethodWebhook)
 
+// AuthzMethodRules authorizes users using the rules in the configuration.
+const AuthzMethodRules AuthzMethod = "rules"
+
+// AuthzRule is a single rule of the rules authorization method. A rule matches if all of its conditions match. A
+// condition with a list of values matches if any of the values matches, an empty condition matches everything.
+type AuthzRule struct {
+	// Name identifies the rule in the logs and in the audit log.
+	Name string `json:"name" yaml:"name"`
+	// Action is the decision if the rule matches.
+	Action AuthzAction `json:"action" yaml:"action"`
+
+	// AuthenticatedUsernames are glob patterns matching the username verified by the authentication, for example the
+	// username from the OAuth2 or Kerberos login.
+	AuthenticatedUsernames []string `json:"authenticatedUsernames" yaml:"authenticatedUsernames"`
+	// Usernames are glob patterns matching the username the user provided to log in as.
+	Usernames []string `json:"usernames" yaml:"usernames"`
+	// SameUsername only matches if the login username is the same as the authenticated username.
+	SameUsername bool `json:"sameUsername" yaml:"sameUsername"`
+	// RemoteAddresses are the CIDR ranges or IP addresses matching the address of the client.
+	RemoteAddresses []string `json:"remoteAddresses" yaml:"remoteAddresses"`
+	// Countries are the ISO 3166-1 alpha-2 country codes matching the GeoIP country of the client. XX matches
+	// clients whose country cannot be determined.
+	Countries []string `json:"countries" yaml:"countries"`
+	// AuthMethods matches if the user authenticated with any of these SSH authentication methods.
+	AuthMethods []SSHAuthMethod `json:"authMethods" yaml:"authMethods"`
+	// Metadata maps metadata keys to glob patterns. A key matches if any of its comma-separated values matches any
+	// of the patterns, for example LDAP_GROUPS: [admins].
+	Metadata map[string][]string `json:"metadata" yaml:"metadata"`
+}
+
+// Validate checks the authorization rule.
+func (r AuthzRule) Validate() error {
+	if err := r.Action.Validate(); err != nil {
+		return wrap(err, "action")
+	}
+	if err := validateGlobs(r.AuthenticatedUsernames); err != nil {
+		return wrap(err, "authenticatedUsernames")
+	}
+	if err := validateGlobs(r.Usernames); err != nil {
+		return wrap(err, "usernames")
+	}
+	for _, remoteAddress := range r.RemoteAddresses {
+		if _, err := ParseCIDROrIP(remoteAddress); err != nil {
+			return wrap(err, "remoteAddresses")
+		}
+	}
+	for _, country := range r.Countries {
+		if len(country) != 2 || strings.ToUpper(country) != country {
+			return newError("countries", "invalid country code, expected two uppercase letters: %s", country)
+		}
+	}
+	for _, method := range r.AuthMethods {
+		if err := method.Validate(); err != nil {
+			return wrap(err, "authMethods")
+		}
+	}
+	for key, patterns := range r.Metadata {
+		if key == "" {
+			return newError("metadata", "the metadata key cannot be empty")
+		}
+		if err := validateGlobs(patterns); err != nil {
+			return wrap(err, "metadata."+key)
+		}
+	}
+	return nil
+}
+
+// ParseCIDROrIP parses a CIDR range or a single IP address. A single IP address is returned as a range containing
+// only that address.
+func ParseCIDROrIP(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, ipNet, err := net.ParseCIDR(value)
+		return ipNet, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address or CIDR range: %s", value)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s (%w)", pattern, err)
+		}
+	}
+	return nil
+}
+
+// AuthzAction is the decision of an authorization rule.
+type AuthzAction string
+
+// AuthzActionAllow allows the user to log in.
+const AuthzActionAllow AuthzAction = "allow"
+
+// AuthzActionDeny rejects the user.
+const AuthzActionDeny AuthzAction = "deny"
+
+// Validate checks if the action is valid.
+func (a AuthzAction) Validate() error {
+	if a == AuthzActionAllow || a == AuthzActionDeny {
+		return nil
+	}
+	return fmt.Errorf("invalid authorization action, must be allow or deny: %s", a)
+}
+
 // endregion
 
 // region Kerberos
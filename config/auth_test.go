@@ -0,0 +1,110 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestAuthMethodSequences(t *testing.T) {
+	newConfig := func(authMethods ...[]config.SSHAuthMethod) config.AuthConfig {
+		cfg := config.AuthConfig{}
+		structutils.Defaults(&cfg)
+		cfg.PasswordAuth.Method = config.PasswordAuthMethodWebhook
+		cfg.PasswordAuth.Webhook.URL = "http://127.0.0.1:8080"
+		cfg.PublicKeyAuth.Method = config.PubKeyAuthMethodWebhook
+		cfg.PublicKeyAuth.Webhook.URL = "http://127.0.0.1:8080"
+		cfg.AuthMethods = authMethods
+		return cfg
+	}
+
+	cfg := newConfig(
+		[]config.SSHAuthMethod{config.SSHAuthMethodPublicKey, config.SSHAuthMethodPassword},
+		[]config.SSHAuthMethod{config.SSHAuthMethodPassword},
+	)
+	assert.NoError(t, cfg.Validate())
+
+	for name, sequence := range map[string][]config.SSHAuthMethod{
+		"empty":          {},
+		"invalid":        {"foo"},
+		"gssapi":         {config.SSHAuthMethodPublicKey, config.SSHAuthMethodGSSAPI},
+		"not configured": {config.SSHAuthMethodPublicKey, config.SSHAuthMethodKeyboardInteractive},
+		"repeated":       {config.SSHAuthMethodPassword, config.SSHAuthMethodPassword},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig(sequence)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestTOTPConfig(t *testing.T) {
+	cfg := config.KeyboardInteractiveAuthConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Method = config.KeyboardInteractiveAuthMethodTOTP
+	assert.NoError(t, cfg.Validate())
+
+	cfg.TOTP.SecretMetadataKey = ""
+	assert.Error(t, cfg.Validate(), "a TOTP source must be configured")
+	cfg.TOTP.SecretsFile = "/etc/containerssh/totp"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.TOTP.Algorithm = "md5"
+	assert.Error(t, cfg.Validate())
+	cfg.TOTP.Algorithm = config.TOTPAlgorithmSHA256
+	cfg.TOTP.Digits = 4
+	assert.Error(t, cfg.Validate())
+}
+
+func TestKeyboardInteractiveWebhookConfig(t *testing.T) {
+	cfg := config.KeyboardInteractiveAuthConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Method = config.KeyboardInteractiveAuthMethodWebhook
+	cfg.Webhook.URL = "http://127.0.0.1:8080"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Webhook.MaxRounds = 0
+	assert.Error(t, cfg.Validate())
+}
+
+func TestAuthzRulesConfig(t *testing.T) {
+	cfg := config.AuthzConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Method = config.AuthzMethodRules
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, config.AuthzActionDeny, cfg.DefaultAction)
+
+	cfg.Rules = []config.AuthzRule{
+		{
+			Name:            "admins",
+			Action:          config.AuthzActionAllow,
+			Usernames:       []string{"*"},
+			RemoteAddresses: []string{"10.0.0.0/8", "192.168.0.1", "::1"},
+			Countries:       []string{"DE"},
+			AuthMethods:     []config.SSHAuthMethod{config.SSHAuthMethodPublicKey},
+			Metadata:        map[string][]string{"LDAP_GROUPS": {"admins"}},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	for name, modify := range map[string]func(rule *config.AuthzRule){
+		"action":         func(rule *config.AuthzRule) { rule.Action = "maybe" },
+		"pattern":        func(rule *config.AuthzRule) { rule.Usernames = []string{"["} },
+		"remote address": func(rule *config.AuthzRule) { rule.RemoteAddresses = []string{"10.0.0.0/33"} },
+		"country":        func(rule *config.AuthzRule) { rule.Countries = []string{"de"} },
+		"auth method":    func(rule *config.AuthzRule) { rule.AuthMethods = []config.SSHAuthMethod{"foo"} },
+		"metadata key":   func(rule *config.AuthzRule) { rule.Metadata = map[string][]string{"": {"admins"}} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := cfg
+			invalid.Rules = []config.AuthzRule{cfg.Rules[0]}
+			modify(&invalid.Rules[0])
+			assert.Error(t, invalid.Validate())
+		})
+	}
+
+	cfg.DefaultAction = ""
+	assert.Error(t, cfg.Validate())
+}
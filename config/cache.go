@@ -0,0 +1,42 @@
+package config
+
+import (
+	"time"
+)
+
+// WebhookCacheConfig configures caching the responses of a webhook server. Public key authentication responses are
+// cached by username, public key fingerprint and remote address, configuration responses additionally by the
+// authenticated username.
+type WebhookCacheConfig struct {
+	// Enable enables the cache.
+	Enable bool `json:"enable" yaml:"enable" default:"false"`
+	// PositiveTTL is the time successful responses are cached for.
+	PositiveTTL time.Duration `json:"positiveTTL" yaml:"positiveTTL" default:"1m"`
+	// NegativeTTL is the time failed authentications are cached for. Configuration responses are never negative.
+	NegativeTTL time.Duration `json:"negativeTTL" yaml:"negativeTTL" default:"10s"`
+	// StaleIfError is the time after expiry a cached configuration response is still served for if the
+	// configuration server is unavailable. 0 disables serving stale responses. It has no effect on authentication.
+	StaleIfError time.Duration `json:"staleIfError" yaml:"staleIfError" default:"0"`
+	// MaxEntries is the maximum number of cached responses. The entries closest to expiry are removed first.
+	MaxEntries int `json:"maxEntries" yaml:"maxEntries" default:"10000"`
+}
+
+// Validate checks the cache configuration.
+func (c WebhookCacheConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.PositiveTTL < 0 {
+		return newError("positiveTTL", "the positive TTL cannot be negative: %s", c.PositiveTTL)
+	}
+	if c.NegativeTTL < 0 {
+		return newError("negativeTTL", "the negative TTL cannot be negative: %s", c.NegativeTTL)
+	}
+	if c.StaleIfError < 0 {
+		return newError("staleIfError", "the stale-if-error time cannot be negative: %s", c.StaleIfError)
+	}
+	if c.MaxEntries < 1 {
+		return newError("maxEntries", "the maximum number of entries must be at least 1: %d", c.MaxEntries)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestWebhookCacheConfig(t *testing.T) {
+	cfg := config.ClientConfig{}
+	structutils.Defaults(&cfg)
+	cfg.URL = "http://127.0.0.1:8080"
+	assert.NoError(t, cfg.Validate())
+	assert.False(t, cfg.Cache.Enable)
+	assert.Equal(t, time.Minute, cfg.Cache.PositiveTTL)
+
+	cfg.Cache.Enable = true
+	assert.NoError(t, cfg.Validate())
+	cfg.Cache.NegativeTTL = -time.Second
+	assert.Error(t, cfg.Validate())
+	cfg.Cache.NegativeTTL = 0
+	cfg.Cache.MaxEntries = 0
+	assert.Error(t, cfg.Validate())
+}
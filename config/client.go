@@ -7,6 +7,13 @@ type ClientConfig struct {
 	// TransmitSensitiveMetadata enables sending sensitive metadata fields to the configuration webhook server.
 	// If disabled, sensitive metadata fields are sanitized from the webhook request.
 	TransmitSensitiveMetadata bool `json:"transmitSensitiveMetadata" yaml:"transmitSensitiveMetadata"`
+
+	// Retry configures how failed requests are retried.
+	Retry WebhookRetryConfig `json:"retry" yaml:"retry"`
+	// CircuitBreaker configures failing configuration requests immediately while the server is unhealthy.
+	CircuitBreaker CircuitBreakerConfig `json:"circuitBreaker" yaml:"circuitBreaker"`
+	// Cache configures caching configuration responses.
+	Cache WebhookCacheConfig `json:"cache" yaml:"cache"`
 }
 
 // Validate validates the client configuration.
@@ -14,5 +21,14 @@ func (c *ClientConfig) Validate() error {
 	if c.HTTPClientConfiguration.URL == "" {
 		return nil
 	}
+	if err := c.Retry.Validate(); err != nil {
+		return wrap(err, "retry")
+	}
+	if err := c.CircuitBreaker.Validate(); err != nil {
+		return wrap(err, "circuitBreaker")
+	}
+	if err := c.Cache.Validate(); err != nil {
+		return wrap(err, "cache")
+	}
 	return c.HTTPClientConfiguration.Validate()
 }
@@ -5,7 +5,6 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"net"
 	"net/url"
 	"os"
 	"strings"
@@ -314,6 +313,31 @@ type HTTPClientConfiguration struct {
 
 	// RequestEncoding is the means by which the request body is encoded. It defaults to JSON encoding.
 	RequestEncoding RequestEncoding `json:"-" yaml:"-"`
+
+	// Signature configures signing the requests with a shared secret.
+	Signature HTTPSignatureConfig `json:"signature" yaml:"signature"`
+}
+
+// HTTPSignatureConfig configures HMAC signatures on webhook requests. The client signs every request with the shared
+// secret and the webhook server rejects requests that are not signed, are too old, or have been sent before.
+type HTTPSignatureConfig struct {
+	// Secret is the shared secret the signatures are calculated with. Signatures are disabled if it is empty.
+	Secret string `json:"secret" yaml:"secret"`
+	// MaxAge is the largest difference between the timestamp of a request and the clock of the server the server
+	// accepts. Nonces are remembered for this long to reject replayed requests. 0 uses the default of 5 minutes. It
+	// has no effect on the client.
+	MaxAge time.Duration `json:"maxAge" yaml:"maxAge" default:"5m"`
+}
+
+// Validate checks the signature configuration.
+func (c HTTPSignatureConfig) Validate() error {
+	if c.Secret == "" {
+		return nil
+	}
+	if c.MaxAge < 0 {
+		return newError("maxAge", "the maximum age cannot be negative: %s", c.MaxAge)
+	}
+	return nil
 }
 
 // HTTPClientCerts is a structure that holds the client certificates after successfully calling ValidateWithCerts
@@ -350,6 +374,10 @@ func (c *HTTPClientConfiguration) ValidateWithCerts() (*HTTPClientCerts, error)
 		return nil, err
 	}
 
+	if err := c.Signature.Validate(); err != nil {
+		return nil, wrap(err, "signature")
+	}
+
 	if strings.HasPrefix(c.URL, "https://") {
 		if err := c.TLSVersion.Validate(); err != nil {
 			return nil, wrap(err, "tlsVersion")
@@ -418,8 +446,12 @@ func (c *HTTPClientConfiguration) validateCACert(certs *HTTPClientCerts) (err er
 //
 //goland:noinspection GoVetStructTag
 type HTTPServerConfiguration struct {
-	// Listen contains the IP and port to listen on.
+	// Listen contains the IP and port to listen on. Alternatively, unix:/path/to/socket listens on a Unix domain socket
+	// and systemd:name uses a socket passed by systemd socket activation.
 	Listen string `json:"listen" yaml:"listen" default:"0.0.0.0:8080"`
+	// SocketMode is the file mode of the Unix domain socket in octal notation, for example 0660. It only applies
+	// when listening on a Unix domain socket.
+	SocketMode UnixSocketMode `json:"socketMode" yaml:"socketMode"`
 	// Key contains either a file name to a private key, or the private key itself in PEM format to use as a server key.
 	Key string `json:"key" yaml:"key"`
 	// Cert contains either a file to a certificate, or the certificate itself in PEM format to use as a server
@@ -436,6 +468,10 @@ type HTTPServerConfiguration struct {
 
 	// CipherSuites is a list of supported cipher suites.
 	CipherSuites CipherSuiteList `json:"cipher" yaml:"cipher" default:"[\"TLS_AES_128_GCM_SHA256\",\"TLS_AES_256_GCM_SHA384\",\"TLS_CHACHA20_POLY1305_SHA256\",\"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256\",\"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256\"]"`
+
+	// Signature configures verifying the signatures of incoming requests. It is only used by the authentication and
+	// configuration webhook servers.
+	Signature HTTPSignatureConfig `json:"signature" yaml:"signature"`
 }
 
 func (config *HTTPServerConfiguration) Validate() error {
@@ -456,9 +492,15 @@ func (config *HTTPServerConfiguration) ValidateWithCerts() (*HTTPServerCerts, er
 	if config.Listen == "" {
 		return nil, fmt.Errorf("no listen address provided")
 	}
-	if _, _, err := net.SplitHostPort(config.Listen); err != nil {
+	if _, _, err := ParseListenAddress(config.Listen); err != nil {
 		return nil, fmt.Errorf("invalid listen address provided (%w)", err)
 	}
+	if err := config.SocketMode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Signature.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid signature configuration (%w)", err)
+	}
 	if config.Cert != "" && config.Key == "" {
 		return nil, fmt.Errorf("certificate provided without a key")
 	}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ListenNetwork is the type of socket a server listens on.
+type ListenNetwork string
+
+const (
+	// ListenNetworkTCP listens on a TCP address in the host:port format.
+	ListenNetworkTCP ListenNetwork = "tcp"
+	// ListenNetworkUnix listens on a Unix domain socket. The listen address has the format unix:/path/to/socket.
+	ListenNetworkUnix ListenNetwork = "unix"
+	// ListenNetworkSystemd uses a socket passed by systemd socket activation (LISTEN_FDS). The listen address has the
+	// format systemd:name, where name is either the FileDescriptorName of the socket unit or the zero-based index of
+	// the passed socket.
+	ListenNetworkSystemd ListenNetwork = "systemd"
+)
+
+// ParseListenAddress splits a listen address into the network type and the address within that network.
+func ParseListenAddress(listen string) (ListenNetwork, string, error) {
+	for _, network := range []ListenNetwork{ListenNetworkUnix, ListenNetworkSystemd} {
+		prefix := string(network) + ":"
+		if strings.HasPrefix(listen, prefix) {
+			address := strings.TrimPrefix(listen, prefix)
+			if address == "" {
+				return "", "", fmt.Errorf("empty %s listen address: %s", network, listen)
+			}
+			return network, address, nil
+		}
+	}
+	if _, _, err := net.SplitHostPort(listen); err != nil {
+		return "", "", fmt.Errorf("invalid listen address: %s (%w)", listen, err)
+	}
+	return ListenNetworkTCP, listen, nil
+}
+
+// UnixSocketMode is the file mode of a Unix domain socket in octal notation, for example 0660. Clients need write
+// permission on the socket to connect. If empty, the mode is determined by the umask of the process.
+type UnixSocketMode string
+
+// Validate checks if the socket mode is a valid octal file mode.
+func (m UnixSocketMode) Validate() error {
+	_, err := m.FileMode()
+	return err
+}
+
+// FileMode returns the socket mode as a file mode. It returns 0 if no mode is set.
+func (m UnixSocketMode) FileMode() (os.FileMode, error) {
+	if m == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(string(m), 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket mode: %s", m)
+	}
+	return os.FileMode(mode), nil
+}
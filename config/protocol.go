@@ -21,6 +21,27 @@ type ResponseBody struct {
 	//
 	// required: true
 	Config AppConfig `json:"config"`
+
+	// Cache controls how long ContainerSSH caches the response if caching is enabled.
+	//
+	// required: false
+	Cache *CacheControl `json:"cache,omitempty"`
+}
+
+// CacheControl lets a webhook server override how long ContainerSSH caches a response, similar to the Cache-Control
+// HTTP header. It has no effect if caching is not enabled.
+//
+// swagger:model CacheControl
+type CacheControl struct {
+	// NoStore prevents caching the response.
+	//
+	// required: false
+	NoStore bool `json:"noStore,omitempty"`
+
+	// MaxAge is the number of seconds the response is cached for, overriding the configured TTL.
+	//
+	// required: false
+	MaxAge *int `json:"maxAge,omitempty"`
 }
 
 // Response is the entire response from the config server
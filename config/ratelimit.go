@@ -0,0 +1,101 @@
+package config
+
+import (
+	"time"
+)
+
+// RateLimitConfig configures the connection and authentication rate limits as well as the temporary bans for source
+// IP addresses that repeatedly fail to authenticate.
+type RateLimitConfig struct {
+	// Enable enables the rate limiting layer.
+	Enable bool `json:"enable" yaml:"enable" default:"false"`
+	// Global limits the rate of new connections across all clients.
+	Global RateLimitBucketConfig `json:"global" yaml:"global"`
+	// PerIP limits the rate of new connections from a single source IP address.
+	PerIP RateLimitBucketConfig `json:"perip" yaml:"perip"`
+	// PerUser limits the rate of authentication attempts for a single username, regardless of the source IP address.
+	// This protects the authentication backend from credential stuffing from many addresses.
+	PerUser RateLimitBucketConfig `json:"peruser" yaml:"peruser"`
+	// Ban configures temporary bans of source IP addresses after repeated authentication failures.
+	Ban RateLimitBanConfig `json:"ban" yaml:"ban"`
+}
+
+// Validate validates the rate limit configuration.
+func (c RateLimitConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if err := c.Global.Validate(); err != nil {
+		return wrap(err, "global")
+	}
+	if err := c.PerIP.Validate(); err != nil {
+		return wrap(err, "perip")
+	}
+	if err := c.PerUser.Validate(); err != nil {
+		return wrap(err, "peruser")
+	}
+	if err := c.Ban.Validate(); err != nil {
+		return wrap(err, "ban")
+	}
+	return nil
+}
+
+// RateLimitBucketConfig configures a token bucket. Each event takes one token from the bucket, and the bucket is
+// refilled at the configured rate up to the burst size. Events are rejected while the bucket is empty.
+type RateLimitBucketConfig struct {
+	// Rate is the number of tokens added to the bucket per second. 0 disables this limit.
+	Rate float64 `json:"rate" yaml:"rate" default:"0"`
+	// Burst is the maximum number of tokens in the bucket. If left at 0 and a rate is set, the burst is rounded up
+	// from the rate.
+	Burst uint `json:"burst" yaml:"burst" default:"0"`
+}
+
+// Enabled returns true if the bucket limits the rate of events.
+func (c RateLimitBucketConfig) Enabled() bool {
+	return c.Rate > 0
+}
+
+// Validate validates the token bucket configuration.
+func (c RateLimitBucketConfig) Validate() error {
+	if c.Rate < 0 {
+		return newError("rate", "the rate cannot be negative")
+	}
+	if c.Rate == 0 && c.Burst > 0 {
+		return newError("burst", "a burst is configured but no rate is set")
+	}
+	return nil
+}
+
+// RateLimitBanConfig configures how source IP addresses are banned after failed authentication attempts.
+type RateLimitBanConfig struct {
+	// MaxFailures is the number of failed authentication attempts within the window after which the source IP address
+	// is banned. 0 disables banning.
+	MaxFailures uint `json:"maxFailures" yaml:"maxFailures" default:"0"`
+	// MaxPublicKeys is the number of distinct public keys a client may offer on a single connection before further
+	// rejected keys count as failed authentication attempts. Clients routinely offer several keys before finding the
+	// right one, so the first rejected keys are not counted. 0 means public key failures are never counted.
+	MaxPublicKeys uint `json:"maxPublicKeys" yaml:"maxPublicKeys" default:"6"`
+	// Window is the time period in which failed attempts are counted.
+	Window time.Duration `json:"window" yaml:"window" default:"5m"`
+	// Duration is how long the source IP address stays banned.
+	Duration time.Duration `json:"duration" yaml:"duration" default:"15m"`
+}
+
+// Enabled returns true if source IP addresses should be banned after failed authentication attempts.
+func (c RateLimitBanConfig) Enabled() bool {
+	return c.MaxFailures > 0
+}
+
+// Validate validates the ban configuration.
+func (c RateLimitBanConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Window <= 0 {
+		return newError("window", "the failure window must be positive")
+	}
+	if c.Duration <= 0 {
+		return newError("duration", "the ban duration must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"time"
+)
+
+// RestartConfig configures the graceful restart. On a graceful restart, triggered by the SIGUSR2 signal, a new
+// ContainerSSH process is started that inherits the listening sockets, while the old process stops accepting
+// connections and waits for the existing connections to end.
+type RestartConfig struct {
+	// ReadyTimeout is the time to wait for the new process to start up. If the new process is not ready in this time
+	// the restart is aborted and the old process keeps running.
+	ReadyTimeout time.Duration `json:"readyTimeout" yaml:"readyTimeout" default:"60s"`
+	// DrainTimeout is the time the old process waits for the existing connections to end after the new process is
+	// ready. Connections that are still open when it expires are closed.
+	DrainTimeout time.Duration `json:"drainTimeout" yaml:"drainTimeout" default:"1h"`
+}
+
+// Validate validates the graceful restart configuration.
+func (c RestartConfig) Validate() error {
+	if c.ReadyTimeout <= 0 {
+		return newError("readyTimeout", "the ready timeout must be positive")
+	}
+	if c.DrainTimeout <= 0 {
+		return newError("drainTimeout", "the drain timeout must be positive")
+	}
+	return nil
+}
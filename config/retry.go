@@ -0,0 +1,76 @@
+package config
+
+import (
+	"time"
+)
+
+// WebhookRetryConfig configures how failed webhook requests are retried. If none of the options are set, for example
+// because the configuration has been created in code, the defaults apply.
+type WebhookRetryConfig struct {
+	// MaxAttempts is the maximum number of attempts for a single request, including the first one. 0 retries until
+	// the timeout of the request is reached.
+	MaxAttempts int `json:"maxAttempts" yaml:"maxAttempts" default:"0"`
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration `json:"initialBackoff" yaml:"initialBackoff" default:"500ms"`
+	// MaxBackoff is the longest time to wait between two attempts.
+	MaxBackoff time.Duration `json:"maxBackoff" yaml:"maxBackoff" default:"10s"`
+	// Multiplier is the factor the wait time grows by after each retry.
+	Multiplier float64 `json:"multiplier" yaml:"multiplier" default:"2"`
+	// Jitter is the fraction of the wait time that is randomized to spread out the retries of concurrent requests.
+	// 0 disables the randomization, 1 waits anywhere between 0 and twice the calculated time.
+	Jitter float64 `json:"jitter" yaml:"jitter" default:"0.2"`
+}
+
+// Validate checks the retry configuration.
+func (c WebhookRetryConfig) Validate() error {
+	if c == (WebhookRetryConfig{}) {
+		return nil
+	}
+	if c.MaxAttempts < 0 {
+		return newError("maxAttempts", "the maximum number of attempts cannot be negative: %d", c.MaxAttempts)
+	}
+	if c.InitialBackoff < 0 {
+		return newError("initialBackoff", "the initial backoff cannot be negative: %s", c.InitialBackoff)
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		return newError(
+			"maxBackoff",
+			"the maximum backoff %s cannot be less than the initial backoff %s",
+			c.MaxBackoff,
+			c.InitialBackoff,
+		)
+	}
+	if c.Multiplier < 1 {
+		return newError("multiplier", "the multiplier must be at least 1: %f", c.Multiplier)
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return newError("jitter", "the jitter must be between 0 and 1: %f", c.Jitter)
+	}
+	return nil
+}
+
+// CircuitBreakerConfig configures the circuit breaker of a webhook client. After a number of consecutive failed
+// requests the circuit breaker opens and requests fail immediately instead of waiting for an unhealthy server. After
+// the open duration a single trial request is let through, which closes the circuit breaker if it succeeds.
+type CircuitBreakerConfig struct {
+	// Enable enables the circuit breaker.
+	Enable bool `json:"enable" yaml:"enable" default:"false"`
+	// FailureThreshold is the number of consecutive failed requests that open the circuit breaker.
+	FailureThreshold int `json:"failureThreshold" yaml:"failureThreshold" default:"5"`
+	// OpenDuration is the time the circuit breaker stays open before a trial request is let through.
+	OpenDuration time.Duration `json:"openDuration" yaml:"openDuration" default:"30s"`
+}
+
+// Validate checks the circuit breaker configuration.
+func (c CircuitBreakerConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.FailureThreshold < 1 {
+		return newError("failureThreshold", "the failure threshold must be at least 1: %d", c.FailureThreshold)
+	}
+	if c.OpenDuration <= 0 {
+		return newError("openDuration", "the open duration must be positive: %s", c.OpenDuration)
+	}
+	return nil
+}
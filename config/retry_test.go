@@ -0,0 +1,32 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestWebhookRetryConfig(t *testing.T) {
+	cfg := config.AuthWebhookClientConfig{}
+	structutils.Defaults(&cfg)
+	cfg.URL = "http://127.0.0.1:8080"
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 500*time.Millisecond, cfg.Retry.InitialBackoff)
+	assert.False(t, cfg.CircuitBreaker.Enable)
+
+	cfg.Retry.MaxBackoff = 100 * time.Millisecond
+	assert.Error(t, cfg.Validate())
+	cfg.Retry.MaxBackoff = 10 * time.Second
+	cfg.Retry.Jitter = 1.5
+	assert.Error(t, cfg.Validate())
+	cfg.Retry = config.WebhookRetryConfig{}
+	assert.NoError(t, cfg.Validate(), "an empty retry configuration should use the defaults")
+
+	cfg.CircuitBreaker.Enable = true
+	assert.NoError(t, cfg.Validate())
+	cfg.CircuitBreaker.FailureThreshold = 0
+	assert.Error(t, cfg.Validate())
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // SecurityConfig is the configuration structure for security settings.
@@ -38,6 +42,30 @@ type SecurityConfig struct {
 	// MaxSessions drives how many session channels can be open at the same time for a single network connection.
 	// -1 means unlimited. It is strongly recommended to configure this to a sane value, e.g. 10.
 	MaxSessions int `json:"maxSessions" yaml:"maxSessions" default:"-1"`
+
+	// MaxForwardChannels limits how many port and socket forwarding channels can be open at the same time for a
+	// single network connection. -1 means unlimited.
+	MaxForwardChannels int `json:"maxForwardChannels" yaml:"maxForwardChannels" default:"-1"`
+
+	// MaxReverseForwards limits how many reverse port and socket forwarding bindings can be active at the same time
+	// for a single network connection. -1 means unlimited.
+	MaxReverseForwards int `json:"maxReverseForwards" yaml:"maxReverseForwards" default:"-1"`
+
+	// MaxX11Channels limits how many X11 channels can be open at the same time for a single network connection. -1
+	// means unlimited.
+	MaxX11Channels int `json:"maxX11Channels" yaml:"maxX11Channels" default:"-1"`
+
+	// MaxConnectionsPerUser limits how many connections a single user can have open at the same time on the whole
+	// server. -1 means unlimited.
+	MaxConnectionsPerUser int `json:"maxConnectionsPerUser" yaml:"maxConnectionsPerUser" default:"-1"`
+
+	// Timeouts configures when idle or long-running sessions and connections are disconnected.
+	Timeouts SecurityTimeoutConfig `json:"timeouts" yaml:"timeouts"`
+
+	// DryRun evaluates all rules, but only logs and audits the requests that would have been rejected and lets them
+	// through. Sessions and connections reaching a timeout are not closed either. This is useful to test a stricter
+	// configuration before enforcing it.
+	DryRun bool `json:"dryRun" yaml:"dryRun" default:"false"`
 }
 
 // Validate validates a shell configuration
@@ -57,6 +85,9 @@ func (c SecurityConfig) Validate() error {
 	if err := c.Subsystem.Validate(); err != nil {
 		return wrap(err, "subsystem")
 	}
+	if err := c.Forwarding.Validate(); err != nil {
+		return wrap(err, "forwarding")
+	}
 	if err := c.TTY.Validate(); err != nil {
 		return wrap(err, "tty")
 	}
@@ -66,6 +97,60 @@ func (c SecurityConfig) Validate() error {
 	if c.MaxSessions < -1 {
 		return newError("maxSessions", "invalid maxSessions setting: %d", c.MaxSessions)
 	}
+	if c.MaxForwardChannels < -1 {
+		return newError("maxForwardChannels", "invalid maxForwardChannels setting: %d", c.MaxForwardChannels)
+	}
+	if c.MaxReverseForwards < -1 {
+		return newError("maxReverseForwards", "invalid maxReverseForwards setting: %d", c.MaxReverseForwards)
+	}
+	if c.MaxX11Channels < -1 {
+		return newError("maxX11Channels", "invalid maxX11Channels setting: %d", c.MaxX11Channels)
+	}
+	if c.MaxConnectionsPerUser < -1 {
+		return newError(
+			"maxConnectionsPerUser",
+			"invalid maxConnectionsPerUser setting: %d",
+			c.MaxConnectionsPerUser,
+		)
+	}
+	if err := c.Timeouts.Validate(); err != nil {
+		return wrap(err, "timeouts")
+	}
+	return nil
+}
+
+// SecurityTimeoutConfig configures the policy-based disconnects. A value of 0 disables the respective limit.
+type SecurityTimeoutConfig struct {
+	// IdleTimeout is the time after which a session is closed if no data has been sent on its standard input or
+	// standard output.
+	IdleTimeout time.Duration `json:"idleTimeout" yaml:"idleTimeout" default:"0"`
+	// MaxSessionDuration is the maximum time a session channel can stay open.
+	MaxSessionDuration time.Duration `json:"maxSessionDuration" yaml:"maxSessionDuration" default:"0"`
+	// MaxConnectionDuration is the maximum time an SSH connection can stay open. When it is reached the open
+	// sessions are closed and the connection is disconnected.
+	MaxConnectionDuration time.Duration `json:"maxConnectionDuration" yaml:"maxConnectionDuration" default:"0"`
+	// Warning is the time before a disconnect when the user is warned on the standard error. 0 disables the warning.
+	Warning time.Duration `json:"warning" yaml:"warning" default:"1m"`
+}
+
+// Validate validates the timeout configuration.
+func (t SecurityTimeoutConfig) Validate() error {
+	if t.IdleTimeout < 0 {
+		return newError("idleTimeout", "the idle timeout cannot be negative: %s", t.IdleTimeout)
+	}
+	if t.MaxSessionDuration < 0 {
+		return newError("maxSessionDuration", "the maximum session duration cannot be negative: %s", t.MaxSessionDuration)
+	}
+	if t.MaxConnectionDuration < 0 {
+		return newError(
+			"maxConnectionDuration",
+			"the maximum connection duration cannot be negative: %s",
+			t.MaxConnectionDuration,
+		)
+	}
+	if t.Warning < 0 {
+		return newError("warning", "the warning time cannot be negative: %s", t.Warning)
+	}
 	return nil
 }
 
@@ -96,6 +181,13 @@ type CommandConfig struct {
 	// Allow takes effect when Mode is ExecutionPolicyFilter and only allows the specified commands to be
 	// executed. Note that the match an exact match is performed to avoid shell injections, etc.
 	Allow []string `json:"allow" yaml:"allow"`
+	// Rules are matched against the command split into arguments using POSIX shell word splitting. The first
+	// matching rule decides what happens to the command. If no rule matches, the command is allowed when Mode is
+	// ExecutionPolicyEnable, and is checked against the Allow list when Mode is ExecutionPolicyFilter.
+	//
+	// When rules are configured, commands containing shell operators such as ; or | outside of quotes are rejected,
+	// and allowed commands are passed to the backend with each argument quoted so no shell can interpret them.
+	Rules []CommandRule `json:"rules" yaml:"rules"`
 }
 
 // Validate validates a shell configuration
@@ -103,9 +195,109 @@ func (c CommandConfig) Validate() error {
 	if err := c.Mode.Validate(); err != nil {
 		return wrap(err, "mode")
 	}
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("rules[%d]", i))
+		}
+	}
 	return nil
 }
 
+// CommandRule is a rule matching the arguments of a command.
+type CommandRule struct {
+	// Name identifies the rule in the logs.
+	Name string `json:"name" yaml:"name"`
+	// Action is the decision if the rule matches.
+	Action CommandAction `json:"action" yaml:"action"`
+	// Args are the patterns matching the arguments of the command, the first pattern matching the program. A pattern
+	// is a glob, where * matches any characters including /, or a regular expression if prefixed with re:. The regular
+	// expression must match the whole argument. The last pattern can be ** to match any number of remaining
+	// arguments. The number of arguments must otherwise match the number of patterns.
+	Args []string `json:"args" yaml:"args"`
+	// Rewrite is the command executed instead of the matching command when Action is rewrite. An argument consisting
+	// of $@ is replaced by all arguments of the original command, and $0 to $9 anywhere in an argument by the single
+	// arguments. For example, [/usr/bin/wrapper, $@] wraps the original command.
+	Rewrite []string `json:"rewrite" yaml:"rewrite"`
+}
+
+// Validate validates the command rule.
+func (r CommandRule) Validate() error {
+	if err := r.Action.Validate(); err != nil {
+		return wrap(err, "action")
+	}
+	if len(r.Args) == 0 {
+		return newError("args", "at least one argument pattern is required")
+	}
+	for i, pattern := range r.Args {
+		if pattern == "**" && i == len(r.Args)-1 {
+			continue
+		}
+		if _, err := CompileCommandArgPattern(pattern); err != nil {
+			return wrap(err, "args")
+		}
+	}
+	if r.Action == CommandActionRewrite && len(r.Rewrite) == 0 {
+		return newError("rewrite", "the rewrite action requires a command to rewrite to")
+	}
+	if r.Action != CommandActionRewrite && len(r.Rewrite) != 0 {
+		return newError("rewrite", "the rewrite command can only be set with the rewrite action")
+	}
+	return nil
+}
+
+// CompileCommandArgPattern compiles an argument pattern of a CommandRule into a regular expression matching the whole
+// argument.
+func CompileCommandArgPattern(pattern string) (*regexp.Regexp, error) {
+	if expression, ok := strings.CutPrefix(pattern, "re:"); ok {
+		compiled, err := regexp.Compile("^(?:" + expression + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s (%w)", expression, err)
+		}
+		return compiled, nil
+	}
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expression.WriteString(".*")
+		case '?':
+			expression.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.Compile(expression.String())
+}
+
+// CommandAction is the decision of a command rule.
+type CommandAction string
+
+// CommandActionAllow allows the command.
+const CommandActionAllow CommandAction = "allow"
+
+// CommandActionDeny rejects the command.
+const CommandActionDeny CommandAction = "deny"
+
+// CommandActionRewrite executes the command of the rule instead of the original command.
+const CommandActionRewrite CommandAction = "rewrite"
+
+// Validate checks if the command action is valid.
+func (a CommandAction) Validate() error {
+	switch a {
+	case CommandActionAllow, CommandActionDeny, CommandActionRewrite:
+		return nil
+	default:
+		return fmt.Errorf("invalid command action: %s", a)
+	}
+}
+
 // SecurityShellConfig controls shell executions via SSH.
 type SecurityShellConfig struct {
 	// Mode configures how to treat shell requests by SSH clients.
@@ -154,6 +346,21 @@ type ForwardingConfig struct {
 
 	// X11forwardingMode configures how to treat X11 forwarding requests from the container to the client
 	X11ForwardingMode SecurityExecutionPolicy `json:"x11ForwardingMode" yaml:"x11ForwardingMode" default:"disable"`
+
+	// AgentForwardingMode configures how to treat requests to forward the SSH agent of the client into the container.
+	AgentForwardingMode SecurityExecutionPolicy `json:"agentForwardingMode" yaml:"agentForwardingMode" default:"disable"`
+
+	// TCPTargets filters the hosts and ports the client can connect to with port forwarding. The deny list applies
+	// when ForwardingMode is enabled, the allow and deny lists both apply when it is set to filter.
+	TCPTargets ForwardingTargetFilter `json:"tcpTargets" yaml:"tcpTargets"`
+
+	// ReverseBindAddresses filters the addresses and ports the client can request to listen on with reverse port
+	// forwarding. The lists apply according to ReverseForwardingMode.
+	ReverseBindAddresses ForwardingTargetFilter `json:"reverseBindAddresses" yaml:"reverseBindAddresses"`
+
+	// SocketPaths filters the unix socket paths the client can connect to or listen on. The lists apply according to
+	// SocketForwardingMode and SocketListenMode.
+	SocketPaths ForwardingPathFilter `json:"socketPaths" yaml:"socketPaths"`
 }
 
 func (f ForwardingConfig) Validate() error {
@@ -172,6 +379,115 @@ func (f ForwardingConfig) Validate() error {
 	if err := f.X11ForwardingMode.Validate(); err != nil {
 		return fmt.Errorf("invalid mode (%w)", err)
 	}
+	if err := f.AgentForwardingMode.Validate(); err != nil {
+		return fmt.Errorf("invalid mode (%w)", err)
+	}
+	if err := f.TCPTargets.Validate(); err != nil {
+		return wrap(err, "tcpTargets")
+	}
+	if err := f.ReverseBindAddresses.Validate(); err != nil {
+		return wrap(err, "reverseBindAddresses")
+	}
+	if err := f.SocketPaths.Validate(); err != nil {
+		return wrap(err, "socketPaths")
+	}
+	return nil
+}
+
+// ForwardingTargetFilter contains the allow and deny lists for forwarding hosts and ports.
+type ForwardingTargetFilter struct {
+	// Allow takes effect when the mode is ExecutionPolicyFilter and only allows targets matching one of the rules.
+	Allow []ForwardingTargetRule `json:"allow" yaml:"allow"`
+	// Deny takes effect when the mode is not ExecutionPolicyDisable and rejects targets matching one of the rules.
+	Deny []ForwardingTargetRule `json:"deny" yaml:"deny"`
+}
+
+// Validate validates the forwarding target filter.
+func (f ForwardingTargetFilter) Validate() error {
+	for i, rule := range f.Allow {
+		if err := rule.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("allow[%d]", i))
+		}
+	}
+	for i, rule := range f.Deny {
+		if err := rule.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("deny[%d]", i))
+		}
+	}
+	return nil
+}
+
+// ForwardingTargetRule matches a forwarding host and port. The rule matches if the host matches one of the hosts or
+// networks and the port matches one of the ports. Empty host and network lists match any host, an empty port list
+// matches any port.
+//
+// Host names are not resolved: hosts only match targets given as a name and networks only match targets given as an
+// IP address. Use the allow list in filter mode if a network must not be reachable by name.
+type ForwardingTargetRule struct {
+	// Hosts are glob patterns matching host names case-insensitively, for example *.example.com.
+	Hosts []string `json:"hosts" yaml:"hosts"`
+	// Networks are CIDR ranges or IP addresses matching IP targets, for example 10.0.0.0/8.
+	Networks []string `json:"networks" yaml:"networks"`
+	// Ports are ports or port ranges, for example 443 or 8000-8999.
+	Ports []string `json:"ports" yaml:"ports"`
+}
+
+// Validate validates the forwarding target rule.
+func (r ForwardingTargetRule) Validate() error {
+	if err := validateGlobs(r.Hosts); err != nil {
+		return wrap(err, "hosts")
+	}
+	for _, network := range r.Networks {
+		if _, err := ParseCIDROrIP(network); err != nil {
+			return wrap(err, "networks")
+		}
+	}
+	for _, port := range r.Ports {
+		if _, _, err := ParsePortRange(port); err != nil {
+			return wrap(err, "ports")
+		}
+	}
+	return nil
+}
+
+// ParsePortRange parses a single port or a port range in the form of 8000-8999.
+func ParsePortRange(value string) (from uint32, to uint32, err error) {
+	fromText, toText, isRange := strings.Cut(value, "-")
+	if !isRange {
+		toText = fromText
+	}
+	fromPort, err := strconv.ParseUint(strings.TrimSpace(fromText), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port or port range: %s", value)
+	}
+	toPort, err := strconv.ParseUint(strings.TrimSpace(toText), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port or port range: %s", value)
+	}
+	if toPort < fromPort {
+		return 0, 0, fmt.Errorf("the end of the port range is lower than the start: %s", value)
+	}
+	return uint32(fromPort), uint32(toPort), nil
+}
+
+// ForwardingPathFilter contains the allow and deny lists for unix socket paths.
+type ForwardingPathFilter struct {
+	// Allow takes effect when the mode is ExecutionPolicyFilter and only allows paths matching one of the glob
+	// patterns.
+	Allow []string `json:"allow" yaml:"allow"`
+	// Deny takes effect when the mode is not ExecutionPolicyDisable and rejects paths matching one of the glob
+	// patterns.
+	Deny []string `json:"deny" yaml:"deny"`
+}
+
+// Validate validates the forwarding path filter.
+func (f ForwardingPathFilter) Validate() error {
+	if err := validateGlobs(f.Allow); err != nil {
+		return wrap(err, "allow")
+	}
+	if err := validateGlobs(f.Deny); err != nil {
+		return wrap(err, "deny")
+	}
 	return nil
 }
 
@@ -0,0 +1,84 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestSecurityTimeoutConfig(t *testing.T) {
+	cfg := config.SecurityConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, time.Duration(0), cfg.Timeouts.IdleTimeout)
+	assert.Equal(t, time.Minute, cfg.Timeouts.Warning)
+
+	cfg.Timeouts.IdleTimeout = 10 * time.Minute
+	cfg.Timeouts.MaxConnectionDuration = 8 * time.Hour
+	assert.NoError(t, cfg.Validate())
+	cfg.Timeouts.MaxSessionDuration = -time.Second
+	assert.Error(t, cfg.Validate())
+}
+
+func TestForwardingFilterConfig(t *testing.T) {
+	cfg := config.SecurityConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Forwarding.TCPTargets.Allow = []config.ForwardingTargetRule{
+		{Hosts: []string{"*.example.com"}, Networks: []string{"10.0.0.0/8", "::1"}, Ports: []string{"22", "8000-8999"}},
+	}
+	cfg.Forwarding.SocketPaths.Deny = []string{"/var/run/*.sock"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Forwarding.ReverseBindAddresses.Deny = []config.ForwardingTargetRule{{Ports: []string{"9000-8000"}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Forwarding.ReverseBindAddresses.Deny = []config.ForwardingTargetRule{{Networks: []string{"example.com"}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Forwarding.ReverseBindAddresses.Deny = []config.ForwardingTargetRule{{Ports: []string{"70000"}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Forwarding.ReverseBindAddresses.Deny = nil
+	cfg.Forwarding.SocketPaths.Allow = []string{"/tmp/["}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestCommandRuleConfig(t *testing.T) {
+	cfg := config.SecurityConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Command.Rules = []config.CommandRule{
+		{Action: config.CommandActionAllow, Args: []string{"git-upload-pack", "re:'?/srv/git/[a-z0-9-]+\\.git'?"}},
+		{Action: config.CommandActionRewrite, Args: []string{"rsync", "--server", "**"}, Rewrite: []string{"/usr/bin/rrsync", "$@"}},
+		{Action: config.CommandActionDeny, Args: []string{"*"}},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Command.Rules = []config.CommandRule{{Action: config.CommandActionAllow}}
+	assert.Error(t, cfg.Validate())
+	cfg.Command.Rules = []config.CommandRule{{Action: "permit", Args: []string{"ls"}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Command.Rules = []config.CommandRule{{Action: config.CommandActionAllow, Args: []string{"re:("}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Command.Rules = []config.CommandRule{{Action: config.CommandActionRewrite, Args: []string{"ls"}}}
+	assert.Error(t, cfg.Validate())
+	cfg.Command.Rules = []config.CommandRule{
+		{Action: config.CommandActionAllow, Args: []string{"ls"}, Rewrite: []string{"/bin/ls"}},
+	}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestSecurityLimitsConfig(t *testing.T) {
+	cfg := config.SecurityConfig{}
+	structutils.Defaults(&cfg)
+	assert.Equal(t, -1, cfg.MaxForwardChannels)
+	assert.Equal(t, -1, cfg.MaxReverseForwards)
+	assert.Equal(t, -1, cfg.MaxX11Channels)
+	assert.Equal(t, -1, cfg.MaxConnectionsPerUser)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxForwardChannels = 10
+	cfg.MaxConnectionsPerUser = 0
+	assert.NoError(t, cfg.Validate())
+	cfg.MaxX11Channels = -2
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// SFTPConfig configures the built-in SFTP server.
+type SFTPConfig struct {
+	// Enable replaces the sftp subsystem of the backend with the built-in SFTP server. The file operations are
+	// executed in the container through a shell, so the container image does not need an sftp-server binary, only a
+	// POSIX shell and the basic file utilities, for example from busybox.
+	Enable bool `json:"enable" yaml:"enable" default:"false"`
+	// Shell is the command starting the shell in the container that executes the file operations.
+	Shell string `json:"shell" yaml:"shell" default:"/bin/sh"`
+	// Root restricts the user to this directory of the container, similar to a chroot. The client sees this
+	// directory as /. Symbolic links pointing outside the root cannot be followed.
+	Root string `json:"root" yaml:"root" default:"/"`
+	// ReadOnly rejects all operations that modify files or directories.
+	ReadOnly bool `json:"readOnly" yaml:"readOnly" default:"false"`
+	// Deny lists the operations that are rejected, for example remove or rename.
+	Deny []SFTPOperation `json:"deny" yaml:"deny"`
+}
+
+// Validate validates the SFTP configuration.
+func (c SFTPConfig) Validate() error {
+	if !c.Enable {
+		return nil
+	}
+	if c.Shell == "" {
+		return newError("shell", "the shell must be set when the built-in SFTP server is enabled")
+	}
+	if !path.IsAbs(c.Root) {
+		return newError("root", "the root must be an absolute path: %s", c.Root)
+	}
+	for i, operation := range c.Deny {
+		if err := operation.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("deny[%d]", i))
+		}
+	}
+	return nil
+}
+
+// SFTPOperation is a group of SFTP requests that can be rejected by the SFTP configuration or a policy hook.
+type SFTPOperation string
+
+const (
+	// SFTPOperationRead opens a file for reading.
+	SFTPOperationRead SFTPOperation = "read"
+	// SFTPOperationWrite opens a file for writing, possibly creating it.
+	SFTPOperationWrite SFTPOperation = "write"
+	// SFTPOperationList lists the contents of a directory.
+	SFTPOperationList SFTPOperation = "list"
+	// SFTPOperationStat queries the attributes of a file or resolves a path.
+	SFTPOperationStat SFTPOperation = "stat"
+	// SFTPOperationSetStat changes the size, permissions, owner or times of a file.
+	SFTPOperationSetStat SFTPOperation = "setstat"
+	// SFTPOperationRemove removes a file.
+	SFTPOperationRemove SFTPOperation = "remove"
+	// SFTPOperationMkdir creates a directory.
+	SFTPOperationMkdir SFTPOperation = "mkdir"
+	// SFTPOperationRmdir removes a directory.
+	SFTPOperationRmdir SFTPOperation = "rmdir"
+	// SFTPOperationRename renames a file or directory.
+	SFTPOperationRename SFTPOperation = "rename"
+	// SFTPOperationReadLink reads the target of a symbolic link.
+	SFTPOperationReadLink SFTPOperation = "readlink"
+	// SFTPOperationSymlink creates a symbolic link.
+	SFTPOperationSymlink SFTPOperation = "symlink"
+)
+
+// Modifies returns true if the operation changes files or directories and is therefore rejected in read only mode.
+func (o SFTPOperation) Modifies() bool {
+	switch o {
+	case SFTPOperationRead, SFTPOperationList, SFTPOperationStat, SFTPOperationReadLink:
+		return false
+	default:
+		return true
+	}
+}
+
+// Validate checks if the operation is a known one.
+func (o SFTPOperation) Validate() error {
+	switch o {
+	case SFTPOperationRead, SFTPOperationWrite, SFTPOperationList, SFTPOperationStat, SFTPOperationSetStat,
+		SFTPOperationRemove, SFTPOperationMkdir, SFTPOperationRmdir, SFTPOperationRename, SFTPOperationReadLink,
+		SFTPOperationSymlink:
+		return nil
+	default:
+		return fmt.Errorf("invalid SFTP operation: %s", o)
+	}
+}
@@ -0,0 +1,30 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestSFTPConfig(t *testing.T) {
+	cfg := config.SFTPConfig{}
+	structutils.Defaults(&cfg)
+	assert.False(t, cfg.Enable)
+	assert.Equal(t, "/bin/sh", cfg.Shell)
+	assert.Equal(t, "/", cfg.Root)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Enable = true
+	cfg.Deny = []config.SFTPOperation{config.SFTPOperationRemove, config.SFTPOperationRename}
+	assert.NoError(t, cfg.Validate())
+	cfg.Deny = append(cfg.Deny, "delete")
+	assert.Error(t, cfg.Validate())
+	cfg.Deny = nil
+	cfg.Root = "home"
+	assert.Error(t, cfg.Validate())
+
+	assert.True(t, config.SFTPOperationSetStat.Modifies())
+	assert.False(t, config.SFTPOperationList.Modifies())
+}
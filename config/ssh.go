@@ -2,12 +2,16 @@ package config
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"regexp"
 	"strings"
@@ -18,8 +22,16 @@ import (
 
 // SSHConfig is the base configuration structure of the SSH server.
 type SSHConfig struct {
-	// Listen is the listen address for the SSH server
+	// Listen is the listen address for the SSH server. Besides host:port, unix:/path/to/socket listens on a Unix
+	// domain socket and systemd:name uses a socket passed by systemd socket activation.
 	Listen string `json:"listen" yaml:"listen" default:"0.0.0.0:2222"`
+	// SocketMode is the file mode of Unix domain sockets in octal notation, for example 0660. It applies to Listen
+	// and to the listeners that do not set their own socket mode.
+	SocketMode UnixSocketMode `json:"socketMode" yaml:"socketMode"`
+	// Listeners configures multiple listening sockets, each with its own address and, optionally, its own host keys,
+	// banner, authentication methods and backend. If set, Listen is ignored. Options left empty on a listener are
+	// inherited from this configuration.
+	Listeners []SSHListenerConfig `json:"listeners" yaml:"listeners"`
 	// ServerVersion is the version sent to the client.
 	//               Must be in the format of "SSH-protoversion-softwareversion SPACE comments".
 	//               See https://tools.ietf.org/html/rfc4253#page-4 section 4.2. Protocol Version Exchange
@@ -35,6 +47,10 @@ type SSHConfig struct {
 	Banner string `json:"banner" yaml:"banner" comment:"Host banner to show after the username" default:""`
 	// HostKeys are the host keys either in PEM format, or filenames to load.
 	HostKeys []string `json:"hostkeys" yaml:"hostkeys" comment:"Host keys in PEM format or files to load PEM host keys from."`
+	// HostCertificates are OpenSSH host certificates in the authorized_keys format, or filenames to load. Each
+	// certificate is matched to the host key it certifies and offered to clients in addition to the plain host key.
+	// This lets clients trust the server using a @cert-authority entry in their known_hosts file.
+	HostCertificates []string `json:"hostcertificates" yaml:"hostcertificates" comment:"Host certificates in OpenSSH format or files to load them from."`
 	// ClientAliveInterval is the duration between keep alive messages that
 	// ContainerSSH will send to each client. If the duration is 0 or unset
 	// it disables the feature.
@@ -43,33 +59,306 @@ type SSHConfig struct {
 	// allowed to be sent without a response being received. If this number
 	// is exceeded the connection is considered dead
 	ClientAliveCountMax int `json:"clientAliveCountMax" yaml:"clientAliveCountMax" default:"3" comment:"Maximum number of failed keepalives"`
+	// ProxyProtocol configures accepting HAProxy PROXY protocol headers from load balancers in front of ContainerSSH.
+	// It applies to Listen and to the listeners that do not configure their own trusted proxies.
+	ProxyProtocol SSHProxyProtocolConfig `json:"proxyProtocol" yaml:"proxyProtocol"`
 }
 
-// GenerateHostKey generates a random host key and adds it to SSHConfig
-func (cfg *SSHConfig) GenerateHostKey() error {
-	reader := rand.Reader
-	bitSize := 4096
-	key, err := rsa.GenerateKey(reader, bitSize)
-	if err != nil {
-		return err
+// SSHProxyProtocolConfig configures the HAProxy PROXY protocol (v1 and v2) support. When enabled, connections must
+// originate from one of the trusted proxies and must start with a PROXY protocol header. The client address from the
+// header is then used as the remote address of the connection.
+type SSHProxyProtocolConfig struct {
+	// TrustedProxies is a list of IP addresses or CIDR ranges of the proxies allowed to send PROXY protocol headers.
+	// Leave empty to disable PROXY protocol support. Proxies connecting over a Unix domain socket have the address
+	// 0.0.0.0.
+	TrustedProxies []string `json:"trustedProxies" yaml:"trustedProxies"`
+	// HeaderTimeout is the time the proxy has to send the PROXY protocol header after connecting.
+	HeaderTimeout time.Duration `json:"headerTimeout" yaml:"headerTimeout" default:"5s"`
+}
+
+// Enabled returns true if at least one trusted proxy is configured.
+func (c SSHProxyProtocolConfig) Enabled() bool {
+	return len(c.TrustedProxies) > 0
+}
+
+// TrustedNetworks returns the trusted proxies as a list of networks.
+func (c SSHProxyProtocolConfig) TrustedNetworks() ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, len(c.TrustedProxies))
+	for i, proxy := range c.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				bits = 8 * net.IPv4len
+			}
+			result[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %s (%w)", proxy, err)
+		}
+		result[i] = network
 	}
-	var privateKey = &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	return result, nil
+}
+
+// Validate checks the PROXY protocol configuration.
+func (c SSHProxyProtocolConfig) Validate() error {
+	if _, err := c.TrustedNetworks(); err != nil {
+		return wrap(err, "trustedProxies")
 	}
-	var hostKeyBuffer bytes.Buffer
-	err = pem.Encode(&hostKeyBuffer, privateKey)
-	if err != nil {
-		return err
+	if c.Enabled() && c.HeaderTimeout <= 0 {
+		return newError("headerTimeout", "headerTimeout must be positive")
 	}
+	return nil
+}
+
+// SSHListenerConfig is the configuration of a single listening socket of the SSH server.
+type SSHListenerConfig struct {
+	// Name identifies the listener in logs and in the connection metadata. Defaults to the listen address.
+	Name string `json:"name" yaml:"name"`
+	// Listen is the listen address for this listener.
+	Listen string `json:"listen" yaml:"listen"`
+	// SocketMode is the file mode of the Unix domain socket of this listener in octal notation, for example 0660.
+	SocketMode UnixSocketMode `json:"socketMode" yaml:"socketMode"`
+	// HostKeys are the host keys for this listener either in PEM format, or filenames to load. If empty, the host
+	// keys and host certificates of the SSH server are used.
+	HostKeys []string `json:"hostkeys" yaml:"hostkeys"`
+	// HostCertificates are the host certificates matching the host keys of this listener.
+	HostCertificates []string `json:"hostcertificates" yaml:"hostcertificates"`
+	// Banner is the banner sent to clients connecting to this listener. If empty, the banner of the SSH server is
+	// used.
+	Banner string `json:"banner" yaml:"banner"`
+	// AuthMethods restricts the authentication methods offered to clients connecting to this listener. If empty, all
+	// configured authentication methods are offered.
+	AuthMethods []SSHAuthMethod `json:"authMethods" yaml:"authMethods"`
+	// Backend overrides the backend for connections to this listener. The configuration server can still change the
+	// backend for individual connections.
+	Backend Backend `json:"backend" yaml:"backend"`
+	// ProxyProtocol configures accepting PROXY protocol headers on this listener. If no trusted proxies are set, the
+	// PROXY protocol configuration of the SSH server is used.
+	ProxyProtocol SSHProxyProtocolConfig `json:"proxyProtocol" yaml:"proxyProtocol"`
+}
+
+// LoadHostKeys loads the host keys and host certificates of the listener.
+func (l SSHListenerConfig) LoadHostKeys() ([]ssh.Signer, error) {
+	return loadHostKeys(l.HostKeys, l.HostCertificates)
+}
+
+// AllowsAuthMethod returns true if the authentication method can be used on this listener.
+func (l SSHListenerConfig) AllowsAuthMethod(method SSHAuthMethod) bool {
+	if len(l.AuthMethods) == 0 {
+		return true
+	}
+	for _, allowedMethod := range l.AuthMethods {
+		if allowedMethod == method {
+			return true
+		}
+	}
+	return false
+}
 
-	cfg.HostKeys = append(cfg.HostKeys, hostKeyBuffer.String())
+// Validate validates the listener configuration.
+func (l SSHListenerConfig) Validate() error {
+	if l.Listen == "" {
+		return newError("listen", "the listen address cannot be empty")
+	}
+	if _, _, err := ParseListenAddress(l.Listen); err != nil {
+		return wrap(err, "listen")
+	}
+	if err := l.SocketMode.Validate(); err != nil {
+		return wrap(err, "socketMode")
+	}
+	for i, hostCertificate := range l.HostCertificates {
+		if strings.TrimSpace(hostCertificate) == "" {
+			return newError(fmt.Sprintf("hostcertificates[%d]", i), "the host certificate cannot be empty")
+		}
+	}
+	for i, method := range l.AuthMethods {
+		if err := method.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("authMethods[%d]", i))
+		}
+	}
+	if l.Backend != "" {
+		if err := l.Backend.Validate(); err != nil {
+			return wrap(err, "backend")
+		}
+	}
+	if err := l.ProxyProtocol.Validate(); err != nil {
+		return wrap(err, "proxyProtocol")
+	}
 	return nil
 }
 
+// SSHAuthMethod is an SSH authentication method as named in the SSH protocol.
+type SSHAuthMethod string
+
+const (
+	// SSHAuthMethodPassword is the password authentication method.
+	SSHAuthMethodPassword SSHAuthMethod = "password"
+	// SSHAuthMethodPublicKey is the public key authentication method.
+	SSHAuthMethodPublicKey SSHAuthMethod = "publickey"
+	// SSHAuthMethodKeyboardInteractive is the keyboard-interactive authentication method.
+	SSHAuthMethodKeyboardInteractive SSHAuthMethod = "keyboard-interactive"
+	// SSHAuthMethodGSSAPI is the GSSAPI authentication method.
+	SSHAuthMethodGSSAPI SSHAuthMethod = "gssapi-with-mic"
+)
+
+// Validate checks if the authentication method is supported.
+func (m SSHAuthMethod) Validate() error {
+	switch m {
+	case SSHAuthMethodPassword, SSHAuthMethodPublicKey, SSHAuthMethodKeyboardInteractive, SSHAuthMethodGSSAPI:
+		return nil
+	default:
+		return fmt.Errorf("unsupported authentication method: %s", m)
+	}
+}
+
+// EffectiveListeners returns the listeners the SSH server should open. If no listeners are configured, a single
+// listener is returned based on Listen. Options not set on a listener are filled in from this configuration.
+func (cfg SSHConfig) EffectiveListeners() []SSHListenerConfig {
+	listeners := cfg.Listeners
+	if len(listeners) == 0 {
+		listeners = []SSHListenerConfig{{Listen: cfg.Listen}}
+	}
+	result := make([]SSHListenerConfig, len(listeners))
+	for i, listener := range listeners {
+		if listener.Name == "" {
+			listener.Name = listener.Listen
+		}
+		if len(listener.HostKeys) == 0 {
+			listener.HostKeys = cfg.HostKeys
+			listener.HostCertificates = cfg.HostCertificates
+		}
+		if listener.Banner == "" {
+			listener.Banner = cfg.Banner
+		}
+		if listener.SocketMode == "" {
+			listener.SocketMode = cfg.SocketMode
+		}
+		if !listener.ProxyProtocol.Enabled() {
+			listener.ProxyProtocol = cfg.ProxyProtocol
+		} else if listener.ProxyProtocol.HeaderTimeout == 0 {
+			listener.ProxyProtocol.HeaderTimeout = cfg.ProxyProtocol.HeaderTimeout
+		}
+		result[i] = listener
+	}
+	return result
+}
+
+// Listener returns the effective configuration of the listener with the specified name.
+func (cfg SSHConfig) Listener(name string) (SSHListenerConfig, bool) {
+	for _, listener := range cfg.EffectiveListeners() {
+		if listener.Name == name {
+			return listener, true
+		}
+	}
+	return SSHListenerConfig{}, false
+}
+
+// SSHHostKeyType is the type of host key to generate.
+type SSHHostKeyType string
+
+const (
+	// SSHHostKeyTypeRSA generates a 4096 bit RSA host key.
+	SSHHostKeyTypeRSA SSHHostKeyType = "rsa"
+	// SSHHostKeyTypeECDSAP256 generates an ECDSA host key on the NIST P-256 curve.
+	SSHHostKeyTypeECDSAP256 SSHHostKeyType = "ecdsa-p256"
+	// SSHHostKeyTypeECDSAP384 generates an ECDSA host key on the NIST P-384 curve.
+	SSHHostKeyTypeECDSAP384 SSHHostKeyType = "ecdsa-p384"
+	// SSHHostKeyTypeECDSAP521 generates an ECDSA host key on the NIST P-521 curve.
+	SSHHostKeyTypeECDSAP521 SSHHostKeyType = "ecdsa-p521"
+	// SSHHostKeyTypeED25519 generates an ed25519 host key.
+	SSHHostKeyTypeED25519 SSHHostKeyType = "ed25519"
+)
+
+// Validate checks if the host key type is supported.
+func (t SSHHostKeyType) Validate() error {
+	switch t {
+	case SSHHostKeyTypeRSA, SSHHostKeyTypeECDSAP256, SSHHostKeyTypeECDSAP384, SSHHostKeyTypeECDSAP521,
+		SSHHostKeyTypeED25519:
+		return nil
+	default:
+		return fmt.Errorf("unsupported host key type: %s", t)
+	}
+}
+
+// GenerateHostKey generates a random host key for each of the specified key types and adds them to SSHConfig. If no
+// key type is specified a single RSA host key is generated.
+func (cfg *SSHConfig) GenerateHostKey(keyTypes ...SSHHostKeyType) error {
+	if len(keyTypes) == 0 {
+		keyTypes = []SSHHostKeyType{SSHHostKeyTypeRSA}
+	}
+	for _, keyType := range keyTypes {
+		privateKey, err := generateHostKey(keyType)
+		if err != nil {
+			return err
+		}
+		var hostKeyBuffer bytes.Buffer
+		if err := pem.Encode(&hostKeyBuffer, privateKey); err != nil {
+			return err
+		}
+		cfg.HostKeys = append(cfg.HostKeys, hostKeyBuffer.String())
+	}
+	return nil
+}
+
+func generateHostKey(keyType SSHHostKeyType) (*pem.Block, error) {
+	reader := rand.Reader
+	switch keyType {
+	case SSHHostKeyTypeRSA:
+		bitSize := 4096
+		key, err := rsa.GenerateKey(reader, bitSize)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}, nil
+	case SSHHostKeyTypeECDSAP256, SSHHostKeyTypeECDSAP384, SSHHostKeyTypeECDSAP521:
+		curve := map[SSHHostKeyType]elliptic.Curve{
+			SSHHostKeyTypeECDSAP256: elliptic.P256(),
+			SSHHostKeyTypeECDSAP384: elliptic.P384(),
+			SSHHostKeyTypeECDSAP521: elliptic.P521(),
+		}[keyType]
+		key, err := ecdsa.GenerateKey(curve, reader)
+		if err != nil {
+			return nil, err
+		}
+		keyBytes, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyBytes,
+		}, nil
+	case SSHHostKeyTypeED25519:
+		_, key, err := ed25519.GenerateKey(reader)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.MarshalPrivateKey(key, "")
+	default:
+		return nil, keyType.Validate()
+	}
+}
+
+// LoadHostKeys loads the host keys and, if configured, the matching host certificates. A host key with a certificate
+// is returned twice: once as a plain key and once as a certificate signer.
 func (cfg *SSHConfig) LoadHostKeys() ([]ssh.Signer, error) {
+	return loadHostKeys(cfg.HostKeys, cfg.HostCertificates)
+}
+
+func loadHostKeys(hostKeyList []string, hostCertificates []string) ([]ssh.Signer, error) {
 	var hostKeys []ssh.Signer
-	for index, hostKey := range cfg.HostKeys {
+	for index, hostKey := range hostKeyList {
 		if strings.TrimSpace(hostKey)[:5] != "-----" {
 			// We are deliberalely loading a dynamic file here.
 			fh, err := os.Open(hostKey) //nolint:gosec
@@ -97,7 +386,52 @@ func (cfg *SSHConfig) LoadHostKeys() ([]ssh.Signer, error) {
 		}
 		hostKeys = append(hostKeys, private)
 	}
-	return hostKeys, nil
+	certSigners, err := loadHostCertificates(hostCertificates, hostKeys)
+	if err != nil {
+		return nil, err
+	}
+	return append(hostKeys, certSigners...), nil
+}
+
+func loadHostCertificates(hostCertificates []string, hostKeys []ssh.Signer) ([]ssh.Signer, error) {
+	var result []ssh.Signer
+	for index, hostCertificate := range hostCertificates {
+		data := []byte(hostCertificate)
+		if _, _, _, _, err := ssh.ParseAuthorizedKey(data); err != nil {
+			// We are deliberately loading a dynamic file here.
+			data, err = os.ReadFile(hostCertificate) //nolint:gosec
+			if err != nil {
+				return nil, fmt.Errorf("failed to load host certificate %s (%w)", hostCertificate, err)
+			}
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host certificate %d (%w)", index, err)
+		}
+		cert, ok := key.(*ssh.Certificate)
+		if !ok {
+			return nil, fmt.Errorf("host certificate %d is a plain public key, not a certificate", index)
+		}
+		if cert.CertType != ssh.HostCert {
+			return nil, fmt.Errorf("host certificate %d is not a host certificate", index)
+		}
+		var signer ssh.Signer
+		for _, hostKey := range hostKeys {
+			if bytes.Equal(hostKey.PublicKey().Marshal(), cert.Key.Marshal()) {
+				signer = hostKey
+				break
+			}
+		}
+		if signer == nil {
+			return nil, fmt.Errorf("host certificate %d does not match any of the configured host keys", index)
+		}
+		certSigner, err := ssh.NewCertSigner(cert, signer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signer for host certificate %d (%w)", index, err)
+		}
+		result = append(result, certSigner)
+	}
+	return result, nil
 }
 
 // Validate validates the configuration and returns an error if invalid.
@@ -117,9 +451,30 @@ func (cfg SSHConfig) Validate() error {
 	if cfg.ClientAliveInterval != 0 && cfg.ClientAliveInterval < 1*time.Second {
 		return newError("clientAliveInterval", "clientAliveInterval should be at least 1 second long")
 	}
+	for i, hostCertificate := range cfg.HostCertificates {
+		if strings.TrimSpace(hostCertificate) == "" {
+			return newError(fmt.Sprintf("hostcertificates[%d]", i), "the host certificate cannot be empty")
+		}
+	}
 	if cfg.ClientAliveCountMax <= 0 {
 		return newError("clientAliveCountMax", "clientAliveCountMax should be at least 1")
 	}
+	if err := cfg.ProxyProtocol.Validate(); err != nil {
+		return wrap(err, "proxyProtocol")
+	}
+	if len(cfg.Listeners) == 0 {
+		return nil
+	}
+	names := map[string]struct{}{}
+	for i, listener := range cfg.EffectiveListeners() {
+		if err := listener.Validate(); err != nil {
+			return wrap(err, fmt.Sprintf("listeners[%d]", i))
+		}
+		if _, ok := names[listener.Name]; ok {
+			return newError(fmt.Sprintf("listeners[%d]", i), "duplicate listener name: %s", listener.Name)
+		}
+		names[listener.Name] = struct{}{}
+	}
 	return nil
 }
 
@@ -191,7 +546,8 @@ func (k SSHKexList) StringList() []string {
 var supportedHostKeyAlgos = []stringer{
 	SSHKeyAlgoSSHRSACertv01, SSHKeyAlgoSSHDSSCertv01, SSHKeyAlgoECDSASHA2NISTp256Certv01,
 	SSHKeyAlgoECDSASHA2NISTp384Certv01, SSHKeyAlgoECDSASHA2NISTp521Certv01, SSHKeyAlgoSSHED25519Certv01,
-	SSHKeyAlgoSSHRSA, SSHKeyAlgoSSHDSS, SSHKeyAlgoSSHED25519,
+	SSHKeyAlgoSSHRSA, SSHKeyAlgoSSHDSS, SSHKeyAlgoECDSASHA2NISTp256, SSHKeyAlgoECDSASHA2NISTp384,
+	SSHKeyAlgoECDSASHA2NISTp521, SSHKeyAlgoSSHED25519,
 }
 
 // SSHKeyAlgo are supported key algorithms.
@@ -207,6 +563,9 @@ const (
 	SSHKeyAlgoSSHED25519Certv01        SSHKeyAlgo = "ssh-ed25519-cert-v01@openssh.com"
 	SSHKeyAlgoSSHRSA                   SSHKeyAlgo = "ssh-rsa"
 	SSHKeyAlgoSSHDSS                   SSHKeyAlgo = "ssh-dss"
+	SSHKeyAlgoECDSASHA2NISTp256        SSHKeyAlgo = "ecdsa-sha2-nistp256"
+	SSHKeyAlgoECDSASHA2NISTp384        SSHKeyAlgo = "ecdsa-sha2-nistp384"
+	SSHKeyAlgoECDSASHA2NISTp521        SSHKeyAlgo = "ecdsa-sha2-nistp521"
 	SSHKeyAlgoSSHED25519               SSHKeyAlgo = "ssh-ed25519"
 )
 
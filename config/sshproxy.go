@@ -36,7 +36,7 @@ type SSHProxyConfig struct {
 	// HostKeyAlgorithms is a list of algorithms for host keys. The server can offer multiple host keys and this list
 	// are the ones we want to accept. The fingerprints for the accepted algorithms should be added to
 	// AllowedHostKeyFingerprints.
-	HostKeyAlgorithms SSHKeyAlgoList `json:"hostKeyAlgos" yaml:"hostKeyAlgos" default:"[\"ssh-rsa-cert-v01@openssh.com\",\"ssh-dss-cert-v01@openssh.com\",\"ecdsa-sha2-nistp256-cert-v01@openssh.com\",\"ecdsa-sha2-nistp384-cert-v01@openssh.com\",\"ecdsa-sha2-nistp521-cert-v01@openssh.com\",\"ssh-ed25519-cert-v01@openssh.com\",\"ssh-rsa\",\"ssh-dss\",\"ssh-ed25519\"]"`
+	HostKeyAlgorithms SSHKeyAlgoList `json:"hostKeyAlgos" yaml:"hostKeyAlgos" default:"[\"ssh-rsa-cert-v01@openssh.com\",\"ssh-dss-cert-v01@openssh.com\",\"ecdsa-sha2-nistp256-cert-v01@openssh.com\",\"ecdsa-sha2-nistp384-cert-v01@openssh.com\",\"ecdsa-sha2-nistp521-cert-v01@openssh.com\",\"ssh-ed25519-cert-v01@openssh.com\",\"ssh-rsa\",\"ssh-dss\",\"ecdsa-sha2-nistp256\",\"ecdsa-sha2-nistp384\",\"ecdsa-sha2-nistp521\",\"ssh-ed25519\"]"`
 	// Timeout is the time ContainerSSH is willing to wait for the backing connection to be established.
 	Timeout time.Duration `json:"timeout" yaml:"timeout" default:"60s"`
 	// ClientVersion is the version sent to the server.
@@ -0,0 +1,24 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+func TestSSHProxyDefaultHostKeyAlgorithms(t *testing.T) {
+	cfg := config.SSHProxyConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.HostKeyAlgorithms.Validate())
+	for _, algo := range []config.SSHKeyAlgo{
+		config.SSHKeyAlgoECDSASHA2NISTp256,
+		config.SSHKeyAlgoECDSASHA2NISTp384,
+		config.SSHKeyAlgoECDSASHA2NISTp521,
+		config.SSHKeyAlgoECDSASHA2NISTp256Certv01,
+		config.SSHKeyAlgoSSHED25519Certv01,
+	} {
+		assert.Contains(t, cfg.HostKeyAlgorithms, algo)
+	}
+}
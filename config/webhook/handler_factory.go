@@ -3,11 +3,18 @@ package webhook
 import (
 	"net/http"
 
+	configuration "go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/internal/config"
 	"go.containerssh.io/libcontainerssh/log"
 )
 
-// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler.
-func NewHandler(h ConfigRequestHandler, logger log.Logger) (http.Handler, error) {
-	return config.NewHandler(h, logger)
+// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler. If a signature
+// secret is configured, requests without a valid signature, with an expired timestamp, or with a nonce that has already
+// been used are rejected.
+func NewHandler(
+	h ConfigRequestHandler,
+	signature configuration.HTTPSignatureConfig,
+	logger log.Logger,
+) (http.Handler, error) {
+	return config.NewHandler(h, signature, logger)
 }
@@ -2,8 +2,12 @@ package libcontainerssh
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 
 	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/internal/adminintegration"
 	"go.containerssh.io/libcontainerssh/internal/auditlogintegration"
 	"go.containerssh.io/libcontainerssh/internal/authintegration"
 	"go.containerssh.io/libcontainerssh/internal/backend"
@@ -12,6 +16,11 @@ import (
 	"go.containerssh.io/libcontainerssh/internal/health"
 	"go.containerssh.io/libcontainerssh/internal/metrics"
 	"go.containerssh.io/libcontainerssh/internal/metricsintegration"
+	"go.containerssh.io/libcontainerssh/internal/ratelimit"
+	"go.containerssh.io/libcontainerssh/internal/ratelimitintegration"
+	"go.containerssh.io/libcontainerssh/internal/restart"
+	"go.containerssh.io/libcontainerssh/internal/retry"
+	"go.containerssh.io/libcontainerssh/internal/socket"
 	"go.containerssh.io/libcontainerssh/internal/sshserver"
 	"go.containerssh.io/libcontainerssh/log"
 	"go.containerssh.io/libcontainerssh/message"
@@ -46,55 +55,93 @@ func New(cfg config.AppConfig, factory log.LoggerFactory) (Service, service.Life
 	}
 
 	metricsCollector := metrics.New(geoIPLookupProvider)
+	healthService.SetBackendCheck(func() []string {
+		return retry.OpenCircuitBreakers(metricsCollector)
+	})
 
 	if err := createMetricsServer(cfg, logger, metricsCollector, pool); err != nil {
 		return nil, nil, err
 	}
 
-	containerBackend, err := createBackend(cfg, logger, metricsCollector)
+	chainFactory := &handlerChainFactory{
+		metricsCollector:    metricsCollector,
+		geoIPLookupProvider: geoIPLookupProvider,
+		rateLimitConfig:     cfg.RateLimit,
+		rateLimiter:         ratelimit.New(cfg.RateLimit),
+	}
+	chain, err := chainFactory.create(cfg, logger)
 	if err != nil {
 		return nil, nil, err
 	}
+	for _, svc := range chain.services {
+		pool.Add(svc)
+	}
+	chainFactory.activate(chain)
+	reloadableHandler := newReloadableHandler(chain.handler)
 
-	authHandler, err := createAuthHandler(cfg, logger, containerBackend, metricsCollector, pool)
+	adminRegistry := admin.NewRegistry()
+	adminHandler, err := adminintegration.NewHandler(cfg.Admin, adminRegistry, reloadableHandler)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	auditLogHandler, err := createAuditLogHandler(cfg, logger, authHandler, geoIPLookupProvider)
+	metricsHandler, err := createMetricsBackend(cfg, metricsCollector, adminHandler)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	metricsHandler, err := createMetricsBackend(cfg, metricsCollector, auditLogHandler)
+	sshServer, err := createSSHServer(cfg, logger, metricsHandler)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if err := createSSHServer(cfg, logger, metricsHandler, pool); err != nil {
+	if err := createAdminServer(cfg, logger, adminRegistry, sshServer, pool); err != nil {
 		return nil, nil, err
 	}
+	// The SSH server is added last so it is stopped first, while the services it depends on are still running.
+	pool.Add(sshServer)
 
-	return setUpService(pool, logger, healthService)
+	return setUpService(pool, logger, healthService, sshServer, cfg, chainFactory, reloadableHandler)
 }
 
-func setUpService(pool service.Pool, logger log.Logger, healthService health.Service) (
+func setUpService(
+	pool service.Pool,
+	logger log.Logger,
+	healthService health.Service,
+	sshServer sshserver.Server,
+	cfg config.AppConfig,
+	chainFactory *handlerChainFactory,
+	reloadableHandler *reloadableHandler,
+) (
 	Service,
 	service.Lifecycle,
 	error,
 ) {
 	poolWrapper := &servicePool{
-		pool,
-		logger,
+		Pool:              pool,
+		logger:            logger,
+		healthService:     healthService,
+		sshServer:         sshServer,
+		config:            cfg,
+		chainFactory:      chainFactory,
+		reloadableHandler: reloadableHandler,
 	}
 	lifecycle := service.NewLifecycle(poolWrapper)
 	lifecycle.OnRunning(
 		func(s service.Service, l service.Lifecycle) {
 			healthService.ChangeStatus(true)
+			// All listeners are open at this point, sockets inherited for listeners that are no longer configured are
+			// not needed.
+			socket.CloseUnusedSystemdFiles()
+			if err := restart.NotifyReady(); err != nil {
+				logger.Warning(message.Wrap(err, message.ECoreRestartFailed, "Failed to notify the previous process"))
+			}
 		},
 	).OnStopping(
 		func(s service.Service, l service.Lifecycle, shutdownContext context.Context) {
-			healthService.ChangeStatus(false)
+			if !poolWrapper.restarting.Load() {
+				healthService.ChangeStatus(false)
+			}
 		},
 	).OnCrashed(
 		func(s service.Service, l service.Lifecycle, err error) {
@@ -107,13 +154,53 @@ func setUpService(pool service.Pool, logger log.Logger, healthService health.Ser
 
 type servicePool struct {
 	service.Pool
-	logger log.Logger
+	logger            log.Logger
+	healthService     health.Service
+	sshServer         sshserver.Server
+	chainFactory      *handlerChainFactory
+	reloadableHandler *reloadableHandler
+	restarting        atomic.Bool
+
+	// lock protects config, which is replaced on configuration reloads.
+	lock   sync.Mutex
+	config config.AppConfig
 }
 
-func (s servicePool) RotateLogs() error {
+func (s *servicePool) RotateLogs() error {
 	return s.logger.Rotate()
 }
 
+func (s *servicePool) Restart(lifecycle service.Lifecycle) error {
+	if !s.restarting.CompareAndSwap(false, true) {
+		return message.NewMessage(message.ECoreRestartFailed, "A graceful restart is already in progress")
+	}
+	s.lock.Lock()
+	restartConfig := s.config.Restart
+	s.lock.Unlock()
+	s.logger.Info(message.NewMessage(message.MCoreRestarting, "Starting a new process for graceful restart..."))
+	if err := restart.Spawn(restartConfig.ReadyTimeout); err != nil {
+		err = message.Wrap(err, message.ECoreRestartFailed, "Graceful restart failed, continuing with the current process")
+		s.logger.Error(err)
+		s.restarting.Store(false)
+		return err
+	}
+	s.healthService.Drain()
+	s.logger.Info(
+		message.NewMessage(
+			message.MCoreDraining,
+			"The new process is ready, waiting up to %s for existing connections to end...",
+			restartConfig.DrainTimeout,
+		),
+	)
+	s.sshServer.Drain()
+	shutdownContext, cancelFunc := context.WithTimeout(context.Background(), restartConfig.DrainTimeout)
+	defer cancelFunc()
+	// The pool stops the SSH server first, so the health, metrics and admin servers keep running until the existing
+	// connections have ended or the drain timeout has passed.
+	lifecycle.Stop(shutdownContext)
+	return nil
+}
+
 func createMetricsBackend(
 	cfg config.AppConfig,
 	collector metrics.Collector,
@@ -144,23 +231,36 @@ func createMetricsServer(
 	return nil
 }
 
-func createSSHServer(
+func createAdminServer(
 	cfg config.AppConfig,
 	logger log.Logger,
-	auditLogHandler sshserver.Handler,
+	registry admin.Registry,
+	sshServer sshserver.Server,
 	pool service.Pool,
 ) error {
+	adminLogger := logger.WithLabel("module", "admin")
+	adminServer, err := admin.NewServer(cfg.Admin, registry, sshServer, adminLogger)
+	if err != nil {
+		return err
+	}
+	if adminServer == nil {
+		return nil
+	}
+	pool.Add(adminServer)
+	return nil
+}
+
+func createSSHServer(
+	cfg config.AppConfig,
+	logger log.Logger,
+	auditLogHandler sshserver.Handler,
+) (sshserver.Server, error) {
 	sshLogger := logger.WithLabel("module", "ssh")
-	sshServer, err := sshserver.New(
+	return sshserver.New(
 		cfg.SSH,
 		auditLogHandler,
 		sshLogger,
 	)
-	if err != nil {
-		return err
-	}
-	pool.Add(sshServer)
-	return nil
 }
 
 func createAuditLogHandler(
@@ -178,28 +278,42 @@ func createAuditLogHandler(
 	)
 }
 
+func createRateLimitHandler(
+	cfg config.RateLimitConfig,
+	limiter ratelimit.Limiter,
+	logger log.Logger,
+	authHandler sshserver.Handler,
+	metricsCollector metrics.Collector,
+) sshserver.Handler {
+	if !cfg.Enable {
+		return authHandler
+	}
+	rateLimitLogger := logger.WithLabel("module", "ratelimit")
+	return ratelimitintegration.NewWithLimiter(
+		cfg,
+		limiter,
+		authHandler,
+		rateLimitLogger,
+		metricsCollector,
+	)
+}
+
 func createAuthHandler(
 	cfg config.AppConfig,
 	logger log.Logger,
 	backend sshserver.Handler,
 	metricsCollector metrics.Collector,
-	pool service.Pool,
-) (sshserver.Handler, error) {
+	geoIPLookupProvider geoipprovider.LookupProvider,
+) (sshserver.Handler, []service.Service, error) {
 	authLogger := logger.WithLabel("module", "auth")
-	handler, services, err := authintegration.New(
+	return authintegration.New(
 		cfg.Auth,
 		backend,
 		authLogger,
 		metricsCollector,
+		geoIPLookupProvider,
 		authintegration.BehaviorNoPassthrough,
 	)
-	if err != nil {
-		return nil, err
-	}
-	for _, svc := range services {
-		pool.Add(svc)
-	}
-	return handler, nil
 }
 
 func createBackend(cfg config.AppConfig, logger log.Logger, metricsCollector metrics.Collector) (sshserver.Handler, error) {
@@ -2,13 +2,17 @@ package http
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/schema"
 	"go.containerssh.io/libcontainerssh/config"
@@ -253,9 +257,30 @@ func (c *client) createRequestForURL(method string, u string, requestBody interf
 		panic(fmt.Errorf("invalid request encoding: %s", c.config.RequestEncoding))
 	}
 	req.Header.Set("Accept", "application/json")
+	if c.config.Signature.Secret != "" {
+		if err := c.sign(req, buffer.Bytes()); err != nil {
+			err := message.Wrap(err, message.EHTTPFailureEncodeFailed, "Failed to sign HTTP request")
+			logger.Critical(err)
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
+// sign adds the timestamp, nonce and signature headers to the request.
+func (c *client) sign(req *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+	req.Header.Set(SignatureTimestampHeader, timestamp)
+	req.Header.Set(SignatureNonceHeader, nonceHex)
+	req.Header.Set(SignatureHeader, Sign(c.config.Signature.Secret, timestamp, nonceHex, body))
+	return nil
+}
+
 func (c *client) createHTTPClient(logger log.Logger) *http.Client {
 	transport := &http.Transport{
 		TLSClientConfig: c.tlsConfig,
@@ -11,6 +11,7 @@ import (
 	"sync"
 
     "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/internal/socket"
     "go.containerssh.io/libcontainerssh/message"
     "go.containerssh.io/libcontainerssh/service"
 )
@@ -48,10 +49,10 @@ func (s *server) RunWithLifecycle(lifecycle service.Lifecycle) error {
 	}()
 	var err error
 
-	ln, err := net.Listen("tcp", s.srv.Addr)
+	ln, err := socket.Listen(lifecycle.Context(), &net.ListenConfig{}, s.config.Listen, s.config.SocketMode)
 	if err != nil {
 		s.lock.Unlock()
-		return message.Wrap(err, message.EHTTPListenFailed, "Failed to listen on %s", s.srv.Addr)
+		return message.Wrap(err, message.EHTTPListenFailed, "Failed to listen on %s", s.config.Listen)
 	}
 	defer func() { _ = ln.Close() }()
 	var url string
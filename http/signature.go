@@ -0,0 +1,31 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignatureTimestampHeader is the header containing the time the request was signed at in Unix seconds.
+const SignatureTimestampHeader = "X-ContainerSSH-Timestamp"
+
+// SignatureNonceHeader is the header containing a random value that is unique to each request.
+const SignatureNonceHeader = "X-ContainerSSH-Nonce"
+
+// SignatureHeader is the header containing the signature of the request.
+const SignatureHeader = "X-ContainerSSH-Signature"
+
+// signaturePrefix is prepended to the hex-encoded signature to allow for other algorithms in the future.
+const signaturePrefix = "sha256="
+
+// Sign calculates the signature of a request. The signature is the hex-encoded HMAC-SHA256 of the timestamp, the
+// nonce and the request body separated by dots, with the shared secret as the key, prefixed with sha256=.
+func Sign(secret string, timestamp string, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write([]byte(nonce))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	goHttp "net/http"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+// defaultSignatureMaxAge is used if the maximum age of a signature is not configured.
+const defaultSignatureMaxAge = 5 * time.Minute
+
+// NewSignatureVerifier wraps the handler in a middleware that rejects requests without a valid signature. Replayed
+// requests are rejected by remembering the nonces of the requests within the maximum age. If no secret is configured
+// the handler is returned unchanged.
+func NewSignatureVerifier(
+	config config.HTTPSignatureConfig,
+	handler goHttp.Handler,
+	logger log.Logger,
+) goHttp.Handler {
+	if handler == nil {
+		panic("BUG: no handler provided to http.NewSignatureVerifier")
+	}
+	if logger == nil {
+		panic("BUG: no logger provided to http.NewSignatureVerifier")
+	}
+	if config.Secret == "" {
+		return handler
+	}
+	maxAge := config.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultSignatureMaxAge
+	}
+	return &signatureVerifier{
+		secret:  config.Secret,
+		maxAge:  maxAge,
+		handler: handler,
+		logger:  logger,
+		now:     time.Now,
+		nonces:  map[string]time.Time{},
+	}
+}
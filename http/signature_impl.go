@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"io"
+	goHttp "net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+type signatureVerifier struct {
+	secret  string
+	maxAge  time.Duration
+	handler goHttp.Handler
+	logger  log.Logger
+	now     func() time.Time
+
+	lock sync.Mutex
+	// nonces holds the nonces of the accepted requests and the time until they need to be remembered.
+	nonces map[string]time.Time
+	// nextCleanup is the time after which the expired nonces are removed the next time.
+	nextCleanup time.Time
+}
+
+func (s *signatureVerifier) ServeHTTP(writer goHttp.ResponseWriter, request *goHttp.Request) {
+	timestamp := request.Header.Get(SignatureTimestampHeader)
+	nonce := request.Header.Get(SignatureNonceHeader)
+	signature := request.Header.Get(SignatureHeader)
+	if timestamp == "" || nonce == "" || signature == "" {
+		s.reject(writer, message.NewMessage(message.EHTTPServerSignatureMissing, "Request is not signed"))
+		return
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		s.reject(
+			writer,
+			message.NewMessage(message.EHTTPServerSignatureInvalid, "Invalid signature timestamp: %s", timestamp),
+		)
+		return
+	}
+	signedAt := time.Unix(seconds, 0)
+	now := s.now()
+	if signedAt.Before(now.Add(-s.maxAge)) || signedAt.After(now.Add(s.maxAge)) {
+		s.reject(
+			writer,
+			message.NewMessage(
+				message.EHTTPServerSignatureExpired,
+				"The request has been signed at %s, which is outside of the accepted %s",
+				signedAt.UTC().Format(time.RFC3339),
+				s.maxAge,
+			),
+		)
+		return
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		writeError(writer, goHttp.StatusBadRequest)
+		return
+	}
+	expected := Sign(s.secret, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		s.reject(
+			writer,
+			message.NewMessage(message.EHTTPServerSignatureInvalid, "The request signature does not match"),
+		)
+		return
+	}
+
+	// The nonce is only recorded after the signature has been checked so unsigned requests cannot fill the list.
+	if !s.useNonce(nonce, signedAt.Add(s.maxAge), now) {
+		s.reject(
+			writer,
+			message.NewMessage(message.EHTTPServerSignatureReplay, "The request nonce has already been used"),
+		)
+		return
+	}
+
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	s.handler.ServeHTTP(writer, request)
+}
+
+// useNonce records the nonce until the expiry. It returns false if the nonce has already been used.
+func (s *signatureVerifier) useNonce(nonce string, expiry time.Time, now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.nonces[nonce]; ok {
+		return false
+	}
+	// Requests with expired nonces are rejected because of their timestamp, so they do not need to be remembered.
+	if now.After(s.nextCleanup) {
+		for n, e := range s.nonces {
+			if e.Before(now) {
+				delete(s.nonces, n)
+			}
+		}
+		s.nextCleanup = now.Add(time.Minute)
+	}
+	s.nonces[nonce] = expiry
+	return true
+}
+
+func (s *signatureVerifier) reject(writer goHttp.ResponseWriter, err message.Message) {
+	s.logger.Warning(err)
+	writeError(writer, goHttp.StatusUnauthorized)
+}
+
+func writeError(writer goHttp.ResponseWriter, statusCode int) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write([]byte("{\"error\":\"" + goHttp.StatusText(statusCode) + "\"}"))
+}
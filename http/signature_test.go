@@ -0,0 +1,75 @@
+package http_test
+
+import (
+	"bytes"
+	goHttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	http2 "go.containerssh.io/libcontainerssh/http"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+func TestSignature(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	signature := config.HTTPSignatureConfig{Secret: "secret"}
+	srv := httptest.NewServer(
+		http2.NewSignatureVerifier(signature, http2.NewServerHandler(&handler{}, logger), logger),
+	)
+	defer srv.Close()
+
+	for name, secret := range map[string]string{"signed": "secret", "unsigned": "", "wrong secret": "other"} {
+		t.Run(name, func(t *testing.T) {
+			clientConfig := config.HTTPClientConfiguration{}
+			structutils.Defaults(&clientConfig)
+			clientConfig.URL = srv.URL
+			clientConfig.Signature.Secret = secret
+			client, err := http2.NewClient(clientConfig, logger)
+			assert.NoError(t, err)
+
+			response := Response{}
+			status, err := client.Post("/", &Request{Message: "Hi"}, &response)
+			if secret == "secret" {
+				assert.NoError(t, err)
+				assert.Equal(t, 200, status)
+				assert.Equal(t, "Hello world!", response.Message)
+			} else {
+				assert.Equal(t, 401, status)
+			}
+		})
+	}
+}
+
+func TestSignatureReplay(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	signature := config.HTTPSignatureConfig{Secret: "secret", MaxAge: time.Minute}
+	srv := httptest.NewServer(
+		http2.NewSignatureVerifier(signature, http2.NewServerHandler(&handler{}, logger), logger),
+	)
+	defer srv.Close()
+
+	body := []byte(`{"Message":"Hi"}`)
+	send := func(timestamp time.Time, nonce string) int {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req, err := goHttp.NewRequest(goHttp.MethodPost, srv.URL, bytes.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set(http2.SignatureTimestampHeader, ts)
+		req.Header.Set(http2.SignatureNonceHeader, nonce)
+		req.Header.Set(http2.SignatureHeader, http2.Sign("secret", ts, nonce, body))
+		resp, err := goHttp.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, 200, send(time.Now(), "nonce1"))
+	assert.Equal(t, 401, send(time.Now(), "nonce1"), "a replayed nonce was accepted")
+	assert.Equal(t, 200, send(time.Now(), "nonce2"))
+	assert.Equal(t, 401, send(time.Now().Add(-2*time.Minute), "nonce3"), "an expired timestamp was accepted")
+	assert.Equal(t, 401, send(time.Now().Add(2*time.Minute), "nonce4"), "a future timestamp was accepted")
+}
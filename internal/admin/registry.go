@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"time"
+
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// Registry keeps track of the live SSH connections and their session channels. The admin handler records the
+// connections as they come and go, while the admin server reads the registry and acts on the channels.
+type Registry interface {
+	// Connections returns a snapshot of the open connections, ordered by the time they were established.
+	Connections() []Connection
+	// Connection returns a snapshot of a single open connection.
+	Connection(connectionID string) (Connection, error)
+	// CloseChannel closes a session channel of an open connection.
+	CloseChannel(connectionID string, channelID uint64) error
+	// Signal sends a signal to the program running in a session channel of an open connection. The signal is subject
+	// to the security configuration of the backend, the same as signals sent by the client.
+	Signal(connectionID string, channelID uint64, signal string) error
+
+	// AddConnection records a connection after a successful handshake.
+	AddConnection(meta metadata.ConnectionAuthenticatedMetadata)
+	// RemoveConnection removes a connection and all its channels when the client disconnects.
+	RemoveConnection(connectionID string)
+	// AddChannel records a session channel. The control is used to close the channel and send signals to it.
+	AddChannel(meta metadata.ChannelMetadata, control ChannelControl)
+	// UpdateChannel changes the recorded details of a session channel, for example when a program is started.
+	UpdateChannel(connectionID string, channelID uint64, update func(channel *Channel))
+	// RemoveChannel removes a session channel when it is closed.
+	RemoveChannel(connectionID string, channelID uint64)
+}
+
+// ChannelControl acts on a session channel on request from the admin API.
+type ChannelControl interface {
+	// Close closes the channel.
+	Close() error
+	// Signal sends a signal to the program running in the channel.
+	Signal(signal string) error
+}
+
+// Connection is the admin API representation of an open SSH connection.
+type Connection struct {
+	// ConnectionID is the unique ID of the connection, as used in the logs.
+	ConnectionID string `json:"connectionId"`
+	// RemoteAddress is the IP address and port of the client.
+	RemoteAddress string `json:"remoteAddress"`
+	// Listener is the name of the SSH listener the client connected to.
+	Listener string `json:"listener,omitempty"`
+	// ListenAddress is the listen address of the SSH listener the client connected to.
+	ListenAddress string `json:"listenAddress,omitempty"`
+	// ClientVersion is the version string the client sent.
+	ClientVersion string `json:"clientVersion,omitempty"`
+	// Username is the username the client provided.
+	Username string `json:"username"`
+	// AuthenticatedUsername is the username the authentication confirmed.
+	AuthenticatedUsername string `json:"authenticatedUsername,omitempty"`
+	// ConnectedAt is the time the handshake completed.
+	ConnectedAt time.Time `json:"connectedAt"`
+	// BackendContainer is the container or pod the backend launched for the connection, if any. It is empty in the
+	// session execution mode, where the backend launches a container or pod per session channel.
+	BackendContainer string `json:"backendContainer,omitempty"`
+	// Metadata contains the metadata of the connection. Sensitive values are omitted.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Channels contains the open session channels, ordered by their ID.
+	Channels []Channel `json:"channels"`
+}
+
+// Channel is the admin API representation of an open session channel.
+type Channel struct {
+	// ChannelID is the ID of the channel within the connection.
+	ChannelID uint64 `json:"channelId"`
+	// OpenedAt is the time the channel was opened.
+	OpenedAt time.Time `json:"openedAt"`
+	// Pty indicates that the client requested an interactive terminal.
+	Pty bool `json:"pty"`
+	// Term is the terminal type the client requested.
+	Term string `json:"term,omitempty"`
+	// Mode is "shell", "exec" or "subsystem" once a program has been started in the channel.
+	Mode string `json:"mode,omitempty"`
+	// Command is the program requested by an exec request.
+	Command string `json:"command,omitempty"`
+	// Subsystem is the name of the requested subsystem.
+	Subsystem string `json:"subsystem,omitempty"`
+}
+
+const (
+	// ChannelModeShell indicates that a shell was started in the channel.
+	ChannelModeShell = "shell"
+	// ChannelModeExec indicates that a program was started in the channel.
+	ChannelModeExec = "exec"
+	// ChannelModeSubsystem indicates that a subsystem was started in the channel.
+	ChannelModeSubsystem = "subsystem"
+)
@@ -0,0 +1,8 @@
+package admin
+
+// NewRegistry creates an empty connection registry.
+func NewRegistry() Registry {
+	return &registry{
+		connections: map[string]*registryConnection{},
+	}
+}
@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type registry struct {
+	lock        sync.Mutex
+	connections map[string]*registryConnection
+}
+
+type registryConnection struct {
+	connection Connection
+	channels   map[uint64]*registryChannel
+}
+
+type registryChannel struct {
+	channel Channel
+	control ChannelControl
+}
+
+func (r *registry) Connections() []Connection {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	result := make([]Connection, 0, len(r.connections))
+	for _, conn := range r.connections {
+		result = append(result, conn.snapshot())
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ConnectedAt.Before(result[j].ConnectedAt)
+	})
+	return result
+}
+
+func (r *registry) Connection(connectionID string) (Connection, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.connections[connectionID]
+	if !ok {
+		return Connection{}, connectionNotFound(connectionID)
+	}
+	return conn.snapshot(), nil
+}
+
+func (r *registry) CloseChannel(connectionID string, channelID uint64) error {
+	control, err := r.control(connectionID, channelID)
+	if err != nil {
+		return err
+	}
+	// The control is called without holding the lock because closing the channel removes it from the registry.
+	return control.Close()
+}
+
+func (r *registry) Signal(connectionID string, channelID uint64, signal string) error {
+	control, err := r.control(connectionID, channelID)
+	if err != nil {
+		return err
+	}
+	return control.Signal(signal)
+}
+
+func (r *registry) control(connectionID string, channelID uint64) (ChannelControl, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.connections[connectionID]
+	if !ok {
+		return nil, connectionNotFound(connectionID)
+	}
+	ch, ok := conn.channels[channelID]
+	if !ok {
+		return nil, message.NewMessage(
+			message.EAdminChannelNotFound,
+			"No open session channel with the ID %d on connection %s",
+			channelID,
+			connectionID,
+		).Label("connectionId", connectionID).Label("channelId", channelID)
+	}
+	return ch.control, nil
+}
+
+func (r *registry) AddConnection(meta metadata.ConnectionAuthenticatedMetadata) {
+	conn := Connection{
+		ConnectionID:          meta.ConnectionID,
+		RemoteAddress:         meta.RemoteAddress.String(),
+		Listener:              meta.Listener,
+		ListenAddress:         meta.ListenAddress,
+		ClientVersion:         meta.ClientVersion,
+		Username:              meta.Username,
+		AuthenticatedUsername: meta.AuthenticatedUsername,
+		ConnectedAt:           time.Now(),
+		Metadata:              map[string]string{},
+	}
+	for key, value := range meta.Metadata {
+		if key == metadata.BackendContainerKey {
+			conn.BackendContainer = value.Value
+			continue
+		}
+		if !value.Sensitive {
+			conn.Metadata[key] = value.Value
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.connections[meta.ConnectionID] = &registryConnection{
+		connection: conn,
+		channels:   map[uint64]*registryChannel{},
+	}
+}
+
+func (r *registry) RemoveConnection(connectionID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.connections, connectionID)
+}
+
+func (r *registry) AddChannel(meta metadata.ChannelMetadata, control ChannelControl) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.connections[meta.Connection.ConnectionID]
+	if !ok {
+		return
+	}
+	conn.channels[meta.ChannelID] = &registryChannel{
+		channel: Channel{
+			ChannelID: meta.ChannelID,
+			OpenedAt:  time.Now(),
+		},
+		control: control,
+	}
+}
+
+func (r *registry) UpdateChannel(connectionID string, channelID uint64, update func(channel *Channel)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.connections[connectionID]
+	if !ok {
+		return
+	}
+	ch, ok := conn.channels[channelID]
+	if !ok {
+		return
+	}
+	update(&ch.channel)
+}
+
+func (r *registry) RemoveChannel(connectionID string, channelID uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conn, ok := r.connections[connectionID]
+	if !ok {
+		return
+	}
+	delete(conn.channels, channelID)
+}
+
+func (c *registryConnection) snapshot() Connection {
+	result := c.connection
+	result.Metadata = make(map[string]string, len(c.connection.Metadata))
+	for key, value := range c.connection.Metadata {
+		result.Metadata[key] = value
+	}
+	result.Channels = make([]Channel, 0, len(c.channels))
+	for _, ch := range c.channels {
+		result.Channels = append(result.Channels, ch.channel)
+	}
+	sort.SliceStable(result.Channels, func(i, j int) bool {
+		return result.Channels[i].ChannelID < result.Channels[j].ChannelID
+	})
+	return result
+}
+
+func connectionNotFound(connectionID string) error {
+	return message.NewMessage(
+		message.EAdminConnectionNotFound,
+		"No open connection with the ID %s",
+		connectionID,
+	).Label("connectionId", connectionID)
+}
@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	http2 "go.containerssh.io/libcontainerssh/http"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// Disconnecter closes SSH connections. It is implemented by the SSH server.
+type Disconnecter interface {
+	// Disconnect closes the SSH connection with the given connection ID.
+	Disconnect(connectionID string) error
+}
+
+// NewServer creates the admin API server based on the configuration. It MAY return nil if the admin API is disabled.
+func NewServer(
+	cfg config.AdminConfig,
+	registry Registry,
+	disconnecter Disconnecter,
+	logger log.Logger,
+) (http2.Server, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return http2.NewServer(
+		"Admin API",
+		cfg.HTTPServerConfiguration,
+		NewHandler(registry, disconnecter, logger),
+		logger,
+		func(url string) {
+			logger.Info(message.NewMessage(message.MAdminServiceAvailable, "Admin API is now available at %s", url))
+		},
+	)
+}
@@ -0,0 +1,210 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	goHttp "net/http"
+	"strconv"
+
+	http2 "go.containerssh.io/libcontainerssh/http"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewHandler creates the HTTP handler of the admin API. It serves the following endpoints:
+//
+//   - GET /connections lists the open connections.
+//   - GET /connections/{connectionId} returns a single connection.
+//   - DELETE /connections/{connectionId} disconnects a connection.
+//   - DELETE /connections/{connectionId}/channels/{channelId} closes a session channel.
+//   - POST /connections/{connectionId}/channels/{channelId}/signal sends the signal in the {"signal":"TERM"} request
+//     body to the program running in a session channel.
+func NewHandler(registry Registry, disconnecter Disconnecter, logger log.Logger) goHttp.Handler {
+	mux := goHttp.NewServeMux()
+	route := func(pattern string, factory func(request *goHttp.Request) http2.RequestHandler) {
+		mux.HandleFunc(pattern, func(writer goHttp.ResponseWriter, request *goHttp.Request) {
+			http2.NewServerHandler(factory(request), logger).ServeHTTP(writer, request)
+		})
+	}
+
+	route("GET /connections", func(_ *goHttp.Request) http2.RequestHandler {
+		return &listHandler{registry: registry}
+	})
+	route("GET /connections/{connectionId}", func(request *goHttp.Request) http2.RequestHandler {
+		return &getHandler{registry: registry, connectionID: request.PathValue("connectionId")}
+	})
+	route("DELETE /connections/{connectionId}", func(request *goHttp.Request) http2.RequestHandler {
+		return &disconnectHandler{
+			disconnecter: disconnecter,
+			connectionID: request.PathValue("connectionId"),
+			logger:       logger,
+		}
+	})
+	route("DELETE /connections/{connectionId}/channels/{channelId}", func(request *goHttp.Request) http2.RequestHandler {
+		return &closeChannelHandler{
+			registry:     registry,
+			connectionID: request.PathValue("connectionId"),
+			channelID:    request.PathValue("channelId"),
+			logger:       logger,
+		}
+	})
+	route("POST /connections/{connectionId}/channels/{channelId}/signal", func(request *goHttp.Request) http2.RequestHandler {
+		return &signalHandler{
+			registry:     registry,
+			connectionID: request.PathValue("connectionId"),
+			channelID:    request.PathValue("channelId"),
+			logger:       logger,
+		}
+	})
+	return mux
+}
+
+// SignalRequest is the request body of the signal endpoint.
+type SignalRequest struct {
+	// Signal is the name of the signal without the SIG prefix, for example TERM.
+	Signal string `json:"signal"`
+}
+
+// ErrorResponse is the response body of failed requests.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type listHandler struct {
+	registry Registry
+}
+
+func (l *listHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
+	response.SetBody(l.registry.Connections())
+	return nil
+}
+
+type getHandler struct {
+	registry     Registry
+	connectionID string
+}
+
+func (g *getHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
+	conn, err := g.registry.Connection(g.connectionID)
+	if err != nil {
+		setError(response, err)
+		return nil
+	}
+	response.SetBody(conn)
+	return nil
+}
+
+type disconnectHandler struct {
+	disconnecter Disconnecter
+	connectionID string
+	logger       log.Logger
+}
+
+func (d *disconnectHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
+	d.logger.Notice(
+		message.NewMessage(
+			message.MAdminDisconnect,
+			"Disconnecting connection %s on request from the admin API",
+			d.connectionID,
+		).Label("connectionId", d.connectionID),
+	)
+	if err := d.disconnecter.Disconnect(d.connectionID); err != nil {
+		setError(response, err)
+		return nil
+	}
+	response.SetBody(struct{}{})
+	return nil
+}
+
+type closeChannelHandler struct {
+	registry     Registry
+	connectionID string
+	channelID    string
+	logger       log.Logger
+}
+
+func (c *closeChannelHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
+	channelID, err := strconv.ParseUint(c.channelID, 10, 64)
+	if err != nil {
+		setBadRequest(response, "invalid channel ID: %s", c.channelID)
+		return nil
+	}
+	c.logger.Notice(
+		message.NewMessage(
+			message.MAdminCloseChannel,
+			"Closing channel %d of connection %s on request from the admin API",
+			channelID,
+			c.connectionID,
+		).Label("connectionId", c.connectionID).Label("channelId", channelID),
+	)
+	if err := c.registry.CloseChannel(c.connectionID, channelID); err != nil {
+		setError(response, err)
+		return nil
+	}
+	response.SetBody(struct{}{})
+	return nil
+}
+
+type signalHandler struct {
+	registry     Registry
+	connectionID string
+	channelID    string
+	logger       log.Logger
+}
+
+func (s *signalHandler) OnRequest(request http2.ServerRequest, response http2.ServerResponse) error {
+	channelID, err := strconv.ParseUint(s.channelID, 10, 64)
+	if err != nil {
+		setBadRequest(response, "invalid channel ID: %s", s.channelID)
+		return nil
+	}
+	signalRequest := SignalRequest{}
+	if err := request.Decode(&signalRequest); err != nil || signalRequest.Signal == "" {
+		setBadRequest(response, "the request body must contain the signal to send")
+		return nil
+	}
+	s.logger.Notice(
+		message.NewMessage(
+			message.MAdminSignal,
+			"Sending signal %s to channel %d of connection %s on request from the admin API",
+			signalRequest.Signal,
+			channelID,
+			s.connectionID,
+		).Label("connectionId", s.connectionID).Label("channelId", channelID),
+	)
+	if err := s.registry.Signal(s.connectionID, channelID, signalRequest.Signal); err != nil {
+		setError(response, err)
+		return nil
+	}
+	response.SetBody(struct{}{})
+	return nil
+}
+
+func setBadRequest(response http2.ServerResponse, format string, args ...interface{}) {
+	response.SetStatus(400)
+	response.SetBody(ErrorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+func setError(response http2.ServerResponse, err error) {
+	var msg message.Message
+	if errors.As(err, &msg) {
+		switch msg.Code() {
+		case message.EAdminConnectionNotFound, message.EAdminChannelNotFound, message.ESSHConnectionNotFound:
+			response.SetStatus(404)
+			response.SetBody(ErrorResponse{Error: msg.Explanation()})
+			return
+		case message.ESecuritySignalRejected:
+			// Signals from the admin API pass through the security layer like the signals sent by the client.
+			response.SetStatus(403)
+			response.SetBody(
+				ErrorResponse{
+					Error: "the signal was rejected by the security configuration (security.signal), which also " +
+						"applies to signals sent from the admin API",
+				},
+			)
+			return
+		}
+	}
+	response.SetStatus(500)
+	response.SetBody(ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,133 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	goHttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestAPI(t *testing.T) {
+	registry := admin.NewRegistry()
+	meta := metadata.ConnectionMetadata{
+		RemoteAddress: metadata.RemoteAddress(net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2222}),
+		ConnectionID:  "test",
+	}.StartAuthentication("SSH-2.0-Test", "foo").Authenticated("foo")
+	registry.AddConnection(meta)
+	control := &testControl{}
+	registry.AddChannel(meta.Channel(0), control)
+	disconnecter := &testDisconnecter{}
+	server := httptest.NewServer(admin.NewHandler(registry, disconnecter, log.NewTestLogger(t)))
+	defer server.Close()
+
+	var connections []admin.Connection
+	assert.Equal(t, 200, request(t, server, "GET", "/connections", "", &connections))
+	if !assert.Equal(t, 1, len(connections)) {
+		return
+	}
+	assert.Equal(t, "test", connections[0].ConnectionID)
+	assert.Equal(t, 1, len(connections[0].Channels))
+
+	var connection admin.Connection
+	assert.Equal(t, 200, request(t, server, "GET", "/connections/test", "", &connection))
+	assert.Equal(t, "foo", connection.Username)
+	assert.Equal(t, 404, request(t, server, "GET", "/connections/other", "", nil))
+
+	assert.Equal(t, 200, request(t, server, "POST", "/connections/test/channels/0/signal", `{"signal":"INT"}`, nil))
+	assert.Equal(t, []string{"INT"}, control.signals)
+	assert.Equal(t, 400, request(t, server, "POST", "/connections/test/channels/0/signal", `{}`, nil))
+	assert.Equal(t, 400, request(t, server, "POST", "/connections/test/channels/x/signal", `{"signal":"INT"}`, nil))
+	assert.Equal(t, 404, request(t, server, "POST", "/connections/test/channels/1/signal", `{"signal":"INT"}`, nil))
+
+	var errorResponse admin.ErrorResponse
+	control.signalErr = message.UserMessage(message.ESecuritySignalRejected, "Sending signals is rejected.", "Rejected")
+	assert.Equal(t, 403, request(t, server, "POST", "/connections/test/channels/0/signal", `{"signal":"KILL"}`, &errorResponse))
+	assert.Contains(t, errorResponse.Error, "security")
+	control.signalErr = fmt.Errorf("backend failure")
+	assert.Equal(t, 500, request(t, server, "POST", "/connections/test/channels/0/signal", `{"signal":"KILL"}`, nil))
+	control.signalErr = nil
+
+	assert.Equal(t, 200, request(t, server, "DELETE", "/connections/test/channels/0", "", nil))
+	assert.True(t, control.closed)
+
+	assert.Equal(t, 200, request(t, server, "DELETE", "/connections/test", "", nil))
+	assert.Equal(t, []string{"test"}, disconnecter.disconnected)
+	assert.Equal(t, 404, request(t, server, "DELETE", "/connections/other", "", nil))
+}
+
+func TestNewServerRequiresClientCertificates(t *testing.T) {
+	_, err := admin.NewServer(
+		config.AdminConfig{
+			HTTPServerConfiguration: config.HTTPServerConfiguration{
+				Listen: "127.0.0.1:9200",
+			},
+			Enable: true,
+		},
+		admin.NewRegistry(),
+		&testDisconnecter{},
+		log.NewTestLogger(t),
+	)
+	assert.Error(t, err)
+
+	server, err := admin.NewServer(config.AdminConfig{}, admin.NewRegistry(), &testDisconnecter{}, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	assert.Nil(t, server)
+}
+
+func request(t *testing.T, server *httptest.Server, method string, path string, body string, target interface{}) int {
+	req, err := goHttp.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	response, err := server.Client().Do(req)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	defer func() { _ = response.Body.Close() }()
+	if target != nil {
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(target))
+	}
+	return response.StatusCode
+}
+
+type testControl struct {
+	closed    bool
+	signals   []string
+	signalErr error
+}
+
+func (t *testControl) Close() error {
+	t.closed = true
+	return nil
+}
+
+func (t *testControl) Signal(signal string) error {
+	if t.signalErr != nil {
+		return t.signalErr
+	}
+	t.signals = append(t.signals, signal)
+	return nil
+}
+
+type testDisconnecter struct {
+	disconnected []string
+}
+
+func (t *testDisconnecter) Disconnect(connectionID string) error {
+	if connectionID != "test" {
+		return message.NewMessage(message.ESSHConnectionNotFound, "No open connection with the ID %s", connectionID)
+	}
+	t.disconnected = append(t.disconnected, connectionID)
+	return nil
+}
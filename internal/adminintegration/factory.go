@@ -0,0 +1,23 @@
+package adminintegration
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+)
+
+// NewHandler creates a handler that records the connections and session channels passing through it in the registry
+// of the admin API. If the admin API is disabled the backend is returned unchanged.
+func NewHandler(
+	cfg config.AdminConfig,
+	registry admin.Registry,
+	backend sshserver.Handler,
+) (sshserver.Handler, error) {
+	if !cfg.Enable {
+		return backend, nil
+	}
+	return &handler{
+		backend:  backend,
+		registry: registry,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package adminintegration
+
+import (
+	"context"
+
+	auth2 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type handler struct {
+	backend  sshserver.Handler
+	registry admin.Registry
+}
+
+func (h *handler) OnReady() error {
+	return h.backend.OnReady()
+}
+
+func (h *handler) OnShutdown(shutdownContext context.Context) {
+	h.backend.OnShutdown(shutdownContext)
+}
+
+func (h *handler) OnNetworkConnection(
+	meta metadata.ConnectionMetadata,
+) (sshserver.NetworkConnectionHandler, metadata.ConnectionMetadata, error) {
+	networkBackend, meta, err := h.backend.OnNetworkConnection(meta)
+	if err != nil {
+		return networkBackend, meta, err
+	}
+	return &networkHandler{
+		backend:      networkBackend,
+		registry:     h.registry,
+		connectionID: meta.ConnectionID,
+	}, meta, nil
+}
+
+type networkHandler struct {
+	backend      sshserver.NetworkConnectionHandler
+	registry     admin.Registry
+	connectionID string
+}
+
+func (n *networkHandler) OnShutdown(shutdownContext context.Context) {
+	n.backend.OnShutdown(shutdownContext)
+}
+
+func (n *networkHandler) OnAuthPassword(meta metadata.ConnectionAuthPendingMetadata, password []byte) (
+	response sshserver.AuthResponse,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	reason error,
+) {
+	return n.backend.OnAuthPassword(meta, password)
+}
+
+func (n *networkHandler) OnAuthPubKey(meta metadata.ConnectionAuthPendingMetadata, pubKey auth2.PublicKey) (
+	response sshserver.AuthResponse,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	reason error,
+) {
+	return n.backend.OnAuthPubKey(meta, pubKey)
+}
+
+func (n *networkHandler) OnAuthKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions sshserver.KeyboardInteractiveQuestions,
+	) (answers sshserver.KeyboardInteractiveAnswers, err error),
+) (
+	response sshserver.AuthResponse,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	reason error,
+) {
+	return n.backend.OnAuthKeyboardInteractive(meta, challenge)
+}
+
+func (n *networkHandler) OnAuthGSSAPI(meta metadata.ConnectionMetadata) auth.GSSAPIServer {
+	return n.backend.OnAuthGSSAPI(meta)
+}
+
+func (n *networkHandler) OnHandshakeFailed(meta metadata.ConnectionMetadata, reason error) {
+	n.backend.OnHandshakeFailed(meta, reason)
+}
+
+func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	connection sshserver.SSHConnectionHandler,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	failureReason error,
+) {
+	connectionHandler, meta, failureReason := n.backend.OnHandshakeSuccess(meta)
+	if failureReason != nil {
+		return connectionHandler, meta, failureReason
+	}
+	n.registry.AddConnection(meta)
+	return &sshConnectionHandler{
+		backend:      connectionHandler,
+		registry:     n.registry,
+		connectionID: meta.ConnectionID,
+	}, meta, nil
+}
+
+func (n *networkHandler) OnDisconnect() {
+	n.registry.RemoveConnection(n.connectionID)
+	n.backend.OnDisconnect()
+}
+
+type sshConnectionHandler struct {
+	backend      sshserver.SSHConnectionHandler
+	registry     admin.Registry
+	connectionID string
+}
+
+func (s *sshConnectionHandler) OnUnsupportedGlobalRequest(requestID uint64, requestType string, payload []byte) {
+	s.backend.OnUnsupportedGlobalRequest(requestID, requestType, payload)
+}
+
+func (s *sshConnectionHandler) OnFailedDecodeGlobalRequest(
+	requestID uint64,
+	requestType string,
+	payload []byte,
+	reason error,
+) {
+	s.backend.OnFailedDecodeGlobalRequest(requestID, requestType, payload, reason)
+}
+
+func (s *sshConnectionHandler) OnUnsupportedChannel(channelID uint64, channelType string, extraData []byte) {
+	s.backend.OnUnsupportedChannel(channelID, channelType, extraData)
+}
+
+func (s *sshConnectionHandler) OnSessionChannel(
+	meta metadata.ChannelMetadata,
+	extraData []byte,
+	session sshserver.SessionChannel,
+) (channel sshserver.SessionChannelHandler, failureReason sshserver.ChannelRejection) {
+	backend, failureReason := s.backend.OnSessionChannel(meta, extraData, session)
+	if failureReason != nil {
+		return nil, failureReason
+	}
+	sessionHandler := &sessionChannelHandler{
+		backend:      backend,
+		session:      session,
+		registry:     s.registry,
+		connectionID: s.connectionID,
+		channelID:    meta.ChannelID,
+	}
+	s.registry.AddChannel(meta, sessionHandler)
+	return sessionHandler, nil
+}
+
+func (s *sshConnectionHandler) OnTCPForwardChannel(
+	channelID uint64,
+	hostToConnect string,
+	portToConnect uint32,
+	originatorHost string,
+	originatorPort uint32,
+) (channel sshserver.ForwardChannel, failureReason sshserver.ChannelRejection) {
+	return s.backend.OnTCPForwardChannel(channelID, hostToConnect, portToConnect, originatorHost, originatorPort)
+}
+
+func (s *sshConnectionHandler) OnRequestTCPReverseForward(
+	bindHost string,
+	bindPort uint32,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	return s.backend.OnRequestTCPReverseForward(bindHost, bindPort, reverseHandler)
+}
+
+func (s *sshConnectionHandler) OnRequestCancelTCPReverseForward(bindHost string, bindPort uint32) error {
+	return s.backend.OnRequestCancelTCPReverseForward(bindHost, bindPort)
+}
+
+func (s *sshConnectionHandler) OnDirectStreamLocal(
+	channelID uint64,
+	path string,
+) (channel sshserver.ForwardChannel, failureReason sshserver.ChannelRejection) {
+	return s.backend.OnDirectStreamLocal(channelID, path)
+}
+
+func (s *sshConnectionHandler) OnRequestStreamLocal(path string, reverseHandler sshserver.ReverseForward) error {
+	return s.backend.OnRequestStreamLocal(path, reverseHandler)
+}
+
+func (s *sshConnectionHandler) OnRequestCancelStreamLocal(path string) error {
+	return s.backend.OnRequestCancelStreamLocal(path)
+}
+
+func (s *sshConnectionHandler) OnShutdown(shutdownContext context.Context) {
+	s.backend.OnShutdown(shutdownContext)
+}
@@ -0,0 +1,161 @@
+package adminintegration
+
+import (
+	"context"
+	"sync"
+
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+)
+
+// sessionChannelHandler records the requests of a session channel in the registry and implements admin.ChannelControl
+// to act on the channel.
+type sessionChannelHandler struct {
+	backend      sshserver.SessionChannelHandler
+	session      sshserver.SessionChannel
+	registry     admin.Registry
+	connectionID string
+	channelID    uint64
+
+	// lock protects nextRequestID, the request ID used for the signals sent from the admin API.
+	lock          sync.Mutex
+	nextRequestID uint64
+}
+
+func (s *sessionChannelHandler) Close() error {
+	return s.session.Close()
+}
+
+// Signal passes the signal to the backend like a signal request from the client, so the security configuration of the
+// backend applies and may reject it.
+func (s *sessionChannelHandler) Signal(signal string) error {
+	s.lock.Lock()
+	requestID := s.nextRequestID
+	s.nextRequestID++
+	s.lock.Unlock()
+	return s.backend.OnSignal(requestID, signal)
+}
+
+// onRequest keeps the request IDs of the signals sent from the admin API after the ones sent by the client.
+func (s *sessionChannelHandler) onRequest(requestID uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if requestID >= s.nextRequestID {
+		s.nextRequestID = requestID + 1
+	}
+}
+
+func (s *sessionChannelHandler) update(update func(channel *admin.Channel)) {
+	s.registry.UpdateChannel(s.connectionID, s.channelID, update)
+}
+
+func (s *sessionChannelHandler) OnUnsupportedChannelRequest(requestID uint64, requestType string, payload []byte) {
+	s.onRequest(requestID)
+	s.backend.OnUnsupportedChannelRequest(requestID, requestType, payload)
+}
+
+func (s *sessionChannelHandler) OnFailedDecodeChannelRequest(
+	requestID uint64,
+	requestType string,
+	payload []byte,
+	reason error,
+) {
+	s.onRequest(requestID)
+	s.backend.OnFailedDecodeChannelRequest(requestID, requestType, payload, reason)
+}
+
+func (s *sessionChannelHandler) OnEnvRequest(requestID uint64, name string, value string) error {
+	s.onRequest(requestID)
+	return s.backend.OnEnvRequest(requestID, name, value)
+}
+
+func (s *sessionChannelHandler) OnPtyRequest(
+	requestID uint64,
+	term string,
+	columns uint32,
+	rows uint32,
+	width uint32,
+	height uint32,
+	modeList []byte,
+) error {
+	s.onRequest(requestID)
+	if err := s.backend.OnPtyRequest(requestID, term, columns, rows, width, height, modeList); err != nil {
+		return err
+	}
+	s.update(func(channel *admin.Channel) {
+		channel.Pty = true
+		channel.Term = term
+	})
+	return nil
+}
+
+func (s *sessionChannelHandler) OnX11Request(
+	requestID uint64,
+	singleConnection bool,
+	protocol string,
+	cookie string,
+	screen uint32,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	s.onRequest(requestID)
+	return s.backend.OnX11Request(requestID, singleConnection, protocol, cookie, screen, reverseHandler)
+}
+
+func (s *sessionChannelHandler) OnAuthAgentRequest(requestID uint64, reverseHandler sshserver.ReverseForward) error {
+	s.onRequest(requestID)
+	return s.backend.OnAuthAgentRequest(requestID, reverseHandler)
+}
+
+func (s *sessionChannelHandler) OnExecRequest(requestID uint64, program string) error {
+	s.onRequest(requestID)
+	if err := s.backend.OnExecRequest(requestID, program); err != nil {
+		return err
+	}
+	s.update(func(channel *admin.Channel) {
+		channel.Mode = admin.ChannelModeExec
+		channel.Command = program
+	})
+	return nil
+}
+
+func (s *sessionChannelHandler) OnShell(requestID uint64) error {
+	s.onRequest(requestID)
+	if err := s.backend.OnShell(requestID); err != nil {
+		return err
+	}
+	s.update(func(channel *admin.Channel) {
+		channel.Mode = admin.ChannelModeShell
+	})
+	return nil
+}
+
+func (s *sessionChannelHandler) OnSubsystem(requestID uint64, subsystem string) error {
+	s.onRequest(requestID)
+	if err := s.backend.OnSubsystem(requestID, subsystem); err != nil {
+		return err
+	}
+	s.update(func(channel *admin.Channel) {
+		channel.Mode = admin.ChannelModeSubsystem
+		channel.Subsystem = subsystem
+	})
+	return nil
+}
+
+func (s *sessionChannelHandler) OnSignal(requestID uint64, signal string) error {
+	s.onRequest(requestID)
+	return s.backend.OnSignal(requestID, signal)
+}
+
+func (s *sessionChannelHandler) OnWindow(requestID uint64, columns uint32, rows uint32, width uint32, height uint32) error {
+	s.onRequest(requestID)
+	return s.backend.OnWindow(requestID, columns, rows, width, height)
+}
+
+func (s *sessionChannelHandler) OnClose() {
+	s.registry.RemoveChannel(s.connectionID, s.channelID)
+	s.backend.OnClose()
+}
+
+func (s *sessionChannelHandler) OnShutdown(shutdownContext context.Context) {
+	s.backend.OnShutdown(shutdownContext)
+}
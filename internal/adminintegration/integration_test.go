@@ -0,0 +1,256 @@
+package adminintegration_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/admin"
+	"go.containerssh.io/libcontainerssh/internal/adminintegration"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestConnectionTracking(t *testing.T) {
+	registry := admin.NewRegistry()
+	backend := &testBackend{}
+	handler, err := adminintegration.NewHandler(config.AdminConfig{Enable: true}, registry, backend)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	connectionMeta := metadata.ConnectionMetadata{
+		RemoteAddress: metadata.RemoteAddress(
+			net.TCPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: 2222,
+			},
+		),
+		ConnectionID: sshserver.GenerateConnectionID(),
+		Listener:     "default",
+	}
+	networkHandler, connectionMeta, err := handler.OnNetworkConnection(connectionMeta)
+	if !assert.NoError(t, err) {
+		return
+	}
+	authenticatedMeta := connectionMeta.StartAuthentication("SSH-2.0-Test", "foo").Authenticated("foo")
+	authenticatedMeta.GetMetadata()["team"] = metadata.Value{Value: "ops"}
+	authenticatedMeta.GetMetadata()["token"] = metadata.Value{Value: "secret", Sensitive: true}
+	assert.Equal(t, 0, len(registry.Connections()))
+
+	sshHandler, authenticatedMeta, err := networkHandler.OnHandshakeSuccess(authenticatedMeta)
+	if !assert.NoError(t, err) {
+		return
+	}
+	connections := registry.Connections()
+	if !assert.Equal(t, 1, len(connections)) {
+		return
+	}
+	assert.Equal(t, connectionMeta.ConnectionID, connections[0].ConnectionID)
+	assert.Equal(t, "127.0.0.1:2222", connections[0].RemoteAddress)
+	assert.Equal(t, "default", connections[0].Listener)
+	assert.Equal(t, "foo", connections[0].AuthenticatedUsername)
+	assert.Equal(t, "test-container", connections[0].BackendContainer)
+	assert.Equal(t, map[string]string{"team": "ops"}, connections[0].Metadata)
+	assert.Equal(t, 0, len(connections[0].Channels))
+
+	session := &testSession{}
+	channelHandler, rejection := sshHandler.OnSessionChannel(authenticatedMeta.Channel(1), nil, session)
+	if !assert.Nil(t, rejection) {
+		return
+	}
+	assert.NoError(t, channelHandler.OnPtyRequest(0, "xterm", 80, 25, 0, 0, nil))
+	assert.NoError(t, channelHandler.OnExecRequest(1, "top"))
+
+	conn, err := registry.Connection(connectionMeta.ConnectionID)
+	if !assert.NoError(t, err) || !assert.Equal(t, 1, len(conn.Channels)) {
+		return
+	}
+	assert.Equal(t, uint64(1), conn.Channels[0].ChannelID)
+	assert.True(t, conn.Channels[0].Pty)
+	assert.Equal(t, "xterm", conn.Channels[0].Term)
+	assert.Equal(t, admin.ChannelModeExec, conn.Channels[0].Mode)
+	assert.Equal(t, "top", conn.Channels[0].Command)
+
+	// Signals from the admin API are passed to the backend with request IDs after the ones of the client.
+	assert.NoError(t, registry.Signal(connectionMeta.ConnectionID, 1, "TERM"))
+	assert.Equal(t, []string{"TERM"}, backend.signals())
+	assert.Equal(t, uint64(2), backend.lastSignalRequestID())
+
+	err = registry.Signal(connectionMeta.ConnectionID, 2, "TERM")
+	assert.Error(t, err)
+	assert.Equal(t, message.EAdminChannelNotFound, err.(message.Message).Code())
+
+	assert.NoError(t, registry.CloseChannel(connectionMeta.ConnectionID, 1))
+	assert.True(t, session.isClosed())
+	channelHandler.OnClose()
+	conn, err = registry.Connection(connectionMeta.ConnectionID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(conn.Channels))
+
+	networkHandler.OnDisconnect()
+	assert.Equal(t, 0, len(registry.Connections()))
+	_, err = registry.Connection(connectionMeta.ConnectionID)
+	assert.Error(t, err)
+	assert.Equal(t, message.EAdminConnectionNotFound, err.(message.Message).Code())
+}
+
+func TestDisabled(t *testing.T) {
+	backend := &testBackend{}
+	handler, err := adminintegration.NewHandler(config.AdminConfig{}, admin.NewRegistry(), backend)
+	assert.NoError(t, err)
+	assert.Equal(t, sshserver.Handler(backend), handler)
+}
+
+type testBackend struct {
+	sshserver.AbstractHandler
+
+	lock            sync.Mutex
+	receivedSignals []string
+	lastRequestID   uint64
+}
+
+func (t *testBackend) signals() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.receivedSignals
+}
+
+func (t *testBackend) lastSignalRequestID() uint64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.lastRequestID
+}
+
+func (t *testBackend) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	return &testNetworkHandler{backend: t}, meta, nil
+}
+
+type testNetworkHandler struct {
+	sshserver.AbstractNetworkConnectionHandler
+
+	backend *testBackend
+}
+
+func (t *testNetworkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	sshserver.SSHConnectionHandler,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	meta.GetMetadata()[metadata.BackendContainerKey] = metadata.Value{Value: "test-container"}
+	return &testSSHHandler{backend: t.backend}, meta, nil
+}
+
+type testSSHHandler struct {
+	sshserver.AbstractSSHConnectionHandler
+
+	backend *testBackend
+}
+
+func (t *testSSHHandler) OnSessionChannel(_ metadata.ChannelMetadata, _ []byte, _ sshserver.SessionChannel) (
+	sshserver.SessionChannelHandler,
+	sshserver.ChannelRejection,
+) {
+	return &testChannelHandler{backend: t.backend}, nil
+}
+
+func (t *testSSHHandler) OnTCPForwardChannel(_ uint64, _ string, _ uint32, _ string, _ uint32) (
+	sshserver.ForwardChannel,
+	sshserver.ChannelRejection,
+) {
+	return nil, sshserver.NewChannelRejection(ssh.Prohibited, message.ESSHNotImplemented, "not implemented", "")
+}
+
+func (t *testSSHHandler) OnRequestTCPReverseForward(_ string, _ uint32, _ sshserver.ReverseForward) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (t *testSSHHandler) OnRequestCancelTCPReverseForward(_ string, _ uint32) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (t *testSSHHandler) OnDirectStreamLocal(_ uint64, _ string) (
+	sshserver.ForwardChannel,
+	sshserver.ChannelRejection,
+) {
+	return nil, sshserver.NewChannelRejection(ssh.Prohibited, message.ESSHNotImplemented, "not implemented", "")
+}
+
+func (t *testSSHHandler) OnRequestStreamLocal(_ string, _ sshserver.ReverseForward) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (t *testSSHHandler) OnRequestCancelStreamLocal(_ string) error {
+	return fmt.Errorf("not implemented")
+}
+
+type testChannelHandler struct {
+	sshserver.AbstractSessionChannelHandler
+
+	backend *testBackend
+}
+
+func (t *testChannelHandler) OnPtyRequest(_ uint64, _ string, _ uint32, _ uint32, _ uint32, _ uint32, _ []byte) error {
+	return nil
+}
+
+func (t *testChannelHandler) OnExecRequest(_ uint64, _ string) error {
+	return nil
+}
+
+func (t *testChannelHandler) OnSignal(requestID uint64, signal string) error {
+	t.backend.lock.Lock()
+	defer t.backend.lock.Unlock()
+	t.backend.receivedSignals = append(t.backend.receivedSignals, signal)
+	t.backend.lastRequestID = requestID
+	return nil
+}
+
+type testSession struct {
+	lock   sync.Mutex
+	closed bool
+}
+
+func (t *testSession) isClosed() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.closed
+}
+
+func (t *testSession) Stdin() io.Reader {
+	return nil
+}
+
+func (t *testSession) Stdout() io.Writer {
+	return io.Discard
+}
+
+func (t *testSession) Stderr() io.Writer {
+	return io.Discard
+}
+
+func (t *testSession) ExitStatus(_ uint32) {}
+
+func (t *testSession) ExitSignal(_ string, _ bool, _ string, _ string) {}
+
+func (t *testSession) CloseWrite() error {
+	return nil
+}
+
+func (t *testSession) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.closed = true
+	return nil
+}
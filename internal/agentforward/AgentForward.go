@@ -1,12 +1,21 @@
 package agentforward
 
 import (
+	"fmt"
 	"io"
 
 	"go.containerssh.io/libcontainerssh/internal/sshserver"
 	"go.containerssh.io/libcontainerssh/log"
 )
 
+// AuthAgentSocketPath returns the path of the unix socket in the container the SSH agent of the client is forwarded to
+// for a single session channel. The backends point the SSH_AUTH_SOCK environment variable to this socket. The path is
+// unique per connection and channel so that sessions sharing a container do not collide, and the agent removes the
+// socket when the forwarding is closed.
+func AuthAgentSocketPath(connectionID string, channelID uint64) string {
+	return fmt.Sprintf("/tmp/containerssh-agent-%s-%d.sock", connectionID, channelID)
+}
+
 // AgentForward is a network connection forwarding interface that uses the ContainerSSH Agent protocol
 type AgentForward interface {
 	// NewX11Forwarding initializes the X11 forwarding mode of the agent
@@ -75,6 +84,25 @@ type AgentForward interface {
 	// CloseX11Forwarding stops accepting new connections for the specified X11 forwarding. Existing connections are left intact
 	CloseX11Forwarding() error
 
+	// NewAuthAgentForwarding initializes the SSH agent forwarding mode of the agent. Connections to the unix socket are
+	// forwarded to the SSH agent of the client. Each session channel should use its own socket path.
+	//
+	// setupAgentCallback is a function that should start the agent on the desired target if it's called. It should return an interface to the stdin and stdout of a new instance of the agent.
+	// logger is the logging interface to be used
+	// path is path to the unix socket that will be listened on
+	// reverseHandler is an interface that notifies the caller of new connections
+	NewAuthAgentForwarding(
+		setupAgentCallback func() (io.Reader, io.Writer, error),
+		logger log.Logger,
+		path string,
+		reverseHandler sshserver.ReverseForward,
+	) error
+
+	// CloseAuthAgentForwarding stops listening on the specified agent socket. Existing connections are left intact
+	//
+	// path is the path to the unix socket passed to NewAuthAgentForwarding
+	CloseAuthAgentForwarding(path string) error
+
 	// NewForwardTCP start a new tcp forwarding connection (from the client to the agent)
 	//
 	// setupAgentCallback is a function that should start the agent on the desired target if it's called. It should return an interface to the stdin and stdout of a new instance of the agent.
@@ -12,20 +12,22 @@ import (
 )
 
 type agentForward struct {
-	lock            sync.Mutex
-	reverseForwards map[string]*protocol.ForwardCtx
-	nX11Channels    uint32
-	x11Forward      *protocol.ForwardCtx
-	directForward   *protocol.ForwardCtx
-	logger          log.Logger
+	lock              sync.Mutex
+	reverseForwards   map[string]*protocol.ForwardCtx
+	nX11Channels      uint32
+	x11Forward        *protocol.ForwardCtx
+	authAgentForwards map[string]*protocol.ForwardCtx
+	directForward     *protocol.ForwardCtx
+	logger            log.Logger
 }
 
 func NewAgentForward(
 	logger log.Logger,
 ) AgentForward {
 	return &agentForward{
-		reverseForwards: make(map[string]*protocol.ForwardCtx),
-		logger:          logger,
+		reverseForwards:   make(map[string]*protocol.ForwardCtx),
+		authAgentForwards: make(map[string]*protocol.ForwardCtx),
+		logger:            logger,
 	}
 }
 
@@ -94,6 +96,32 @@ func (f *agentForward) serveX11(connChan chan *protocol.Connection, reverseHandl
 	}
 }
 
+func (f *agentForward) serveAuthAgent(connChan chan *protocol.Connection, reverseHandler sshserver.ReverseForward) {
+	for {
+		agentConn, ok := <-connChan
+		if !ok {
+			return
+		}
+
+		forwardChannel, _, err := reverseHandler.NewChannelAuthAgent()
+		if err != nil {
+			f.logger.Warning("Failed to open SSH agent channel", err)
+			_ = agentConn.Reject()
+			continue
+		}
+
+		err = agentConn.Accept()
+		if err != nil {
+			f.logger.Warning("Failed to accept SSH agent connection", err)
+			_ = forwardChannel.Close()
+			continue
+		}
+
+		go serveConnection(f.logger, forwardChannel, agentConn)
+		go serveConnection(f.logger, agentConn, forwardChannel)
+	}
+}
+
 func (f *agentForward) serveReverseForward(connChan chan *protocol.Connection, reverseHandler sshserver.ReverseForward) {
 	for {
 		agentConn, ok := <-connChan
@@ -313,6 +341,47 @@ func (f *agentForward) CloseX11Forwarding() error {
 	return nil
 }
 
+func (f *agentForward) NewAuthAgentForwarding(
+	setupAgentCallback func() (io.Reader, io.Writer, error),
+	logger log.Logger,
+	path string,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, ok := f.authAgentForwards[path]; ok {
+		return fmt.Errorf("Agent forwarding already started for this socket")
+	}
+
+	fromAgent, toAgent, err := setupAgentCallback()
+	if err != nil {
+		return err
+	}
+
+	authAgentForward := protocol.NewForwardCtx(fromAgent, toAgent, logger)
+	connChan, err := authAgentForward.StartReverseForwardClientUnix(path, false)
+	if err != nil {
+		return err
+	}
+	f.authAgentForwards[path] = authAgentForward
+
+	go f.serveAuthAgent(connChan, reverseHandler)
+
+	return nil
+}
+
+func (f *agentForward) CloseAuthAgentForwarding(path string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ctx, ok := f.authAgentForwards[path]
+	if !ok {
+		return fmt.Errorf("Agent forwarding not found for this socket")
+	}
+	delete(f.authAgentForwards, path)
+
+	return ctx.NoMoreConnections()
+}
+
 func (f *agentForward) setupDirectForward(
 	setupAgentCallback func() (io.Reader, io.Writer, error),
 	logger log.Logger,
@@ -398,6 +467,10 @@ func (f *agentForward) OnShutdown() {
 		_ = f.directForward.NoMoreConnections()
 		f.x11Forward.Kill()
 	}
+	for _, forward := range f.authAgentForwards {
+		_ = forward.NoMoreConnections()
+		forward.Kill()
+	}
 	for _, forward := range f.reverseForwards {
 		_ = forward.NoMoreConnections()
 		forward.Kill()
@@ -0,0 +1,160 @@
+package agentforward_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	protocol "go.containerssh.io/libcontainerssh/agentprotocol"
+	"go.containerssh.io/libcontainerssh/internal/agentforward"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+func TestAuthAgentSocketPath(t *testing.T) {
+	assert.Equal(
+		t,
+		"/tmp/containerssh-agent-abcd-1.sock",
+		agentforward.AuthAgentSocketPath("abcd", 1),
+	)
+	assert.NotEqual(
+		t,
+		agentforward.AuthAgentSocketPath("abcd", 1),
+		agentforward.AuthAgentSocketPath("abcd", 2),
+	)
+}
+
+func TestAuthAgentForwarding(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	forward := agentforward.NewAgentForward(logger)
+	path := agentforward.AuthAgentSocketPath("test", 0)
+
+	container := newTestContainer(logger)
+	reverseHandler := &testReverseHandler{
+		failures: 1,
+		agents:   make(chan net.Conn),
+	}
+	go reverseHandler.serveClientAgent()
+
+	assert.NoError(t, forward.NewAuthAgentForwarding(container.setupAgent, logger, path, reverseHandler))
+	setup := <-container.setup
+	assert.Equal(t, "unix", setup.Protocol)
+	assert.Equal(t, path, setup.BindHost)
+
+	assert.Error(
+		t,
+		forward.NewAuthAgentForwarding(container.setupAgent, logger, path, reverseHandler),
+		"starting agent forwarding twice on the same socket should fail",
+	)
+
+	t.Run("failed channel is rejected", func(t *testing.T) {
+		conn, err := container.ctx.NewConnectionUnix(path, nil)
+		assert.NoError(t, err)
+		_, err = conn.Write([]byte("ping"))
+		assert.Error(t, err)
+	})
+
+	t.Run("subsequent connections are forwarded", func(t *testing.T) {
+		conn, err := container.ctx.NewConnectionUnix(path, nil)
+		assert.NoError(t, err)
+		_, err = conn.Write([]byte("ping"))
+		assert.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(buf))
+		_ = conn.Close()
+	})
+
+	assert.NoError(t, forward.CloseAuthAgentForwarding(path))
+	assert.Error(t, forward.CloseAuthAgentForwarding(path))
+	forward.OnShutdown()
+}
+
+type testContainer struct {
+	logger log.Logger
+	ctx    *protocol.ForwardCtx
+	setup  chan protocol.SetupPacket
+}
+
+func newTestContainer(logger log.Logger) *testContainer {
+	return &testContainer{
+		logger: logger,
+		setup:  make(chan protocol.SetupPacket, 1),
+	}
+}
+
+// setupAgent simulates starting the agent in the container and returns the stdout and stdin of the agent.
+func (c *testContainer) setupAgent() (io.Reader, io.Writer, error) {
+	fromAgentReader, fromAgentWriter := io.Pipe()
+	toAgentReader, toAgentWriter := io.Pipe()
+	c.ctx = protocol.NewForwardCtx(toAgentReader, fromAgentWriter, c.logger)
+	go func() {
+		_, setup, connChan, err := c.ctx.StartClient()
+		if err != nil {
+			close(c.setup)
+			return
+		}
+		c.setup <- setup
+		for {
+			conn, ok := <-connChan
+			if !ok {
+				return
+			}
+			_ = conn.Reject()
+		}
+	}()
+	return fromAgentReader, toAgentWriter, nil
+}
+
+type testReverseHandler struct {
+	lock     sync.Mutex
+	failures int
+	agents   chan net.Conn
+}
+
+// serveClientAgent simulates the SSH agent of the client, which answers every "ping" with "pong".
+func (r *testReverseHandler) serveClientAgent() {
+	for conn := range r.agents {
+		go func(conn net.Conn) {
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return
+			}
+			if string(buf) == "ping" {
+				_, _ = conn.Write([]byte("pong"))
+			}
+		}(conn)
+	}
+}
+
+func (r *testReverseHandler) NewChannelAuthAgent() (sshserver.ForwardChannel, uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		return nil, 0, fmt.Errorf("client refused the agent channel")
+	}
+	serverSide, clientSide := net.Pipe()
+	r.agents <- clientSide
+	return serverSide, 0, nil
+}
+
+func (r *testReverseHandler) NewChannelTCP(_ string, _ uint32, _ string, _ uint32) (
+	sshserver.ForwardChannel,
+	uint64,
+	error,
+) {
+	return nil, 0, fmt.Errorf("not supported")
+}
+
+func (r *testReverseHandler) NewChannelUnix(_ string) (sshserver.ForwardChannel, uint64, error) {
+	return nil, 0, fmt.Errorf("not supported")
+}
+
+func (r *testReverseHandler) NewChannelX11(_ string, _ uint32) (sshserver.ForwardChannel, uint64, error) {
+	return nil, 0, fmt.Errorf("not supported")
+}
@@ -59,6 +59,11 @@ type Connection interface {
 	OnAuthKeyboardInteractiveFailed(username string)
 	// OnAuthKeyboardInteractiveBackendError records a backend failure during the keyboard-interactive authentication.
 	OnAuthKeyboardInteractiveBackendError(username string, reason string)
+	// OnAuthBanned records that the source IP address of the connection has been banned due to too many failed
+	// authentication attempts.
+	OnAuthBanned(username string, reason string)
+	// OnAuthorization records the decision of the authorization provider and the rule that made it, if any.
+	OnAuthorization(username string, authenticatedUsername string, allowed bool, rule string)
 
 	// OnHandshakeFailed creates an entry that indicates a handshake failure.
 	OnHandshakeFailed(reason string)
@@ -98,6 +103,9 @@ type Connection interface {
 	// OnReverseX11ForwardChannel creates an audit log message for requesting to open a channel to forward an X11 connection to the client.
 	OnReverseX11ForwardChannel(channelID message.ChannelID, originatorHost string, originatorPort uint32)
 
+	// OnReverseAuthAgentChannel creates an audit log message for requesting to open a channel to the SSH agent of the client.
+	OnReverseAuthAgentChannel(channelID message.ChannelID)
+
 	// OnDirectStreamLocal creates an audit log message for requesting to open a unix socket forwarding channel.
 	OnDirectStreamLocal(channelID message.ChannelID, path string)
 
@@ -106,6 +114,14 @@ type Connection interface {
 
 	// OnRequestCancelStreamLocal creates an audit log message for requesting the server to stop listening on a unix socket for incoming connections.
 	OnRequestCancelStreamLocal(path string)
+
+	// OnGlobalRequestFailed creates an audit log message for a global request, such as a reverse forwarding request,
+	// that has been rejected.
+	OnGlobalRequestFailed(requestType string, reason string)
+
+	// OnPolicyDryRun creates an audit log message for a request that would have been rejected by the security
+	// settings in dry run mode.
+	OnPolicyDryRun(rule string, reason string)
 }
 
 // Channel is an audit logger for one specific channel
@@ -126,6 +142,8 @@ type Channel interface {
 	OnRequestPty(requestID uint64, term string, columns uint32, rows uint32, width uint32, height uint32, modeList []byte)
 	// OnRequestX11 create an audit log message for a channel request to start X11 forwarding
 	OnRequestX11(requestID uint64, singleConnection bool, protocol string, cookie string, screen uint32)
+	// OnRequestAuthAgent creates an audit log message for a channel request to forward the SSH agent of the client.
+	OnRequestAuthAgent(requestID uint64)
 	// OnRequestShell creates an audit log message for a channel request to execute a shell.
 	OnRequestShell(requestID uint64)
 	// OnRequestSignal creates an audit log message for a channel request to send a signal to the currently running
@@ -155,6 +173,10 @@ type Channel interface {
 	// OnWriteClose is called when the channel is closed for writing.
 	OnWriteClose()
 
+	// OnClosedByPolicy is called when the channel, or the whole connection if connection is true, is closed because
+	// of a policy, such as an idle timeout.
+	OnClosedByPolicy(reason string, connection bool)
+
 	// OnClose is called when the channel is closed.
 	OnClose()
 }
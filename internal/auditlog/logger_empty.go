@@ -23,6 +23,10 @@ func (e *empty) OnAuthKeyboardInteractiveFailed(_ string) {}
 
 func (e *empty) OnAuthKeyboardInteractiveBackendError(_ string, _ string) {}
 
+func (e *empty) OnAuthBanned(_ string, _ string) {}
+
+func (e *empty) OnAuthorization(_ string, _ string, _ bool, _ string) {}
+
 func (e *empty) OnRequestUnknown(_ uint64, _ string, _ []byte) {}
 
 func (e *empty) OnRequestDecodeFailed(_ uint64, _ string, _ []byte, _ string) {}
@@ -37,6 +41,8 @@ func (e *empty) OnRequestPty(_ uint64, _ string, _ uint32, _ uint32, _ uint32, _
 
 func (e *empty) OnRequestX11(_ uint64, _ bool, _ string, _ string, _ uint32) {}
 
+func (e *empty) OnRequestAuthAgent(_ uint64) {}
+
 func (e *empty) OnRequestShell(_ uint64) {}
 
 func (e *empty) OnRequestSignal(_ uint64, _ string) {}
@@ -67,6 +73,8 @@ func (e *empty) OnExitSignal(_ string, _ bool, _ string, _ string) {}
 
 func (e *empty) OnWriteClose() {}
 
+func (e *empty) OnClosedByPolicy(_ string, _ bool) {}
+
 func (e *empty) OnClose() {}
 
 func (e *empty) OnDisconnect() {}
@@ -116,12 +124,18 @@ func (l *empty) OnReverseStreamLocalChannel(_ message.ChannelID, _ string) {}
 
 func (l *empty) OnReverseX11ForwardChannel(_ message.ChannelID, _ string, _ uint32) {}
 
+func (l *empty) OnReverseAuthAgentChannel(_ message.ChannelID) {}
+
 func (l *empty) OnDirectStreamLocal(_ message.ChannelID, _ string) {}
 
 func (l *empty) OnRequestStreamLocal(_ string) {}
 
 func (l *empty) OnRequestCancelStreamLocal(_ string) {}
 
+func (l *empty) OnGlobalRequestFailed(_ string, _ string) {}
+
+func (l *empty) OnPolicyDryRun(_ string, _ string) {}
+
 func (e *empty) OnConnect(_ message.ConnectionID, _ net.TCPAddr) (Connection, error) {
 	return e, nil
 }
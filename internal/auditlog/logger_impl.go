@@ -265,6 +265,34 @@ func (l *loggerConnection) OnAuthKeyboardInteractiveBackendError(username string
 	})
 }
 
+func (l *loggerConnection) OnAuthBanned(username string, reason string) {
+	l.log(message.Message{
+		ConnectionID: l.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeAuthBanned,
+		Payload: message.PayloadAuthBanned{
+			Username: username,
+			Reason:   reason,
+		},
+		ChannelID: nil,
+	})
+}
+
+func (l *loggerConnection) OnAuthorization(username string, authenticatedUsername string, allowed bool, rule string) {
+	l.log(message.Message{
+		ConnectionID: l.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeAuthorization,
+		Payload: message.PayloadAuthorization{
+			Username:              username,
+			AuthenticatedUsername: authenticatedUsername,
+			Allowed:               allowed,
+			Rule:                  rule,
+		},
+		ChannelID: nil,
+	})
+}
+
 func (l *loggerConnection) OnHandshakeFailed(reason string) {
 	l.log(message.Message{
 		ConnectionID: l.connectionID,
@@ -356,6 +384,32 @@ func (l *loggerConnection) OnNewChannelSuccess(channelID message.ChannelID, chan
 	}
 }
 
+func (l *loggerConnection) OnGlobalRequestFailed(requestType string, reason string) {
+	l.log(message.Message{
+		ConnectionID: l.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeGlobalRequestFailed,
+		Payload: message.PayloadGlobalRequestFailed{
+			RequestType: requestType,
+			Reason:      reason,
+		},
+		ChannelID: nil,
+	})
+}
+
+func (l *loggerConnection) OnPolicyDryRun(rule string, reason string) {
+	l.log(message.Message{
+		ConnectionID: l.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypePolicyDryRun,
+		Payload: message.PayloadPolicyDryRun{
+			Rule:   rule,
+			Reason: reason,
+		},
+		ChannelID: nil,
+	})
+}
+
 func (l *loggerConnection) OnRequestTCPReverseForward(bindHost string, bindPort uint32) {
 	l.log(message.Message{
 		ConnectionID: l.connectionID,
@@ -437,6 +491,15 @@ func (l *loggerConnection) OnReverseX11ForwardChannel(channelID message.ChannelI
 	})
 }
 
+func (l *loggerConnection) OnReverseAuthAgentChannel(channelID message.ChannelID) {
+	l.log(message.Message{
+		ConnectionID: l.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeNewReverseAuthAgentChannel,
+		ChannelID:    channelID,
+	})
+}
+
 func (l *loggerConnection) OnDirectStreamLocal(channelID message.ChannelID, path string) {
 	l.log(message.Message{
 		ConnectionID: l.connectionID,
@@ -579,6 +642,18 @@ func (l *loggerChannel) OnRequestX11(
 	})
 }
 
+func (l *loggerChannel) OnRequestAuthAgent(requestID uint64) {
+	l.c.log(message.Message{
+		ConnectionID: l.c.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeChannelRequestAuthAgent,
+		Payload: message.PayloadChannelRequestAuthAgent{
+			RequestID: requestID,
+		},
+		ChannelID: l.channelID,
+	})
+}
+
 func (l *loggerChannel) OnRequestShell(requestID uint64) {
 	l.c.log(message.Message{
 		ConnectionID: l.c.connectionID,
@@ -747,6 +822,19 @@ func (l *loggerChannel) OnWriteClose() {
 	})
 }
 
+func (l *loggerChannel) OnClosedByPolicy(reason string, connection bool) {
+	l.c.log(message.Message{
+		ConnectionID: l.c.connectionID,
+		Timestamp:    time.Now().UnixNano(),
+		MessageType:  message.TypeClosedByPolicy,
+		Payload: message.PayloadClosedByPolicy{
+			Reason:     reason,
+			Connection: connection,
+		},
+		ChannelID: l.channelID,
+	})
+}
+
 func (l *loggerChannel) OnClose() {
 	l.c.log(message.Message{
 		ConnectionID: l.c.connectionID,
@@ -2,12 +2,14 @@ package auditlogintegration
 
 import (
 	"context"
+	"errors"
 
 	"go.containerssh.io/libcontainerssh/auditlog/message"
 	publicAuth "go.containerssh.io/libcontainerssh/auth"
 	"go.containerssh.io/libcontainerssh/internal/auditlog"
 	internalAuth "go.containerssh.io/libcontainerssh/internal/auth"
 	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	messageCodes "go.containerssh.io/libcontainerssh/message"
 	"go.containerssh.io/libcontainerssh/metadata"
 )
 
@@ -23,7 +25,7 @@ func (n *networkConnectionHandler) OnAuthKeyboardInteractive(
 		questions sshserver.KeyboardInteractiveQuestions,
 	) (answers sshserver.KeyboardInteractiveAnswers, err error),
 ) (response sshserver.AuthResponse, metadata metadata.ConnectionAuthenticatedMetadata, reason error) {
-	return n.backend.OnAuthKeyboardInteractive(
+	response, metadata, reason = n.backend.OnAuthKeyboardInteractive(
 		meta,
 		func(
 			instruction string,
@@ -58,6 +60,9 @@ func (n *networkConnectionHandler) OnAuthKeyboardInteractive(
 			return answers, err
 		},
 	)
+	n.auditAuthorization(meta.Username, metadata)
+	n.auditBan(meta.Username, reason)
+	return response, metadata, reason
 }
 
 func (n *networkConnectionHandler) OnShutdown(shutdownContext context.Context) {
@@ -70,6 +75,7 @@ func (n *networkConnectionHandler) OnAuthPassword(
 ) (response sshserver.AuthResponse, authenticatedMetadata metadata.ConnectionAuthenticatedMetadata, reason error) {
 	n.audit.OnAuthPassword(meta.Username, password)
 	response, authenticatedMetadata, reason = n.backend.OnAuthPassword(meta, password)
+	n.auditAuthorization(meta.Username, authenticatedMetadata)
 	switch response {
 	case sshserver.AuthResponseSuccess:
 		// TODO add authenticated username
@@ -77,6 +83,7 @@ func (n *networkConnectionHandler) OnAuthPassword(
 	case sshserver.AuthResponseFailure:
 		// TODO add authenticated username
 		n.audit.OnAuthPasswordFailed(meta.Username, password)
+		n.auditBan(meta.Username, reason)
 	case sshserver.AuthResponseUnavailable:
 		if reason != nil {
 			n.audit.OnAuthPasswordBackendError(meta.Username, password, reason.Error())
@@ -98,6 +105,7 @@ func (n *networkConnectionHandler) OnAuthPubKey(
 	// TODO add authenticated username
 	n.audit.OnAuthPubKey(meta.Username, pubKey.PublicKey)
 	response, authMeta, reason := n.backend.OnAuthPubKey(meta, pubKey)
+	n.auditAuthorization(meta.Username, authMeta)
 	switch response {
 	case sshserver.AuthResponseSuccess:
 		n.audit.OnAuthPubKeySuccess(authMeta.Username, pubKey.PublicKey)
@@ -113,6 +121,22 @@ func (n *networkConnectionHandler) OnAuthPubKey(
 	return response, authMeta, reason
 }
 
+// auditAuthorization records the decision of the authorization provider if the authentication reached authorization.
+func (n *networkConnectionHandler) auditAuthorization(username string, meta metadata.ConnectionAuthenticatedMetadata) {
+	if meta.Authorization == nil {
+		return
+	}
+	n.audit.OnAuthorization(username, meta.AuthenticatedUsername, meta.Authorization.Allowed, meta.Authorization.Rule)
+}
+
+// auditBan records a ban if the source address has been banned as a result of a failed authentication attempt.
+func (n *networkConnectionHandler) auditBan(username string, reason error) {
+	var msg messageCodes.Message
+	if errors.As(reason, &msg) && msg.Code() == messageCodes.ERateLimitBanned {
+		n.audit.OnAuthBanned(username, msg.Explanation())
+	}
+}
+
 func (n *networkConnectionHandler) OnAuthGSSAPI(meta metadata.ConnectionMetadata) internalAuth.GSSAPIServer {
 	// TODO add audit logging
 	return n.backend.OnAuthGSSAPI(meta)
@@ -148,3 +148,19 @@ func (s *sessionChannelHandler) OnX11Request(
 		},
 	)
 }
+
+func (s *sessionChannelHandler) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	s.audit.OnRequestAuthAgent(requestID)
+
+	return s.backend.OnAuthAgentRequest(
+		requestID,
+		&reverseHandlerProxy{
+			backend:           reverseHandler,
+			connectionHandler: s.connectionHandler,
+			channelType:       sshserver.ChannelTypeAuthAgent,
+		},
+	)
+}
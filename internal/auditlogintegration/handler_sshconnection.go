@@ -42,7 +42,8 @@ func (s *sshConnectionHandler) OnSessionChannel(
 	session sshserver.SessionChannel,
 ) (channel sshserver.SessionChannelHandler, failureReason sshserver.ChannelRejection) {
 	proxy := &sessionProxy{
-		backend: session,
+		backend:    session,
+		connection: s.audit,
 	}
 	backend, err := s.backend.OnSessionChannel(meta, extraData, proxy)
 	if err != nil {
@@ -69,6 +70,7 @@ func (s *sshConnectionHandler) OnTCPForwardChannel(
 
 	backend, err := s.backend.OnTCPForwardChannel(channelID, hostToConnect, portToConnect, originatorHost, originatorPort)
 	if err != nil {
+		s.audit.OnNewChannelFailed(message.MakeChannelID(channelID), sshserver.ChannelTypeDirectTCPIP, err.Error())
 		return nil, err
 	}
 	auditChannel := s.audit.OnNewChannelSuccess(message.MakeChannelID(channelID), "direct-tcpip")
@@ -82,7 +84,7 @@ func (s *sshConnectionHandler) OnRequestTCPReverseForward(
 	reverseHandler sshserver.ReverseForward,
 ) error {
 	s.audit.OnRequestTCPReverseForward(bindHost, bindPort)
-	return s.backend.OnRequestTCPReverseForward(
+	if err := s.backend.OnRequestTCPReverseForward(
 		bindHost,
 		bindPort,
 		&reverseHandlerProxy{
@@ -90,7 +92,11 @@ func (s *sshConnectionHandler) OnRequestTCPReverseForward(
 			connectionHandler: s,
 			channelType:       sshserver.ChannelTypeReverseForward,
 		},
-	)
+	); err != nil {
+		s.audit.OnGlobalRequestFailed("tcpip-forward", err.Error())
+		return err
+	}
+	return nil
 }
 
 func (s *sshConnectionHandler) OnRequestCancelTCPReverseForward(
@@ -108,6 +114,7 @@ func (s *sshConnectionHandler) OnDirectStreamLocal(
 	s.audit.OnDirectStreamLocal(message.MakeChannelID(channelID), path)
 	channel, err := s.backend.OnDirectStreamLocal(channelID, path)
 	if err != nil {
+		s.audit.OnNewChannelFailed(message.MakeChannelID(channelID), sshserver.ChannelTypeDirectStreamLocal, err.Error())
 		return nil, err
 	}
 	auditChannel := s.audit.OnNewChannelSuccess(message.MakeChannelID(channelID), sshserver.ChannelTypeDirectStreamLocal)
@@ -119,14 +126,18 @@ func (s *sshConnectionHandler) OnRequestStreamLocal(
 	reverseHandler sshserver.ReverseForward,
 ) error {
 	s.audit.OnRequestStreamLocal(path)
-	return s.backend.OnRequestStreamLocal(
+	if err := s.backend.OnRequestStreamLocal(
 		path,
 		&reverseHandlerProxy{
 			backend:           reverseHandler,
 			connectionHandler: s,
 			channelType:       sshserver.ChannelTypeForwardedStreamLocal,
 		},
-	)
+	); err != nil {
+		s.audit.OnGlobalRequestFailed("streamlocal-forward@openssh.com", err.Error())
+		return err
+	}
+	return nil
 }
 
 func (s *sshConnectionHandler) OnRequestCancelStreamLocal(
@@ -142,6 +153,13 @@ type reverseHandlerProxy struct {
 	channelType       string
 }
 
+func (r *reverseHandlerProxy) AuditPolicyDryRun(rule string, reason string) {
+	r.connectionHandler.audit.OnPolicyDryRun(rule, reason)
+	if auditor, ok := r.backend.(sshserver.PolicyAuditor); ok {
+		auditor.AuditPolicyDryRun(rule, reason)
+	}
+}
+
 func (r *reverseHandlerProxy) NewChannelTCP(connectedAddress string, connectedPort uint32, originatorAddress string, originatorPort uint32) (sshserver.ForwardChannel, uint64, error) {
 	channel, id, err := r.backend.NewChannelTCP(connectedAddress, connectedPort, originatorAddress, originatorPort)
 	if err != nil {
@@ -177,12 +195,33 @@ func (r *reverseHandlerProxy) NewChannelX11(originatorAddress string, originator
 	return forwardProxy, id, nil
 }
 
+func (r *reverseHandlerProxy) NewChannelAuthAgent() (sshserver.ForwardChannel, uint64, error) {
+	channel, id, err := r.backend.NewChannelAuthAgent()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r.connectionHandler.audit.OnReverseAuthAgentChannel(message.MakeChannelID(id))
+	auditChannel := r.connectionHandler.audit.OnNewChannelSuccess(message.MakeChannelID(id), r.channelType)
+	forwardProxy := auditChannel.GetForwardingProxy(channel)
+	return forwardProxy, id, nil
+}
+
 type sessionProxy struct {
 	backend sshserver.SessionChannel
-	audit   auditlog.Channel
-	stdin   io.Reader
-	stdout  io.Writer
-	stderr  io.Writer
+	// connection is the audit logger of the connection, which is available before the channel is open.
+	connection auditlog.Connection
+	audit      auditlog.Channel
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+func (s *sessionProxy) AuditPolicyDryRun(rule string, reason string) {
+	s.connection.OnPolicyDryRun(rule, reason)
+	if auditor, ok := s.backend.(sshserver.PolicyAuditor); ok {
+		auditor.AuditPolicyDryRun(rule, reason)
+	}
 }
 
 func (s *sessionProxy) Stdin() io.Reader {
@@ -243,3 +282,25 @@ func (s *sessionProxy) Close() error {
 	// Audit logging is done via the session channel hook.
 	return s.backend.Close()
 }
+
+func (s *sessionProxy) CloseForPolicy(reason string) error {
+	if s.audit == nil {
+		panic("BUG: close requested before channel is open")
+	}
+	s.audit.OnClosedByPolicy(reason, false)
+	if closer, ok := s.backend.(sshserver.PolicyCloser); ok {
+		return closer.CloseForPolicy(reason)
+	}
+	return s.backend.Close()
+}
+
+func (s *sessionProxy) DisconnectForPolicy(reason string) error {
+	if s.audit == nil {
+		panic("BUG: disconnect requested before channel is open")
+	}
+	s.audit.OnClosedByPolicy(reason, true)
+	if closer, ok := s.backend.(sshserver.PolicyCloser); ok {
+		return closer.DisconnectForPolicy(reason)
+	}
+	return s.backend.Close()
+}
@@ -305,6 +305,13 @@ func (s *backendHandler) OnX11Request(
 	return fmt.Errorf("Unimplemented")
 }
 
+func (s *backendHandler) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	return fmt.Errorf("Unimplemented")
+}
+
 func (b *backendHandler) OnUnsupportedGlobalRequest(_ uint64, _ string, _ []byte) {
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/geoipprovider"
 	"go.containerssh.io/libcontainerssh/internal/metrics"
 	"go.containerssh.io/libcontainerssh/log"
 	"go.containerssh.io/libcontainerssh/service"
@@ -29,14 +30,18 @@ func NewPasswordAuthenticator(
 	case config.PasswordAuthMethodKerberos:
 		cli, err := NewKerberosClient(AuthenticationTypePassword, cfg.Kerberos, logger, metrics)
 		return cli, nil, err
+	case config.PasswordAuthMethodLDAP:
+		cli, err := NewLDAPClient(cfg.LDAP, logger, metrics)
+		return cli, nil, err
 	default:
 		return nil, nil, fmt.Errorf("unsupported method: %s", cfg.Method)
 	}
 }
 
 // NewPublicKeyAuthenticator returns a public key authenticator as configured, and if needed a backing service that
-// needs to run for the authentication to work. If public key authentication is disabled it returns nil. If the
-// configuration is invalid an error is returned.
+// needs to run for the authentication to work. If trusted CA keys are configured, the authenticator validates
+// certificates natively and passes all other keys to the configured method. If public key authentication is disabled
+// and no trusted CA keys are configured it returns nil. If the configuration is invalid an error is returned.
 func NewPublicKeyAuthenticator(
 	cfg config.PublicKeyAuthConfig,
 	logger log.Logger,
@@ -45,15 +50,35 @@ func NewPublicKeyAuthenticator(
 	if err := cfg.Validate(); err != nil {
 		return nil, nil, err
 	}
+	var authenticator PublicKeyAuthenticator
 	switch cfg.Method {
 	case config.PubKeyAuthMethodDisabled:
-		return nil, nil, nil
 	case config.PubKeyAuthMethodWebhook:
 		cli, err := NewWebhookClient(AuthenticationTypePublicKey, cfg.Webhook, logger, metrics)
-		return cli, nil, err
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticator = cli
+	case config.PubKeyAuthMethodAuthorizedKeys:
+		cli, err := NewAuthorizedKeysClient(cfg.AuthorizedKeys, logger, metrics)
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticator = cli
+	case config.PubKeyAuthMethodLDAP:
+		cli, err := NewLDAPClient(cfg.LDAP, logger, metrics)
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticator = cli
 	default:
 		return nil, nil, fmt.Errorf("unsupported method: %s", cfg.Method)
 	}
+	if cfg.Certificates.Enabled() {
+		cli, err := NewUserCertificateClient(cfg.Certificates, authenticator, logger, metrics)
+		return cli, nil, err
+	}
+	return authenticator, nil, nil
 }
 
 // NewKeyboardInteractiveAuthenticator returns a keyboard-interactive authenticator as configured, and if needed a
@@ -72,6 +97,12 @@ func NewKeyboardInteractiveAuthenticator(
 		return nil, nil, nil
 	case config.KeyboardInteractiveAuthMethodOAuth2:
 		return NewOAuth2Client(cfg.OAuth2, logger, metrics)
+	case config.KeyboardInteractiveAuthMethodTOTP:
+		cli, err := NewTOTPClient(cfg.TOTP, logger, metrics)
+		return cli, nil, err
+	case config.KeyboardInteractiveAuthMethodWebhook:
+		cli, err := NewKeyboardInteractiveWebhookClient(cfg.Webhook, logger, metrics)
+		return cli, nil, err
 	default:
 		return nil, nil, fmt.Errorf("unsupported method: %s", cfg.Method)
 	}
@@ -104,6 +135,7 @@ func NewGSSAPIAuthenticator(
 // invalid an error is returned.
 func NewAuthorizationProvider(
 	cfg config.AuthzConfig,
+	geoIPLookupProvider geoipprovider.LookupProvider,
 	logger log.Logger,
 	metrics metrics.Collector,
 ) (AuthzProvider, service.Service, error) {
@@ -116,6 +148,9 @@ func NewAuthorizationProvider(
 	case config.AuthzMethodWebhook:
 		cli, err := NewWebhookClient(AuthenticationTypeAuthz, cfg.Webhook, logger, metrics)
 		return cli, nil, err
+	case config.AuthzMethodRules:
+		cli, err := NewAuthzRulesProvider(cfg, geoIPLookupProvider, logger, metrics)
+		return cli, nil, err
 	default:
 		return nil, nil, fmt.Errorf("unsupported method: %s", cfg.Method)
 	}
@@ -0,0 +1,7 @@
+package auth
+
+// AuthorizedKeysClient is the authenticator that checks public keys against OpenSSH-style authorized_keys files
+// stored on the local filesystem.
+type AuthorizedKeysClient interface {
+	PublicKeyAuthenticator
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewAuthorizedKeysClient creates a public key authenticator that reads the keys from authorized_keys files.
+func NewAuthorizedKeysClient(
+	cfg config.AuthAuthorizedKeysConfig,
+	logger log.Logger,
+	metrics metrics.Collector,
+) (AuthorizedKeysClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"Authorized keys configuration failed to validate",
+		)
+	}
+	authSuccessMetric, authFailureMetric := createAuthResultMetrics(metrics)
+	return &authorizedKeysClient{
+		config:            cfg,
+		logger:            logger,
+		cache:             map[string]*authorizedKeysFile{},
+		authSuccessMetric: authSuccessMetric,
+		authFailureMetric: authFailureMetric,
+	}, nil
+}
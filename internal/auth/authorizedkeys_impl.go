@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"golang.org/x/crypto/ssh"
+)
+
+type authorizedKeysContext struct {
+	meta    metadata.ConnectionAuthenticatedMetadata
+	success bool
+	err     error
+}
+
+func (a *authorizedKeysContext) Success() bool {
+	return a.success
+}
+
+func (a *authorizedKeysContext) Error() error {
+	return a.err
+}
+
+func (a *authorizedKeysContext) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return a.meta
+}
+
+func (a *authorizedKeysContext) OnDisconnect() {
+}
+
+// authorizedKeysEntry is a single parsed line of an authorized_keys file.
+type authorizedKeysEntry struct {
+	key     []byte
+	options authorizedKeysOptions
+}
+
+// authorizedKeysOptions holds the supported OpenSSH options of a single entry.
+type authorizedKeysOptions struct {
+	from              []string
+	command           string
+	environment       map[string]string
+	noPortForwarding  bool
+	noPTY             bool
+	noX11Forwarding   bool
+	noAgentForwarding bool
+	expiry            *time.Time
+}
+
+// authorizedKeysFile is the cached content of an authorized_keys file. The file is parsed again if the modification
+// time or the size changes.
+type authorizedKeysFile struct {
+	modTime time.Time
+	size    int64
+	entries []authorizedKeysEntry
+}
+
+type authorizedKeysClient struct {
+	config            config.AuthAuthorizedKeysConfig
+	logger            log.Logger
+	lock              sync.Mutex
+	cache             map[string]*authorizedKeysFile
+	authSuccessMetric metrics.GeoCounter
+	authFailureMetric metrics.GeoCounter
+}
+
+func (c *authorizedKeysClient) PubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth.PublicKey,
+) AuthenticationContext {
+	authContext := c.pubKey(meta, pubKey)
+	labels := []metrics.MetricLabel{
+		metrics.Label("authtype", "pubkey"),
+	}
+	if authContext.Success() {
+		c.authSuccessMetric.Increment(meta.RemoteAddress.IP, labels...)
+	} else if authContext.Error() == nil {
+		c.authFailureMetric.Increment(meta.RemoteAddress.IP, labels...)
+	}
+	return authContext
+}
+
+func (c *authorizedKeysClient) pubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth.PublicKey,
+) AuthenticationContext {
+	logger := c.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username)
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubKey.PublicKey))
+	if err != nil {
+		return &authorizedKeysContext{meta.AuthFailed(), false, err}
+	}
+
+	filePath, err := c.getPath(meta.Username)
+	if err != nil {
+		err := message.Wrap(
+			err,
+			message.EAuthAuthorizedKeysInvalidUsername,
+			"Cannot look up authorized_keys file for user",
+		)
+		logger.Debug(err)
+		return &authorizedKeysContext{meta.AuthFailed(), false, nil}
+	}
+
+	entries, err := c.load(filePath, logger)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &authorizedKeysContext{meta.AuthFailed(), false, nil}
+		}
+		err := message.Wrap(
+			err,
+			message.EAuthAuthorizedKeysReadFailed,
+			"Failed to read authorized_keys file %s",
+			filePath,
+		)
+		logger.Error(err)
+		return &authorizedKeysContext{meta.AuthFailed(), false, err}
+	}
+
+	marshalledKey := key.Marshal()
+	for _, entry := range entries {
+		if !bytes.Equal(entry.key, marshalledKey) {
+			continue
+		}
+		if entry.options.expiry != nil && time.Now().After(*entry.options.expiry) {
+			continue
+		}
+		if len(entry.options.from) > 0 && !matchFrom(entry.options.from, meta.RemoteAddress.IP) {
+			logger.Debug(
+				message.NewMessage(
+					message.EAuthAuthorizedKeysSourceMismatch,
+					"Public key matched, but the from= option does not permit %s",
+					meta.RemoteAddress.IP.String(),
+				),
+			)
+			continue
+		}
+		return &authorizedKeysContext{
+			meta:    entry.options.apply(meta.Authenticated(meta.Username), c.config.PermitUserEnvironment),
+			success: true,
+		}
+	}
+	return &authorizedKeysContext{meta.AuthFailed(), false, nil}
+}
+
+// getPath returns the path of the authorized_keys file for the specified user.
+func (c *authorizedKeysClient) getPath(username string) (string, error) {
+	if username == "" || username == "." || username == ".." ||
+		strings.ContainsAny(username, "/\\\x00") {
+		return "", fmt.Errorf("invalid username: %q", username)
+	}
+	result := strings.Builder{}
+	tpl := c.config.Path
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] == '%' && i+1 < len(tpl) {
+			switch tpl[i+1] {
+			case 'u':
+				result.WriteString(username)
+				i++
+				continue
+			case '%':
+				result.WriteByte('%')
+				i++
+				continue
+			}
+		}
+		result.WriteByte(tpl[i])
+	}
+	return path.Clean(result.String()), nil
+}
+
+// load returns the entries of the authorized_keys file, reading it again if it has changed since it was last read.
+func (c *authorizedKeysClient) load(filePath string, logger log.Logger) ([]authorizedKeysEntry, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		delete(c.cache, filePath)
+		return nil, err
+	}
+	if cached, ok := c.cache[filePath]; ok {
+		if cached.modTime.Equal(stat.ModTime()) && cached.size == stat.Size() {
+			return cached.entries, nil
+		}
+		logger.Debug(
+			message.NewMessage(
+				message.MAuthAuthorizedKeysReloaded,
+				"The authorized_keys file %s has changed, reloading...",
+				filePath,
+			),
+		)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseAuthorizedKeys(data, logger)
+	c.cache[filePath] = &authorizedKeysFile{
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+		entries: entries,
+	}
+	return entries, nil
+}
+
+// parseAuthorizedKeys parses the content of an authorized_keys file. Invalid entries and entries with unsupported
+// options are skipped.
+func parseAuthorizedKeys(data []byte, logger log.Logger) []authorizedKeysEntry {
+	var entries []authorizedKeysEntry
+	rest := data
+	for len(rest) > 0 {
+		var (
+			key     ssh.PublicKey
+			options []string
+			err     error
+		)
+		key, _, options, rest, err = ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			// ParseAuthorizedKey only returns an error if no further keys are found.
+			break
+		}
+		opts, err := parseAuthorizedKeysOptions(options)
+		if err != nil {
+			logger.Warning(
+				message.Wrap(
+					err,
+					message.EAuthAuthorizedKeysReadFailed,
+					"Ignoring authorized_keys entry with invalid options",
+				),
+			)
+			continue
+		}
+		entries = append(entries, authorizedKeysEntry{
+			key:     key.Marshal(),
+			options: opts,
+		})
+	}
+	return entries
+}
+
+func parseAuthorizedKeysOptions(options []string) (authorizedKeysOptions, error) {
+	result := authorizedKeysOptions{}
+	for _, option := range options {
+		name, value, hasValue := strings.Cut(option, "=")
+		if hasValue {
+			value = unquoteAuthorizedKeysOption(value)
+		}
+		switch strings.ToLower(name) {
+		case "from":
+			result.from = strings.Split(value, ",")
+		case "command":
+			result.command = value
+		case "environment":
+			envName, envValue, ok := strings.Cut(value, "=")
+			if !ok || envName == "" {
+				return result, fmt.Errorf("invalid environment option: %s", value)
+			}
+			if result.environment == nil {
+				result.environment = map[string]string{}
+			}
+			result.environment[envName] = envValue
+		case "expiry-time":
+			expiry, err := parseAuthorizedKeysExpiry(value)
+			if err != nil {
+				return result, err
+			}
+			result.expiry = &expiry
+		case "restrict":
+			result.noPortForwarding = true
+			result.noPTY = true
+			result.noX11Forwarding = true
+			result.noAgentForwarding = true
+		case "no-port-forwarding":
+			result.noPortForwarding = true
+		case "port-forwarding":
+			result.noPortForwarding = false
+		case "no-pty":
+			result.noPTY = true
+		case "pty":
+			result.noPTY = false
+		case "no-x11-forwarding":
+			result.noX11Forwarding = true
+		case "x11-forwarding":
+			result.noX11Forwarding = false
+		case "no-agent-forwarding":
+			result.noAgentForwarding = true
+		case "agent-forwarding":
+			result.noAgentForwarding = false
+		case "no-user-rc", "user-rc":
+			// User rc files are not supported, nothing to restrict.
+		default:
+			return result, fmt.Errorf("unsupported option: %s", name)
+		}
+	}
+	return result, nil
+}
+
+func unquoteAuthorizedKeysOption(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return strings.ReplaceAll(value, `\"`, `"`)
+}
+
+func parseAuthorizedKeysExpiry(value string) (time.Time, error) {
+	location := time.Local
+	if strings.HasSuffix(value, "Z") || strings.HasSuffix(value, "z") {
+		location = time.UTC
+		value = value[:len(value)-1]
+	}
+	for _, layout := range []string{"20060102150405", "200601021504", "20060102"} {
+		if len(value) == len(layout) {
+			return time.ParseInLocation(layout, value, location)
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry-time: %s", value)
+}
+
+// apply stores the options in the metadata so they can be applied to the connection after authentication. The
+// environment options are only applied if permitEnvironment is set.
+func (o authorizedKeysOptions) apply(
+	meta metadata.ConnectionAuthenticatedMetadata,
+	permitEnvironment bool,
+) metadata.ConnectionAuthenticatedMetadata {
+	meta = cloneMetadata(meta)
+	env := meta.GetEnvironment()
+	meta.Restrictions = meta.Restrictions.Merge(metadata.Restrictions{
+		ForceCommand:      o.command,
+		NoPortForwarding:  o.noPortForwarding,
+		NoPTY:             o.noPTY,
+		NoX11Forwarding:   o.noX11Forwarding,
+		NoAgentForwarding: o.noAgentForwarding,
+	})
+	if permitEnvironment {
+		for k, v := range o.environment {
+			env[k] = metadata.Value{Value: v}
+		}
+	}
+	return meta
+}
+
+// matchFrom checks the remote IP address against the patterns of a from= option. Patterns may be IP addresses, CIDR
+// ranges, or wildcard patterns using * and ?. A pattern prefixed with ! denies the address even if other patterns
+// match. Host names are not resolved.
+func matchFrom(patterns []string, ip net.IP) bool {
+	matched := false
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		negate := strings.HasPrefix(pattern, "!")
+		if negate {
+			pattern = pattern[1:]
+		}
+		if !matchFromPattern(pattern, ip) {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func matchFromPattern(pattern string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if strings.Contains(pattern, "/") {
+		_, network, err := net.ParseCIDR(pattern)
+		return err == nil && network.Contains(ip)
+	}
+	if patternIP := net.ParseIP(pattern); patternIP != nil {
+		return patternIP.Equal(ip)
+	}
+	ok, err := path.Match(pattern, ip.String())
+	return err == nil && ok
+}
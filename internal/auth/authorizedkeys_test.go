@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	auth3 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestAuthorizedKeys(t *testing.T) {
+	dir := t.TempDir()
+	key := generateAuthorizedKeysTestKey(t)
+	otherKey := generateAuthorizedKeysTestKey(t)
+	authorizedKey := string(ssh.MarshalAuthorizedKey(key))
+
+	writeAuthorizedKeys := func(content string) {
+		file := filepath.Join(dir, "foo")
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0600))
+		// Make sure the modification time changes even on filesystems with a coarse resolution.
+		modTime := time.Now().Add(time.Duration(len(content)) * time.Second)
+		assert.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+
+	metricsCollector := metrics.New(dummy.New())
+	client, err := auth.NewAuthorizedKeysClient(
+		config.AuthAuthorizedKeysConfig{Path: filepath.Join(dir, "%u")},
+		log.NewTestLogger(t),
+		metricsCollector,
+	)
+	assert.NoError(t, err)
+
+	pubKey := func(username string, ip string, k ssh.PublicKey) auth.AuthenticationContext {
+		meta := metadata.NewTestAuthenticatingMetadata(username)
+		meta.RemoteAddress = metadata.RemoteAddress(net.TCPAddr{IP: net.ParseIP(ip), Port: 2222})
+		return client.PubKey(meta, auth3.PublicKey{PublicKey: string(ssh.MarshalAuthorizedKey(k))})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		ctx := pubKey("foo", "127.0.0.1", key)
+		assert.False(t, ctx.Success())
+		assert.NoError(t, ctx.Error())
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		writeAuthorizedKeys(authorizedKey)
+		assert.True(t, pubKey("foo", "127.0.0.1", key).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", otherKey).Success())
+		assert.False(t, pubKey("bar", "127.0.0.1", key).Success())
+		assert.False(t, pubKey("../foo", "127.0.0.1", key).Success())
+	})
+
+	t.Run("from", func(t *testing.T) {
+		writeAuthorizedKeys(`from="10.0.0.0/8,!10.0.0.1,192.168.1.*" ` + authorizedKey)
+		assert.True(t, pubKey("foo", "10.1.2.3", key).Success())
+		assert.True(t, pubKey("foo", "192.168.1.5", key).Success())
+		assert.False(t, pubKey("foo", "10.0.0.1", key).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", key).Success())
+	})
+
+	t.Run("options", func(t *testing.T) {
+		writeAuthorizedKeys(
+			`command="/bin/echo \"hello\"",environment="FOO=bar",no-port-forwarding,no-pty,no-agent-forwarding ` + authorizedKey,
+		)
+		ctx := pubKey("foo", "127.0.0.1", key)
+		assert.True(t, ctx.Success())
+		meta := ctx.Metadata()
+		assert.Equal(t, "foo", meta.AuthenticatedUsername)
+		assert.NotContains(t, meta.Environment, "FOO")
+
+		cfg := config.SecurityConfig{ForceCommand: ""}
+		auth.ApplyRestrictions(meta, &cfg)
+		assert.Equal(t, `/bin/echo "hello"`, cfg.ForceCommand)
+		assert.Equal(t, config.ExecutionPolicyDisable, cfg.Forwarding.ForwardingMode)
+		assert.Equal(t, config.ExecutionPolicyDisable, cfg.TTY.Mode)
+		assert.Equal(t, config.ExecutionPolicyUnconfigured, cfg.Forwarding.X11ForwardingMode)
+		assert.Equal(t, config.ExecutionPolicyDisable, cfg.Forwarding.AgentForwardingMode)
+
+		cfg = config.SecurityConfig{ForceCommand: "/bin/admin"}
+		auth.ApplyRestrictions(meta, &cfg)
+		assert.Equal(t, "/bin/admin", cfg.ForceCommand)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		envClient, err := auth.NewAuthorizedKeysClient(
+			config.AuthAuthorizedKeysConfig{Path: filepath.Join(dir, "%u"), PermitUserEnvironment: true},
+			log.NewTestLogger(t),
+			metricsCollector,
+		)
+		assert.NoError(t, err)
+		writeAuthorizedKeys(`environment="FOO=bar" ` + authorizedKey)
+		meta := metadata.NewTestAuthenticatingMetadata("foo")
+		ctx := envClient.PubKey(meta, auth3.PublicKey{PublicKey: authorizedKey})
+		assert.True(t, ctx.Success())
+		assert.Equal(t, "bar", ctx.Metadata().Environment["FOO"].Value)
+	})
+
+	t.Run("metadata cannot set restrictions", func(t *testing.T) {
+		writeAuthorizedKeys(authorizedKey)
+		ctx := pubKey("foo", "127.0.0.1", key)
+		assert.True(t, ctx.Success())
+		meta := ctx.Metadata()
+		meta.Metadata["SSH_FORCE_COMMAND"] = metadata.Value{Value: "/bin/evil"}
+		meta.Metadata["SSH_NO_PTY"] = metadata.Value{Value: "true"}
+
+		cfg := config.SecurityConfig{}
+		auth.ApplyRestrictions(meta, &cfg)
+		assert.Equal(t, "", cfg.ForceCommand)
+		assert.Equal(t, config.ExecutionPolicyUnconfigured, cfg.TTY.Mode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		writeAuthorizedKeys(`expiry-time="20000101Z" ` + authorizedKey)
+		assert.False(t, pubKey("foo", "127.0.0.1", key).Success())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		writeAuthorizedKeys(`unknown-option ` + authorizedKey)
+		assert.False(t, pubKey("foo", "127.0.0.1", key).Success())
+	})
+}
+
+func TestAuthorizedKeysConfig(t *testing.T) {
+	cfg := config.AuthAuthorizedKeysConfig{Path: "/etc/keys/%u"}
+	assert.NoError(t, cfg.Validate())
+	cfg.Path = "/etc/keys/%%u"
+	assert.Error(t, cfg.Validate())
+	cfg.Path = "/etc/keys/%h/%u"
+	assert.Error(t, cfg.Validate())
+}
+
+func generateAuthorizedKeysTestKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err)
+	return key
+}
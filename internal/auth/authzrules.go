@@ -0,0 +1,7 @@
+package auth
+
+// AuthzRulesProvider is the authorization provider that evaluates the rules from the configuration in-process instead
+// of calling an authorization server.
+type AuthzRulesProvider interface {
+	AuthzProvider
+}
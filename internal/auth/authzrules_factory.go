@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/geoipprovider"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewAuthzRulesProvider creates an authorization provider that evaluates the rules of the configuration.
+func NewAuthzRulesProvider(
+	cfg config.AuthzConfig,
+	geoIPLookupProvider geoipprovider.LookupProvider,
+	logger log.Logger,
+	metrics metrics.Collector,
+) (AuthzRulesProvider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"Authorization rules failed to validate",
+		)
+	}
+	rules := make([]authzRule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = authzRule{
+			AuthzRule: rule,
+		}
+		for _, remoteAddress := range rule.RemoteAddresses {
+			network, err := config.ParseCIDROrIP(remoteAddress)
+			if err != nil {
+				// Cannot happen, the configuration has been validated.
+				return nil, err
+			}
+			rules[i].networks = append(rules[i].networks, network)
+		}
+	}
+	authSuccessMetric, authFailureMetric := createAuthResultMetrics(metrics)
+	return &authzRulesProvider{
+		rules:             rules,
+		defaultAction:     cfg.DefaultAction,
+		geoIP:             geoIPLookupProvider,
+		logger:            logger,
+		authSuccessMetric: authSuccessMetric,
+		authFailureMetric: authFailureMetric,
+	}, nil
+}
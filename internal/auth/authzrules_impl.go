@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/geoipprovider"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// authzRule is an authorization rule with the remote address ranges parsed.
+type authzRule struct {
+	config.AuthzRule
+
+	networks []*net.IPNet
+}
+
+type authzRulesResponse struct {
+	meta    metadata.ConnectionAuthenticatedMetadata
+	success bool
+}
+
+func (a *authzRulesResponse) Success() bool {
+	return a.success
+}
+
+func (a *authzRulesResponse) Error() error {
+	return nil
+}
+
+func (a *authzRulesResponse) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return a.meta
+}
+
+func (a *authzRulesResponse) OnDisconnect() {
+}
+
+type authzRulesProvider struct {
+	rules             []authzRule
+	defaultAction     config.AuthzAction
+	geoIP             geoipprovider.LookupProvider
+	logger            log.Logger
+	authSuccessMetric metrics.GeoCounter
+	authFailureMetric metrics.GeoCounter
+}
+
+func (p *authzRulesProvider) Authorize(meta metadata.ConnectionAuthenticatedMetadata) AuthorizationResponse {
+	logger := p.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("authenticatedUsername", meta.AuthenticatedUsername).
+		WithLabel("providedUsername", meta.Username)
+
+	country := p.geoIP.Lookup(meta.RemoteAddress.IP)
+	action := p.defaultAction
+	ruleName := ""
+	for _, rule := range p.rules {
+		if rule.matches(meta, country) {
+			action = rule.Action
+			ruleName = rule.Name
+			break
+		}
+	}
+
+	allowed := action == config.AuthzActionAllow
+	description := "the default action"
+	if ruleName != "" {
+		description = "rule " + ruleName
+	}
+	labels := []metrics.MetricLabel{
+		metrics.Label("authtype", string(AuthenticationTypeAuthz)),
+	}
+	if allowed {
+		logger.Info(
+			message.NewMessage(
+				message.MAuthzRuleAllowed,
+				"User %s allowed to log in as %s from %s by %s",
+				meta.AuthenticatedUsername,
+				meta.Username,
+				meta.RemoteAddress.IP.String(),
+				description,
+			),
+		)
+		p.authSuccessMetric.Increment(meta.RemoteAddress.IP, labels...)
+	} else {
+		logger.Info(
+			message.NewMessage(
+				message.EAuthzRuleDenied,
+				"User %s denied logging in as %s from %s by %s",
+				meta.AuthenticatedUsername,
+				meta.Username,
+				meta.RemoteAddress.IP.String(),
+				description,
+			),
+		)
+		p.authFailureMetric.Increment(meta.RemoteAddress.IP, labels...)
+	}
+
+	meta.Authorization = &metadata.Authorization{
+		Allowed: allowed,
+		Rule:    ruleName,
+	}
+	return &authzRulesResponse{
+		meta:    meta,
+		success: allowed,
+	}
+}
+
+// matches returns true if all conditions of the rule match the connection.
+func (r authzRule) matches(meta metadata.ConnectionAuthenticatedMetadata, country string) bool {
+	if len(r.AuthenticatedUsernames) > 0 && !matchesAnyGlob(r.AuthenticatedUsernames, meta.AuthenticatedUsername) {
+		return false
+	}
+	if len(r.Usernames) > 0 && !matchesAnyGlob(r.Usernames, meta.Username) {
+		return false
+	}
+	if r.SameUsername && meta.Username != meta.AuthenticatedUsername {
+		return false
+	}
+	if len(r.networks) > 0 && !r.matchesNetwork(meta.RemoteAddress.IP) {
+		return false
+	}
+	if len(r.Countries) > 0 && !containsString(r.Countries, country) {
+		return false
+	}
+	if len(r.AuthMethods) > 0 && !r.matchesAuthMethod(meta.AuthenticatedMethods) {
+		return false
+	}
+	for key, patterns := range r.Metadata {
+		if !matchesMetadata(meta.Metadata, key, patterns) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r authzRule) matchesNetwork(ip net.IP) bool {
+	for _, network := range r.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r authzRule) matchesAuthMethod(authenticatedMethods []string) bool {
+	for _, method := range r.AuthMethods {
+		if containsString(authenticatedMethods, string(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMetadata returns true if any of the comma-separated values of the metadata key matches any of the patterns.
+func matchesMetadata(meta map[string]metadata.Value, key string, patterns []string) bool {
+	value, ok := meta[key]
+	if !ok {
+		return false
+	}
+	for _, item := range strings.Split(value.Value, ",") {
+		if matchesAnyGlob(patterns, strings.TrimSpace(item)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
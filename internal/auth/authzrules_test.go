@@ -0,0 +1,136 @@
+package auth_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestAuthzRules(t *testing.T) {
+	cfg := config.AuthzConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Method = config.AuthzMethodRules
+	cfg.Rules = []config.AuthzRule{
+		{
+			Name:     "admins",
+			Action:   config.AuthzActionAllow,
+			Metadata: map[string][]string{"LDAP_GROUPS": {"admins"}},
+		},
+		{
+			Name:            "blocked-network",
+			Action:          config.AuthzActionDeny,
+			RemoteAddresses: []string{"192.168.0.0/16"},
+		},
+		{
+			Name:         "own-account",
+			Action:       config.AuthzActionAllow,
+			SameUsername: true,
+			AuthMethods:  []config.SSHAuthMethod{config.SSHAuthMethodPublicKey},
+		},
+		{
+			Name:      "unknown-country",
+			Action:    config.AuthzActionAllow,
+			Usernames: []string{"guest-*"},
+			Countries: []string{"XX"},
+		},
+	}
+	provider, err := auth.NewAuthzRulesProvider(
+		cfg,
+		dummy.New(),
+		log.NewTestLogger(t),
+		metrics.New(dummy.New()),
+	)
+	assert.NoError(t, err)
+
+	newMeta := func(
+		username string,
+		authenticatedUsername string,
+		ip string,
+		method config.SSHAuthMethod,
+		groups string,
+	) metadata.ConnectionAuthenticatedMetadata {
+		pending := metadata.NewTestAuthenticatingMetadata(username)
+		pending.RemoteAddress.IP = net.ParseIP(ip)
+		if groups != "" {
+			pending.Metadata["LDAP_GROUPS"] = metadata.Value{Value: groups}
+		}
+		meta := pending.Authenticated(authenticatedUsername)
+		meta.AuthenticatedMethods = []string{string(method)}
+		return meta
+	}
+
+	for name, tc := range map[string]struct {
+		meta    metadata.ConnectionAuthenticatedMetadata
+		allowed bool
+		rule    string
+	}{
+		"group": {
+			newMeta("root", "alice", "192.168.0.1", config.SSHAuthMethodPassword, "users, admins"),
+			true,
+			"admins",
+		},
+		"network": {
+			newMeta("alice", "alice", "192.168.0.1", config.SSHAuthMethodPublicKey, "users"),
+			false,
+			"blocked-network",
+		},
+		"same username": {
+			newMeta("alice", "alice", "10.0.0.1", config.SSHAuthMethodPublicKey, ""),
+			true,
+			"own-account",
+		},
+		"auth method": {
+			newMeta("alice", "alice", "10.0.0.1", config.SSHAuthMethodPassword, ""),
+			false,
+			"",
+		},
+		"other username": {
+			newMeta("root", "alice", "10.0.0.1", config.SSHAuthMethodPublicKey, ""),
+			false,
+			"",
+		},
+		"country": {
+			newMeta("guest-1", "guest-1", "10.0.0.1", config.SSHAuthMethodPassword, ""),
+			true,
+			"unknown-country",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			response := provider.Authorize(tc.meta)
+			assert.NoError(t, response.Error())
+			assert.Equal(t, tc.allowed, response.Success())
+			authorization := response.Metadata().Authorization
+			if assert.NotNil(t, authorization) {
+				assert.Equal(t, tc.allowed, authorization.Allowed)
+				assert.Equal(t, tc.rule, authorization.Rule)
+			}
+		})
+	}
+}
+
+func TestAuthzRulesDefaultAllow(t *testing.T) {
+	cfg := config.AuthzConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Method = config.AuthzMethodRules
+	cfg.DefaultAction = config.AuthzActionAllow
+	provider, err := auth.NewAuthzRulesProvider(
+		cfg,
+		dummy.New(),
+		log.NewTestLogger(t),
+		metrics.New(dummy.New()),
+	)
+	assert.NoError(t, err)
+
+	response := provider.Authorize(metadata.NewTestAuthenticatingMetadata("root").Authenticated("alice"))
+	assert.True(t, response.Success())
+	assert.Equal(t, "alice", response.Metadata().AuthenticatedUsername)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/auth"
+	"golang.org/x/crypto/ssh"
+)
+
+// UserCertificateClient is a public key authenticator that validates OpenSSH user certificates signed by trusted
+// certificate authorities. Keys that are not certificates, or are signed by an unknown CA are passed to the backing
+// authenticator, if any.
+type UserCertificateClient interface {
+	PublicKeyAuthenticator
+}
+
+// The following metadata keys are set after a successful certificate authentication.
+const (
+	// MetadataCertificateSerial contains the serial number of the certificate.
+	MetadataCertificateSerial = "SSH_CERT_SERIAL"
+	// MetadataCertificateKeyID contains the key ID of the certificate.
+	MetadataCertificateKeyID = "SSH_CERT_KEY_ID"
+	// MetadataCertificatePrincipals contains the comma-separated list of principals of the certificate.
+	MetadataCertificatePrincipals = "SSH_CERT_PRINCIPALS"
+	// MetadataCertificateExtensions contains the comma-separated list of extensions of the certificate.
+	MetadataCertificateExtensions = "SSH_CERT_EXTENSIONS"
+	// MetadataCertificateCAFingerprint contains the SHA256 fingerprint of the CA key that signed the certificate.
+	MetadataCertificateCAFingerprint = "SSH_CERT_CA_FINGERPRINT"
+)
+
+// DecodeCertificate converts an SSH certificate into the structure passed to webhooks.
+func DecodeCertificate(cert *ssh.Certificate) *auth.Certificate {
+	result := &auth.Certificate{
+		PublicKey:       strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert.Key))),
+		Type:            "user",
+		Serial:          cert.Serial,
+		KeyID:           cert.KeyId,
+		Principals:      cert.ValidPrincipals,
+		ValidAfter:      time.Unix(int64(cert.ValidAfter), 0).UTC(),
+		CriticalOptions: cert.CriticalOptions,
+		Extensions:      cert.Extensions,
+		SignatureKey:    strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert.SignatureKey))),
+	}
+	if cert.CertType == ssh.HostCert {
+		result.Type = "host"
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		validBefore := time.Unix(int64(cert.ValidBefore), 0).UTC()
+		result.ValidBefore = &validBefore
+	}
+	return result
+}
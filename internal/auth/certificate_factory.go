@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewUserCertificateClient creates a public key authenticator validating OpenSSH user certificates. The backend is
+// used for all keys that are not certificates signed by a trusted CA. The backend may be nil, in which case such keys
+// are rejected.
+func NewUserCertificateClient(
+	cfg config.AuthUserCertificateConfig,
+	backend PublicKeyAuthenticator,
+	logger log.Logger,
+	_ metrics.Collector,
+) (UserCertificateClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"User certificate configuration failed to validate",
+		)
+	}
+	caKeys, err := cfg.LoadTrustedCAKeys()
+	if err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"Failed to load trusted CA keys",
+		)
+	}
+	authorities := make(map[string]struct{}, len(caKeys))
+	for _, caKey := range caKeys {
+		authorities[string(caKey.Marshal())] = struct{}{}
+	}
+	var revokedKeys *revokedKeysFile
+	if cfg.RevokedKeys != "" {
+		revokedKeys = &revokedKeysFile{path: cfg.RevokedKeys}
+		if _, err := revokedKeys.get(); err != nil {
+			return nil, message.Wrap(
+				err,
+				message.EAuthConfigError,
+				"Failed to load revoked keys from %s",
+				cfg.RevokedKeys,
+			)
+		}
+	}
+	return &userCertificateClient{
+		authorities: authorities,
+		revokedKeys: revokedKeys,
+		backend:     backend,
+		logger:      logger,
+	}, nil
+}
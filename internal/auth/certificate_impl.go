@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	certOptionForceCommand  = "force-command"
+	certOptionSourceAddress = "source-address"
+
+	certExtensionPortForwarding  = "permit-port-forwarding"
+	certExtensionPTY             = "permit-pty"
+	certExtensionX11Forwarding   = "permit-X11-forwarding"
+	certExtensionAgentForwarding = "permit-agent-forwarding"
+)
+
+type userCertificateContext struct {
+	meta    metadata.ConnectionAuthenticatedMetadata
+	success bool
+	err     error
+}
+
+func (u *userCertificateContext) Success() bool {
+	return u.success
+}
+
+func (u *userCertificateContext) Error() error {
+	return u.err
+}
+
+func (u *userCertificateContext) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return u.meta
+}
+
+func (u *userCertificateContext) OnDisconnect() {
+}
+
+type userCertificateClient struct {
+	authorities map[string]struct{}
+	revokedKeys *revokedKeysFile
+	backend     PublicKeyAuthenticator
+	logger      log.Logger
+}
+
+func (c *userCertificateClient) PubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth.PublicKey,
+) AuthenticationContext {
+	logger := c.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username)
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubKey.PublicKey))
+	if err != nil {
+		return &userCertificateContext{meta.AuthFailed(), false, err}
+	}
+
+	if c.revokedKeys != nil {
+		revocationList, err := c.revokedKeys.get()
+		if err != nil {
+			err := message.Wrap(
+				err,
+				message.EAuthRevokedKeysReadFailed,
+				"Failed to read revoked keys file %s",
+				c.revokedKeys.path,
+			)
+			logger.Error(err)
+			return &userCertificateContext{meta.AuthFailed(), false, err}
+		}
+		if revocationList.isRevoked(key) {
+			logger.Info(
+				message.NewMessage(
+					message.EAuthKeyRevoked,
+					"The presented key %s is revoked",
+					ssh.FingerprintSHA256(key),
+				),
+			)
+			return &userCertificateContext{meta.AuthFailed(), false, nil}
+		}
+	}
+
+	cert, ok := key.(*ssh.Certificate)
+	if !ok || !c.isTrusted(cert.SignatureKey) {
+		if c.backend == nil {
+			return &userCertificateContext{meta.AuthFailed(), false, nil}
+		}
+		return c.backend.PubKey(meta, pubKey)
+	}
+
+	if err := c.checkCertificate(meta, cert); err != nil {
+		logger.Debug(
+			message.Wrap(
+				err,
+				message.EAuthCertificateRejected,
+				"Certificate %s (serial %d) rejected",
+				cert.KeyId,
+				cert.Serial,
+			),
+		)
+		return &userCertificateContext{meta.AuthFailed(), false, nil}
+	}
+
+	return &userCertificateContext{
+		meta:    c.certificateMetadata(meta.Authenticated(meta.Username), cert),
+		success: true,
+	}
+}
+
+func (c *userCertificateClient) isTrusted(caKey ssh.PublicKey) bool {
+	_, ok := c.authorities[string(caKey.Marshal())]
+	return ok
+}
+
+func (c *userCertificateClient) checkCertificate(
+	meta metadata.ConnectionAuthPendingMetadata,
+	cert *ssh.Certificate,
+) error {
+	if cert.CertType != ssh.UserCert {
+		return fmt.Errorf("not a user certificate")
+	}
+	// Unlike ssh.CertChecker, OpenSSH does not accept certificates without principals when using trusted CA keys.
+	if len(cert.ValidPrincipals) == 0 {
+		return fmt.Errorf("the certificate has no principals")
+	}
+	checker := &ssh.CertChecker{
+		SupportedCriticalOptions: []string{certOptionForceCommand, certOptionSourceAddress},
+	}
+	if err := checker.CheckCert(meta.Username, cert); err != nil {
+		return err
+	}
+	if sourceAddress, ok := cert.CriticalOptions[certOptionSourceAddress]; ok {
+		if !matchSourceAddress(sourceAddress, meta.RemoteAddress.IP) {
+			return fmt.Errorf("the source address %s is not permitted by the certificate", meta.RemoteAddress.IP)
+		}
+	}
+	return nil
+}
+
+// certificateMetadata exposes the certificate fields in the metadata and maps the critical options and extensions to
+// restrictions.
+func (c *userCertificateClient) certificateMetadata(
+	meta metadata.ConnectionAuthenticatedMetadata,
+	cert *ssh.Certificate,
+) metadata.ConnectionAuthenticatedMetadata {
+	meta = cloneMetadata(meta)
+	values := meta.GetMetadata()
+
+	extensions := make([]string, 0, len(cert.Extensions))
+	for extension := range cert.Extensions {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+
+	values[MetadataCertificateSerial] = metadata.Value{Value: strconv.FormatUint(cert.Serial, 10)}
+	values[MetadataCertificateKeyID] = metadata.Value{Value: cert.KeyId}
+	values[MetadataCertificatePrincipals] = metadata.Value{Value: strings.Join(cert.ValidPrincipals, ",")}
+	values[MetadataCertificateExtensions] = metadata.Value{Value: strings.Join(extensions, ",")}
+	values[MetadataCertificateCAFingerprint] = metadata.Value{Value: ssh.FingerprintSHA256(cert.SignatureKey)}
+
+	_, permitPortForwarding := cert.Extensions[certExtensionPortForwarding]
+	_, permitPTY := cert.Extensions[certExtensionPTY]
+	_, permitX11Forwarding := cert.Extensions[certExtensionX11Forwarding]
+	_, permitAgentForwarding := cert.Extensions[certExtensionAgentForwarding]
+	meta.Restrictions = meta.Restrictions.Merge(metadata.Restrictions{
+		ForceCommand:      cert.CriticalOptions[certOptionForceCommand],
+		NoPortForwarding:  !permitPortForwarding,
+		NoPTY:             !permitPTY,
+		NoX11Forwarding:   !permitX11Forwarding,
+		NoAgentForwarding: !permitAgentForwarding,
+	})
+	return meta
+}
+
+// matchSourceAddress checks the IP address against the comma-separated list of addresses and CIDR ranges in the
+// source-address critical option.
+func matchSourceAddress(sourceAddress string, ip net.IP) bool {
+	for _, address := range strings.Split(sourceAddress, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" || strings.ContainsAny(address, "*?!") {
+			continue
+		}
+		if matchFromPattern(address, ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	auth3 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestUserCertificate(t *testing.T) {
+	ca := generateCertificateTestSigner(t)
+	otherCA := generateCertificateTestSigner(t)
+	userKey := generateAuthorizedKeysTestKey(t)
+	dir := t.TempDir()
+	revokedKeysFile := filepath.Join(dir, "revoked")
+	assert.NoError(t, os.WriteFile(revokedKeysFile, nil, 0600))
+
+	client, err := auth.NewUserCertificateClient(
+		config.AuthUserCertificateConfig{
+			TrustedCAKeys: []string{string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+			RevokedKeys:   revokedKeysFile,
+		},
+		nil,
+		log.NewTestLogger(t),
+		nil,
+	)
+	assert.NoError(t, err)
+
+	newCert := func(signer ssh.Signer, serial uint64, principals ...string) *ssh.Certificate {
+		cert := &ssh.Certificate{
+			Key:             userKey,
+			Serial:          serial,
+			CertType:        ssh.UserCert,
+			KeyId:           "test",
+			ValidPrincipals: principals,
+			ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+			ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+			Permissions: ssh.Permissions{
+				CriticalOptions: map[string]string{},
+				Extensions:      map[string]string{"permit-pty": ""},
+			},
+		}
+		assert.NoError(t, cert.SignCert(rand.Reader, signer))
+		return cert
+	}
+	pubKey := func(username string, ip string, key ssh.PublicKey) auth.AuthenticationContext {
+		meta := metadata.NewTestAuthenticatingMetadata(username)
+		meta.RemoteAddress = metadata.RemoteAddress(net.TCPAddr{IP: net.ParseIP(ip), Port: 2222})
+		return client.PubKey(meta, auth3.PublicKey{PublicKey: string(ssh.MarshalAuthorizedKey(key))})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ctx := pubKey("foo", "127.0.0.1", newCert(ca, 1, "foo", "bar"))
+		assert.True(t, ctx.Success())
+		meta := ctx.Metadata()
+		assert.Equal(t, "1", meta.Metadata[auth.MetadataCertificateSerial].Value)
+		assert.Equal(t, "test", meta.Metadata[auth.MetadataCertificateKeyID].Value)
+		assert.Equal(t, "foo,bar", meta.Metadata[auth.MetadataCertificatePrincipals].Value)
+		assert.Equal(t, "permit-pty", meta.Metadata[auth.MetadataCertificateExtensions].Value)
+
+		cfg := config.SecurityConfig{}
+		auth.ApplyRestrictions(meta, &cfg)
+		assert.Equal(t, config.ExecutionPolicyUnconfigured, cfg.TTY.Mode)
+		assert.Equal(t, config.ExecutionPolicyDisable, cfg.Forwarding.ForwardingMode)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.False(t, pubKey("baz", "127.0.0.1", newCert(ca, 1, "foo")).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", newCert(ca, 1)).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", newCert(otherCA, 1, "foo")).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", userKey).Success())
+
+		expired := newCert(ca, 1, "foo")
+		expired.ValidBefore = uint64(time.Now().Add(-time.Minute).Unix())
+		assert.NoError(t, expired.SignCert(rand.Reader, ca))
+		assert.False(t, pubKey("foo", "127.0.0.1", expired).Success())
+	})
+
+	t.Run("criticalOptions", func(t *testing.T) {
+		cert := newCert(ca, 1, "foo")
+		cert.CriticalOptions["source-address"] = "10.0.0.0/8,192.168.0.1"
+		cert.CriticalOptions["force-command"] = "/bin/true"
+		assert.NoError(t, cert.SignCert(rand.Reader, ca))
+		assert.False(t, pubKey("foo", "127.0.0.1", cert).Success())
+		assert.True(t, pubKey("foo", "192.168.0.1", cert).Success())
+		ctx := pubKey("foo", "10.1.1.1", cert)
+		assert.True(t, ctx.Success())
+		cfg := config.SecurityConfig{}
+		auth.ApplyRestrictions(ctx.Metadata(), &cfg)
+		assert.Equal(t, "/bin/true", cfg.ForceCommand)
+		assert.Equal(t, config.ExecutionPolicyDisable, cfg.Forwarding.AgentForwardingMode)
+
+		cert.Extensions["permit-agent-forwarding"] = ""
+		assert.NoError(t, cert.SignCert(rand.Reader, ca))
+		ctx = pubKey("foo", "10.1.1.1", cert)
+		assert.True(t, ctx.Success())
+		cfg = config.SecurityConfig{}
+		auth.ApplyRestrictions(ctx.Metadata(), &cfg)
+		assert.Equal(t, config.SecurityExecutionPolicy(""), cfg.Forwarding.AgentForwardingMode)
+
+		cert.CriticalOptions["unknown"] = ""
+		assert.NoError(t, cert.SignCert(rand.Reader, ca))
+		assert.False(t, pubKey("foo", "10.1.1.1", cert).Success())
+	})
+
+	t.Run("krl", func(t *testing.T) {
+		writeRevokedKeys := func(data []byte) {
+			assert.NoError(t, os.WriteFile(revokedKeysFile, data, 0600))
+			modTime := time.Now().Add(time.Duration(len(data)) * time.Second)
+			assert.NoError(t, os.Chtimes(revokedKeysFile, modTime, modTime))
+		}
+
+		writeRevokedKeys(buildTestKRL(ca.PublicKey(), []uint64{5}, []string{"revoked-id"}))
+		assert.True(t, pubKey("foo", "127.0.0.1", newCert(ca, 4, "foo")).Success())
+		assert.False(t, pubKey("foo", "127.0.0.1", newCert(ca, 5, "foo")).Success())
+		revokedID := newCert(ca, 6, "foo")
+		revokedID.KeyId = "revoked-id"
+		assert.NoError(t, revokedID.SignCert(rand.Reader, ca))
+		assert.False(t, pubKey("foo", "127.0.0.1", revokedID).Success())
+
+		writeRevokedKeys(ssh.MarshalAuthorizedKey(userKey))
+		assert.False(t, pubKey("foo", "127.0.0.1", newCert(ca, 4, "foo")).Success())
+
+		writeRevokedKeys(append([]byte("# revoked keys\n\n"), ssh.MarshalAuthorizedKey(userKey)...))
+		assert.False(t, pubKey("foo", "127.0.0.1", newCert(ca, 4, "foo")).Success())
+
+		writeRevokedKeys(append([]byte("not-a-key\n"), ssh.MarshalAuthorizedKey(userKey)...))
+		ctx := pubKey("foo", "127.0.0.1", newCert(ca, 4, "foo"))
+		assert.False(t, ctx.Success())
+		assert.Error(t, ctx.Error())
+
+		writeRevokedKeys([]byte("SSHKRL\n\x00garbage"))
+		ctx = pubKey("foo", "127.0.0.1", newCert(ca, 4, "foo"))
+		assert.False(t, ctx.Success())
+		assert.Error(t, ctx.Error())
+
+		writeRevokedKeys(nil)
+	})
+}
+
+func TestUserCertificateFallback(t *testing.T) {
+	ca := generateCertificateTestSigner(t)
+	dir := t.TempDir()
+	key := generateAuthorizedKeysTestKey(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "foo"), ssh.MarshalAuthorizedKey(key), 0600))
+
+	backend, err := auth.NewAuthorizedKeysClient(
+		config.AuthAuthorizedKeysConfig{Path: filepath.Join(dir, "%u")},
+		log.NewTestLogger(t),
+		metrics.New(dummy.New()),
+	)
+	assert.NoError(t, err)
+	client, err := auth.NewUserCertificateClient(
+		config.AuthUserCertificateConfig{
+			TrustedCAKeys: []string{string(ssh.MarshalAuthorizedKey(ca.PublicKey()))},
+		},
+		backend,
+		log.NewTestLogger(t),
+		nil,
+	)
+	assert.NoError(t, err)
+
+	ctx := client.PubKey(
+		metadata.NewTestAuthenticatingMetadata("foo"),
+		auth3.PublicKey{PublicKey: string(ssh.MarshalAuthorizedKey(key))},
+	)
+	assert.True(t, ctx.Success())
+}
+
+func generateCertificateTestSigner(t *testing.T) ssh.Signer {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(private)
+	assert.NoError(t, err)
+	return signer
+}
+
+// buildTestKRL creates a minimal OpenSSH key revocation list revoking the specified serials and key IDs of a CA.
+func buildTestKRL(ca ssh.PublicKey, serials []uint64, keyIDs []string) []byte {
+	appendString := func(buf []byte, data []byte) []byte {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+		return append(buf, data...)
+	}
+
+	var serialList []byte
+	for _, serial := range serials {
+		serialList = binary.BigEndian.AppendUint64(serialList, serial)
+	}
+	var keyIDList []byte
+	for _, keyID := range keyIDs {
+		keyIDList = appendString(keyIDList, []byte(keyID))
+	}
+	var certSection []byte
+	certSection = appendString(certSection, ca.Marshal())
+	certSection = appendString(certSection, nil)
+	certSection = append(certSection, 0x20)
+	certSection = appendString(certSection, serialList)
+	certSection = append(certSection, 0x23)
+	certSection = appendString(certSection, keyIDList)
+
+	krl := []byte("SSHKRL\n\x00")
+	krl = binary.BigEndian.AppendUint32(krl, 1)
+	krl = binary.BigEndian.AppendUint64(krl, 1)
+	krl = binary.BigEndian.AppendUint64(krl, uint64(time.Now().Unix()))
+	krl = binary.BigEndian.AppendUint64(krl, 0)
+	krl = appendString(krl, nil)
+	krl = appendString(krl, []byte("test"))
+	krl = append(krl, 1)
+	krl = appendString(krl, certSection)
+	return krl
+}
@@ -40,3 +40,24 @@ type Handler interface {
 		meta metadata.ConnectionAuthenticatedMetadata,
 	) (bool, metadata.ConnectionAuthenticatedMetadata, error)
 }
+
+// KeyboardInteractiveHandler is an optional interface a Handler can implement to support keyboard-interactive
+// authentication. If the Handler does not implement it, keyboard-interactive requests are answered with HTTP 404.
+type KeyboardInteractiveHandler interface {
+	// OnKeyboardInteractive is called for each round of a keyboard-interactive authentication.
+	//
+	// - meta is the metadata of the connection, including the username provided by the user.
+	// - round is the number of the round, starting at 1.
+	// - state is the state of the challenge returned in the previous round.
+	// - answers contains the answers of the user to the questions of the previous round, keyed by the question ID.
+	//
+	// The method must return a boolean if the authentication was successful. To ask the user further questions it
+	// must return an unsuccessful result with a challenge. If an error is returned the server responds with an HTTP
+	// 500 response.
+	OnKeyboardInteractive(
+		meta metadata.ConnectionAuthPendingMetadata,
+		round int,
+		state string,
+		answers map[string]string,
+	) (bool, *auth2.KeyboardInteractiveChallenge, metadata.ConnectionAuthenticatedMetadata, error)
+}
@@ -3,13 +3,22 @@ package auth
 import (
 	goHttp "net/http"
 
+	"go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/http"
 	"go.containerssh.io/libcontainerssh/log"
 )
 
-// NewHandler creates a handler that is compatible with the Go HTTP server.
-func NewHandler(h Handler, logger log.Logger) goHttp.Handler {
-	return &handler{
+// NewHandler creates a handler that is compatible with the Go HTTP server. If a signature secret is configured, requests
+// without a valid signature are rejected.
+func NewHandler(h Handler, signature config.HTTPSignatureConfig, logger log.Logger) goHttp.Handler {
+	var keyboardInteractive goHttp.Handler
+	if backend, ok := h.(KeyboardInteractiveHandler); ok {
+		keyboardInteractive = http.NewServerHandler(&keyboardInteractiveHandler{
+			backend: backend,
+			logger:  logger,
+		}, logger)
+	}
+	return http.NewSignatureVerifier(signature, &handler{
 		authzHandler: http.NewServerHandler(&authzHandler{
 			backend: h,
 			logger:  logger,
@@ -22,5 +31,6 @@ func NewHandler(h Handler, logger log.Logger) goHttp.Handler {
 			backend: h,
 			logger:  logger,
 		}, logger),
-	}
+		keyboardInteractiveHandler: keyboardInteractive,
+	}, logger)
 }
@@ -13,9 +13,10 @@ import (
 )
 
 type handler struct {
-	authzHandler    goHttp.Handler
-	passwordHandler goHttp.Handler
-	pubkeyHandler   goHttp.Handler
+	authzHandler               goHttp.Handler
+	passwordHandler            goHttp.Handler
+	pubkeyHandler              goHttp.Handler
+	keyboardInteractiveHandler goHttp.Handler
 }
 
 func (h handler) ServeHTTP(writer goHttp.ResponseWriter, request *goHttp.Request) {
@@ -27,6 +28,12 @@ func (h handler) ServeHTTP(writer goHttp.ResponseWriter, request *goHttp.Request
 		h.passwordHandler.ServeHTTP(writer, request)
 	case "pubkey":
 		h.pubkeyHandler.ServeHTTP(writer, request)
+	case "keyboard-interactive":
+		if h.keyboardInteractiveHandler == nil {
+			writer.WriteHeader(404)
+			return
+		}
+		h.keyboardInteractiveHandler.ServeHTTP(writer, request)
 	default:
 		writer.WriteHeader(404)
 	}
@@ -132,3 +139,46 @@ func (p *pubKeyHandler) OnRequest(request http.ServerRequest, response http.Serv
 	}
 	return nil
 }
+
+type keyboardInteractiveHandler struct {
+	backend KeyboardInteractiveHandler
+	logger  log.Logger
+}
+
+func (k *keyboardInteractiveHandler) OnRequest(request http.ServerRequest, response http.ServerResponse) error {
+	requestObject := auth.KeyboardInteractiveAuthRequest{}
+	if err := request.Decode(&requestObject); err != nil {
+		return err
+	}
+	success, challenge, meta, err := k.backend.OnKeyboardInteractive(
+		requestObject.ConnectionAuthPendingMetadata,
+		requestObject.Round,
+		requestObject.State,
+		requestObject.Answers,
+	)
+	if err != nil {
+		k.logger.Debug(
+			message.Wrap(err, message.EAuthRequestDecodeFailed, "failed to execute keyboard-interactive request"),
+		)
+		response.SetStatus(500)
+		response.SetBody(
+			auth.KeyboardInteractiveResponseBody{
+				ResponseBody: auth.ResponseBody{
+					ConnectionAuthenticatedMetadata: meta,
+					Success:                         false,
+				},
+			},
+		)
+		return nil
+	}
+	response.SetBody(
+		auth.KeyboardInteractiveResponseBody{
+			ResponseBody: auth.ResponseBody{
+				ConnectionAuthenticatedMetadata: meta,
+				Success:                         success,
+			},
+			Challenge: challenge,
+		},
+	)
+	return nil
+}
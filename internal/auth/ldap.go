@@ -0,0 +1,7 @@
+package auth
+
+// LDAPClient is the authenticator that verifies passwords and public keys against an LDAP directory.
+type LDAPClient interface {
+	PasswordAuthenticator
+	PublicKeyAuthenticator
+}
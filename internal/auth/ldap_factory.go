@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/tls"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewLDAPClient creates an authenticator that verifies the users against an LDAP directory.
+func NewLDAPClient(
+	cfg config.AuthLDAPConfig,
+	logger log.Logger,
+	metrics metrics.Collector,
+) (LDAPClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"LDAP configuration failed to validate",
+		)
+	}
+	caCertPool, err := cfg.CACertPool()
+	if err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"Failed to load the LDAP CA certificate",
+		)
+	}
+	authSuccessMetric, authFailureMetric := createAuthResultMetrics(metrics)
+	return &ldapClient{
+		config: cfg,
+		logger: logger,
+		tlsConfig: &tls.Config{
+			MinVersion: cfg.TLSVersion.GetTLSVersion(),
+			RootCAs:    caCertPool,
+		},
+		authSuccessMetric: authSuccessMetric,
+		authFailureMetric: authFailureMetric,
+	}, nil
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"strings"
+
+	"go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/ldap"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"golang.org/x/crypto/ssh"
+)
+
+type ldapContext struct {
+	meta    metadata.ConnectionAuthenticatedMetadata
+	success bool
+	err     error
+}
+
+func (l *ldapContext) Success() bool {
+	return l.success
+}
+
+func (l *ldapContext) Error() error {
+	return l.err
+}
+
+func (l *ldapContext) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return l.meta
+}
+
+func (l *ldapContext) OnDisconnect() {
+}
+
+type ldapClient struct {
+	config            config.AuthLDAPConfig
+	logger            log.Logger
+	tlsConfig         *tls.Config
+	authSuccessMetric metrics.GeoCounter
+	authFailureMetric metrics.GeoCounter
+}
+
+func (c *ldapClient) Password(
+	meta metadata.ConnectionAuthPendingMetadata,
+	password []byte,
+) AuthenticationContext {
+	return c.countResult(meta, AuthenticationTypePassword, c.password(meta, password))
+}
+
+func (c *ldapClient) PubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth.PublicKey,
+) AuthenticationContext {
+	return c.countResult(meta, AuthenticationTypePublicKey, c.pubKey(meta, pubKey))
+}
+
+func (c *ldapClient) countResult(
+	meta metadata.ConnectionAuthPendingMetadata,
+	authType AuthenticationType,
+	authContext AuthenticationContext,
+) AuthenticationContext {
+	labels := []metrics.MetricLabel{
+		metrics.Label("authtype", string(authType)),
+	}
+	if authContext.Success() {
+		c.authSuccessMetric.Increment(meta.RemoteAddress.IP, labels...)
+	} else if authContext.Error() == nil {
+		c.authFailureMetric.Increment(meta.RemoteAddress.IP, labels...)
+	}
+	return authContext
+}
+
+func (c *ldapClient) password(
+	meta metadata.ConnectionAuthPendingMetadata,
+	password []byte,
+) AuthenticationContext {
+	logger := c.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username)
+
+	if len(password) == 0 {
+		return &ldapContext{meta.AuthFailed(), false, nil}
+	}
+
+	conn, entry, err := c.findUser(meta.Username, nil, logger)
+	if err != nil {
+		return &ldapContext{meta.AuthFailed(), false, err}
+	}
+	defer func() { _ = conn.Close() }()
+	if entry == nil {
+		return &ldapContext{meta.AuthFailed(), false, nil}
+	}
+
+	if err := conn.Bind(entry.DN, string(password)); err != nil {
+		var ldapErr *ldap.Error
+		if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.ResultInvalidCredentials {
+			return &ldapContext{meta.AuthFailed(), false, nil}
+		}
+		err := message.Wrap(
+			err,
+			message.EAuthLDAPBindFailed,
+			"Failed to verify the password of %s on the LDAP server",
+			entry.DN,
+		)
+		logger.Error(err)
+		return &ldapContext{meta.AuthFailed(), false, err}
+	}
+	return &ldapContext{c.metadata(meta, entry), true, nil}
+}
+
+func (c *ldapClient) pubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth.PublicKey,
+) AuthenticationContext {
+	logger := c.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username)
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubKey.PublicKey))
+	if err != nil {
+		return &ldapContext{meta.AuthFailed(), false, err}
+	}
+
+	conn, entry, err := c.findUser(meta.Username, []string{c.config.PublicKeyAttribute}, logger)
+	if err != nil {
+		return &ldapContext{meta.AuthFailed(), false, err}
+	}
+	_ = conn.Close()
+	if entry == nil {
+		return &ldapContext{meta.AuthFailed(), false, nil}
+	}
+
+	marshalledKey := key.Marshal()
+	for _, value := range entry.Get(c.config.PublicKeyAttribute) {
+		storedKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(value))
+		if err != nil {
+			logger.Warning(
+				message.Wrap(
+					err,
+					message.EAuthLDAPInvalidPublicKey,
+					"Ignoring invalid public key in the %s attribute of %s",
+					c.config.PublicKeyAttribute,
+					entry.DN,
+				),
+			)
+			continue
+		}
+		if bytes.Equal(storedKey.Marshal(), marshalledKey) {
+			return &ldapContext{c.metadata(meta, entry), true, nil}
+		}
+	}
+	return &ldapContext{meta.AuthFailed(), false, nil}
+}
+
+// findUser connects to the LDAP server and searches for the user. It returns a nil entry if the search did not return
+// exactly one entry. If no error is returned, the caller must close the connection.
+func (c *ldapClient) findUser(username string, attributes []string, logger log.Logger) (
+	*ldap.Conn,
+	*ldap.Entry,
+	error,
+) {
+	conn, err := ldap.Dial(
+		ldap.DialConfig{
+			URL:       c.config.URL,
+			StartTLS:  c.config.StartTLS,
+			TLSConfig: c.tlsConfig,
+			Timeout:   c.config.Timeout,
+		},
+	)
+	if err != nil {
+		err := message.Wrap(
+			err,
+			message.EAuthLDAPConnectionFailed,
+			"Failed to connect to the LDAP server at %s",
+			c.config.URL,
+		)
+		logger.Error(err)
+		return nil, nil, err
+	}
+	if c.config.BindDN != "" {
+		if err := conn.Bind(c.config.BindDN, c.config.BindPassword); err != nil {
+			_ = conn.Close()
+			err := message.Wrap(
+				err,
+				message.EAuthLDAPConnectionFailed,
+				"Failed to bind to the LDAP server as %s",
+				c.config.BindDN,
+			)
+			logger.Error(err)
+			return nil, nil, err
+		}
+	}
+
+	entries, err := conn.Search(
+		ldap.SearchRequest{
+			BaseDN:     c.config.BaseDN,
+			Filter:     c.config.UserFilterFor(username),
+			Attributes: c.searchAttributes(attributes),
+			// Two entries are enough to detect an ambiguous filter.
+			SizeLimit: 2,
+		},
+	)
+	if err != nil {
+		_ = conn.Close()
+		err := message.Wrap(
+			err,
+			message.EAuthLDAPSearchFailed,
+			"Failed to search for the user on the LDAP server",
+		)
+		logger.Error(err)
+		return nil, nil, err
+	}
+	switch len(entries) {
+	case 0:
+		logger.Debug(
+			message.NewMessage(
+				message.EAuthLDAPUserNotFound,
+				"User not found on the LDAP server",
+			),
+		)
+		return conn, nil, nil
+	case 1:
+		return conn, entries[0], nil
+	default:
+		logger.Warning(
+			message.NewMessage(
+				message.EAuthLDAPAmbiguousUser,
+				"The LDAP user filter matched more than one entry, rejecting the user",
+			),
+		)
+		return conn, nil, nil
+	}
+}
+
+func (c *ldapClient) searchAttributes(attributes []string) []string {
+	if c.config.GroupAttribute != "" {
+		attributes = append(attributes, c.config.GroupAttribute)
+	}
+	for attribute := range c.config.Metadata {
+		attributes = append(attributes, attribute)
+	}
+	if len(attributes) == 0 {
+		// 1.1 requests no attributes, an empty list would return all of them. See RFC 4511 section 4.5.1.8.
+		return []string{"1.1"}
+	}
+	return attributes
+}
+
+// metadata returns the authenticated metadata with the group memberships and the mapped attributes of the entry.
+func (c *ldapClient) metadata(
+	meta metadata.ConnectionAuthPendingMetadata,
+	entry *ldap.Entry,
+) metadata.ConnectionAuthenticatedMetadata {
+	result := meta.Authenticated(meta.Username)
+	values := result.GetMetadata()
+	if c.config.GroupAttribute != "" {
+		var groups []string
+		for _, group := range entry.Get(c.config.GroupAttribute) {
+			groups = append(groups, groupName(group))
+		}
+		values[c.config.GroupMetadataKey] = metadata.Value{Value: strings.Join(groups, ",")}
+	}
+	for attribute, key := range c.config.Metadata {
+		if attributeValues := entry.Get(attribute); len(attributeValues) > 0 {
+			values[key] = metadata.Value{Value: strings.Join(attributeValues, ",")}
+		}
+	}
+	return result
+}
+
+// groupName returns the value of the first RDN if the group is given as a DN, otherwise the group unchanged.
+func groupName(group string) string {
+	equals := strings.IndexByte(group, '=')
+	if equals < 0 {
+		return group
+	}
+	name := strings.Builder{}
+	for i := equals + 1; i < len(group); i++ {
+		switch group[i] {
+		case '\\':
+			if i+1 < len(group) {
+				i++
+				name.WriteByte(group[i])
+			}
+		case ',', '+':
+			return name.String()
+		default:
+			name.WriteByte(group[i])
+		}
+	}
+	return name.String()
+}
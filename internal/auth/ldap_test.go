@@ -0,0 +1,178 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	auth3 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/test"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+const (
+	ldapTestServiceDN = "cn=containerssh,ou=services,dc=example,dc=com"
+	ldapTestUserDN    = "uid=foo,ou=people,dc=example,dc=com"
+)
+
+func TestLDAPPassword(t *testing.T) {
+	srv := newLDAPTestServer(t, "")
+
+	for name, cfg := range map[string]config.AuthLDAPConfig{
+		"StartTLS": newLDAPTestConfig(srv, srv.URL(), true),
+		"LDAPS":    newLDAPTestConfig(srv, srv.TLSURL(), false),
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := newLDAPTestClient(t, cfg)
+
+			ctx := client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+			assert.True(t, ctx.Success())
+			assert.NoError(t, ctx.Error())
+			meta := ctx.Metadata().Metadata
+			assert.Equal(t, "developers,admins", meta["LDAP_GROUPS"].Value)
+			assert.Equal(t, "1000", meta["UID"].Value)
+
+			ctx = client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("baz"))
+			assert.False(t, ctx.Success())
+			assert.NoError(t, ctx.Error())
+
+			ctx = client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte(""))
+			assert.False(t, ctx.Success(), "an empty password must not result in an unauthenticated bind")
+			assert.NoError(t, ctx.Error())
+
+			ctx = client.Password(metadata.NewTestAuthenticatingMetadata("nobody"), []byte("bar"))
+			assert.False(t, ctx.Success())
+			assert.NoError(t, ctx.Error())
+
+			ctx = client.Password(metadata.NewTestAuthenticatingMetadata("*"), []byte("bar"))
+			assert.False(t, ctx.Success(), "the username must be escaped in the search filter")
+			assert.NoError(t, ctx.Error())
+		})
+	}
+	assert.True(t, srv.TLSBinds(), "all credentials should have been sent over TLS")
+	assert.Contains(t, srv.Binds(), ldapTestServiceDN)
+	assert.Contains(t, srv.Binds(), ldapTestUserDN)
+}
+
+func TestLDAPAmbiguousUser(t *testing.T) {
+	srv := newLDAPTestServer(t, "")
+	cfg := newLDAPTestConfig(srv, srv.TLSURL(), false)
+	cfg.UserFilter = "(|(uid=%u)(uid=bar))"
+	client := newLDAPTestClient(t, cfg)
+
+	ctx := client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+	assert.False(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+}
+
+func TestLDAPUnavailable(t *testing.T) {
+	srv := newLDAPTestServer(t, "")
+	cfg := newLDAPTestConfig(srv, srv.TLSURL(), false)
+	cfg.BindPassword = "invalid"
+	client := newLDAPTestClient(t, cfg)
+
+	ctx := client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+	assert.False(t, ctx.Success())
+	assert.Error(t, ctx.Error(), "a failing service account bind should be reported as an error")
+
+	cfg = newLDAPTestConfig(srv, srv.TLSURL(), false)
+	cfg.CACert = ""
+	client = newLDAPTestClient(t, cfg)
+	ctx = client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+	assert.False(t, ctx.Success())
+	assert.Error(t, ctx.Error(), "an untrusted server certificate should be reported as an error")
+}
+
+func TestLDAPPublicKey(t *testing.T) {
+	key := generateAuthorizedKeysTestKey(t)
+	otherKey := generateAuthorizedKeysTestKey(t)
+	srv := newLDAPTestServer(t, string(ssh.MarshalAuthorizedKey(key)))
+	client := newLDAPTestClient(t, newLDAPTestConfig(srv, srv.URL(), true))
+
+	pubKey := func(username string, k ssh.PublicKey) auth.AuthenticationContext {
+		return client.PubKey(
+			metadata.NewTestAuthenticatingMetadata(username),
+			auth3.PublicKey{PublicKey: string(ssh.MarshalAuthorizedKey(k))},
+		)
+	}
+
+	ctx := pubKey("foo", key)
+	assert.True(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+	assert.Equal(t, "developers,admins", ctx.Metadata().Metadata["LDAP_GROUPS"].Value)
+
+	ctx = pubKey("foo", otherKey)
+	assert.False(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+
+	ctx = pubKey("bar", key)
+	assert.False(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+	assert.Equal(t, []string{ldapTestServiceDN, ldapTestServiceDN, ldapTestServiceDN}, srv.Binds())
+}
+
+func newLDAPTestServer(t *testing.T, publicKey string) test.LDAPServerInstance {
+	return test.LDAPServer(
+		t, []test.LDAPEntry{
+			{
+				DN:       ldapTestServiceDN,
+				Password: "service",
+			},
+			{
+				DN:       ldapTestUserDN,
+				Password: "bar",
+				Attributes: map[string][]string{
+					"uid":          {"foo"},
+					"uidNumber":    {"1000"},
+					"sshPublicKey": {"invalid", publicKey},
+					"memberOf": {
+						"cn=developers,ou=groups,dc=example,dc=com",
+						"cn=admins,ou=groups,dc=example,dc=com",
+					},
+				},
+			},
+			{
+				DN:       "uid=bar,ou=people,dc=example,dc=com",
+				Password: "baz",
+				Attributes: map[string][]string{
+					"uid": {"bar"},
+				},
+			},
+		},
+	)
+}
+
+func newLDAPTestConfig(srv test.LDAPServerInstance, url string, startTLS bool) config.AuthLDAPConfig {
+	return config.AuthLDAPConfig{
+		URL:                url,
+		StartTLS:           startTLS,
+		CACert:             srv.CACert(),
+		TLSVersion:         config.TLSVersion12,
+		BindDN:             ldapTestServiceDN,
+		BindPassword:       "service",
+		BaseDN:             "ou=people,dc=example,dc=com",
+		UserFilter:         "(&(uid=%u)(!(disabled=*)))",
+		PublicKeyAttribute: "sshPublicKey",
+		GroupAttribute:     "memberOf",
+		GroupMetadataKey:   "LDAP_GROUPS",
+		Metadata: map[string]string{
+			"uidNumber": "UID",
+		},
+		Timeout: 5 * time.Second,
+	}
+}
+
+func newLDAPTestClient(t *testing.T, cfg config.AuthLDAPConfig) auth.LDAPClient {
+	client, err := auth.NewLDAPClient(cfg, log.NewTestLogger(t), metrics.New(dummy.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
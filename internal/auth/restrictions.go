@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// ApplyRestrictions applies the restrictions placed on the connection by the authenticator to the security
+// configuration. Restrictions can only make the configuration stricter. If the security configuration already has a
+// forced command it takes precedence over the one set by the authenticator, similar to how OpenSSH treats
+// ForceCommand.
+func ApplyRestrictions(meta metadata.ConnectionAuthenticatedMetadata, cfg *config.SecurityConfig) {
+	restrictions := meta.Restrictions
+	if restrictions.ForceCommand != "" && cfg.ForceCommand == "" {
+		cfg.ForceCommand = restrictions.ForceCommand
+	}
+	if restrictions.NoPortForwarding {
+		cfg.Forwarding.ForwardingMode = config.ExecutionPolicyDisable
+		cfg.Forwarding.ReverseForwardingMode = config.ExecutionPolicyDisable
+		cfg.Forwarding.SocketForwardingMode = config.ExecutionPolicyDisable
+		cfg.Forwarding.SocketListenMode = config.ExecutionPolicyDisable
+	}
+	if restrictions.NoPTY {
+		cfg.TTY.Mode = config.ExecutionPolicyDisable
+	}
+	if restrictions.NoX11Forwarding {
+		cfg.Forwarding.X11ForwardingMode = config.ExecutionPolicyDisable
+	}
+	if restrictions.NoAgentForwarding {
+		cfg.Forwarding.AgentForwardingMode = config.ExecutionPolicyDisable
+	}
+}
+
+// cloneMetadata returns a copy of the metadata with its own metadata and environment maps so that values set by an
+// authenticator do not leak into other authentication attempts on the same connection.
+func cloneMetadata(meta metadata.ConnectionAuthenticatedMetadata) metadata.ConnectionAuthenticatedMetadata {
+	values := make(map[string]metadata.Value, len(meta.Metadata))
+	for k, v := range meta.Metadata {
+		values[k] = v
+	}
+	env := make(map[string]metadata.Value, len(meta.Environment))
+	for k, v := range meta.Environment {
+		env[k] = v
+	}
+	meta.Metadata = values
+	meta.Environment = env
+	return meta
+}
@@ -16,7 +16,7 @@ func NewServer(
 	return http.NewServer(
 		"Auth Server",
 		configuration,
-		NewHandler(h, logger),
+		NewHandler(h, configuration.Signature, logger),
 		logger,
 		func(url string) {
 			logger.Info(message.NewMessage(
@@ -0,0 +1,7 @@
+package auth
+
+// TOTPClient is the authenticator asking the user for a time-based one-time password (RFC 6238) using the
+// keyboard-interactive facility. It is typically used as the second step of an authentication method sequence.
+type TOTPClient interface {
+	KeyboardInteractiveAuthenticator
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/sha1" //nolint:gosec // RFC 6238 uses HMAC-SHA1 by default
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// NewTOTPClient creates an authenticator that asks the user for a time-based one-time password.
+func NewTOTPClient(
+	cfg config.AuthTOTPConfig,
+	logger log.Logger,
+	metrics metrics.Collector,
+) (TOTPClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"TOTP configuration failed to validate",
+		)
+	}
+	var hashFunc func() hash.Hash
+	switch cfg.Algorithm {
+	case config.TOTPAlgorithmSHA1:
+		hashFunc = sha1.New
+	case config.TOTPAlgorithmSHA256:
+		hashFunc = sha256.New
+	case config.TOTPAlgorithmSHA512:
+		hashFunc = sha512.New
+	}
+	authSuccessMetric, authFailureMetric := createAuthResultMetrics(metrics)
+	return &totpClient{
+		config:            cfg,
+		logger:            logger,
+		hash:              hashFunc,
+		now:               time.Now,
+		lastUsed:          map[string]uint64{},
+		authSuccessMetric: authSuccessMetric,
+		authFailureMetric: authFailureMetric,
+	}, nil
+}
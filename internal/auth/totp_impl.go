@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// totpQuestionID is the ID of the one-time password question.
+const totpQuestionID = "totp"
+
+type totpContext struct {
+	meta    metadata.ConnectionAuthenticatedMetadata
+	success bool
+	err     error
+}
+
+func (t *totpContext) Success() bool {
+	return t.success
+}
+
+func (t *totpContext) Error() error {
+	return t.err
+}
+
+func (t *totpContext) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return t.meta
+}
+
+func (t *totpContext) OnDisconnect() {
+}
+
+// totpSecretsFile is the cached content of the secrets file. The file is parsed again if the modification time or the
+// size changes.
+type totpSecretsFile struct {
+	modTime time.Time
+	size    int64
+	secrets map[string]string
+}
+
+type totpClient struct {
+	config config.AuthTOTPConfig
+	logger log.Logger
+	hash   func() hash.Hash
+	now    func() time.Time
+
+	lock    sync.Mutex
+	secrets *totpSecretsFile
+	// lastUsed holds the time step of the last accepted one-time password of each user. Only later time steps are
+	// accepted to prevent replaying a one-time password within the accepted time window.
+	lastUsed map[string]uint64
+
+	authSuccessMetric metrics.GeoCounter
+	authFailureMetric metrics.GeoCounter
+}
+
+func (c *totpClient) KeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions KeyboardInteractiveQuestions,
+	) (answers KeyboardInteractiveAnswers, err error),
+) AuthenticationContext {
+	authContext := c.keyboardInteractive(meta, challenge)
+	labels := []metrics.MetricLabel{
+		metrics.Label("authtype", string(AuthenticationTypeKeyboardInteractive)),
+	}
+	if authContext.Success() {
+		c.authSuccessMetric.Increment(meta.RemoteAddress.IP, labels...)
+	} else if authContext.Error() == nil {
+		c.authFailureMetric.Increment(meta.RemoteAddress.IP, labels...)
+	}
+	return authContext
+}
+
+func (c *totpClient) keyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions KeyboardInteractiveQuestions,
+	) (answers KeyboardInteractiveAnswers, err error),
+) AuthenticationContext {
+	logger := c.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username)
+
+	secret, err := c.getSecret(meta)
+	if err != nil {
+		err := message.Wrap(
+			err,
+			message.EAuthTOTPSecretsReadFailed,
+			"Failed to read the TOTP secrets file %s",
+			c.config.SecretsFile,
+		)
+		logger.Error(err)
+		return &totpContext{meta.AuthFailed(), false, err}
+	}
+
+	// The question is asked even if the user has no secret so the response does not reveal which users have a
+	// secret.
+	answers, err := challenge(
+		c.config.Instruction,
+		KeyboardInteractiveQuestions{
+			{
+				ID:           totpQuestionID,
+				Question:     c.config.Question,
+				EchoResponse: false,
+			},
+		},
+	)
+	if err != nil {
+		return &totpContext{meta.AuthFailed(), false, err}
+	}
+
+	if secret == "" {
+		logger.Debug(message.NewMessage(message.EAuthTOTPNoSecret, "No TOTP secret found for user"))
+		return &totpContext{meta.AuthFailed(), false, nil}
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		logger.Warning(message.Wrap(err, message.EAuthTOTPInvalidSecret, "The TOTP secret of the user is invalid"))
+		return &totpContext{meta.AuthFailed(), false, nil}
+	}
+
+	step, ok := c.verify(key, strings.TrimSpace(answers.Answers[totpQuestionID]))
+	if !ok {
+		logger.Debug(message.NewMessage(message.EAuthTOTPInvalidCode, "Invalid one-time password"))
+		return &totpContext{meta.AuthFailed(), false, nil}
+	}
+	if !c.use(meta.Username, step) {
+		logger.Debug(
+			message.NewMessage(
+				message.EAuthTOTPReplay,
+				"The one-time password of time step %d has already been used",
+				step,
+			),
+		)
+		return &totpContext{meta.AuthFailed(), false, nil}
+	}
+	return &totpContext{meta.Authenticated(meta.Username), true, nil}
+}
+
+// getSecret returns the secret of the user from the connection metadata, or if not present, from the secrets file. It
+// returns an empty string if the user has no secret.
+func (c *totpClient) getSecret(meta metadata.ConnectionAuthPendingMetadata) (string, error) {
+	if c.config.SecretMetadataKey != "" {
+		if value, ok := meta.Metadata[c.config.SecretMetadataKey]; ok && value.Value != "" {
+			return value.Value, nil
+		}
+	}
+	if c.config.SecretsFile == "" {
+		return "", nil
+	}
+	secrets, err := c.loadSecrets()
+	if err != nil {
+		return "", err
+	}
+	return secrets[meta.Username], nil
+}
+
+// loadSecrets returns the secrets of the secrets file, reading it again if it has changed since it was last read.
+func (c *totpClient) loadSecrets() (map[string]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stat, err := os.Stat(c.config.SecretsFile)
+	if err != nil {
+		c.secrets = nil
+		return nil, err
+	}
+	if c.secrets != nil && c.secrets.modTime.Equal(stat.ModTime()) && c.secrets.size == stat.Size() {
+		return c.secrets.secrets, nil
+	}
+	data, err := os.ReadFile(c.config.SecretsFile)
+	if err != nil {
+		c.secrets = nil
+		return nil, err
+	}
+	secrets, err := parseTOTPSecrets(data)
+	if err != nil {
+		c.secrets = nil
+		return nil, err
+	}
+	c.secrets = &totpSecretsFile{
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+		secrets: secrets,
+	}
+	return secrets, nil
+}
+
+// verify checks the code against the time steps within the configured drift and returns the matching time step.
+func (c *totpClient) verify(key []byte, code string) (uint64, bool) {
+	if len(code) != c.config.Digits {
+		return 0, false
+	}
+	current := uint64(c.now().UnixNano() / c.config.Period.Nanoseconds())
+	drift := uint64(c.config.Drift)
+	var matched uint64
+	found := false
+	for step := current - min(drift, current); step <= current+drift; step++ {
+		expected := generateTOTP(c.hash, key, step, c.config.Digits)
+		// All steps are checked so the time taken does not depend on which step matched.
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 && !found {
+			matched = step
+			found = true
+		}
+	}
+	return matched, found
+}
+
+// use records the time step as used for the user. It returns false if the same or a later time step has already been
+// used.
+func (c *totpClient) use(username string, step uint64) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if last, ok := c.lastUsed[username]; ok && step <= last {
+		return false
+	}
+	c.lastUsed[username] = step
+
+	// Time steps before the drift window can no longer be replayed, so they do not need to be remembered.
+	current := uint64(c.now().UnixNano() / c.config.Period.Nanoseconds())
+	drift := uint64(c.config.Drift)
+	for user, last := range c.lastUsed {
+		if last+drift < current {
+			delete(c.lastUsed, user)
+		}
+	}
+	return true
+}
+
+// generateTOTP generates the one-time password for the time step as described in RFC 4226 and RFC 6238.
+func generateTOTP(hashFunc func() hash.Hash, key []byte, step uint64, digits int) string {
+	mac := hmac.New(hashFunc, key)
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, step)
+	_, _ = mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	modulus := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulus)
+}
+
+// decodeTOTPSecret decodes a base32-encoded secret. Spaces, dashes and padding are ignored and lowercase letters are
+// accepted, as authenticator apps display secrets in several formats.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(
+		strings.TrimRight(
+			strings.NewReplacer(" ", "", "-", "").Replace(secret),
+			"=",
+		),
+	)
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, errors.New("empty secret")
+	}
+	return key, nil
+}
+
+// parseTOTPSecrets parses the username:secret lines of the secrets file.
+func parseTOTPSecrets(data []byte) (map[string]string, error) {
+	secrets := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, secret, ok := strings.Cut(line, ":")
+		username = strings.TrimSpace(username)
+		secret = strings.TrimSpace(secret)
+		if !ok || username == "" || secret == "" {
+			return nil, fmt.Errorf("invalid line %d, expected username:secret", lineNumber)
+		}
+		secrets[username] = secret
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
@@ -0,0 +1,148 @@
+package auth //nolint:testpackage
+
+import (
+	"crypto/sha1" //nolint:gosec // RFC 6238 test vectors
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestTOTPRFC6238(t *testing.T) {
+	keys := map[string][]byte{
+		"sha1":   []byte("12345678901234567890"),
+		"sha256": []byte("12345678901234567890123456789012"),
+		"sha512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	hashes := map[string]func() hash.Hash{
+		"sha1":   sha1.New,
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+	}
+	vectors := []struct {
+		time     int64
+		expected map[string]string
+	}{
+		{59, map[string]string{"sha1": "94287082", "sha256": "46119246", "sha512": "90693936"}},
+		{1111111109, map[string]string{"sha1": "07081804", "sha256": "68084774", "sha512": "25091201"}},
+		{1111111111, map[string]string{"sha1": "14050471", "sha256": "67062674", "sha512": "99943326"}},
+		{1234567890, map[string]string{"sha1": "89005924", "sha256": "91819424", "sha512": "93441116"}},
+		{2000000000, map[string]string{"sha1": "69279037", "sha256": "90698825", "sha512": "38618901"}},
+		{20000000000, map[string]string{"sha1": "65353130", "sha256": "77737706", "sha512": "47863826"}},
+	}
+	for _, vector := range vectors {
+		for algorithm, expected := range vector.expected {
+			assert.Equal(
+				t,
+				expected,
+				generateTOTP(hashes[algorithm], keys[algorithm], uint64(vector.time/30), 8),
+				"%s at %d",
+				algorithm,
+				vector.time,
+			)
+		}
+	}
+}
+
+func TestTOTPAuthentication(t *testing.T) {
+	key := []byte("12345678901234567890")
+	secret := base32.StdEncoding.EncodeToString(key)
+	secretsFile := filepath.Join(t.TempDir(), "secrets")
+	assert.NoError(t, os.WriteFile(secretsFile, []byte("# comment\n\nfoo: "+secret+"\n"), 0600))
+
+	now := time.Unix(1111111109, 0)
+	client := newTOTPTestClient(t, secretsFile, &now)
+	step := uint64(now.Unix() / 30)
+
+	ctx := client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP("000000"))
+	assert.False(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+
+	code := generateTOTP(sha1.New, key, step, 6)
+	ctx = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(code))
+	assert.True(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+
+	ctx = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(code))
+	assert.False(t, ctx.Success(), "a one-time password must not be accepted twice")
+
+	previous := generateTOTP(sha1.New, key, step-1, 6)
+	ctx = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(previous))
+	assert.False(t, ctx.Success(), "a one-time password older than the last used one must not be accepted")
+
+	next := generateTOTP(sha1.New, key, step+1, 6)
+	ctx = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(next))
+	assert.True(t, ctx.Success(), "the one-time password of the next time step should be accepted within the drift")
+
+	tooLate := generateTOTP(sha1.New, key, step+2, 6)
+	ctx = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(tooLate))
+	assert.False(t, ctx.Success(), "a one-time password outside the drift must not be accepted")
+
+	asked := false
+	ctx = client.KeyboardInteractive(
+		metadata.NewTestAuthenticatingMetadata("bar"),
+		func(_ string, questions KeyboardInteractiveQuestions) (KeyboardInteractiveAnswers, error) {
+			asked = true
+			return answerTOTP(code)("", questions)
+		},
+	)
+	assert.False(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+	assert.True(t, asked, "users without a secret should be asked for a one-time password too")
+}
+
+func TestTOTPMetadataSecret(t *testing.T) {
+	key := []byte("secret from the webhook")
+	now := time.Unix(2000000000, 0)
+	client := newTOTPTestClient(t, "", &now)
+	code := generateTOTP(sha1.New, key, uint64(now.Unix()/30), 6)
+
+	ctx := client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), answerTOTP(code))
+	assert.False(t, ctx.Success())
+
+	meta := metadata.NewTestAuthenticatingMetadata("foo")
+	meta.Metadata["TOTP_SECRET"] = metadata.Value{
+		Value:     base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key),
+		Sensitive: true,
+	}
+	ctx = client.KeyboardInteractive(meta, answerTOTP(code))
+	assert.True(t, ctx.Success())
+	assert.NoError(t, ctx.Error())
+}
+
+func newTOTPTestClient(t *testing.T, secretsFile string, now *time.Time) KeyboardInteractiveAuthenticator {
+	cfg := config.AuthTOTPConfig{}
+	structutils.Defaults(&cfg)
+	cfg.SecretsFile = secretsFile
+	client, err := NewTOTPClient(cfg, log.NewTestLogger(t), metrics.New(dummy.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.(*totpClient).now = func() time.Time {
+		return *now
+	}
+	return client
+}
+
+func answerTOTP(code string) func(string, KeyboardInteractiveQuestions) (KeyboardInteractiveAnswers, error) {
+	return func(_ string, questions KeyboardInteractiveQuestions) (KeyboardInteractiveAnswers, error) {
+		answers := KeyboardInteractiveAnswers{Answers: map[string]string{}}
+		for _, question := range questions {
+			answers.Answers[question.ID] = code
+		}
+		return answers, nil
+	}
+}
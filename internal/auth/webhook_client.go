@@ -2,10 +2,11 @@
 
 package auth
 
-// WebhookClient is a urlEncodedClient that authenticates using HTTP webhooks. It only supports password and public key
-// authentication.
+// WebhookClient is a urlEncodedClient that authenticates using HTTP webhooks. It supports password, public key and
+// keyboard-interactive authentication.
 type WebhookClient interface {
 	PasswordAuthenticator
 	PublicKeyAuthenticator
+	KeyboardInteractiveAuthenticator
 	AuthzProvider
 }
@@ -1,12 +1,20 @@
 package auth
 
 import (
+	"go.containerssh.io/libcontainerssh/auth"
 	"go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/http"
+	"go.containerssh.io/libcontainerssh/internal/cache"
 	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/retry"
 	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
 )
 
+// defaultKeyboardInteractiveMaxRounds is the maximum number of keyboard-interactive rounds if the client is not created
+// from a keyboard-interactive configuration.
+const defaultKeyboardInteractiveMaxRounds = 10
+
 // NewWebhookClient creates a new HTTP authentication urlEncodedClient.
 //
 //goland:noinspection GoUnusedExportedFunction
@@ -41,9 +49,46 @@ func NewWebhookClient(
 		enablePassword:        authType == AuthenticationTypePassword || authType == AuthenticationTypeAll,
 		enablePubKey:          authType == AuthenticationTypePublicKey || authType == AuthenticationTypeAll,
 		enableAuthz:           authType == AuthenticationTypeAuthz || authType == AuthenticationTypeAll,
+		enableKeyboardInteractive: authType == AuthenticationTypeKeyboardInteractive ||
+			authType == AuthenticationTypeAll,
+		keyboardInteractiveMaxRounds: defaultKeyboardInteractiveMaxRounds,
+		backoff:                      retry.NewBackoff(cfg.Retry),
+		circuitBreaker:               retry.NewCircuitBreaker(backendName(authType), cfg.CircuitBreaker, logger, metrics),
+		cache:                        cache.New[auth.ResponseBody](backendName(authType), cfg.Cache, metrics),
 	}, nil
 }
 
+// backendName returns the name of the client as the backend in the logs and metrics of the circuit breaker and the
+// cache.
+func backendName(authType AuthenticationType) string {
+	if authType == AuthenticationTypeAll {
+		return "auth"
+	}
+	return "auth_" + string(authType)
+}
+
+// NewKeyboardInteractiveWebhookClient creates a webhook client for keyboard-interactive authentication that relays the
+// questions of the auth server to the user.
+func NewKeyboardInteractiveWebhookClient(
+	cfg config.AuthKeyboardInteractiveWebhookConfig,
+	logger log.Logger,
+	metrics metrics.Collector,
+) (WebhookClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, message.Wrap(
+			err,
+			message.EAuthConfigError,
+			"Keyboard-interactive webhook configuration failed to validate",
+		)
+	}
+	client, err := NewWebhookClient(AuthenticationTypeKeyboardInteractive, cfg.AuthWebhookClientConfig, logger, metrics)
+	if err != nil {
+		return nil, err
+	}
+	client.(*webhookClient).keyboardInteractiveMaxRounds = cfg.MaxRounds
+	return client, nil
+}
+
 func createMetrics(metrics metrics.Collector) (
 	metrics.Counter,
 	metrics.Counter,
@@ -60,6 +105,15 @@ func createMetrics(metrics metrics.Collector) (
 		"failures_total",
 		"The number of request failures to the configuration server.",
 	)
+	authSuccessMetric, authFailureMetric := createAuthResultMetrics(metrics)
+	return backendRequestsMetric, backendFailureMetric, authSuccessMetric, authFailureMetric
+}
+
+// createAuthResultMetrics creates the success and failure metrics shared by all authenticators.
+func createAuthResultMetrics(metrics metrics.Collector) (
+	metrics.GeoCounter,
+	metrics.GeoCounter,
+) {
 	authSuccessMetric := metrics.MustCreateCounterGeo(
 		MetricNameAuthSuccess,
 		"success_total",
@@ -70,5 +124,5 @@ func createMetrics(metrics metrics.Collector) (
 		"failures_total",
 		"The number of failed authentications.",
 	)
-	return backendRequestsMetric, backendFailureMetric, authSuccessMetric, authFailureMetric
+	return authSuccessMetric, authFailureMetric
 }
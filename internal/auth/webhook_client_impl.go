@@ -5,30 +5,38 @@ import (
 	"encoding/base64"
 	"errors"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.containerssh.io/libcontainerssh/auth"
 	"go.containerssh.io/libcontainerssh/http"
+	"go.containerssh.io/libcontainerssh/internal/cache"
 	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/retry"
 	"go.containerssh.io/libcontainerssh/log"
 	"go.containerssh.io/libcontainerssh/message"
 	"go.containerssh.io/libcontainerssh/metadata"
 )
 
 type webhookClient struct {
-	timeout               time.Duration
-	httpClient            http.Client
-	endpoint              string
-	logger                log.Logger
-	metrics               metrics.Collector
-	backendRequestsMetric metrics.SimpleCounter
-	backendFailureMetric  metrics.SimpleCounter
-	authSuccessMetric     metrics.GeoCounter
-	authFailureMetric     metrics.GeoCounter
-	enablePassword        bool
-	enablePubKey          bool
-	enableAuthz           bool
+	timeout                      time.Duration
+	httpClient                   http.Client
+	endpoint                     string
+	logger                       log.Logger
+	metrics                      metrics.Collector
+	backendRequestsMetric        metrics.SimpleCounter
+	backendFailureMetric         metrics.SimpleCounter
+	authSuccessMetric            metrics.GeoCounter
+	authFailureMetric            metrics.GeoCounter
+	enablePassword               bool
+	enablePubKey                 bool
+	enableAuthz                  bool
+	enableKeyboardInteractive    bool
+	keyboardInteractiveMaxRounds int
+	backoff                      retry.Backoff
+	circuitBreaker               retry.CircuitBreaker
+	cache                        cache.Cache[auth.ResponseBody]
 }
 
 func (client *webhookClient) Authorize(
@@ -73,7 +81,7 @@ func (client *webhookClient) Password(
 		Password:                      base64.StdEncoding.EncodeToString(password),
 	}
 
-	return client.processAuthWithRetry(meta, method, authType, url, authRequest)
+	return client.processAuthWithRetry(meta, method, authType, url, authRequest, "")
 }
 
 func (client *webhookClient) PubKey(
@@ -96,8 +104,99 @@ func (client *webhookClient) PubKey(
 	}
 	method := "Public key"
 	authType := "pubkey"
+	cacheKey := cache.Key(meta.Username, pubKey.Fingerprint(), meta.RemoteAddress.IP.String())
 
-	return client.processAuthWithRetry(meta, method, authType, url, authRequest)
+	return client.processAuthWithRetry(meta, method, authType, url, authRequest, cacheKey)
+}
+
+func (client *webhookClient) KeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions KeyboardInteractiveQuestions,
+	) (answers KeyboardInteractiveAnswers, err error),
+) AuthenticationContext {
+	if !client.enableKeyboardInteractive {
+		err := message.UserMessage(
+			message.EAuthDisabled,
+			"Keyboard-interactive authentication failed.",
+			"Keyboard-interactive authentication is disabled.",
+		)
+		client.logger.Debug(err)
+		return &webhookClientContext{meta.AuthFailed(), false, err}
+	}
+	url := client.endpoint + "/keyboard-interactive"
+	method := "Keyboard-interactive"
+	authType := string(AuthenticationTypeKeyboardInteractive)
+	logger := client.logger.
+		WithLabel("connectionId", meta.ConnectionID).
+		WithLabel("username", meta.Username).
+		WithLabel("url", url).
+		WithLabel("authtype", authType)
+
+	authRequest := auth.KeyboardInteractiveAuthRequest{
+		ConnectionAuthPendingMetadata: meta,
+	}
+	for round := 1; ; round++ {
+		authRequest.Round = round
+		authResponse, lastLabels, lastError := authRequestWithRetry[auth.KeyboardInteractiveResponseBody](
+			client,
+			logger,
+			method,
+			authType,
+			url,
+			authRequest,
+		)
+		if lastError != nil {
+			return client.logAndReturnPermanentFailure(meta, lastError, method, lastLabels, logger)
+		}
+		if authResponse.Success || authResponse.Challenge == nil {
+			authenticatedMeta := meta.Authenticated("")
+			authenticatedMeta.Merge(authResponse.ConnectionAuthenticatedMetadata)
+			client.logAuthResponse(
+				logger,
+				method,
+				&authResponse.ResponseBody,
+				lastLabels,
+				authenticatedMeta.RemoteAddress.IP,
+			)
+			return &webhookClientContext{
+				authenticatedMeta,
+				authResponse.Success,
+				nil,
+			}
+		}
+		if round >= client.keyboardInteractiveMaxRounds {
+			logger.Warning(
+				message.NewMessage(
+					message.EAuthKeyboardInteractiveTooManyRounds,
+					"The auth server sent more than %d rounds of keyboard-interactive questions",
+					client.keyboardInteractiveMaxRounds,
+				),
+			)
+			client.authFailureMetric.Increment(meta.RemoteAddress.IP, lastLabels...)
+			return &webhookClientContext{meta.AuthFailed(), false, nil}
+		}
+
+		questions := make(KeyboardInteractiveQuestions, len(authResponse.Challenge.Questions))
+		for i, question := range authResponse.Challenge.Questions {
+			id := question.ID
+			if id == "" {
+				id = strconv.Itoa(i)
+			}
+			questions[i] = KeyboardInteractiveQuestion{
+				ID:           id,
+				Question:     question.Question,
+				EchoResponse: question.Echo,
+			}
+		}
+		answers, err := challenge(authResponse.Challenge.Instruction, questions)
+		if err != nil {
+			return &webhookClientContext{meta.AuthFailed(), false, err}
+		}
+		authRequest.State = authResponse.Challenge.State
+		authRequest.Answers = answers.Answers
+	}
 }
 
 func (client *webhookClient) processAuthWithRetry(
@@ -106,18 +205,70 @@ func (client *webhookClient) processAuthWithRetry(
 	authType string,
 	url string,
 	authRequest interface{},
+	cacheKey string,
 ) AuthenticationContext {
-	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
-	defer cancel()
-	var lastError error
-	var lastLabels []metrics.MetricLabel
 	logger := client.logger.
 		WithLabel("connectionId", meta.ConnectionID).
 		WithLabel("username", meta.Username).
 		WithLabel("url", url).
 		WithLabel("authtype", authType)
+	if cacheKey != "" {
+		if cached, ok := client.cache.Get(cacheKey); ok {
+			logger.Debug(message.NewMessage(message.MAuthCached, "%s authentication response served from cache", method))
+			authenticatedMeta := meta.Authenticated("")
+			authenticatedMeta.Merge(cached.ConnectionAuthenticatedMetadata)
+			client.logAuthResponse(
+				logger,
+				method,
+				&cached,
+				[]metrics.MetricLabel{metrics.Label("authtype", authType)},
+				authenticatedMeta.RemoteAddress.IP,
+			)
+			return &webhookClientContext{authenticatedMeta, cached.Success, nil}
+		}
+	}
+	authResponse, lastLabels, lastError := authRequestWithRetry[auth.ResponseBody](
+		client,
+		logger,
+		method,
+		authType,
+		url,
+		authRequest,
+	)
+	if lastError != nil {
+		return client.logAndReturnPermanentFailure(meta, lastError, method, lastLabels, logger)
+	}
+	if cacheKey != "" {
+		client.cache.Set(cacheKey, *authResponse, authResponse.Success, authResponse.Cache)
+	}
+	authenticatedMeta := meta.Authenticated("")
+	authenticatedMeta.Merge(authResponse.ConnectionAuthenticatedMetadata)
+	client.logAuthResponse(logger, method, authResponse, lastLabels, authenticatedMeta.RemoteAddress.IP)
+
+	return &webhookClientContext{
+		authenticatedMeta,
+		authResponse.Success,
+		nil,
+	}
+}
+
+// authRequestWithRetry sends an authentication request to the auth server and decodes the response into a new T. If
+// the request fails, it is retried with backoff until the attempts are exhausted, the authentication timeout is
+// reached or the circuit breaker opens. The metric labels of the last attempt are returned for recording the result.
+func authRequestWithRetry[T any](
+	client *webhookClient,
+	logger log.Logger,
+	method string,
+	authType string,
+	url string,
+	authRequest interface{},
+) (*T, []metrics.MetricLabel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	defer cancel()
+	var lastError error
+	var lastLabels []metrics.MetricLabel
 loop:
-	for {
+	for attempts := 1; ; attempts++ {
 		lastLabels = []metrics.MetricLabel{
 			metrics.Label("authtype", authType),
 		}
@@ -132,31 +283,33 @@ loop:
 				metrics.Label("retry", "0"),
 			)
 		}
+		if lastError = client.circuitBreaker.Allow(); lastError != nil {
+			lastLabels = append(lastLabels, metrics.Label("reason", message.ECircuitBreakerOpen))
+			break
+		}
 		client.logAttempt(logger, method, lastLabels)
 
-		authResponse := &auth.ResponseBody{}
+		authResponse := new(T)
 		lastError = client.authServerRequest(url, authRequest, authResponse)
 		if lastError == nil {
-			authenticatedMeta := meta.Authenticated("")
-			authenticatedMeta.Merge(authResponse.ConnectionAuthenticatedMetadata)
-			client.logAuthResponse(logger, method, authResponse, lastLabels, authenticatedMeta.RemoteAddress.IP)
-
-			return &webhookClientContext{
-				authenticatedMeta,
-				authResponse.Success,
-				nil,
-			}
+			client.circuitBreaker.Success()
+			return authResponse, lastLabels, nil
 		}
+		client.circuitBreaker.Failure()
 		reason := client.getReason(lastError)
 		lastLabels = append(lastLabels, metrics.Label("reason", reason))
-		client.logTemporaryFailure(logger, lastError, method, reason, lastLabels)
+		if !client.backoff.ShouldRetry(attempts) {
+			break
+		}
+		delay := client.backoff.Delay(attempts)
+		client.logTemporaryFailure(logger, lastError, method, reason, delay, lastLabels)
 		select {
 		case <-ctx.Done():
 			break loop
-		case <-time.After(10 * time.Second):
+		case <-time.After(delay):
 		}
 	}
-	return client.logAndReturnPermanentFailure(meta, lastError, method, lastLabels, logger)
+	return nil, lastLabels, lastError
 }
 
 func (client *webhookClient) logAttempt(logger log.Logger, method string, lastLabels []metrics.MetricLabel) {
@@ -199,14 +352,16 @@ func (client *webhookClient) logTemporaryFailure(
 	lastError error,
 	method string,
 	reason string,
+	delay time.Duration,
 	lastLabels []metrics.MetricLabel,
 ) {
 	logger.Debug(
 		message.Wrap(
 			lastError,
 			message.EAuthBackendError,
-			"%s authentication request to backend failed, retrying in 10 seconds",
+			"%s authentication request to backend failed, retrying in %s",
 			method,
+			delay.Round(time.Millisecond),
 		).
 			Label("reason", reason),
 	)
@@ -287,7 +442,7 @@ func (client *webhookClient) processAuthzWithRetry(
 		WithLabel("providedUsername", meta.Username).
 		WithLabel("url", client.endpoint)
 loop:
-	for {
+	for attempts := 1; ; attempts++ {
 		lastLabels = []metrics.MetricLabel{
 			metrics.Label("authtype", "authorization"),
 		}
@@ -302,11 +457,16 @@ loop:
 				metrics.Label("retry", "0"),
 			)
 		}
+		if lastError = client.circuitBreaker.Allow(); lastError != nil {
+			lastLabels = append(lastLabels, metrics.Label("reason", message.ECircuitBreakerOpen))
+			break
+		}
 		client.logAuthzAttempt(logger, lastLabels)
 
 		authResponse := &auth.ResponseBody{}
 		lastError = client.authServerRequest(url, authzRequest, authResponse)
 		if lastError == nil {
+			client.circuitBreaker.Success()
 			authenticatedMeta := meta.Authenticated("")
 			authenticatedMeta.Merge(authResponse.ConnectionAuthenticatedMetadata)
 			client.logAuthzResponse(authenticatedMeta, logger, authResponse, lastLabels)
@@ -316,13 +476,18 @@ loop:
 				nil,
 			}
 		}
+		client.circuitBreaker.Failure()
 		reason := client.getReason(lastError)
 		lastLabels = append(lastLabels, metrics.Label("reason", reason))
-		client.logTemporaryAuthzFailure(logger, lastError, reason, lastLabels)
+		if !client.backoff.ShouldRetry(attempts) {
+			break
+		}
+		delay := client.backoff.Delay(attempts)
+		client.logTemporaryAuthzFailure(logger, lastError, reason, delay, lastLabels)
 		select {
 		case <-ctx.Done():
 			break loop
-		case <-time.After(10 * time.Second):
+		case <-time.After(delay):
 		}
 	}
 	return client.logAndReturnPermanentAuthzFailure(meta, lastError, lastLabels, logger)
@@ -389,13 +554,15 @@ func (client *webhookClient) logTemporaryAuthzFailure(
 	logger log.Logger,
 	lastError error,
 	reason string,
+	delay time.Duration,
 	lastLabels []metrics.MetricLabel,
 ) {
 	logger.Debug(
 		message.Wrap(
 			lastError,
 			message.EAuthBackendError,
-			"authorization request to backend failed, retrying in 10 seconds",
+			"authorization request to backend failed, retrying in %s",
+			delay.Round(time.Millisecond),
 		).
 			Label("reason", reason),
 	)
@@ -13,6 +13,7 @@ import (
 	"go.containerssh.io/libcontainerssh/internal/auth"
 	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
 	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/retry"
 	"go.containerssh.io/libcontainerssh/internal/test"
 	"go.containerssh.io/libcontainerssh/log"
 	"go.containerssh.io/libcontainerssh/message"
@@ -86,6 +87,86 @@ func (h *handler) OnAuthorization(meta metadata.ConnectionAuthenticatedMetadata)
 	return false, meta.AuthFailed(), nil
 }
 
+func (h *handler) OnKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	round int,
+	state string,
+	answers map[string]string,
+) (bool, *auth3.KeyboardInteractiveChallenge, metadata.ConnectionAuthenticatedMetadata, error) {
+	if meta.Username == "loop" {
+		return false, &auth3.KeyboardInteractiveChallenge{Instruction: "Again"}, meta.AuthFailed(), nil
+	}
+	switch {
+	case round == 1:
+		return false, &auth3.KeyboardInteractiveChallenge{
+			Instruction: "Enter the code sent to your phone",
+			Questions:   []auth3.KeyboardInteractiveQuestion{{ID: "code", Question: "Code: "}},
+			State:       "code",
+		}, meta.AuthFailed(), nil
+	case round == 2 && state == "code" && answers["code"] == "123456":
+		return false, &auth3.KeyboardInteractiveChallenge{
+			Questions: []auth3.KeyboardInteractiveQuestion{{Question: "Favorite color: ", Echo: true}},
+			State:     "color",
+		}, meta.AuthFailed(), nil
+	case round == 3 && state == "color" && answers["0"] == "blue":
+		authenticated := meta.Authenticated(meta.Username)
+		authenticated.GetMetadata()["color"] = metadata.Value{Value: answers["0"]}
+		return true, nil, authenticated, nil
+	}
+	return false, nil, meta.AuthFailed(), nil
+}
+
+func TestKeyboardInteractiveWebhook(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	client, lifecycle, _, err := initializeAuth(t, logger, "")
+	if err != nil {
+		assert.Fail(t, "failed to initialize auth", err)
+		return
+	}
+	defer lifecycle.Stop(context.Background())
+
+	answerer := func(answers ...string) (
+		*[]auth.KeyboardInteractiveQuestions,
+		func(string, auth.KeyboardInteractiveQuestions) (auth.KeyboardInteractiveAnswers, error),
+	) {
+		var asked []auth.KeyboardInteractiveQuestions
+		return &asked, func(_ string, questions auth.KeyboardInteractiveQuestions) (
+			auth.KeyboardInteractiveAnswers,
+			error,
+		) {
+			asked = append(asked, questions)
+			result := auth.KeyboardInteractiveAnswers{Answers: map[string]string{}}
+			for _, question := range questions {
+				result.Answers[question.ID] = answers[0]
+				answers = answers[1:]
+			}
+			return result, nil
+		}
+	}
+
+	asked, challenge := answerer("123456", "blue")
+	authenticationContext := client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), challenge)
+	assert.NoError(t, authenticationContext.Error())
+	assert.True(t, authenticationContext.Success())
+	assert.Equal(t, "blue", authenticationContext.Metadata().Metadata["color"].Value)
+	if assert.Len(t, *asked, 2) {
+		assert.Equal(t, "code", (*asked)[0][0].ID)
+		assert.Equal(t, "0", (*asked)[1][0].ID)
+		assert.True(t, (*asked)[1][0].EchoResponse)
+	}
+
+	_, challenge = answerer("654321")
+	authenticationContext = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("foo"), challenge)
+	assert.NoError(t, authenticationContext.Error())
+	assert.False(t, authenticationContext.Success())
+
+	asked, challenge = answerer()
+	authenticationContext = client.KeyboardInteractive(metadata.NewTestAuthenticatingMetadata("loop"), challenge)
+	assert.NoError(t, authenticationContext.Error())
+	assert.False(t, authenticationContext.Success(), "the number of rounds should be limited")
+	assert.Len(t, *asked, 9)
+}
+
 func TestAuth(t *testing.T) {
 	logger := log.NewTestLogger(t)
 	logger.Info(
@@ -179,11 +260,70 @@ func TestAuth(t *testing.T) {
 	}
 }
 
+func TestWebhookCircuitBreaker(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	client, lifecycle, metricsCollector, err := initializeAuthWithConfig(
+		t,
+		logger,
+		"",
+		func(cfg *config.AuthWebhookClientConfig) {
+			cfg.Retry = config.WebhookRetryConfig{
+				MaxAttempts:    2,
+				InitialBackoff: 10 * time.Millisecond,
+				MaxBackoff:     10 * time.Millisecond,
+				Multiplier:     1,
+			}
+			cfg.CircuitBreaker = config.CircuitBreakerConfig{
+				Enable:           true,
+				FailureThreshold: 3,
+				OpenDuration:     time.Minute,
+			}
+		},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer lifecycle.Stop(context.Background())
+
+	// The first request is retried once. The second request opens the circuit breaker, so its retry is rejected.
+	for i := 0; i < 2; i++ {
+		authenticationContext := client.Password(metadata.NewTestAuthenticatingMetadata("crash"), []byte("bar"))
+		assert.Error(t, authenticationContext.Error())
+	}
+	assert.Equal(t, []string{"auth"}, retry.OpenCircuitBreakers(metricsCollector))
+
+	start := time.Now()
+	authenticationContext := client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, authenticationContext.Success())
+	var wrappedErr message.WrappingMessage
+	if assert.ErrorAs(t, authenticationContext.Error(), &wrappedErr) {
+		var typedErr message.Message
+		assert.ErrorAs(t, wrappedErr.Unwrap(), &typedErr)
+		assert.Equal(t, message.ECircuitBreakerOpen, typedErr.Code())
+	}
+	assert.Equal(t, float64(2), metricsCollector.GetMetric(retry.MetricNameCircuitBreakerRejected)[0].Value)
+}
+
 func initializeAuth(t *testing.T, logger log.Logger, subpath string) (
 	auth.WebhookClient,
 	service.Lifecycle,
 	metrics.Collector,
 	error,
+) {
+	return initializeAuthWithConfig(t, logger, subpath, func(_ *config.AuthWebhookClientConfig) {})
+}
+
+func initializeAuthWithConfig(
+	t *testing.T,
+	logger log.Logger,
+	subpath string,
+	configure func(cfg *config.AuthWebhookClientConfig),
+) (
+	auth.WebhookClient,
+	service.Lifecycle,
+	metrics.Collector,
+	error,
 ) {
 	ready := make(chan bool, 1)
 	errors := make(chan error)
@@ -202,15 +342,17 @@ func initializeAuth(t *testing.T, logger log.Logger, subpath string) (
 
 	metricsCollector := metrics.New(dummy.New())
 
+	clientConfig := config.AuthWebhookClientConfig{
+		HTTPClientConfiguration: config.HTTPClientConfiguration{
+			URL:     fmt.Sprintf("http://127.0.0.1:%d%s", port, subpath),
+			Timeout: 2 * time.Second,
+		},
+		AuthTimeout: 2 * time.Second,
+	}
+	configure(&clientConfig)
 	client, err := auth.NewWebhookClient(
 		auth.AuthenticationTypeAll,
-		config.AuthWebhookClientConfig{
-			HTTPClientConfiguration: config.HTTPClientConfiguration{
-				URL:     fmt.Sprintf("http://127.0.0.1:%d%s", port, subpath),
-				Timeout: 2 * time.Second,
-			},
-			AuthTimeout: 2 * time.Second,
-		},
+		clientConfig,
 		logger,
 		metricsCollector,
 	)
@@ -234,3 +376,59 @@ func initializeAuth(t *testing.T, logger log.Logger, subpath string) (
 	<-ready
 	return client, lifecycle, metricsCollector, nil
 }
+
+func TestWebhookCache(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	client, lifecycle, metricsCollector, err := initializeAuthWithConfig(
+		t,
+		logger,
+		"",
+		func(cfg *config.AuthWebhookClientConfig) {
+			cfg.Cache = config.WebhookCacheConfig{
+				Enable:      true,
+				PositiveTTL: time.Minute,
+				NegativeTTL: time.Minute,
+				MaxEntries:  10,
+			}
+		},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer lifecycle.Stop(context.Background())
+
+	backendRequests := func() float64 {
+		result := float64(0)
+		for _, value := range metricsCollector.GetMetric(auth.MetricNameAuthBackendRequests) {
+			result += value.Value
+		}
+		return result
+	}
+
+	for i := 0; i < 2; i++ {
+		authenticationContext := client.PubKey(
+			metadata.NewTestAuthenticatingMetadata("foo"),
+			auth3.PublicKey{PublicKey: "ssh-rsa asdf"},
+		)
+		assert.NoError(t, authenticationContext.Error())
+		assert.True(t, authenticationContext.Success())
+	}
+	assert.Equal(t, float64(1), backendRequests(), "the second authentication was not served from the cache")
+
+	for i := 0; i < 2; i++ {
+		authenticationContext := client.PubKey(
+			metadata.NewTestAuthenticatingMetadata("foo"),
+			auth3.PublicKey{PublicKey: "ssh-rsa other"},
+		)
+		assert.NoError(t, authenticationContext.Error())
+		assert.False(t, authenticationContext.Success())
+	}
+	assert.Equal(t, float64(2), backendRequests(), "a different key was served from the cache or the failure was not cached")
+
+	// Password authentications are never cached.
+	for i := 0; i < 2; i++ {
+		authenticationContext := client.Password(metadata.NewTestAuthenticatingMetadata("foo"), []byte("bar"))
+		assert.True(t, authenticationContext.Success())
+	}
+	assert.Equal(t, float64(4), backendRequests())
+}
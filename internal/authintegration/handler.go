@@ -5,6 +5,7 @@ import (
 	"net"
 
 	auth2 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/internal/auth"
 	"go.containerssh.io/libcontainerssh/internal/sshserver"
 	"go.containerssh.io/libcontainerssh/message"
@@ -48,6 +49,7 @@ type handler struct {
 	keyboardInteractiveAuthenticator auth.KeyboardInteractiveAuthenticator
 	authorizationProvider            auth.AuthzProvider
 	behavior                         Behavior
+	authMethods                      authMethodPolicy
 }
 
 func (h *handler) OnReady() error {
@@ -87,6 +89,7 @@ func (h *handler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
 		gssapiAuthenticator:              h.gssapiAuthenticator,
 		keyboardInteractiveAuthenticator: h.keyboardInteractiveAuthenticator,
 		authorizationProvider:            h.authorizationProvider,
+		authMethods:                      h.authMethods,
 	}
 
 	if h.authorizationProvider != nil {
@@ -114,6 +117,10 @@ type networkConnectionHandler struct {
 	gssapiAuthenticator              auth.GSSAPIAuthenticator
 	keyboardInteractiveAuthenticator auth.KeyboardInteractiveAuthenticator
 	authorizationProvider            auth.AuthzProvider
+	authMethods                      authMethodPolicy
+	// partialAuthContexts are the authentication contexts of the methods that ended with a partial success. They are
+	// kept until the connection is closed.
+	partialAuthContexts []auth.AuthenticationContext
 }
 
 func (h *networkConnectionHandler) OnShutdown(shutdownContext context.Context) {
@@ -123,6 +130,17 @@ func (h *networkConnectionHandler) OnShutdown(shutdownContext context.Context) {
 func (h *networkConnectionHandler) OnAuthPassword(
 	meta metadata.ConnectionAuthPendingMetadata,
 	password []byte,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if err := h.checkAuthMethod(meta, config.SSHAuthMethodPassword); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMeta, err := h.authPassword(meta, password)
+	return h.requireFurtherMethods(meta, config.SSHAuthMethodPassword, response, authenticatedMeta, err)
+}
+
+func (h *networkConnectionHandler) authPassword(
+	meta metadata.ConnectionAuthPendingMetadata,
+	password []byte,
 ) (response sshserver.AuthResponse, returnMeta metadata.ConnectionAuthenticatedMetadata, reason error) {
 	if h.authContext != nil {
 		h.authContext.OnDisconnect()
@@ -158,6 +176,20 @@ func (h *networkConnectionHandler) OnAuthPassword(
 func (h *networkConnectionHandler) OnAuthPubKey(
 	meta metadata.ConnectionAuthPendingMetadata,
 	pubKey auth2.PublicKey,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if err := h.checkAuthMethod(meta, config.SSHAuthMethodPublicKey); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMeta, err := h.authPubKey(meta, pubKey)
+	if response == sshserver.AuthResponseSuccess {
+		authenticatedMeta.PublicKeyFingerprint = pubKey.Fingerprint()
+	}
+	return h.requireFurtherMethods(meta, config.SSHAuthMethodPublicKey, response, authenticatedMeta, err)
+}
+
+func (h *networkConnectionHandler) authPubKey(
+	meta metadata.ConnectionAuthPendingMetadata,
+	pubKey auth2.PublicKey,
 ) (response sshserver.AuthResponse, meta2 metadata.ConnectionAuthenticatedMetadata, reason error) {
 	if h.authContext != nil {
 		h.authContext.OnDisconnect()
@@ -195,6 +227,26 @@ func (h *networkConnectionHandler) OnAuthKeyboardInteractive(
 		instruction string,
 		questions sshserver.KeyboardInteractiveQuestions,
 	) (answers sshserver.KeyboardInteractiveAnswers, err error),
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if err := h.checkAuthMethod(meta, config.SSHAuthMethodKeyboardInteractive); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMeta, err := h.authKeyboardInteractive(meta, challenge)
+	return h.requireFurtherMethods(
+		meta,
+		config.SSHAuthMethodKeyboardInteractive,
+		response,
+		authenticatedMeta,
+		err,
+	)
+}
+
+func (h *networkConnectionHandler) authKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions sshserver.KeyboardInteractiveQuestions,
+	) (answers sshserver.KeyboardInteractiveAnswers, err error),
 ) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
 	if h.authContext != nil {
 		h.authContext.OnDisconnect()
@@ -252,8 +304,51 @@ func (h *networkConnectionHandler) OnAuthKeyboardInteractive(
 	return sshserver.AuthResponseSuccess, authContext.Metadata(), authContext.Error()
 }
 
+// checkAuthMethod returns an error if the authentication method sequences do not allow the method after the methods
+// the client has already completed.
+func (h *networkConnectionHandler) checkAuthMethod(
+	meta metadata.ConnectionAuthPendingMetadata,
+	method config.SSHAuthMethod,
+) error {
+	if allowed, _, _ := h.authMethods.evaluate(meta.AuthenticatedMethods, method); allowed {
+		return nil
+	}
+	return message.UserMessage(
+		message.EAuthMethodNotAllowed,
+		"This authentication method is not allowed at this point.",
+		"The %s authentication method is not allowed after the completed methods %v.",
+		method,
+		meta.AuthenticatedMethods,
+	)
+}
+
+// requireFurtherMethods turns a successful authentication into a partial success if the authentication method
+// sequences require further methods.
+func (h *networkConnectionHandler) requireFurtherMethods(
+	meta metadata.ConnectionAuthPendingMetadata,
+	method config.SSHAuthMethod,
+	response sshserver.AuthResponse,
+	authenticatedMeta metadata.ConnectionAuthenticatedMetadata,
+	err error,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if response != sshserver.AuthResponseSuccess {
+		return response, authenticatedMeta, err
+	}
+	_, complete, next := h.authMethods.evaluate(meta.AuthenticatedMethods, method)
+	if complete {
+		return response, authenticatedMeta, err
+	}
+	// The authentication context of the completed method must stay valid until the connection is closed.
+	if h.authContext != nil {
+		h.partialAuthContexts = append(h.partialAuthContexts, h.authContext)
+		h.authContext = nil
+	}
+	return sshserver.AuthResponsePartialSuccess, authenticatedMeta, &sshserver.PartialSuccess{Methods: next}
+}
+
 func (h *networkConnectionHandler) OnAuthGSSAPI(meta metadata.ConnectionMetadata) auth.GSSAPIServer {
-	if h.gssapiAuthenticator == nil {
+	// GSSAPI cannot be part of an authentication method sequence, see config.AuthConfig.AuthMethods.
+	if h.gssapiAuthenticator == nil || len(h.authMethods) > 0 {
 		return nil
 	}
 	return h.gssapiAuthenticator.GSSAPI(meta)
@@ -275,6 +370,9 @@ func (h *networkConnectionHandler) OnDisconnect() {
 	if h.authContext != nil {
 		h.authContext.OnDisconnect()
 	}
+	for _, authContext := range h.partialAuthContexts {
+		authContext.OnDisconnect()
+	}
 	h.backend.OnDisconnect()
 }
 
@@ -288,6 +386,7 @@ type authzNetworkConnectionHandler struct {
 // genericAuthorization is a helper function that takes the response of an authentication call (e.g. OnAuthPassword) and performs authorization.
 func (a *authzNetworkConnectionHandler) genericAuthorization(
 	meta metadata.ConnectionAuthPendingMetadata,
+	method config.SSHAuthMethod,
 	authResponse sshserver.AuthResponse,
 	authenticatedMeta metadata.ConnectionAuthenticatedMetadata,
 	err error,
@@ -296,18 +395,61 @@ func (a *authzNetworkConnectionHandler) genericAuthorization(
 		return authResponse, authenticatedMeta, err
 	}
 
-	authzResponse := a.authorizationProvider.Authorize(authenticatedMeta)
+	// The authorization provider sees the method of this attempt as completed so rules can match it.
+	authenticatedMeta.AuthenticatedMethods = append(
+		append([]string(nil), meta.AuthenticatedMethods...),
+		string(method),
+	)
+	authzResponse := authorize(a.authorizationProvider, authenticatedMeta)
 	if authzResponse.Success() {
-		return sshserver.AuthResponseSuccess, authzResponse.Metadata(), err
+		// The authorization provider cannot lift the restrictions placed on the connection by the authenticator.
+		authorizedMeta := authzResponse.Metadata()
+		authorizedMeta.Restrictions = authorizedMeta.Restrictions.Merge(authenticatedMeta.Restrictions)
+		return sshserver.AuthResponseSuccess, authorizedMeta, err
 	}
-	return sshserver.AuthResponseFailure, authzResponse.Metadata(), authzResponse.Error()
+	if authzResponse.Error() != nil {
+		// The authorization server could not be reached, for example because its circuit breaker is open.
+		return sshserver.AuthResponseUnavailable, authzResponse.Metadata(), authzResponse.Error()
+	}
+	return sshserver.AuthResponseFailure, authzResponse.Metadata(), nil
+}
+
+// authorize calls the authorization provider and records the decision in the metadata for the audit log if the
+// provider has not done so.
+func authorize(
+	authorizationProvider auth.AuthzProvider,
+	meta metadata.ConnectionAuthenticatedMetadata,
+) auth.AuthorizationResponse {
+	authzResponse := authorizationProvider.Authorize(meta)
+	if authzResponse.Metadata().Authorization != nil || authzResponse.Error() != nil {
+		return authzResponse
+	}
+	authorizedMeta := authzResponse.Metadata()
+	authorizedMeta.Authorization = &metadata.Authorization{
+		Allowed: authzResponse.Success(),
+	}
+	return &authorizationResponse{
+		AuthorizationResponse: authzResponse,
+		meta:                  authorizedMeta,
+	}
+}
+
+// authorizationResponse replaces the metadata of an authorization response.
+type authorizationResponse struct {
+	auth.AuthorizationResponse
+
+	meta metadata.ConnectionAuthenticatedMetadata
+}
+
+func (a *authorizationResponse) Metadata() metadata.ConnectionAuthenticatedMetadata {
+	return a.meta
 }
 
 // OnAuthPassword is called when a user attempts a password authentication. The implementation must always supply
 // AuthResponse and may supply error as a reason description.
 func (a *authzNetworkConnectionHandler) OnAuthPassword(meta metadata.ConnectionAuthPendingMetadata, password []byte) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
 	authResponse, authenticatedMeta, err := a.backend.OnAuthPassword(meta, password)
-	return a.genericAuthorization(meta, authResponse, authenticatedMeta, err)
+	return a.genericAuthorization(meta, config.SSHAuthMethodPassword, authResponse, authenticatedMeta, err)
 }
 
 // OnAuthPubKey is called when a user attempts a pubkey authentication. The implementation must always supply
@@ -315,7 +457,7 @@ func (a *authzNetworkConnectionHandler) OnAuthPassword(meta metadata.ConnectionA
 // the form of "ssh-rsa KEY HERE".
 func (a *authzNetworkConnectionHandler) OnAuthPubKey(meta metadata.ConnectionAuthPendingMetadata, pubKey auth2.PublicKey) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
 	authResponse, authenticatedMeta, err := a.backend.OnAuthPubKey(meta, pubKey)
-	return a.genericAuthorization(meta, authResponse, authenticatedMeta, err)
+	return a.genericAuthorization(meta, config.SSHAuthMethodPublicKey, authResponse, authenticatedMeta, err)
 }
 
 // OnAuthKeyboardInteractive is a callback for interactive authentication. The implementer will be passed a callback
@@ -325,14 +467,18 @@ func (a *authzNetworkConnectionHandler) OnAuthKeyboardInteractive(meta metadata.
 	instruction string,
 	questions sshserver.KeyboardInteractiveQuestions) (answers sshserver.KeyboardInteractiveAnswers, err error)) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
 	authResponse, authenticatedMeta, err := a.backend.OnAuthKeyboardInteractive(meta, challenge)
-	return a.genericAuthorization(meta, authResponse, authenticatedMeta, err)
+	return a.genericAuthorization(meta, config.SSHAuthMethodKeyboardInteractive, authResponse, authenticatedMeta, err)
 }
 
 // OnAuthGSSAPI returns a GSSAPIServer which can perform a GSSAPI authentication.
 func (a *authzNetworkConnectionHandler) OnAuthGSSAPI(metadata metadata.ConnectionMetadata) auth.GSSAPIServer {
 	gssApiServer := a.backend.OnAuthGSSAPI(metadata)
+	if gssApiServer == nil {
+		return nil
+	}
 	authzGssApiServer := authzGssApiServer{
-		backend: gssApiServer,
+		backend:               gssApiServer,
+		authorizationProvider: a.authorizationProvider,
 	}
 	return &authzGssApiServer
 }
@@ -412,7 +558,8 @@ func (g *authzGssApiServer) AllowLogin(username string, meta metadata.Connection
 		return authenticatedMetadata, err
 	}
 
-	authzResponse := g.authorizationProvider.Authorize(authenticatedMetadata)
+	authenticatedMetadata.AuthenticatedMethods = []string{string(config.SSHAuthMethodGSSAPI)}
+	authzResponse := authorize(g.authorizationProvider, authenticatedMetadata)
 	g.authzResponse = authzResponse
 	return authzResponse.Metadata(), authzResponse.Error()
 }
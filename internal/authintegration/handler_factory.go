@@ -5,6 +5,7 @@ import (
 
     "go.containerssh.io/libcontainerssh/config"
     "go.containerssh.io/libcontainerssh/internal/auth"
+    "go.containerssh.io/libcontainerssh/internal/geoip/geoipprovider"
     "go.containerssh.io/libcontainerssh/internal/metrics"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/log"
@@ -18,6 +19,7 @@ func New(
 	backend sshserver.Handler,
 	logger log.Logger,
 	metricsCollector metrics.Collector,
+	geoIPLookupProvider geoipprovider.LookupProvider,
 	behavior Behavior,
 ) (sshserver.Handler, []service.Service, error) {
 	if backend == nil {
@@ -69,7 +71,12 @@ func New(
 		services = append(services, svc)
 	}
 
-	authorizationProvider, svc, err := auth.NewAuthorizationProvider(config.Authz, logger, metricsCollector)
+	authorizationProvider, svc, err := auth.NewAuthorizationProvider(
+		config.Authz,
+		geoIPLookupProvider,
+		logger,
+		metricsCollector,
+	)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -85,5 +92,22 @@ func New(
 		authorizationProvider:            authorizationProvider,
 		backend:                          backend,
 		behavior:                         behavior,
+		authMethods:                      config.AuthMethods,
 	}, services, nil
 }
+
+// WithBackend creates a handler that uses the authenticators of a handler returned by New, but passes the
+// authenticated connections to a different backend. The services of the original handler are shared and must not be
+// started again.
+func WithBackend(authHandler sshserver.Handler, backend sshserver.Handler) (sshserver.Handler, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("the backend parameter to authintegration.WithBackend cannot be nil")
+	}
+	h, ok := authHandler.(*handler)
+	if !ok {
+		return nil, fmt.Errorf("the authHandler parameter to authintegration.WithBackend was not created by authintegration.New")
+	}
+	newHandler := *h
+	newHandler.backend = backend
+	return &newHandler, nil
+}
@@ -2,7 +2,12 @@ package authintegration_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,6 +46,145 @@ func TestAuthentication(t *testing.T) {
 	testConnection(t, "foonoauthz", ssh.Password("baz"), sshServerConfig, false)
 }
 
+func TestMultiFactorAuthentication(t *testing.T) {
+	logger := log.NewTestLogger(t)
+
+	authServerPort := test.GetNextPort(t, "auth server")
+	authLifecycle := startAuthServer(t, logger, authServerPort)
+	defer authLifecycle.Stop(context.Background())
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	assert.NoError(t, err)
+	authorizedKeysDir := t.TempDir()
+	assert.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(authorizedKeysDir, "foo"),
+			[]byte(`environment="KEY_STEP=1" `+string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+			0600,
+		),
+	)
+
+	webhookConfig := config.AuthWebhookClientConfig{
+		HTTPClientConfiguration: config.HTTPClientConfiguration{
+			URL:     fmt.Sprintf("http://127.0.0.1:%d", authServerPort),
+			Timeout: 10 * time.Second,
+		},
+		AuthTimeout: 30 * time.Second,
+	}
+	backend := &recordingBackend{
+		handshakes: make(chan metadata.ConnectionAuthenticatedMetadata, 10),
+	}
+	handler, _, err := authintegration.New(
+		config.AuthConfig{
+			PasswordAuth: config.PasswordAuthConfig{
+				Method:  config.PasswordAuthMethodWebhook,
+				Webhook: webhookConfig,
+			},
+			PublicKeyAuth: config.PublicKeyAuthConfig{
+				Method: config.PubKeyAuthMethodAuthorizedKeys,
+				AuthorizedKeys: config.AuthAuthorizedKeysConfig{
+					Path:                  filepath.Join(authorizedKeysDir, "%u"),
+					PermitUserEnvironment: true,
+				},
+			},
+			Authz: config.AuthzConfig{
+				Method:  config.AuthzMethodWebhook,
+				Webhook: webhookConfig,
+			},
+			AuthMethods: [][]config.SSHAuthMethod{
+				{config.SSHAuthMethodPublicKey, config.SSHAuthMethodPassword},
+			},
+		},
+		backend,
+		logger,
+		metrics.New(dummy.New()),
+		dummy.New(),
+		authintegration.BehaviorNoPassthrough,
+	)
+	assert.NoError(t, err)
+	sshServerConfig, lifecycle := startSSHServerWithHandler(t, logger, handler)
+	defer lifecycle.Stop(context.Background())
+
+	testConnection(t, "foo", ssh.PublicKeys(signer), sshServerConfig, false)
+	testConnection(t, "foo", ssh.Password("bar"), sshServerConfig, false)
+	assert.Len(t, backend.handshakes, 0)
+
+	clientConfig := ssh.ClientConfig{
+		User: "foo",
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer), ssh.Password("baz")},
+		// We don't care about host key verification for this test.
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+	}
+	client, err := ssh.Dial("tcp", sshServerConfig.Listen, &clientConfig)
+	assert.Error(t, err, "an invalid password after the public key should fail")
+	if client != nil {
+		_ = client.Close()
+	}
+	assert.Len(t, backend.handshakes, 0)
+
+	clientConfig.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer), ssh.Password("bar")}
+	client, err = ssh.Dial("tcp", sshServerConfig.Listen, &clientConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = client.Close()
+
+	meta := <-backend.handshakes
+	assert.Equal(t, "foo", meta.AuthenticatedUsername)
+	assert.Equal(t, []string{"publickey", "password"}, meta.AuthenticatedMethods)
+	assert.Equal(t, "1", meta.Environment["KEY_STEP"].Value, "the metadata of the public key step was lost")
+	assert.Equal(t, "publickey", meta.Metadata["COMPLETED_METHODS"].Value)
+}
+
+func TestAuthzRules(t *testing.T) {
+	logger := log.NewTestLogger(t)
+
+	authServerPort := test.GetNextPort(t, "auth server")
+	authLifecycle := startAuthServer(t, logger, authServerPort)
+	defer authLifecycle.Stop(context.Background())
+
+	authzConfig := config.AuthzConfig{}
+	structutils.Defaults(&authzConfig)
+	authzConfig.Method = config.AuthzMethodRules
+	authzConfig.Rules = []config.AuthzRule{
+		{
+			Name:        "foo-password",
+			Action:      config.AuthzActionAllow,
+			Usernames:   []string{"foo"},
+			AuthMethods: []config.SSHAuthMethod{config.SSHAuthMethodPassword},
+		},
+	}
+	handler, _, err := authintegration.New(
+		config.AuthConfig{
+			PasswordAuth: config.PasswordAuthConfig{
+				Method: config.PasswordAuthMethodWebhook,
+				Webhook: config.AuthWebhookClientConfig{
+					HTTPClientConfiguration: config.HTTPClientConfiguration{
+						URL:     fmt.Sprintf("http://127.0.0.1:%d", authServerPort),
+						Timeout: 10 * time.Second,
+					},
+					AuthTimeout: 30 * time.Second,
+				},
+			},
+			Authz: authzConfig,
+		},
+		&testBackend{},
+		logger,
+		metrics.New(dummy.New()),
+		dummy.New(),
+		authintegration.BehaviorNoPassthrough,
+	)
+	assert.NoError(t, err)
+	sshServerConfig, lifecycle := startSSHServerWithHandler(t, logger, handler)
+	defer lifecycle.Stop(context.Background())
+
+	testConnection(t, "foo", ssh.Password("bar"), sshServerConfig, true)
+	testConnection(t, "foonoauthz", ssh.Password("bar"), sshServerConfig, false)
+}
+
 func startAuthServer(t *testing.T, logger log.Logger, authServerPort int) service.Lifecycle {
 	server, err := auth.NewServer(
 		config.HTTPServerConfiguration{
@@ -90,10 +234,17 @@ func startSSHServer(t *testing.T, logger log.Logger, authServerPort int) (config
 		backend,
 		logger,
 		collector,
+		dummy.New(),
 		authintegration.BehaviorNoPassthrough,
 	)
 	assert.NoError(t, err)
+	return startSSHServerWithHandler(t, logger, handler)
+}
 
+func startSSHServerWithHandler(t *testing.T, logger log.Logger, handler sshserver.Handler) (
+	config.SSHConfig,
+	service.Lifecycle,
+) {
 	sshServerConfig := config.SSHConfig{}
 	structutils.Defaults(&sshServerConfig)
 	assert.NoError(t, sshServerConfig.GenerateHostKey())
@@ -232,6 +383,29 @@ func (t *testBackend) OnNetworkConnection(meta metadata.ConnectionMetadata) (
 	return t, meta, nil
 }
 
+type recordingBackend struct {
+	testBackend
+
+	handshakes chan metadata.ConnectionAuthenticatedMetadata
+}
+
+func (r *recordingBackend) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	return r, meta, nil
+}
+
+func (r *recordingBackend) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	connection sshserver.SSHConnectionHandler,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	failureReason error,
+) {
+	r.handshakes <- meta
+	return r, meta, nil
+}
+
 // endregion
 
 // region AuthHandler
@@ -243,7 +417,13 @@ func (h *authHandler) OnPassword(
 	Password []byte,
 ) (bool, metadata.ConnectionAuthenticatedMetadata, error) {
 	if (meta.Username == "foo" || meta.Username == "foonoauthz") && string(Password) == "bar" {
-		return true, meta.Authenticated(meta.Username), nil
+		authenticated := meta.Authenticated(meta.Username)
+		if len(meta.AuthenticatedMethods) > 0 {
+			authenticated.GetMetadata()["COMPLETED_METHODS"] = metadata.Value{
+				Value: strings.Join(meta.AuthenticatedMethods, ","),
+			}
+		}
+		return true, authenticated, nil
 	}
 	if meta.Username == "crash" {
 		// Simulate a database failure
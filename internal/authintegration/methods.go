@@ -0,0 +1,57 @@
+package authintegration
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// authMethodPolicy contains the authentication method sequences of config.AuthConfig.AuthMethods. An empty policy
+// accepts any single authentication method.
+type authMethodPolicy [][]config.SSHAuthMethod
+
+// evaluate checks if the client can use the authentication method after completing the listed methods. If the method
+// completes a sequence, complete is true. Otherwise, next contains the methods the client can continue with.
+func (p authMethodPolicy) evaluate(completed []string, method config.SSHAuthMethod) (
+	allowed bool,
+	complete bool,
+	next []config.SSHAuthMethod,
+) {
+	if len(p) == 0 {
+		return true, true, nil
+	}
+	for _, sequence := range p {
+		if len(sequence) <= len(completed) || sequence[len(completed)] != method || !hasPrefix(sequence, completed) {
+			continue
+		}
+		allowed = true
+		if len(sequence) == len(completed)+1 {
+			complete = true
+			continue
+		}
+		nextMethod := sequence[len(completed)+1]
+		if !containsMethod(next, nextMethod) {
+			next = append(next, nextMethod)
+		}
+	}
+	if complete {
+		return true, true, nil
+	}
+	return allowed, false, next
+}
+
+func hasPrefix(sequence []config.SSHAuthMethod, completed []string) bool {
+	for i, method := range completed {
+		if string(sequence[i]) != method {
+			return false
+		}
+	}
+	return true
+}
+
+func containsMethod(methods []config.SSHAuthMethod, method config.SSHAuthMethod) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package authintegration //nolint:testpackage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+func TestAuthMethodPolicy(t *testing.T) {
+	policy := authMethodPolicy{
+		{config.SSHAuthMethodPublicKey, config.SSHAuthMethodKeyboardInteractive},
+		{config.SSHAuthMethodPublicKey, config.SSHAuthMethodPassword},
+		{config.SSHAuthMethodPassword},
+	}
+
+	allowed, complete, next := policy.evaluate(nil, config.SSHAuthMethodPublicKey)
+	assert.True(t, allowed)
+	assert.False(t, complete)
+	assert.Equal(
+		t,
+		[]config.SSHAuthMethod{config.SSHAuthMethodKeyboardInteractive, config.SSHAuthMethodPassword},
+		next,
+	)
+
+	allowed, complete, _ = policy.evaluate(nil, config.SSHAuthMethodPassword)
+	assert.True(t, allowed)
+	assert.True(t, complete)
+
+	allowed, _, _ = policy.evaluate(nil, config.SSHAuthMethodKeyboardInteractive)
+	assert.False(t, allowed)
+
+	allowed, complete, _ = policy.evaluate([]string{"publickey"}, config.SSHAuthMethodKeyboardInteractive)
+	assert.True(t, allowed)
+	assert.True(t, complete)
+
+	allowed, _, _ = policy.evaluate([]string{"publickey"}, config.SSHAuthMethodPublicKey)
+	assert.False(t, allowed)
+
+	allowed, _, _ = policy.evaluate([]string{"password"}, config.SSHAuthMethodPublicKey)
+	assert.False(t, allowed)
+
+	allowed, complete, _ = authMethodPolicy{}.evaluate(nil, config.SSHAuthMethodKeyboardInteractive)
+	assert.True(t, allowed)
+	assert.True(t, complete)
+}
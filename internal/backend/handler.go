@@ -9,11 +9,13 @@ import (
 
     auth2 "go.containerssh.io/libcontainerssh/auth"
     "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/internal/auth"
     internalConfig "go.containerssh.io/libcontainerssh/internal/config"
     "go.containerssh.io/libcontainerssh/internal/docker"
     "go.containerssh.io/libcontainerssh/internal/kubernetes"
     "go.containerssh.io/libcontainerssh/internal/metrics"
     "go.containerssh.io/libcontainerssh/internal/security"
+    "go.containerssh.io/libcontainerssh/internal/sftp"
     "go.containerssh.io/libcontainerssh/internal/sshproxy"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/internal/structutils"
@@ -32,7 +34,13 @@ type handler struct {
 	logger                 log.Logger
 	backendRequestsCounter metrics.Counter
 	backendErrorCounter    metrics.Counter
-	lock                   *sync.Mutex
+	// policyDisconnectCounter counts the sessions and connections closed by the security timeouts.
+	policyDisconnectCounter metrics.Counter
+	// dryRunCounter counts the requests that would have been rejected by the security settings in dry run mode.
+	dryRunCounter metrics.Counter
+	// userConnections counts the open connections of each user for the security limits.
+	userConnections *security.UserConnections
+	lock            *sync.Mutex
 }
 
 func (h *handler) OnNetworkConnection(
@@ -108,6 +116,9 @@ func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticate
 		meta.Username,
 	).WithLabel("authenticatedUsername", meta.AuthenticatedUsername)
 
+	// Apply the restrictions requested by the authenticator, e.g. from authorized_keys options.
+	auth.ApplyRestrictions(meta, &appConfig.Security)
+
 	return n.initBackend(newMeta, appConfig, backendLogger)
 }
 
@@ -126,8 +137,21 @@ func (n *networkHandler) initBackend(
 		return nil, meta, failureReason
 	}
 
+	// Inject the built-in SFTP server below the security overlay so its policies also apply to SFTP
+	backend, failureReason = sftp.New(appConfig.SFTP, backend, n.logger, nil)
+	if failureReason != nil {
+		return nil, meta, failureReason
+	}
+
 	// Inject security overlay
-	backend, failureReason = security.New(appConfig.Security, backend, n.logger)
+	backend, failureReason = security.New(
+		appConfig.Security,
+		backend,
+		n.logger,
+		n.rootHandler.policyDisconnectCounter,
+		n.rootHandler.dryRunCounter,
+		n.rootHandler.userConnections,
+	)
 	if failureReason != nil {
 		return nil, meta, failureReason
 	}
@@ -190,6 +214,9 @@ func (n *networkHandler) loadConnectionSpecificConfig(
 	if err := structutils.Copy(&appConfig, &n.rootHandler.config); err != nil {
 		return appConfig, meta, fmt.Errorf("failed to copy application configuration (%w)", err)
 	}
+	if listener, ok := appConfig.SSH.Listener(meta.Listener); ok && listener.Backend != "" {
+		appConfig.Backend = listener.Backend
+	}
 
 	newMeta, err := n.rootHandler.configLoader.LoadConnection(
 		ctx,
@@ -6,6 +6,7 @@ import (
     "go.containerssh.io/libcontainerssh/config"
     internalConfig "go.containerssh.io/libcontainerssh/internal/config"
     "go.containerssh.io/libcontainerssh/internal/metrics"
+    "go.containerssh.io/libcontainerssh/internal/security"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/log"
 )
@@ -37,15 +38,28 @@ func New(
 		MetricUnitBackendError,
 		MetricHelpBackendError,
 	)
+	policyDisconnectCounter := metricsCollector.MustCreateCounter(
+		security.MetricNamePolicyDisconnects,
+		security.MetricUnitPolicyDisconnects,
+		security.MetricHelpPolicyDisconnects,
+	)
+	dryRunCounter := metricsCollector.MustCreateCounter(
+		security.MetricNameDryRunRejections,
+		security.MetricUnitDryRunRejections,
+		security.MetricHelpDryRunRejections,
+	)
 
 	return &handler{
-		config:                 config,
-		configLoader:           loader,
-		authResponse:           defaultAuthResponse,
-		metricsCollector:       metricsCollector,
-		logger:                 logger,
-		backendRequestsCounter: backendRequestsCounter,
-		backendErrorCounter:    backendErrorCounter,
-		lock:                   &sync.Mutex{},
+		config:                  config,
+		configLoader:            loader,
+		authResponse:            defaultAuthResponse,
+		metricsCollector:        metricsCollector,
+		logger:                  logger,
+		backendRequestsCounter:  backendRequestsCounter,
+		backendErrorCounter:     backendErrorCounter,
+		policyDisconnectCounter: policyDisconnectCounter,
+		dryRunCounter:           dryRunCounter,
+		userConnections:         security.NewUserConnections(),
+		lock:                    &sync.Mutex{},
 	}, nil
 }
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"strings"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// MetricNameCacheRequests is the number of lookups in the webhook response caches by result: hit, miss or stale.
+const MetricNameCacheRequests = "containerssh_webhook_cache_requests_total"
+
+// Cache stores the responses of a webhook server for a limited time.
+type Cache[T any] interface {
+	// Get returns a copy of the value cached for the key if it has not expired.
+	Get(key string) (T, bool)
+	// GetStale returns a copy of the value cached for the key if it has expired less than the stale-if-error time ago.
+	// It is used when the webhook server is unavailable.
+	GetStale(key string) (T, bool)
+	// Set stores a copy of the value for the key. Positive indicates a successful response, which determines the TTL
+	// unless the cache control of the response overrides it.
+	Set(key string, value T, positive bool, control *config.CacheControl)
+}
+
+// Key creates a cache key from the parts.
+func Key(parts ...string) string {
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+)
+
+// New creates a cache for the responses of the webhook server identified by name. The name is used as the backend
+// label of the metrics. If the cache is disabled, nothing is stored.
+func New[T any](
+	name string,
+	cfg config.WebhookCacheConfig,
+	metricsCollector metrics.Collector,
+) Cache[T] {
+	if !cfg.Enable {
+		return &disabledCache[T]{}
+	}
+	return &cache[T]{
+		name: name,
+		cfg:  cfg,
+		now:  time.Now,
+		requestsMetric: metricsCollector.MustCreateCounter(
+			MetricNameCacheRequests,
+			"requests_total",
+			"The number of lookups in the webhook response caches by result: hit, miss or stale.",
+		),
+		entries: map[string]entry[T]{},
+	}
+}
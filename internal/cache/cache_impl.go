@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+)
+
+type entry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+type cache[T any] struct {
+	name           string
+	cfg            config.WebhookCacheConfig
+	now            func() time.Time
+	requestsMetric metrics.SimpleCounter
+
+	lock    sync.Mutex
+	entries map[string]entry[T]
+}
+
+func (c *cache[T]) Get(key string) (T, bool) {
+	return c.get(key, 0, "hit")
+}
+
+func (c *cache[T]) GetStale(key string) (T, bool) {
+	if c.cfg.StaleIfError == 0 {
+		var empty T
+		return empty, false
+	}
+	return c.get(key, c.cfg.StaleIfError, "stale")
+}
+
+func (c *cache[T]) get(key string, stale time.Duration, result string) (T, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var value T
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires.Add(stale)) {
+		c.requestsMetric.Increment(metrics.Label("backend", c.name), metrics.Label("result", "miss"))
+		return value, false
+	}
+	// The value is copied so the caller can modify the maps and slices of the response.
+	if err := structutils.Copy(&value, &e.value); err != nil {
+		c.requestsMetric.Increment(metrics.Label("backend", c.name), metrics.Label("result", "miss"))
+		return value, false
+	}
+	c.requestsMetric.Increment(metrics.Label("backend", c.name), metrics.Label("result", result))
+	return value, true
+}
+
+func (c *cache[T]) Set(key string, value T, positive bool, control *config.CacheControl) {
+	ttl := c.cfg.NegativeTTL
+	if positive {
+		ttl = c.cfg.PositiveTTL
+	}
+	if control != nil {
+		if control.NoStore {
+			return
+		}
+		if control.MaxAge != nil {
+			ttl = time.Duration(*control.MaxAge) * time.Second
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if ttl <= 0 {
+		// A response that must not be cached also replaces the earlier one.
+		delete(c.entries, key)
+		return
+	}
+	var stored T
+	if err := structutils.Copy(&stored, &value); err != nil {
+		return
+	}
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.cfg.MaxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry[T]{
+		value:   stored,
+		expires: now.Add(ttl),
+	}
+}
+
+// evict removes the entries that can no longer be served. If none of them can be removed, the entry closest to
+// expiry is removed instead.
+func (c *cache[T]) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	found := false
+	for key, e := range c.entries {
+		if !now.Before(e.expires.Add(c.cfg.StaleIfError)) {
+			delete(c.entries, key)
+			continue
+		}
+		if !found || e.expires.Before(oldest) {
+			oldestKey = key
+			oldest = e.expires
+			found = true
+		}
+	}
+	if found && len(c.entries) >= c.cfg.MaxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+type disabledCache[T any] struct {
+}
+
+func (d *disabledCache[T]) Get(_ string) (T, bool) {
+	var empty T
+	return empty, false
+}
+
+func (d *disabledCache[T]) GetStale(_ string) (T, bool) {
+	var empty T
+	return empty, false
+}
+
+func (d *disabledCache[T]) Set(_ string, _ T, _ bool, _ *config.CacheControl) {
+}
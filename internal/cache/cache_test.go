@@ -0,0 +1,124 @@
+package cache //nolint:testpackage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+)
+
+type testValue struct {
+	Name   string
+	Values map[string]string
+}
+
+func newTestCache(t *testing.T, cfg config.WebhookCacheConfig) (*cache[testValue], *time.Time) {
+	cfg.Enable = true
+	c, ok := New[testValue]("test", cfg, metrics.New(dummy.New())).(*cache[testValue])
+	if !ok {
+		t.Fatalf("cache not enabled")
+	}
+	now := time.Now()
+	c.now = func() time.Time {
+		return now
+	}
+	return c, &now
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, now := newTestCache(
+		t,
+		config.WebhookCacheConfig{PositiveTTL: time.Minute, NegativeTTL: 10 * time.Second, MaxEntries: 10},
+	)
+	c.Set("positive", testValue{Name: "positive"}, true, nil)
+	c.Set("negative", testValue{Name: "negative"}, false, nil)
+
+	value, ok := c.Get("positive")
+	assert.True(t, ok)
+	assert.Equal(t, "positive", value.Name)
+	_, ok = c.Get("negative")
+	assert.True(t, ok)
+
+	*now = now.Add(30 * time.Second)
+	_, ok = c.Get("positive")
+	assert.True(t, ok)
+	_, ok = c.Get("negative")
+	assert.False(t, ok, "the negative TTL was not applied")
+
+	*now = now.Add(time.Minute)
+	_, ok = c.Get("positive")
+	assert.False(t, ok, "the positive TTL was not applied")
+}
+
+func TestCacheControl(t *testing.T) {
+	c, now := newTestCache(t, config.WebhookCacheConfig{PositiveTTL: time.Minute, MaxEntries: 10})
+	maxAge := 300
+	c.Set("maxAge", testValue{}, true, &config.CacheControl{MaxAge: &maxAge})
+	c.Set("noStore", testValue{}, true, &config.CacheControl{NoStore: true})
+
+	_, ok := c.Get("noStore")
+	assert.False(t, ok)
+	*now = now.Add(2 * time.Minute)
+	_, ok = c.Get("maxAge")
+	assert.True(t, ok, "the max age of the response did not override the TTL")
+}
+
+func TestCacheStale(t *testing.T) {
+	c, now := newTestCache(
+		t,
+		config.WebhookCacheConfig{PositiveTTL: time.Minute, StaleIfError: time.Hour, MaxEntries: 10},
+	)
+	c.Set("key", testValue{Name: "value"}, true, nil)
+
+	*now = now.Add(30 * time.Minute)
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+	value, ok := c.GetStale("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value.Name)
+
+	*now = now.Add(time.Hour)
+	_, ok = c.GetStale("key")
+	assert.False(t, ok)
+}
+
+func TestCacheCopy(t *testing.T) {
+	c, _ := newTestCache(t, config.WebhookCacheConfig{PositiveTTL: time.Minute, MaxEntries: 10})
+	original := testValue{Values: map[string]string{"foo": "bar"}}
+	c.Set("key", original, true, nil)
+	original.Values["foo"] = "baz"
+
+	value, ok := c.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value.Values["foo"])
+	value.Values["foo"] = "baz"
+
+	value, _ = c.Get("key")
+	assert.Equal(t, "bar", value.Values["foo"], "modifying a returned value changed the cache")
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, now := newTestCache(t, config.WebhookCacheConfig{PositiveTTL: time.Minute, MaxEntries: 2})
+	c.Set("first", testValue{}, true, nil)
+	*now = now.Add(time.Second)
+	c.Set("second", testValue{}, true, nil)
+	c.Set("third", testValue{}, true, nil)
+
+	_, ok := c.Get("first")
+	assert.False(t, ok, "the entry closest to expiry was not evicted")
+	_, ok = c.Get("second")
+	assert.True(t, ok)
+	_, ok = c.Get("third")
+	assert.True(t, ok)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := New[testValue]("test", config.WebhookCacheConfig{PositiveTTL: time.Minute}, metrics.New(dummy.New()))
+	c.Set("key", testValue{}, true, nil)
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+}
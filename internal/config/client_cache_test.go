@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"context"
+	goHttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configuration "go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/config"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestClientCache(t *testing.T) {
+	var requests int32
+	var unavailable atomic.Bool
+	srv := httptest.NewServer(goHttp.HandlerFunc(func(writer goHttp.ResponseWriter, request *goHttp.Request) {
+		atomic.AddInt32(&requests, 1)
+		if unavailable.Load() {
+			writer.WriteHeader(503)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"config":{"backend":"docker"}}`))
+	}))
+	defer srv.Close()
+
+	newClient := func(cacheConfig configuration.WebhookCacheConfig) config.Client {
+		client, err := config.NewClient(
+			configuration.ClientConfig{
+				HTTPClientConfiguration: configuration.HTTPClientConfiguration{
+					URL:     srv.URL,
+					Timeout: 2 * time.Second,
+				},
+				Retry: configuration.WebhookRetryConfig{
+					MaxAttempts:    1,
+					InitialBackoff: time.Millisecond,
+					MaxBackoff:     time.Millisecond,
+					Multiplier:     1,
+				},
+				Cache: cacheConfig,
+			}, log.NewTestLogger(t), getMetricsCollector(t),
+		)
+		assert.NoError(t, err)
+		return client
+	}
+	get := func(client config.Client) (configuration.AppConfig, error) {
+		meta := metadata.NewTestAuthenticatingMetadata("foo").Authenticated("foo")
+		meta.PublicKeyFingerprint = "SHA256:test"
+		cfg, _, err := client.Get(context.Background(), meta)
+		return cfg, err
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		client := newClient(
+			configuration.WebhookCacheConfig{Enable: true, PositiveTTL: time.Minute, MaxEntries: 10},
+		)
+		for i := 0; i < 2; i++ {
+			cfg, err := get(client)
+			assert.NoError(t, err)
+			assert.Equal(t, configuration.BackendDocker, cfg.Backend)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("stale if error", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		unavailable.Store(false)
+		client := newClient(
+			configuration.WebhookCacheConfig{
+				Enable:       true,
+				PositiveTTL:  time.Millisecond,
+				StaleIfError: time.Hour,
+				MaxEntries:   10,
+			},
+		)
+		_, err := get(client)
+		assert.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		unavailable.Store(true)
+		cfg, err := get(client)
+		assert.NoError(t, err, "the stale configuration was not served")
+		assert.Equal(t, configuration.BackendDocker, cfg.Backend)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests), "the expired entry was served without a request")
+	})
+
+	t.Run("no stale", func(t *testing.T) {
+		unavailable.Store(false)
+		client := newClient(
+			configuration.WebhookCacheConfig{Enable: true, PositiveTTL: time.Millisecond, MaxEntries: 10},
+		)
+		_, err := get(client)
+		assert.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		unavailable.Store(true)
+		_, err = get(client)
+		assert.Error(t, err)
+	})
+}
@@ -3,7 +3,9 @@ package config
 import (
     "go.containerssh.io/libcontainerssh/config"
     http2 "go.containerssh.io/libcontainerssh/http"
+    "go.containerssh.io/libcontainerssh/internal/cache"
     "go.containerssh.io/libcontainerssh/internal/metrics"
+    "go.containerssh.io/libcontainerssh/internal/retry"
     "go.containerssh.io/libcontainerssh/log"
 )
 
@@ -42,5 +44,18 @@ func NewClient(
 		logger:                logger,
 		backendRequestsMetric: backendRequestsMetric,
 		backendFailureMetric:  backendFailureMetric,
+		backoff:               retry.NewBackoff(config.Retry),
+		circuitBreaker: retry.NewCircuitBreaker(
+			"config",
+			config.CircuitBreaker,
+			logger,
+			metricsCollector,
+		),
+		cache: newCache(config.Cache, metricsCollector),
 	}, nil
 }
+
+// newCache creates the cache of the configuration responses.
+func newCache(cfg config.WebhookCacheConfig, metricsCollector metrics.Collector) cache.Cache[config.ResponseBody] {
+	return cache.New[config.ResponseBody]("config", cfg, metricsCollector)
+}
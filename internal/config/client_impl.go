@@ -7,7 +7,9 @@ import (
 
     "go.containerssh.io/libcontainerssh/config"
     "go.containerssh.io/libcontainerssh/http"
+    "go.containerssh.io/libcontainerssh/internal/cache"
     "go.containerssh.io/libcontainerssh/internal/metrics"
+    "go.containerssh.io/libcontainerssh/internal/retry"
     "go.containerssh.io/libcontainerssh/log"
     "go.containerssh.io/libcontainerssh/message"
     "go.containerssh.io/libcontainerssh/metadata"
@@ -18,6 +20,9 @@ type client struct {
 	logger                log.Logger
 	backendRequestsMetric metrics.SimpleCounter
 	backendFailureMetric  metrics.SimpleCounter
+	backoff               retry.Backoff
+	circuitBreaker        retry.CircuitBreaker
+	cache                 cache.Cache[config.ResponseBody]
 }
 
 func (c *client) Get(
@@ -30,11 +35,21 @@ func (c *client) Get(
 	logger := c.logger.
 		WithLabel("connectionId", meta.ConnectionID).
 		WithLabel("username", meta.Username)
+	cacheKey := cache.Key(
+		meta.Username,
+		meta.AuthenticatedUsername,
+		meta.PublicKeyFingerprint,
+		meta.RemoteAddress.IP.String(),
+	)
+	if cached, ok := c.cache.Get(cacheKey); ok {
+		logger.Debug(message.NewMessage(message.MConfigCached, "User-specific configuration served from cache"))
+		return cached.Config, c.mergeCachedMetadata(meta, cached), nil
+	}
 	request, response := c.createRequestResponse(meta)
 	var lastError error = nil
 	var lastLabels []metrics.MetricLabel
 loop:
-	for {
+	for attempts := 1; ; attempts++ {
 		lastLabels = []metrics.MetricLabel{}
 		if lastError != nil {
 			lastLabels = append(
@@ -47,25 +62,55 @@ loop:
 				metrics.Label("retry", "0"),
 			)
 		}
+		if lastError = c.circuitBreaker.Allow(); lastError != nil {
+			lastLabels = append(lastLabels, metrics.Label("reason", message.ECircuitBreakerOpen))
+			break
+		}
 		c.logAttempt(logger, lastLabels)
 
 		lastError = c.configServerRequest(&request, &response)
 		if lastError == nil {
+			c.circuitBreaker.Success()
 			c.logConfigResponse(logger)
+			c.cache.Set(cacheKey, response, true, response.Cache)
 			return response.Config, response.ConnectionAuthenticatedMetadata, nil
 		}
+		c.circuitBreaker.Failure()
 		reason := c.getReason(lastError)
 		lastLabels = append(lastLabels, metrics.Label("reason", reason))
-		c.logTemporaryFailure(logger, lastError, reason, lastLabels)
+		if !c.backoff.ShouldRetry(attempts) {
+			break
+		}
+		delay := c.backoff.Delay(attempts)
+		c.logTemporaryFailure(logger, lastError, reason, delay, lastLabels)
 		select {
 		case <-ctx.Done():
 			break loop
-		case <-time.After(10 * time.Second):
+		case <-time.After(delay):
 		}
 	}
+	if cached, ok := c.cache.GetStale(cacheKey); ok {
+		logger.Warning(
+			message.Wrap(
+				lastError,
+				message.WConfigStale,
+				"Configuration server unavailable, using expired user-specific configuration from cache",
+			),
+		)
+		return cached.Config, c.mergeCachedMetadata(meta, cached), nil
+	}
 	return c.logAndReturnPermanentFailure(meta, lastError, lastLabels, logger)
 }
 
+// mergeCachedMetadata merges the metadata of a cached response into the metadata of the current connection. The
+// cached metadata belongs to an earlier connection, so only the fields the configuration server can change are taken.
+func (c *client) mergeCachedMetadata(
+	meta metadata.ConnectionAuthenticatedMetadata,
+	cached config.ResponseBody,
+) metadata.ConnectionAuthenticatedMetadata {
+	return meta.Merge(cached.ConnectionAuthenticatedMetadata)
+}
+
 func (c *client) createRequestResponse(
 	meta metadata.ConnectionAuthenticatedMetadata,
 ) (config.Request, config.ResponseBody) {
@@ -112,13 +157,15 @@ func (c *client) logTemporaryFailure(
 	logger log.Logger,
 	lastError error,
 	reason string,
+	delay time.Duration,
 	lastLabels []metrics.MetricLabel,
 ) {
 	logger.Debug(
 		message.Wrap(
 			lastError,
 			message.EConfigBackendError,
-			"Configuration request to backend failed, retrying in 10 seconds",
+			"Configuration request to backend failed, retrying in %s",
+			delay.Round(time.Millisecond),
 		).
 			Label("reason", reason),
 	)
@@ -3,14 +3,20 @@ package config
 import (
 	goHttp "net/http"
 
+	"go.containerssh.io/libcontainerssh/config"
 	"go.containerssh.io/libcontainerssh/http"
 	"go.containerssh.io/libcontainerssh/log"
 )
 
-// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler.
-func NewHandler(h RequestHandler, logger log.Logger) (goHttp.Handler, error) {
-	return http.NewServerHandler(&handler{
-		handler: h,
-		logger:  logger,
-	}, logger), nil
+// NewHandler creates an HTTP handler that forwards calls to the provided h config request handler. If a signature
+// secret is configured, requests without a valid signature are rejected.
+func NewHandler(h RequestHandler, signature config.HTTPSignatureConfig, logger log.Logger) (goHttp.Handler, error) {
+	return http.NewSignatureVerifier(
+		signature,
+		http.NewServerHandler(&handler{
+			handler: h,
+			logger:  logger,
+		}, logger),
+		logger,
+	), nil
 }
@@ -13,7 +13,7 @@ func NewServer(
 	h RequestHandler,
 	logger log.Logger,
 ) (http2.Server, error) {
-	handler, err := NewHandler(h, logger)
+	handler, err := NewHandler(h, configuration.Signature, logger)
 	if err != nil {
 		return nil, err
 	}
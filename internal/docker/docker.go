@@ -56,6 +56,9 @@ type dockerContainer interface {
 
 	// remove removes the container within the given context.
 	remove(ctx context.Context) error
+
+	// id returns the ID of the container.
+	id() string
 }
 
 // dockerExecution is an execution process on either an "exec" process or attached to the main console of a container.
@@ -383,6 +383,10 @@ loop:
 	return err
 }
 
+func (d *dockerV20Container) id() string {
+	return d.containerID
+}
+
 func (d *dockerV20Container) writeFile(path string, content []byte) error {
 	if d.config.Execution.DisableAgent {
 		return message.NewMessage(
@@ -8,6 +8,7 @@ import (
 	"strings"
 
     "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/internal/agentforward"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/internal/unixutils"
     "go.containerssh.io/libcontainerssh/message"
@@ -26,6 +27,7 @@ type channelHandler struct {
 	rows              uint32
 	exitSent          bool
 	x11               bool
+	authAgentSocket   string
 	exec              dockerExecution
 	session           sshserver.SessionChannel
 }
@@ -287,6 +289,30 @@ func (c *channelHandler) OnX11Request(
 	return nil
 }
 
+func (c *channelHandler) OnAuthAgentRequest(
+	_ uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	if c.authAgentSocket != "" {
+		return fmt.Errorf("agent forwarding already setup for this channel")
+	}
+
+	path := agentforward.AuthAgentSocketPath(c.networkHandler.connectionID, c.channelID)
+	err := c.connectionHandler.agentForward.NewAuthAgentForwarding(
+		c.connectionHandler.setupAgent,
+		c.networkHandler.logger,
+		path,
+		reverseHandler,
+	)
+	if err != nil {
+		return err
+	}
+	c.env["SSH_AUTH_SOCK"] = path
+	c.authAgentSocket = path
+
+	return nil
+}
+
 func (c *channelHandler) OnClose() {
 	if c.exec != nil {
 		c.exec.kill()
@@ -297,6 +323,12 @@ func (c *channelHandler) OnClose() {
 			c.networkHandler.logger.Info(fmt.Errorf("Failed to close X11 forwarding (%w)", err))
 		}
 	}
+	if c.authAgentSocket != "" {
+		err := c.connectionHandler.agentForward.CloseAuthAgentForwarding(c.authAgentSocket)
+		if err != nil {
+			c.networkHandler.logger.Info(fmt.Errorf("Failed to close agent forwarding (%w)", err))
+		}
+	}
 	container := c.networkHandler.container
 	if container != nil && c.networkHandler.config.Execution.Mode == config.DockerExecutionModeSession {
 		ctx, cancel := context.WithTimeout(context.Background(), c.networkHandler.config.Timeouts.ContainerStop)
@@ -52,7 +52,7 @@ func (n *networkHandler) OnHandshakeFailed(metadata.ConnectionMetadata, error) {
 
 func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
 	connection sshserver.SSHConnectionHandler,
-	metadata metadata.ConnectionAuthenticatedMetadata,
+	returnMeta metadata.ConnectionAuthenticatedMetadata,
 	failureReason error,
 ) {
 	n.mutex.Lock()
@@ -86,6 +86,7 @@ func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticate
 	n.labels = labels
 	var cnt dockerContainer
 	var err error
+	// In the session mode the containers are created per channel after the handshake, so BackendContainerKey is not set.
 	if n.config.Execution.Mode == config.DockerExecutionModeConnection {
 		if cnt, err = n.dockerClient.createContainer(ctx, labels, env, nil, nil); err != nil {
 			return nil, meta, err
@@ -94,6 +95,7 @@ func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticate
 		if err := n.container.start(ctx); err != nil {
 			return nil, meta, err
 		}
+		meta.GetMetadata()[metadata.BackendContainerKey] = metadata.Value{Value: cnt.id()}
 
 		for path, content := range meta.GetFiles() {
 			err := cnt.writeFile(path, content.Value)
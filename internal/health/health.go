@@ -2,6 +2,9 @@ package health
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"go.containerssh.io/libcontainerssh/config"
 	http2 "go.containerssh.io/libcontainerssh/http"
@@ -61,6 +64,14 @@ func NewClient(cfg config.HealthConfig, logger log.Logger) (Client, error) {
 type Service interface {
 	service.Service
 	ChangeStatus(ok bool)
+	// Drain reports that the service is draining its existing connections before shutting down and is not accepting
+	// new ones. The draining state is reported with the 503 status code and the "draining" body.
+	Drain()
+	// SetBackendCheck sets a function that returns the names of the backends ContainerSSH depends on that are
+	// currently unhealthy, for example the webhook servers with an open circuit breaker. They are listed in the
+	// response body, but do not change the status code, as ContainerSSH can still serve users who do not depend on
+	// them.
+	SetBackendCheck(check func() []string)
 }
 
 // Client is the client to run health checks.
@@ -75,23 +86,60 @@ type healthCheckService struct {
 }
 
 func (h *healthCheckService) ChangeStatus(ok bool) {
-	h.requestHandler.ok = ok
+	if ok {
+		h.requestHandler.status.Store(statusOK)
+	} else {
+		h.requestHandler.status.Store(statusNotOK)
+	}
+}
+
+func (h *healthCheckService) Drain() {
+	h.requestHandler.status.Store(statusDraining)
 }
 
+func (h *healthCheckService) SetBackendCheck(check func() []string) {
+	h.requestHandler.backendCheck.Store(&check)
+}
+
+const (
+	statusNotOK int32 = iota
+	statusOK
+	statusDraining
+)
+
 type requestHandler struct {
-	ok bool
+	status       atomic.Int32
+	backendCheck atomic.Pointer[func() []string]
 }
 
-func (r requestHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
-	if r.ok {
-		response.SetBody("ok")
-	} else {
+func (r *requestHandler) OnRequest(_ http2.ServerRequest, response http2.ServerResponse) error {
+	switch r.status.Load() {
+	case statusOK:
+		response.SetBody("ok" + r.unhealthyBackends())
+	case statusDraining:
+		response.SetBody("draining")
+		response.SetStatus(503)
+	default:
 		response.SetBody("not ok")
 		response.SetStatus(503)
 	}
 	return nil
 }
 
+// unhealthyBackends returns the list of unhealthy backends to append to the response body.
+func (r *requestHandler) unhealthyBackends() string {
+	check := r.backendCheck.Load()
+	if check == nil {
+		return ""
+	}
+	backends := (*check)()
+	if len(backends) == 0 {
+		return ""
+	}
+	sort.Strings(backends)
+	return ", unhealthy backends: " + strings.Join(backends, ", ")
+}
+
 type healthCheckClient struct {
 	httpClient http2.Client
 	logger     log.Logger
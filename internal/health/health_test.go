@@ -1,7 +1,10 @@
 package health_test
 
 import (
+	"context"
+
     "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/http"
     "go.containerssh.io/libcontainerssh/internal/health"
     "go.containerssh.io/libcontainerssh/log"
     service2 "go.containerssh.io/libcontainerssh/service"
@@ -60,4 +63,60 @@ func TestOk(t *testing.T) {
 	if client.Run() {
 		t.Fatal("Health check did not fail, even though status is false.")
 	}
+
+	srv.ChangeStatus(true)
+	srv.Drain()
+	if client.Run() {
+		t.Fatal("Health check did not fail, even though the service is draining.")
+	}
+}
+
+func TestBackendCheck(t *testing.T) {
+	logger := log.NewTestLogger(t)
+	cfg := config.HealthConfig{
+		Enable: true,
+		HTTPServerConfiguration: config.HTTPServerConfiguration{
+			Listen: "127.0.0.1:23075",
+		},
+		Client: config.HTTPClientConfiguration{
+			URL:     "http://127.0.0.1:23075",
+			Timeout: 5 * time.Second,
+		},
+	}
+
+	srv, err := health.New(cfg, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := service2.NewLifecycle(srv)
+	running := make(chan struct{})
+	l.OnRunning(func(s service2.Service, l service2.Lifecycle) {
+		running <- struct{}{}
+	})
+	go func() {
+		_ = l.Run()
+	}()
+	<-running
+	defer l.Stop(context.Background())
+
+	srv.ChangeStatus(true)
+	srv.SetBackendCheck(func() []string {
+		return []string{"config", "auth_password"}
+	})
+
+	httpClient, err := http.NewClient(cfg.Client, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := ""
+	statusCode, err := httpClient.Get("", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statusCode != 200 {
+		t.Fatalf("Unhealthy backends should not fail the health check (status code %d)", statusCode)
+	}
+	if body != "ok, unhealthy backends: auth_password, config" {
+		t.Fatalf("Unexpected health check response: %s", body)
+	}
 }
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/agentforward"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+func TestAuthAgentRequest(t *testing.T) {
+	forward := &testAgentForward{
+		sockets: map[string]bool{},
+	}
+	network := &networkHandler{
+		mutex:        &sync.Mutex{},
+		connectionID: "abcd",
+		config:       config.KubernetesConfig{},
+		logger:       log.NewTestLogger(t),
+	}
+	connection := &sshConnectionHandler{
+		networkHandler: network,
+		agentForward:   forward,
+	}
+	newChannel := func(channelID uint64) *channelHandler {
+		return &channelHandler{
+			channelID:         channelID,
+			networkHandler:    network,
+			connectionHandler: connection,
+			env:               map[string]string{},
+		}
+	}
+
+	channel1 := newChannel(1)
+	channel2 := newChannel(2)
+	assert.NoError(t, channel1.OnAuthAgentRequest(1, nil))
+	assert.Error(t, channel1.OnAuthAgentRequest(2, nil))
+	assert.NoError(t, channel2.OnAuthAgentRequest(1, nil))
+
+	assert.Equal(t, agentforward.AuthAgentSocketPath("abcd", 1), channel1.env["SSH_AUTH_SOCK"])
+	assert.Equal(t, agentforward.AuthAgentSocketPath("abcd", 2), channel2.env["SSH_AUTH_SOCK"])
+	assert.True(t, forward.sockets[channel1.env["SSH_AUTH_SOCK"]])
+	assert.True(t, forward.sockets[channel2.env["SSH_AUTH_SOCK"]])
+
+	channel1.OnClose()
+	assert.False(t, forward.sockets[channel1.env["SSH_AUTH_SOCK"]])
+	assert.True(t, forward.sockets[channel2.env["SSH_AUTH_SOCK"]])
+}
+
+// testAgentForward records the agent sockets that are currently forwarded.
+type testAgentForward struct {
+	agentforward.AgentForward
+
+	sockets map[string]bool
+}
+
+func (f *testAgentForward) NewAuthAgentForwarding(
+	_ func() (io.Reader, io.Writer, error),
+	_ log.Logger,
+	path string,
+	_ sshserver.ReverseForward,
+) error {
+	f.sockets[path] = true
+	return nil
+}
+
+func (f *testAgentForward) CloseAuthAgentForwarding(path string) error {
+	delete(f.sockets, path)
+	return nil
+}
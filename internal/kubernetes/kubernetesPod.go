@@ -17,4 +17,7 @@ type kubernetesPod interface {
 
 	// remove removes the Pod within the given context.
 	remove(ctx context.Context) error
+
+	// name returns the namespace and name of the Pod separated by a slash.
+	name() string
 }
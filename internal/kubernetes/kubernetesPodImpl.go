@@ -228,6 +228,10 @@ func (k *kubernetesPodImpl) createExecLocked(
 	}, nil
 }
 
+func (k *kubernetesPodImpl) name() string {
+	return k.pod.Namespace + "/" + k.pod.Name
+}
+
 func (k *kubernetesPodImpl) writeFile(ctx context.Context, path string, content []byte) error {
 	writeCmd := []string{k.config.Pod.AgentPath, "write-file", path}
 	if k.config.Pod.Mode == config2.KubernetesExecutionModeSession {
@@ -116,10 +116,12 @@ func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticate
 		}
 	}
 
+	// In the session mode the pods are created per channel after the handshake, so BackendContainerKey is not set.
 	if n.config.Pod.Mode == publicConfig.KubernetesExecutionModeConnection {
 		if n.pod, err = n.cli.createPod(ctx, n.labels, n.annotations, env, nil, nil); err != nil {
 			return nil, meta, err
 		}
+		meta.GetMetadata()[metadata.BackendContainerKey] = metadata.Value{Value: n.pod.name()}
 		for path, content := range meta.GetFiles() {
 			ctx, cancelFunc := context.WithTimeout(
 				context.Background(),
@@ -0,0 +1,240 @@
+package ldap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// maxPacketSize is the largest BER element PacketReader accepts, to protect against a malicious or broken server.
+const maxPacketSize = 16 * 1024 * 1024
+
+// Universal BER tags used in LDAP messages.
+const (
+	TagBoolean     byte = 0x01
+	TagInteger     byte = 0x02
+	TagOctetString byte = 0x04
+	TagNull        byte = 0x05
+	TagEnumerated  byte = 0x0a
+	TagSequence    byte = 0x30
+	TagSet         byte = 0x31
+)
+
+// Application tags of the LDAP protocol operations as defined in RFC 4511.
+const (
+	TagBindRequest           byte = 0x60
+	TagBindResponse          byte = 0x61
+	TagUnbindRequest         byte = 0x42
+	TagSearchRequest         byte = 0x63
+	TagSearchResultEntry     byte = 0x64
+	TagSearchResultDone      byte = 0x65
+	TagSearchResultReference byte = 0x73
+	TagExtendedRequest       byte = 0x77
+	TagExtendedResponse      byte = 0x78
+)
+
+// Packet is a single BER element. Primitive elements hold their content in Value, constructed elements in Children.
+type Packet struct {
+	Tag      byte
+	Value    []byte
+	Children []*Packet
+}
+
+// NewPrimitive creates a primitive element with the given content.
+func NewPrimitive(tag byte, value []byte) *Packet {
+	return &Packet{Tag: tag, Value: value}
+}
+
+// NewString creates a primitive element with a string content, for example an octet string.
+func NewString(tag byte, value string) *Packet {
+	return &Packet{Tag: tag, Value: []byte(value)}
+}
+
+// NewInteger creates an integer or enumerated element.
+func NewInteger(tag byte, value int64) *Packet {
+	var content []byte
+	for {
+		content = append([]byte{byte(value)}, content...)
+		if (value >= -128 && value < 128) || len(content) == 8 {
+			break
+		}
+		value >>= 8
+	}
+	return &Packet{Tag: tag, Value: content}
+}
+
+// NewBoolean creates a boolean element.
+func NewBoolean(tag byte, value bool) *Packet {
+	if value {
+		return &Packet{Tag: tag, Value: []byte{0xff}}
+	}
+	return &Packet{Tag: tag, Value: []byte{0x00}}
+}
+
+// NewConstructed creates a constructed element, such as a sequence.
+func NewConstructed(tag byte, children ...*Packet) *Packet {
+	return &Packet{Tag: tag, Children: children}
+}
+
+// Constructed returns true if the element contains other elements.
+func (p *Packet) Constructed() bool {
+	return p.Tag&0x20 != 0
+}
+
+// Int decodes the content of an integer, enumerated or boolean element.
+func (p *Packet) Int() (int64, error) {
+	if len(p.Value) == 0 || len(p.Value) > 8 {
+		return 0, fmt.Errorf("invalid integer length: %d", len(p.Value))
+	}
+	// Start with the sign extension of the first byte.
+	result := int64(int8(p.Value[0]))
+	for _, b := range p.Value[1:] {
+		result = result<<8 | int64(b)
+	}
+	return result, nil
+}
+
+// Bool decodes the content of a boolean element.
+func (p *Packet) Bool() bool {
+	return len(p.Value) > 0 && p.Value[0] != 0
+}
+
+// String returns the content of a primitive element as a string.
+func (p *Packet) String() string {
+	return string(p.Value)
+}
+
+// Bytes encodes the element.
+func (p *Packet) Bytes() []byte {
+	content := p.Value
+	if p.Constructed() {
+		content = nil
+		for _, child := range p.Children {
+			content = append(content, child.Bytes()...)
+		}
+	}
+	result := append([]byte{p.Tag}, encodeLength(len(content))...)
+	return append(result, content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var result []byte
+	for length > 0 {
+		result = append([]byte{byte(length)}, result...)
+		length >>= 8
+	}
+	return append([]byte{0x80 | byte(len(result))}, result...)
+}
+
+// PacketReader reads consecutive elements from a stream.
+type PacketReader struct {
+	reader *bufio.Reader
+}
+
+// NewPacketReader creates a reader for consecutive elements. The reader buffers the stream, so it must be the only
+// reader of it.
+func NewPacketReader(r io.Reader) *PacketReader {
+	return &PacketReader{reader: bufio.NewReader(r)}
+}
+
+// Read reads and decodes the next element.
+func (r *PacketReader) Read() (*Packet, error) {
+	return readPacket(r.reader)
+}
+
+func readPacket(reader *bufio.Reader) (*Packet, error) {
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, fmt.Errorf("multi-byte tags are not supported")
+	}
+	length, err := readLength(reader)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return decode(tag, content)
+}
+
+func readLength(reader *bufio.Reader) (int, error) {
+	first, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	octets := int(first & 0x7f)
+	if octets == 0 || octets > 4 {
+		return 0, fmt.Errorf("unsupported length encoding")
+	}
+	length := 0
+	for i := 0; i < octets; i++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("element too large: %d bytes", length)
+	}
+	return length, nil
+}
+
+func decode(tag byte, content []byte) (*Packet, error) {
+	p := &Packet{Tag: tag}
+	if !p.Constructed() {
+		p.Value = content
+		return p, nil
+	}
+	for len(content) > 0 {
+		child, rest, err := parse(content)
+		if err != nil {
+			return nil, err
+		}
+		p.Children = append(p.Children, child)
+		content = rest
+	}
+	return p, nil
+}
+
+// parse decodes the first element of data and returns the remaining bytes.
+func parse(data []byte) (*Packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("truncated element")
+	}
+	tag := data[0]
+	if tag&0x1f == 0x1f {
+		return nil, nil, fmt.Errorf("multi-byte tags are not supported")
+	}
+	length := int(data[1])
+	offset := 2
+	if length >= 0x80 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 4 || len(data) < offset+octets {
+			return nil, nil, fmt.Errorf("invalid length encoding")
+		}
+		length = 0
+		for _, b := range data[offset : offset+octets] {
+			length = length<<8 | int(b)
+		}
+		offset += octets
+	}
+	if length < 0 || len(data)-offset < length {
+		return nil, nil, fmt.Errorf("truncated element")
+	}
+	p, err := decode(tag, data[offset:offset+length])
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, data[offset+length:], nil
+}
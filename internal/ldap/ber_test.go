@@ -0,0 +1,33 @@
+package ldap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/internal/ldap"
+)
+
+func TestPacketEncoding(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		packet := ldap.NewInteger(ldap.TagInteger, value)
+		decoded, err := packet.Int()
+		assert.NoError(t, err)
+		assert.Equal(t, value, decoded)
+	}
+
+	long := make([]byte, 300)
+	message := ldap.NewConstructed(
+		ldap.TagSequence,
+		ldap.NewInteger(ldap.TagInteger, 5),
+		ldap.NewPrimitive(ldap.TagOctetString, long),
+	)
+	decoded, err := ldap.NewPacketReader(bytes.NewReader(message.Bytes())).Read()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ldap.TagSequence, decoded.Tag)
+	assert.Len(t, decoded.Children, 2)
+	assert.Len(t, decoded.Children[1].Value, 300)
+}
@@ -0,0 +1,357 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Result codes of the LDAP protocol as defined in RFC 4511. Only the codes the client acts on are listed.
+const (
+	ResultSuccess            int64 = 0
+	ResultSizeLimitExceeded  int64 = 4
+	ResultNoSuchObject       int64 = 32
+	ResultInvalidCredentials int64 = 49
+	ResultUnwillingToPerform int64 = 53
+)
+
+const (
+	startTLSOID                   = "1.3.6.1.4.1.1466.20037"
+	protocolVersion         int64 = 3
+	searchScopeWholeSubtree int64 = 2
+	searchDerefAliasesNever int64 = 0
+	defaultPort                   = "389"
+	defaultTLSPort                = "636"
+	tagSimpleAuthentication byte  = 0x80
+	tagExtendedRequestName  byte  = 0x80
+)
+
+// Error is a result other than success returned by the LDAP server.
+type Error struct {
+	// ResultCode is the LDAP result code, for example ResultInvalidCredentials.
+	ResultCode int64
+	// MatchedDN is the DN the server matched, if any.
+	MatchedDN string
+	// Message is the diagnostic message of the server.
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("LDAP result code %d", e.ResultCode)
+	}
+	return fmt.Sprintf("LDAP result code %d: %s", e.ResultCode, e.Message)
+}
+
+// DialConfig is the configuration for connecting to an LDAP server.
+type DialConfig struct {
+	// URL is the address of the server in the form of ldap://host:port or ldaps://host:port.
+	URL string
+	// StartTLS upgrades an ldap:// connection to TLS before any other operation.
+	StartTLS bool
+	// TLSConfig is used for ldaps:// and StartTLS connections. If the server name is not set, it is taken from the URL.
+	TLSConfig *tls.Config
+	// Timeout limits the time for establishing the connection and for each operation.
+	Timeout time.Duration
+}
+
+// Entry is a search result entry.
+type Entry struct {
+	// DN is the distinguished name of the entry.
+	DN string
+	// Attributes contains the returned attributes of the entry.
+	Attributes map[string][]string
+}
+
+// Get returns the values of an attribute. Attribute names are not case-sensitive.
+func (e *Entry) Get(attribute string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// SearchRequest is a search for the entries below BaseDN that match the filter.
+type SearchRequest struct {
+	// BaseDN is the DN the search starts from. The whole subtree is searched.
+	BaseDN string
+	// Filter is the search filter in the string representation of RFC 4515.
+	Filter string
+	// Attributes are the attributes to return. If empty, all user attributes are returned.
+	Attributes []string
+	// SizeLimit is the maximum number of entries to return. 0 means no limit.
+	SizeLimit int64
+}
+
+// Conn is a connection to an LDAP server. Operations are sent one at a time, so a connection must not be used from
+// multiple goroutines.
+type Conn struct {
+	conn    net.Conn
+	reader  *PacketReader
+	nextID  int64
+	timeout time.Duration
+}
+
+// Dial connects to the LDAP server and performs the StartTLS operation if configured.
+func Dial(cfg DialConfig) (*Conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL %s (%w)", cfg.URL, err)
+	}
+	tlsConfig := cfg.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = u.Hostname()
+	}
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPort(u, defaultPort))
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, defaultTLSPort), tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported LDAP URL scheme: %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		conn:    conn,
+		reader:  NewPacketReader(conn),
+		nextID:  1,
+		timeout: cfg.Timeout,
+	}
+	if cfg.StartTLS && u.Scheme == "ldap" {
+		if err := c.startTLS(tlsConfig); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return u.Host
+}
+
+func (c *Conn) startTLS(tlsConfig *tls.Config) error {
+	response, err := c.roundTrip(
+		NewConstructed(
+			TagExtendedRequest,
+			NewString(tagExtendedRequestName, startTLSOID),
+		),
+		TagExtendedResponse,
+	)
+	if err != nil {
+		return fmt.Errorf("StartTLS failed (%w)", err)
+	}
+	if err := resultError(response); err != nil {
+		return fmt.Errorf("StartTLS failed (%w)", err)
+	}
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	if c.timeout > 0 {
+		_ = tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake after StartTLS failed (%w)", err)
+	}
+	c.conn = tlsConn
+	c.reader = NewPacketReader(tlsConn)
+	return nil
+}
+
+// Bind performs a simple bind. A bind with a DN but an empty password is rejected without contacting the server,
+// because servers treat it as an unauthenticated bind that always succeeds.
+func (c *Conn) Bind(dn string, password string) error {
+	if dn != "" && password == "" {
+		return &Error{
+			ResultCode: ResultUnwillingToPerform,
+			Message:    "unauthenticated bind with an empty password refused",
+		}
+	}
+	response, err := c.roundTrip(
+		NewConstructed(
+			TagBindRequest,
+			NewInteger(TagInteger, protocolVersion),
+			NewString(TagOctetString, dn),
+			NewString(tagSimpleAuthentication, password),
+		),
+		TagBindResponse,
+	)
+	if err != nil {
+		return err
+	}
+	return resultError(response)
+}
+
+// Search returns the entries matching the search request. If the size limit is exceeded, the entries returned until
+// then are returned without an error.
+func (c *Conn) Search(request SearchRequest) ([]*Entry, error) {
+	filter, err := CompileFilter(request.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := NewConstructed(TagSequence)
+	for _, attribute := range request.Attributes {
+		attributes.Children = append(attributes.Children, NewString(TagOctetString, attribute))
+	}
+	id, err := c.send(
+		NewConstructed(
+			TagSearchRequest,
+			NewString(TagOctetString, request.BaseDN),
+			NewInteger(TagEnumerated, searchScopeWholeSubtree),
+			NewInteger(TagEnumerated, searchDerefAliasesNever),
+			NewInteger(TagInteger, request.SizeLimit),
+			NewInteger(TagInteger, 0),
+			NewBoolean(TagBoolean, false),
+			filter,
+			attributes,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Tag {
+		case TagSearchResultEntry:
+			entry, err := decodeEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case TagSearchResultReference:
+			// Referrals are not followed.
+		case TagSearchResultDone:
+			if err := resultError(op); err != nil {
+				var ldapErr *Error
+				if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ResultSizeLimitExceeded {
+					return nil, err
+				}
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("unexpected LDAP response type: 0x%02x", op.Tag)
+		}
+	}
+}
+
+// Close sends an unbind request and closes the connection.
+func (c *Conn) Close() error {
+	_, _ = c.send(NewPrimitive(TagUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+func (c *Conn) roundTrip(request *Packet, responseTag byte) (*Packet, error) {
+	id, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.Tag != responseTag {
+		return nil, fmt.Errorf("unexpected LDAP response type: 0x%02x", response.Tag)
+	}
+	return response, nil
+}
+
+func (c *Conn) send(op *Packet) (int64, error) {
+	id := c.nextID
+	c.nextID++
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	message := NewConstructed(TagSequence, NewInteger(TagInteger, id), op)
+	if _, err := c.conn.Write(message.Bytes()); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// receive reads the next response and returns its protocol operation.
+func (c *Conn) receive(id int64) (*Packet, error) {
+	message, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if message.Tag != TagSequence || len(message.Children) < 2 {
+		return nil, fmt.Errorf("invalid LDAP message")
+	}
+	responseID, err := message.Children[0].Int()
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP message ID (%w)", err)
+	}
+	op := message.Children[1]
+	if responseID == 0 && op.Tag == TagExtendedResponse {
+		// Unsolicited notification, typically a notice of disconnection.
+		if err := resultError(op); err != nil {
+			return nil, fmt.Errorf("the LDAP server closed the connection (%w)", err)
+		}
+		return nil, fmt.Errorf("the LDAP server closed the connection")
+	}
+	if responseID != id {
+		return nil, fmt.Errorf("unexpected LDAP message ID %d, expected %d", responseID, id)
+	}
+	return op, nil
+}
+
+// resultError decodes the LDAPResult at the start of a response and returns an *Error if it is not a success.
+func resultError(op *Packet) error {
+	if len(op.Children) < 3 {
+		return fmt.Errorf("invalid LDAP result")
+	}
+	code, err := op.Children[0].Int()
+	if err != nil {
+		return fmt.Errorf("invalid LDAP result code (%w)", err)
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{
+		ResultCode: code,
+		MatchedDN:  op.Children[1].String(),
+		Message:    op.Children[2].String(),
+	}
+}
+
+func decodeEntry(op *Packet) (*Entry, error) {
+	if len(op.Children) < 2 {
+		return nil, fmt.Errorf("invalid LDAP search result entry")
+	}
+	entry := &Entry{
+		DN:         op.Children[0].String(),
+		Attributes: map[string][]string{},
+	}
+	for _, attribute := range op.Children[1].Children {
+		if len(attribute.Children) < 2 {
+			return nil, fmt.Errorf("invalid LDAP attribute in entry %s", entry.DN)
+		}
+		name := attribute.Children[0].String()
+		for _, value := range attribute.Children[1].Children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.String())
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,202 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Context-specific tags of the search filter choices as defined in RFC 4511.
+const (
+	FilterAnd             byte = 0xa0
+	FilterOr              byte = 0xa1
+	FilterNot             byte = 0xa2
+	FilterEqualityMatch   byte = 0xa3
+	FilterSubstrings      byte = 0xa4
+	FilterGreaterOrEqual  byte = 0xa5
+	FilterLessOrEqual     byte = 0xa6
+	FilterPresent         byte = 0x87
+	FilterApproxMatch     byte = 0xa8
+	FilterSubstringsInit  byte = 0x80
+	FilterSubstringsAny   byte = 0x81
+	FilterSubstringsFinal byte = 0x82
+)
+
+// EscapeFilter escapes a value so it can be safely inserted into a search filter as defined in RFC 4515.
+func EscapeFilter(value string) string {
+	result := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '*', '(', ')', '\\', 0:
+			result.WriteString(fmt.Sprintf("\\%02x", c))
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// CompileFilter parses a search filter in the string representation of RFC 4515 into its BER encoding. Extensible
+// match filters are not supported.
+func CompileFilter(filter string) (*Packet, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	p, rest, err := compileFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search filter %q (%w)", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid search filter %q (unexpected characters after the filter: %q)", filter, rest)
+	}
+	return p, nil
+}
+
+// compileFilter compiles the parenthesized filter at the start of the string and returns the rest of the string.
+func compileFilter(filter string) (*Packet, string, error) {
+	if !strings.HasPrefix(filter, "(") {
+		return nil, "", fmt.Errorf("expected '(' at %q", filter)
+	}
+	filter = filter[1:]
+	if filter == "" {
+		return nil, "", fmt.Errorf("unexpected end of filter")
+	}
+	switch filter[0] {
+	case '&', '|':
+		tag := FilterAnd
+		if filter[0] == '|' {
+			tag = FilterOr
+		}
+		p := NewConstructed(tag)
+		rest := filter[1:]
+		for strings.HasPrefix(rest, "(") {
+			var child *Packet
+			var err error
+			child, rest, err = compileFilter(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			p.Children = append(p.Children, child)
+		}
+		if len(p.Children) == 0 {
+			return nil, "", fmt.Errorf("empty filter list")
+		}
+		return closeFilter(p, rest)
+	case '!':
+		child, rest, err := compileFilter(filter[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		return closeFilter(NewConstructed(FilterNot, child), rest)
+	default:
+		end := strings.IndexByte(filter, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing ')'")
+		}
+		p, err := compileItem(filter[:end])
+		if err != nil {
+			return nil, "", err
+		}
+		return p, filter[end+1:], nil
+	}
+}
+
+func closeFilter(p *Packet, rest string) (*Packet, string, error) {
+	if !strings.HasPrefix(rest, ")") {
+		return nil, "", fmt.Errorf("missing ')'")
+	}
+	return p, rest[1:], nil
+}
+
+// compileItem compiles a simple attribute-value assertion without the surrounding parentheses.
+func compileItem(item string) (*Packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("invalid filter item %q", item)
+	}
+	attribute := item[:eq]
+	value := item[eq+1:]
+	tag := FilterEqualityMatch
+	switch attribute[len(attribute)-1] {
+	case '>':
+		tag = FilterGreaterOrEqual
+		attribute = attribute[:len(attribute)-1]
+	case '<':
+		tag = FilterLessOrEqual
+		attribute = attribute[:len(attribute)-1]
+	case '~':
+		tag = FilterApproxMatch
+		attribute = attribute[:len(attribute)-1]
+	case ':':
+		return nil, fmt.Errorf("extensible match filters are not supported")
+	}
+	if attribute == "" || strings.ContainsAny(attribute, "()*\\ ") {
+		return nil, fmt.Errorf("invalid attribute name %q", attribute)
+	}
+
+	if tag == FilterEqualityMatch && value == "*" {
+		return NewString(FilterPresent, attribute), nil
+	}
+	if tag == FilterEqualityMatch && strings.Contains(value, "*") {
+		return compileSubstrings(attribute, value)
+	}
+	unescaped, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return NewConstructed(
+		tag,
+		NewString(TagOctetString, attribute),
+		NewString(TagOctetString, unescaped),
+	), nil
+}
+
+func compileSubstrings(attribute string, value string) (*Packet, error) {
+	parts := strings.Split(value, "*")
+	substrings := NewConstructed(TagSequence)
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		unescaped, err := unescapeFilterValue(part)
+		if err != nil {
+			return nil, err
+		}
+		tag := FilterSubstringsAny
+		switch i {
+		case 0:
+			tag = FilterSubstringsInit
+		case len(parts) - 1:
+			tag = FilterSubstringsFinal
+		}
+		substrings.Children = append(substrings.Children, NewString(tag, unescaped))
+	}
+	return NewConstructed(FilterSubstrings, NewString(TagOctetString, attribute), substrings), nil
+}
+
+// unescapeFilterValue decodes the \XX escape sequences of a filter value.
+func unescapeFilterValue(value string) (string, error) {
+	result := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '\\':
+			if i+2 >= len(value) {
+				return "", fmt.Errorf("incomplete escape sequence in %q", value)
+			}
+			decoded, err := hex.DecodeString(value[i+1 : i+3])
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence in %q", value)
+			}
+			result.Write(decoded)
+			i += 2
+		case '(', ')', '*':
+			return "", fmt.Errorf("unescaped %q in filter value %q", c, value)
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,69 @@
+package ldap_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/internal/ldap"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	assert.Equal(t, "foo", ldap.EscapeFilter("foo"))
+	assert.Equal(t, `\2a\29\28\5c`, ldap.EscapeFilter(`*)(\`))
+	assert.Equal(t, `a\00b`, ldap.EscapeFilter("a\x00b"))
+}
+
+func TestCompileFilter(t *testing.T) {
+	filter, err := ldap.CompileFilter("(&(objectClass=person)(|(uid=foo)(mail=foo*@example.com))(!(disabled=*)))")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ldap.FilterAnd, filter.Tag)
+	assert.Len(t, filter.Children, 3)
+
+	equality := filter.Children[0]
+	assert.Equal(t, ldap.FilterEqualityMatch, equality.Tag)
+	assert.Equal(t, "objectClass", equality.Children[0].String())
+	assert.Equal(t, "person", equality.Children[1].String())
+
+	or := filter.Children[1]
+	assert.Equal(t, ldap.FilterOr, or.Tag)
+	substrings := or.Children[1]
+	assert.Equal(t, ldap.FilterSubstrings, substrings.Tag)
+	assert.Equal(t, "mail", substrings.Children[0].String())
+	assert.Equal(t, ldap.FilterSubstringsInit, substrings.Children[1].Children[0].Tag)
+	assert.Equal(t, "foo", substrings.Children[1].Children[0].String())
+	assert.Equal(t, ldap.FilterSubstringsFinal, substrings.Children[1].Children[1].Tag)
+	assert.Equal(t, "@example.com", substrings.Children[1].Children[1].String())
+
+	not := filter.Children[2]
+	assert.Equal(t, ldap.FilterNot, not.Tag)
+	assert.Equal(t, ldap.FilterPresent, not.Children[0].Tag)
+	assert.Equal(t, "disabled", not.Children[0].String())
+}
+
+func TestCompileFilterEscapes(t *testing.T) {
+	filter, err := ldap.CompileFilter("cn=" + ldap.EscapeFilter("a*b(c)"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ldap.FilterEqualityMatch, filter.Tag)
+	assert.Equal(t, "a*b(c)", filter.Children[1].String())
+}
+
+func TestCompileFilterInvalid(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"(uid=foo",
+		"(uid=foo))",
+		"(&)",
+		"(=foo)",
+		"(uid=\\4)",
+		"(cn:dn:=foo)",
+		"(uid=foo)(uid=bar)",
+	} {
+		_, err := ldap.CompileFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
@@ -382,3 +382,10 @@ func (s *dummySession) OnX11Request(
 ) error {
 	return fmt.Errorf("Unimplemented")
 }
+
+func (s *dummySession) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	return fmt.Errorf("Unimplemented")
+}
@@ -0,0 +1,21 @@
+package ratelimit
+
+import (
+	"net"
+)
+
+// Limiter keeps track of connection and authentication rates globally, per source IP address and per username, and
+// temporarily bans source IP addresses that fail to authenticate too often.
+type Limiter interface {
+	// OnConnection checks if a new connection from the specified IP address is allowed and takes a token from the
+	// global and per-IP buckets. It returns an error if the connection must be rejected. Connections from banned
+	// addresses are rejected with the message.ERateLimitConnectionBanned code.
+	OnConnection(ip net.IP) error
+	// OnAuthAttempt checks if an authentication attempt for the specified username is allowed and takes a token from
+	// the per-user bucket. It returns an error if the attempt must be rejected without consulting the authentication
+	// backend.
+	OnAuthAttempt(ip net.IP, username string) error
+	// OnAuthFailure records a failed authentication attempt from the specified IP address. It returns an error if the
+	// IP address has been banned as a result of this failure.
+	OnAuthFailure(ip net.IP) error
+}
@@ -0,0 +1,20 @@
+package ratelimit
+
+import (
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// New creates a new rate limiter with the specified configuration.
+func New(cfg config.RateLimitConfig) Limiter {
+	return &limiter{
+		cfg:        cfg,
+		now:        time.Now,
+		maxBuckets: maxBuckets,
+		perIP:      map[string]*tokenBucket{},
+		perUser:    map[string]*tokenBucket{},
+		failures:   map[string][]time.Time{},
+		bans:       map[string]time.Time{},
+	}
+}
@@ -0,0 +1,247 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// cleanupInterval is the interval in which idle buckets, expired failures and expired bans are removed.
+const cleanupInterval = time.Minute
+
+// bucketIdleTimeout is the time after which the bucket of an IP address or username that has not been seen is removed,
+// even if it has not been refilled yet.
+const bucketIdleTimeout = 10 * time.Minute
+
+// maxBuckets is the maximum number of per-IP and per-user buckets each. Once reached, the least recently used bucket
+// is evicted to make room for a new one.
+const maxBuckets = 100000
+
+type limiter struct {
+	cfg        config.RateLimitConfig
+	now        func() time.Time
+	maxBuckets int
+
+	lock        sync.Mutex
+	global      tokenBucket
+	perIP       map[string]*tokenBucket
+	perUser     map[string]*tokenBucket
+	failures    map[string][]time.Time
+	bans        map[string]time.Time
+	lastCleanup time.Time
+}
+
+func (l *limiter) OnConnection(ip net.IP) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.cleanup(now)
+
+	if until, banned := l.banned(ip, now); banned {
+		return message.NewMessage(
+			message.ERateLimitConnectionBanned,
+			"Connection from %s rejected, the address is banned until %s",
+			ip,
+			until.Format(time.RFC3339),
+		)
+	}
+	if l.cfg.PerIP.Enabled() && !l.bucket(l.perIP, ip.String(), l.cfg.PerIP, now).available(l.cfg.PerIP, now) {
+		return message.NewMessage(
+			message.ERateLimitConnectionRejected,
+			"Connection from %s rejected, the per-IP connection rate limit has been exceeded",
+			ip,
+		)
+	}
+	if l.cfg.Global.Enabled() && !l.global.available(l.cfg.Global, now) {
+		return message.NewMessage(
+			message.ERateLimitConnectionRejected,
+			"Connection from %s rejected, the global connection rate limit has been exceeded",
+			ip,
+		)
+	}
+	// Tokens are only taken once all buckets agree so a rejected connection does not drain the other buckets.
+	if l.cfg.PerIP.Enabled() {
+		l.perIP[ip.String()].take()
+	}
+	if l.cfg.Global.Enabled() {
+		l.global.take()
+	}
+	return nil
+}
+
+func (l *limiter) OnAuthAttempt(ip net.IP, username string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.cleanup(now)
+
+	if until, banned := l.banned(ip, now); banned {
+		return message.NewMessage(
+			message.ERateLimitAuthRejected,
+			"Authentication attempt for user %s from %s rejected, the address is banned until %s",
+			username,
+			ip,
+			until.Format(time.RFC3339),
+		)
+	}
+	if l.cfg.PerUser.Enabled() {
+		bucket := l.bucket(l.perUser, username, l.cfg.PerUser, now)
+		if !bucket.available(l.cfg.PerUser, now) {
+			return message.NewMessage(
+				message.ERateLimitAuthRejected,
+				"Authentication attempt for user %s from %s rejected, the per-user authentication rate limit has been exceeded",
+				username,
+				ip,
+			)
+		}
+		bucket.take()
+	}
+	return nil
+}
+
+func (l *limiter) OnAuthFailure(ip net.IP) error {
+	if !l.cfg.Ban.Enabled() {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	key := ip.String()
+
+	failures := append(expireFailures(l.failures[key], now.Add(-l.cfg.Ban.Window)), now)
+	if uint(len(failures)) < l.cfg.Ban.MaxFailures {
+		l.failures[key] = failures
+		return nil
+	}
+	delete(l.failures, key)
+	until := now.Add(l.cfg.Ban.Duration)
+	l.bans[key] = until
+	return message.NewMessage(
+		message.ERateLimitBanned,
+		"Address %s banned until %s after %d failed authentication attempts",
+		ip,
+		until.Format(time.RFC3339),
+		len(failures),
+	)
+}
+
+func (l *limiter) banned(ip net.IP, now time.Time) (time.Time, bool) {
+	until, ok := l.bans[ip.String()]
+	if !ok || !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func (l *limiter) bucket(
+	buckets map[string]*tokenBucket,
+	key string,
+	cfg config.RateLimitBucketConfig,
+	now time.Time,
+) *tokenBucket {
+	bucket, ok := buckets[key]
+	if !ok {
+		if len(buckets) >= l.maxBuckets {
+			evictLeastRecentlyUsed(buckets)
+		}
+		bucket = &tokenBucket{tokens: burst(cfg), last: now}
+		buckets[key] = bucket
+	}
+	bucket.lastUsed = now
+	return bucket
+}
+
+func evictLeastRecentlyUsed(buckets map[string]*tokenBucket) {
+	oldestKey := ""
+	var oldest time.Time
+	for key, bucket := range buckets {
+		if oldestKey == "" || bucket.lastUsed.Before(oldest) {
+			oldestKey = key
+			oldest = bucket.lastUsed
+		}
+	}
+	delete(buckets, oldestKey)
+}
+
+// cleanup removes the state that no longer has an effect so the memory use stays bounded. It runs at most once per
+// cleanupInterval and must be called with the lock held.
+func (l *limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < cleanupInterval {
+		return
+	}
+	l.lastCleanup = now
+	for key, bucket := range l.perIP {
+		if bucket.idle(l.cfg.PerIP, now) {
+			delete(l.perIP, key)
+		}
+	}
+	for key, bucket := range l.perUser {
+		if bucket.idle(l.cfg.PerUser, now) {
+			delete(l.perUser, key)
+		}
+	}
+	for key, failures := range l.failures {
+		if failures = expireFailures(failures, now.Add(-l.cfg.Ban.Window)); len(failures) == 0 {
+			delete(l.failures, key)
+		} else {
+			l.failures[key] = failures
+		}
+	}
+	for key, until := range l.bans {
+		if !now.Before(until) {
+			delete(l.bans, key)
+		}
+	}
+}
+
+func expireFailures(failures []time.Time, since time.Time) []time.Time {
+	for i, failure := range failures {
+		if failure.After(since) {
+			return failures[i:]
+		}
+	}
+	return nil
+}
+
+func burst(cfg config.RateLimitBucketConfig) float64 {
+	if cfg.Burst > 0 {
+		return float64(cfg.Burst)
+	}
+	return math.Max(1, math.Ceil(cfg.Rate))
+}
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+func (b *tokenBucket) refill(cfg config.RateLimitBucketConfig, now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(burst(cfg), b.tokens+now.Sub(b.last).Seconds()*cfg.Rate)
+		b.last = now
+	}
+}
+
+func (b *tokenBucket) available(cfg config.RateLimitBucketConfig, now time.Time) bool {
+	b.refill(cfg, now)
+	return b.tokens >= 1
+}
+
+func (b *tokenBucket) take() {
+	b.tokens--
+}
+
+// idle returns true if the bucket has no effect anymore because it is full, or has not been used for
+// bucketIdleTimeout.
+func (b *tokenBucket) idle(cfg config.RateLimitBucketConfig, now time.Time) bool {
+	if now.Sub(b.lastUsed) >= bucketIdleTimeout {
+		return true
+	}
+	b.refill(cfg, now)
+	return b.tokens >= burst(cfg)
+}
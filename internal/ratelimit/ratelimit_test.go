@@ -0,0 +1,128 @@
+package ratelimit //nolint:testpackage
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// testClock is a manually advanced clock so the tests do not depend on timing.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(cfg config.RateLimitConfig) (*limiter, *testClock) {
+	clock := &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(cfg).(*limiter)
+	l.now = clock.Now
+	return l, clock
+}
+
+func TestTokenRefill(t *testing.T) {
+	l, clock := newTestLimiter(config.RateLimitConfig{
+		PerIP: config.RateLimitBucketConfig{Rate: 1, Burst: 2},
+	})
+	ip := net.ParseIP("192.0.2.1")
+
+	assert.NoError(t, l.OnConnection(ip))
+	assert.NoError(t, l.OnConnection(ip))
+	assert.Error(t, l.OnConnection(ip))
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Error(t, l.OnConnection(ip), "half a token should not allow a connection")
+
+	clock.Advance(500 * time.Millisecond)
+	assert.NoError(t, l.OnConnection(ip))
+	assert.Error(t, l.OnConnection(ip))
+
+	clock.Advance(time.Hour)
+	assert.NoError(t, l.OnConnection(ip))
+	assert.NoError(t, l.OnConnection(ip))
+	assert.Error(t, l.OnConnection(ip), "the bucket should not be refilled beyond the burst")
+}
+
+func TestBanExpiry(t *testing.T) {
+	l, clock := newTestLimiter(config.RateLimitConfig{
+		Ban: config.RateLimitBanConfig{
+			MaxFailures: 2,
+			Window:      time.Minute,
+			Duration:    10 * time.Minute,
+		},
+	})
+	ip := net.ParseIP("192.0.2.1")
+
+	assert.NoError(t, l.OnAuthFailure(ip))
+	clock.Advance(2 * time.Minute)
+	assert.NoError(t, l.OnAuthFailure(ip), "failures outside the window should not be counted")
+	assert.Error(t, l.OnAuthFailure(ip))
+
+	assert.Error(t, l.OnConnection(ip))
+	assert.Error(t, l.OnAuthAttempt(ip, "foo"))
+	assert.NoError(t, l.OnConnection(net.ParseIP("192.0.2.2")))
+
+	clock.Advance(10 * time.Minute)
+	assert.NoError(t, l.OnConnection(ip))
+	assert.NoError(t, l.OnAuthAttempt(ip, "foo"))
+}
+
+func TestCleanup(t *testing.T) {
+	l, clock := newTestLimiter(config.RateLimitConfig{
+		PerIP:   config.RateLimitBucketConfig{Rate: 1, Burst: 1},
+		PerUser: config.RateLimitBucketConfig{Rate: 0.0001, Burst: 1},
+		Ban: config.RateLimitBanConfig{
+			MaxFailures: 1,
+			Window:      time.Minute,
+			Duration:    time.Minute,
+		},
+	})
+	ip := net.ParseIP("192.0.2.1")
+
+	assert.NoError(t, l.OnConnection(ip))
+	assert.NoError(t, l.OnAuthAttempt(ip, "foo"))
+	assert.Error(t, l.OnAuthFailure(net.ParseIP("192.0.2.2")))
+	assert.Len(t, l.perIP, 1)
+	assert.Len(t, l.perUser, 1)
+	assert.Len(t, l.bans, 1)
+
+	// The per-IP bucket is full again and the ban has expired, but the per-user bucket is still empty.
+	clock.Advance(2 * time.Minute)
+	l.cleanup(clock.Now())
+	assert.Len(t, l.perIP, 0)
+	assert.Len(t, l.perUser, 1)
+	assert.Len(t, l.bans, 0)
+
+	// Idle buckets are removed even if they have not been refilled.
+	clock.Advance(bucketIdleTimeout)
+	l.cleanup(clock.Now())
+	assert.Len(t, l.perUser, 0)
+}
+
+func TestBucketEviction(t *testing.T) {
+	l, clock := newTestLimiter(config.RateLimitConfig{
+		PerUser: config.RateLimitBucketConfig{Rate: 0.0001, Burst: 1},
+	})
+	l.maxBuckets = 3
+	ip := net.ParseIP("192.0.2.1")
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.OnAuthAttempt(ip, fmt.Sprintf("user%d", i)))
+		clock.Advance(time.Second)
+	}
+	assert.Len(t, l.perUser, 3)
+	for i := 7; i < 10; i++ {
+		assert.Contains(t, l.perUser, fmt.Sprintf("user%d", i), "the most recently used buckets should be kept")
+	}
+}
@@ -0,0 +1,19 @@
+package ratelimitintegration
+
+// MetricNameRejectedConnections is the number of connections rejected by the rate limiter.
+const MetricNameRejectedConnections = "containerssh_ratelimit_rejected_connections_total"
+
+// MetricHelpRejectedConnections is the help text for the number of connections rejected by the rate limiter.
+const MetricHelpRejectedConnections = "Number of connections rejected due to rate limits or bans"
+
+// MetricNameRejectedAuth is the number of authentication attempts rejected by the rate limiter.
+const MetricNameRejectedAuth = "containerssh_ratelimit_rejected_auth_total"
+
+// MetricHelpRejectedAuth is the help text for the number of authentication attempts rejected by the rate limiter.
+const MetricHelpRejectedAuth = "Number of authentication attempts rejected due to rate limits or bans"
+
+// MetricNameBans is the number of source IP addresses banned due to failed authentication attempts.
+const MetricNameBans = "containerssh_ratelimit_bans_total"
+
+// MetricHelpBans is the help text for the number of source IP addresses banned due to failed authentication attempts.
+const MetricHelpBans = "Number of bans issued due to failed authentication attempts"
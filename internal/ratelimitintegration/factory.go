@@ -0,0 +1,57 @@
+package ratelimitintegration
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/ratelimit"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+// New creates a rate limiting handler in front of the backend. Connections and authentication attempts that exceed
+// the configured limits are rejected before they reach the backend. If rate limiting is not enabled the backend is
+// returned directly.
+func New(
+	cfg config.RateLimitConfig,
+	backend sshserver.Handler,
+	logger log.Logger,
+	metricsCollector metrics.Collector,
+) (sshserver.Handler, error) {
+	if !cfg.Enable {
+		return backend, nil
+	}
+	return NewWithLimiter(cfg, ratelimit.New(cfg), backend, logger, metricsCollector), nil
+}
+
+// NewWithLimiter creates a rate limiting handler in front of the backend using an existing limiter created from the
+// same configuration. Handlers sharing a limiter share its limits and bans, so the limiter state survives when the
+// handler is recreated, for example on a configuration reload.
+func NewWithLimiter(
+	cfg config.RateLimitConfig,
+	limiter ratelimit.Limiter,
+	backend sshserver.Handler,
+	logger log.Logger,
+	metricsCollector metrics.Collector,
+) sshserver.Handler {
+	return &handler{
+		cfg:     cfg,
+		backend: backend,
+		limiter: limiter,
+		logger:  logger,
+		rejectedConnectionsMetric: metricsCollector.MustCreateCounterGeo(
+			MetricNameRejectedConnections,
+			"connections_total",
+			MetricHelpRejectedConnections,
+		),
+		rejectedAuthMetric: metricsCollector.MustCreateCounterGeo(
+			MetricNameRejectedAuth,
+			"attempts_total",
+			MetricHelpRejectedAuth,
+		),
+		bansMetric: metricsCollector.MustCreateCounterGeo(
+			MetricNameBans,
+			"bans_total",
+			MetricHelpBans,
+		),
+	}
+}
@@ -0,0 +1,169 @@
+package ratelimitintegration
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	auth2 "go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/ratelimit"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type handler struct {
+	cfg                       config.RateLimitConfig
+	backend                   sshserver.Handler
+	limiter                   ratelimit.Limiter
+	logger                    log.Logger
+	rejectedConnectionsMetric metrics.SimpleGeoCounter
+	rejectedAuthMetric        metrics.SimpleGeoCounter
+	bansMetric                metrics.SimpleGeoCounter
+}
+
+func (h *handler) OnReady() error {
+	return h.backend.OnReady()
+}
+
+func (h *handler) OnShutdown(shutdownContext context.Context) {
+	h.backend.OnShutdown(shutdownContext)
+}
+
+func (h *handler) OnNetworkConnection(
+	meta metadata.ConnectionMetadata,
+) (sshserver.NetworkConnectionHandler, metadata.ConnectionMetadata, error) {
+	if err := h.limiter.OnConnection(meta.RemoteAddress.IP); err != nil {
+		h.rejectedConnectionsMetric.Increment(meta.RemoteAddress.IP)
+		var msg message.Message
+		if errors.As(err, &msg) && msg.Code() == message.ERateLimitConnectionBanned {
+			// Rejections due to a ban are logged so repeated attempts from a banned address remain visible.
+			h.logger.WithLabel("remoteAddr", meta.RemoteAddress.IP.String()).Notice(err)
+		}
+		return nil, meta, err
+	}
+	networkBackend, meta, err := h.backend.OnNetworkConnection(meta)
+	if err != nil {
+		return networkBackend, meta, err
+	}
+	return &networkHandler{
+		backend:    networkBackend,
+		client:     net.TCPAddr(meta.RemoteAddress),
+		handler:    h,
+		logger:     h.logger.WithLabel("connectionId", meta.ConnectionID),
+		failedKeys: map[string]struct{}{},
+	}, meta, nil
+}
+
+type networkHandler struct {
+	backend    sshserver.NetworkConnectionHandler
+	client     net.TCPAddr
+	handler    *handler
+	logger     log.Logger
+	failedKeys map[string]struct{}
+}
+
+func (n *networkHandler) OnShutdown(shutdownContext context.Context) {
+	n.backend.OnShutdown(shutdownContext)
+}
+
+func (n *networkHandler) OnAuthPassword(meta metadata.ConnectionAuthPendingMetadata, password []byte) (
+	sshserver.AuthResponse,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	if err := n.checkAttempt(meta.Username); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMetadata, reason := n.backend.OnAuthPassword(meta, password)
+	return response, authenticatedMetadata, n.recordResult(response, reason)
+}
+
+func (n *networkHandler) OnAuthPubKey(meta metadata.ConnectionAuthPendingMetadata, pubKey auth2.PublicKey) (
+	sshserver.AuthResponse,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	if err := n.checkAttempt(meta.Username); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMetadata, reason := n.backend.OnAuthPubKey(meta, pubKey)
+	if response != sshserver.AuthResponseFailure || n.handler.cfg.Ban.MaxPublicKeys == 0 {
+		return response, authenticatedMetadata, reason
+	}
+	// Clients routinely offer several keys before finding the right one, so only the keys beyond the configured
+	// number count as failures.
+	n.failedKeys[pubKey.PublicKey] = struct{}{}
+	if uint(len(n.failedKeys)) <= n.handler.cfg.Ban.MaxPublicKeys {
+		return response, authenticatedMetadata, reason
+	}
+	return response, authenticatedMetadata, n.recordResult(response, reason)
+}
+
+func (n *networkHandler) OnAuthKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	challenge func(
+		instruction string,
+		questions sshserver.KeyboardInteractiveQuestions,
+	) (answers sshserver.KeyboardInteractiveAnswers, err error),
+) (
+	sshserver.AuthResponse,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	if err := n.checkAttempt(meta.Username); err != nil {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), err
+	}
+	response, authenticatedMetadata, reason := n.backend.OnAuthKeyboardInteractive(meta, challenge)
+	return response, authenticatedMetadata, n.recordResult(response, reason)
+}
+
+func (n *networkHandler) OnAuthGSSAPI(meta metadata.ConnectionMetadata) auth.GSSAPIServer {
+	return n.backend.OnAuthGSSAPI(meta)
+}
+
+func (n *networkHandler) OnHandshakeFailed(meta metadata.ConnectionMetadata, reason error) {
+	n.backend.OnHandshakeFailed(meta, reason)
+}
+
+func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	sshserver.SSHConnectionHandler,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	return n.backend.OnHandshakeSuccess(meta)
+}
+
+func (n *networkHandler) OnDisconnect() {
+	n.backend.OnDisconnect()
+}
+
+func (n *networkHandler) checkAttempt(username string) error {
+	if err := n.handler.limiter.OnAuthAttempt(n.client.IP, username); err != nil {
+		n.handler.rejectedAuthMetric.Increment(n.client.IP)
+		return err
+	}
+	return nil
+}
+
+// recordResult records a failed authentication with the limiter. If the failure results in a ban the reason is
+// wrapped in the ban message so the layers above can record the ban.
+func (n *networkHandler) recordResult(response sshserver.AuthResponse, reason error) error {
+	if response != sshserver.AuthResponseFailure {
+		return reason
+	}
+	banErr := n.handler.limiter.OnAuthFailure(n.client.IP)
+	if banErr == nil {
+		return reason
+	}
+	n.handler.bansMetric.Increment(n.client.IP)
+	n.logger.Notice(banErr)
+	if reason == nil {
+		return banErr
+	}
+	return message.Wrap(reason, message.ERateLimitBanned, "%s", banErr.Error())
+}
@@ -0,0 +1,263 @@
+package ratelimitintegration_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/ratelimitintegration"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestConnectionRateLimit(t *testing.T) {
+	metricsCollector := metrics.New(dummy.New())
+	handler := newTestHandler(
+		t, config.RateLimitConfig{
+			Enable: true,
+			PerIP: config.RateLimitBucketConfig{
+				Rate:  10,
+				Burst: 2,
+			},
+			Global: config.RateLimitBucketConfig{
+				Rate:  10,
+				Burst: 3,
+			},
+		}, metricsCollector,
+	)
+
+	_, err := connect(handler, "127.0.0.1")
+	assert.NoError(t, err)
+	_, err = connect(handler, "127.0.0.1")
+	assert.NoError(t, err)
+	_, err = connect(handler, "127.0.0.1")
+	assertMessageCode(t, err, message.ERateLimitConnectionRejected)
+
+	// The per-IP limit of the other address is not exhausted, but the global limit is after this connection.
+	_, err = connect(handler, "127.0.0.2")
+	assert.NoError(t, err)
+	_, err = connect(handler, "127.0.0.2")
+	assertMessageCode(t, err, message.ERateLimitConnectionRejected)
+
+	assert.Equal(
+		t,
+		float64(2),
+		metricsCollector.GetMetric(ratelimitintegration.MetricNameRejectedConnections)[0].Value,
+	)
+
+	// Buckets are refilled over time.
+	time.Sleep(200 * time.Millisecond)
+	_, err = connect(handler, "127.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestUserRateLimit(t *testing.T) {
+	metricsCollector := metrics.New(dummy.New())
+	handler := newTestHandler(
+		t, config.RateLimitConfig{
+			Enable: true,
+			PerUser: config.RateLimitBucketConfig{
+				Rate:  0.001,
+				Burst: 2,
+			},
+		}, metricsCollector,
+	)
+
+	for i, ip := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		networkHandler, err := connect(handler, ip)
+		if !assert.NoError(t, err) {
+			return
+		}
+		response, _, err := authPassword(networkHandler, ip, "foo", "bar")
+		if i < 2 {
+			assert.Equal(t, sshserver.AuthResponseSuccess, response)
+			assert.NoError(t, err)
+		} else {
+			assert.Equal(t, sshserver.AuthResponseFailure, response)
+			assertMessageCode(t, err, message.ERateLimitAuthRejected)
+		}
+	}
+
+	networkHandler, err := connect(handler, "127.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	response, _, err := authPassword(networkHandler, "127.0.0.1", "baz", "bar")
+	assert.Equal(t, sshserver.AuthResponseFailure, response)
+	assert.NotNil(t, err)
+	assert.False(t, hasMessageCode(err, message.ERateLimitAuthRejected))
+
+	assert.Equal(t, float64(1), metricsCollector.GetMetric(ratelimitintegration.MetricNameRejectedAuth)[0].Value)
+}
+
+func TestBan(t *testing.T) {
+	metricsCollector := metrics.New(dummy.New())
+	handler := newTestHandler(
+		t, config.RateLimitConfig{
+			Enable: true,
+			Ban: config.RateLimitBanConfig{
+				MaxFailures: 3,
+				Window:      time.Minute,
+				Duration:    300 * time.Millisecond,
+			},
+		}, metricsCollector,
+	)
+
+	networkHandler, err := connect(handler, "127.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 2; i++ {
+		response, _, err := authPassword(networkHandler, "127.0.0.1", "foo", "invalid")
+		assert.Equal(t, sshserver.AuthResponseFailure, response)
+		assert.False(t, hasMessageCode(err, message.ERateLimitBanned))
+	}
+	response, _, err := authPassword(networkHandler, "127.0.0.1", "foo", "invalid")
+	assert.Equal(t, sshserver.AuthResponseFailure, response)
+	assertMessageCode(t, err, message.ERateLimitBanned)
+
+	// The ban applies to the already open connection with the correct password too.
+	response, _, err = authPassword(networkHandler, "127.0.0.1", "foo", "bar")
+	assert.Equal(t, sshserver.AuthResponseFailure, response)
+	assertMessageCode(t, err, message.ERateLimitAuthRejected)
+
+	_, err = connect(handler, "127.0.0.1")
+	assertMessageCode(t, err, message.ERateLimitConnectionBanned)
+
+	// Other addresses are not affected.
+	otherHandler, err := connect(handler, "127.0.0.2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	response, _, err = authPassword(otherHandler, "127.0.0.2", "foo", "bar")
+	assert.Equal(t, sshserver.AuthResponseSuccess, response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(1), metricsCollector.GetMetric(ratelimitintegration.MetricNameBans)[0].Value)
+
+	time.Sleep(400 * time.Millisecond)
+	networkHandler, err = connect(handler, "127.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	response, _, err = authPassword(networkHandler, "127.0.0.1", "foo", "bar")
+	assert.Equal(t, sshserver.AuthResponseSuccess, response)
+	assert.NoError(t, err)
+}
+
+func TestPublicKeyBan(t *testing.T) {
+	metricsCollector := metrics.New(dummy.New())
+	handler := newTestHandler(
+		t, config.RateLimitConfig{
+			Enable: true,
+			Ban: config.RateLimitBanConfig{
+				MaxFailures:   2,
+				MaxPublicKeys: 2,
+				Window:        time.Minute,
+				Duration:      time.Minute,
+			},
+		}, metricsCollector,
+	)
+
+	networkHandler, err := connect(handler, "127.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The first keys and repeated keys are not counted.
+	for _, key := range []string{"key1", "key2", "key1", "key2"} {
+		response, _, err := authPubKey(networkHandler, "127.0.0.1", "foo", key)
+		assert.Equal(t, sshserver.AuthResponseFailure, response)
+		assert.False(t, hasMessageCode(err, message.ERateLimitBanned))
+	}
+	response, _, err := authPubKey(networkHandler, "127.0.0.1", "foo", "key3")
+	assert.Equal(t, sshserver.AuthResponseFailure, response)
+	assert.False(t, hasMessageCode(err, message.ERateLimitBanned))
+	response, _, err = authPubKey(networkHandler, "127.0.0.1", "foo", "key4")
+	assert.Equal(t, sshserver.AuthResponseFailure, response)
+	assertMessageCode(t, err, message.ERateLimitBanned)
+
+	_, err = connect(handler, "127.0.0.1")
+	assertMessageCode(t, err, message.ERateLimitConnectionBanned)
+}
+
+func newTestHandler(
+	t *testing.T,
+	cfg config.RateLimitConfig,
+	metricsCollector metrics.Collector,
+) sshserver.Handler {
+	user := sshserver.NewTestUser("foo")
+	user.SetPassword("bar")
+	handler, err := ratelimitintegration.New(
+		cfg,
+		sshserver.NewTestAuthenticationHandler(sshserver.NewTestHandler(), user),
+		log.NewTestLogger(t),
+		metricsCollector,
+	)
+	assert.NoError(t, err)
+	return handler
+}
+
+func connect(handler sshserver.Handler, ip string) (sshserver.NetworkConnectionHandler, error) {
+	networkHandler, _, err := handler.OnNetworkConnection(connectionMetadata(ip))
+	return networkHandler, err
+}
+
+func authPassword(
+	networkHandler sshserver.NetworkConnectionHandler,
+	ip string,
+	username string,
+	password string,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	return networkHandler.OnAuthPassword(
+		connectionMetadata(ip).StartAuthentication("", username),
+		[]byte(password),
+	)
+}
+
+func authPubKey(
+	networkHandler sshserver.NetworkConnectionHandler,
+	ip string,
+	username string,
+	publicKey string,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	return networkHandler.OnAuthPubKey(
+		connectionMetadata(ip).StartAuthentication("", username),
+		auth.PublicKey{PublicKey: publicKey},
+	)
+}
+
+func connectionMetadata(ip string) metadata.ConnectionMetadata {
+	return metadata.ConnectionMetadata{
+		RemoteAddress: metadata.RemoteAddress(
+			net.TCPAddr{
+				IP:   net.ParseIP(ip),
+				Port: 2222,
+			},
+		),
+		ConnectionID: sshserver.GenerateConnectionID(),
+		Metadata:     map[string]metadata.Value{},
+		Environment:  map[string]metadata.Value{},
+		Files:        map[string]metadata.BinaryValue{},
+	}
+}
+
+func hasMessageCode(err error, code string) bool {
+	var msg message.Message
+	return errors.As(err, &msg) && msg.Code() == code
+}
+
+func assertMessageCode(t *testing.T, err error, code string) {
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.True(t, hasMessageCode(err, code), "expected message code %s, got: %v", code, err)
+}
@@ -0,0 +1,115 @@
+package restart
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/internal/socket"
+)
+
+// readyFDEnv is the environment variable containing the file descriptor the new process uses to report that it is
+// ready.
+const readyFDEnv = "CONTAINERSSH_RESTART_READY_FD"
+
+// Spawn starts a new instance of the current executable with the same arguments and hands over all open listening
+// sockets to it. It returns once the new process has reported that it is ready, which it does by calling NotifyReady.
+// If the new process exits or does not become ready within readyTimeout, it is stopped and an error is returned. The
+// sockets remain open in this process either way, so it is up to the caller to stop accepting connections.
+func Spawn(readyTimeout time.Duration) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine the current executable (%w)", err)
+	}
+	files, names, err := socket.HandoffFiles()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create the readiness pipe (%w)", err)
+	}
+	defer func() {
+		_ = readyReader.Close()
+	}()
+
+	cmd := exec.Command(executable, os.Args[1:]...) //nolint:gosec // Re-executing ourselves is intended.
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyWriter)
+	cmd.Env = append(
+		socket.HandoffEnvironment(os.Environ(), names),
+		readyFDEnv+"="+strconv.Itoa(3+len(files)),
+	)
+	err = cmd.Start()
+	// The write end of the pipe must only be open in the new process, otherwise its exit would not be noticed.
+	_ = readyWriter.Close()
+	if err != nil {
+		return fmt.Errorf("failed to start %s (%w)", executable, err)
+	}
+
+	if err := waitReady(readyReader, readyTimeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	socket.HandedOff()
+	// Reap the new process should it exit while this one is still draining.
+	go func() {
+		_ = cmd.Wait()
+	}()
+	return nil
+}
+
+func waitReady(readyReader *os.File, readyTimeout time.Duration) error {
+	if err := readyReader.SetReadDeadline(time.Now().Add(readyTimeout)); err != nil {
+		return fmt.Errorf("failed to set the readiness timeout (%w)", err)
+	}
+	buf := make([]byte, 1)
+	if _, err := readyReader.Read(buf); err != nil {
+		switch {
+		case errors.Is(err, io.EOF):
+			return fmt.Errorf("the new process exited before it became ready")
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return fmt.Errorf("the new process did not become ready in %s", readyTimeout)
+		default:
+			return fmt.Errorf("failed to read the readiness of the new process (%w)", err)
+		}
+	}
+	return nil
+}
+
+// NotifyReady reports to the previous process that this process has started up after a graceful restart. It does
+// nothing if the process was not started by Spawn.
+func NotifyReady() error {
+	fdString := os.Getenv(readyFDEnv)
+	if fdString == "" {
+		return nil
+	}
+	_ = os.Unsetenv(readyFDEnv)
+	fd, err := strconv.Atoi(fdString)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", readyFDEnv, fdString)
+	}
+	readyFile := os.NewFile(uintptr(fd), "ready")
+	if readyFile == nil {
+		return fmt.Errorf("invalid %s: %s", readyFDEnv, fdString)
+	}
+	defer func() {
+		_ = readyFile.Close()
+	}()
+	if _, err := readyFile.Write([]byte{1}); err != nil {
+		return fmt.Errorf("failed to notify the previous process (%w)", err)
+	}
+	return nil
+}
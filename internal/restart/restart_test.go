@@ -0,0 +1,98 @@
+package restart_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/internal/restart"
+	"go.containerssh.io/libcontainerssh/internal/socket"
+	"go.containerssh.io/libcontainerssh/internal/test"
+)
+
+const (
+	childListenEnv = "RESTART_TEST_LISTEN"
+	childModeEnv   = "RESTART_TEST_MODE"
+)
+
+// TestMain runs the test binary as the new process when it is started by restart.Spawn from the tests below.
+func TestMain(m *testing.M) {
+	if listen := os.Getenv(childListenEnv); listen != "" {
+		os.Exit(runChild(listen, os.Getenv(childModeEnv)))
+	}
+	os.Exit(m.Run())
+}
+
+func runChild(listen string, mode string) int {
+	if mode == "fail" {
+		return 1
+	}
+	listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, listen, "")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to listen: %v\n", err)
+		return 1
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	if err := restart.NotifyReady(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to notify: %v\n", err)
+		return 1
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		return 1
+	}
+	_, _ = conn.Write([]byte("new"))
+	_ = conn.Close()
+	return 0
+}
+
+func TestSpawnHandsOverListener(t *testing.T) {
+	listen := fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "restart"))
+	listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, listen, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Setenv(childListenEnv, listen)
+
+	if !assert.NoError(t, restart.Spawn(30*time.Second)) {
+		_ = listener.Close()
+		return
+	}
+	// The old process stops accepting, the socket stays open in the new one.
+	assert.NoError(t, listener.Close())
+
+	conn, err := net.Dial("tcp", listen)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	data, err := io.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestSpawnFailure(t *testing.T) {
+	listen := fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "restart"))
+	listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, listen, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	t.Setenv(childListenEnv, listen)
+	t.Setenv(childModeEnv, "fail")
+
+	assert.Error(t, restart.Spawn(30*time.Second))
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"time"
+)
+
+// MetricNameCircuitBreakerState is the current state of each circuit breaker: 0 closed, 1 half-open, 2 open.
+const MetricNameCircuitBreakerState = "containerssh_circuit_breaker_state"
+
+// MetricNameCircuitBreakerRejected is the number of requests the circuit breakers failed without sending them.
+const MetricNameCircuitBreakerRejected = "containerssh_circuit_breaker_rejected_requests_total"
+
+// Backoff decides if and when a failed request is attempted again.
+type Backoff interface {
+	// ShouldRetry returns true if another attempt is allowed after the specified number of failed attempts.
+	ShouldRetry(attempts int) bool
+	// Delay returns the time to wait before the next attempt after the specified number of failed attempts. The delay
+	// grows exponentially with the attempts and is randomized by the configured jitter.
+	Delay(attempts int) time.Duration
+}
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// StateClosed means the requests are sent to the server.
+	StateClosed State = 0
+	// StateHalfOpen means a single trial request is being sent to the server after the open duration has passed.
+	// Other requests fail until the result of the trial request is known.
+	StateHalfOpen State = 1
+	// StateOpen means the server is considered unhealthy and requests fail without being sent.
+	StateOpen State = 2
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker keeps track of the consecutive failed requests to a server and fails requests immediately while the
+// server is considered unhealthy.
+type CircuitBreaker interface {
+	// Allow returns an error with the message.ECircuitBreakerOpen code if the request must not be sent. Otherwise, the
+	// result of the request must be reported by calling Success or Failure.
+	Allow() error
+	// Success records a successful request and closes the circuit breaker.
+	Success()
+	// Failure records a failed request and opens the circuit breaker if the failure threshold has been reached.
+	Failure()
+	// State returns the current state of the circuit breaker.
+	State() State
+}
@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+// NewBackoff creates a backoff from the retry configuration. If the configuration is empty, the defaults apply.
+func NewBackoff(cfg config.WebhookRetryConfig) Backoff {
+	if cfg == (config.WebhookRetryConfig{}) {
+		structutils.Defaults(&cfg)
+	}
+	return &backoff{
+		cfg:    cfg,
+		random: rand.Float64,
+	}
+}
+
+// NewCircuitBreaker creates a circuit breaker for the server identified by name. The name is used in the logs and as
+// the backend label of the metrics. If the circuit breaker is disabled, all requests are allowed.
+func NewCircuitBreaker(
+	name string,
+	cfg config.CircuitBreakerConfig,
+	logger log.Logger,
+	metricsCollector metrics.Collector,
+) CircuitBreaker {
+	stateMetric := metricsCollector.MustCreateGauge(
+		MetricNameCircuitBreakerState,
+		"state",
+		"The state of the circuit breaker of each webhook server, 0 closed, 1 half-open, 2 open.",
+	)
+	rejectedMetric := metricsCollector.MustCreateCounter(
+		MetricNameCircuitBreakerRejected,
+		"requests_total",
+		"The number of requests failed by an open circuit breaker without being sent.",
+	)
+	// A circuit breaker replacing an earlier one with the same name, for example after a configuration reload, starts
+	// closed.
+	stateMetric.Set(float64(StateClosed), metrics.Label("backend", name))
+	if !cfg.Enable {
+		return &disabledCircuitBreaker{}
+	}
+	return &circuitBreaker{
+		name:           name,
+		cfg:            cfg,
+		logger:         logger.WithLabel("backend", name),
+		now:            time.Now,
+		stateMetric:    stateMetric,
+		rejectedMetric: rejectedMetric,
+	}
+}
+
+// OpenCircuitBreakers returns the names of the circuit breakers that are currently not closed, as recorded in the
+// metrics. It is used to report the state of the webhook servers in the health check.
+func OpenCircuitBreakers(metricsCollector metrics.Collector) []string {
+	var result []string
+	for _, value := range metricsCollector.GetMetric(MetricNameCircuitBreakerState) {
+		if State(value.Value) != StateClosed {
+			result = append(result, value.Labels["backend"])
+		}
+	}
+	return result
+}
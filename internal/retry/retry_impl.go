@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+type backoff struct {
+	cfg    config.WebhookRetryConfig
+	random func() float64
+}
+
+func (b *backoff) ShouldRetry(attempts int) bool {
+	return b.cfg.MaxAttempts == 0 || attempts < b.cfg.MaxAttempts
+}
+
+func (b *backoff) Delay(attempts int) time.Duration {
+	delay := float64(b.cfg.InitialBackoff) * math.Pow(b.cfg.Multiplier, float64(max(attempts-1, 0)))
+	delay = math.Min(delay, float64(b.cfg.MaxBackoff))
+	// The jitter spreads the delay evenly around the calculated value.
+	delay *= 1 + b.cfg.Jitter*(2*b.random()-1)
+	return time.Duration(delay)
+}
+
+type circuitBreaker struct {
+	name           string
+	cfg            config.CircuitBreakerConfig
+	logger         log.Logger
+	now            func() time.Time
+	stateMetric    metrics.Gauge
+	rejectedMetric metrics.Counter
+
+	lock     sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+func (c *circuitBreaker) Allow() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch c.state {
+	case StateOpen:
+		if c.now().Sub(c.openedAt) >= c.cfg.OpenDuration {
+			// Let a single trial request through to find out if the server has recovered.
+			c.setState(StateHalfOpen)
+			return nil
+		}
+	case StateHalfOpen:
+	default:
+		return nil
+	}
+	c.rejectedMetric.Increment(metrics.Label("backend", c.name))
+	return message.UserMessage(
+		message.ECircuitBreakerOpen,
+		"Cannot authenticate at this time.",
+		"The circuit breaker of %s is open after %d consecutive failed requests, not sending request",
+		c.name,
+		c.failures,
+	)
+}
+
+func (c *circuitBreaker) Success() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.failures = 0
+	if c.state != StateClosed {
+		c.logger.Info(
+			message.NewMessage(
+				message.MCircuitBreakerClosed,
+				"The circuit breaker of %s has closed, the server is responding again",
+				c.name,
+			),
+		)
+		c.setState(StateClosed)
+	}
+}
+
+func (c *circuitBreaker) Failure() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.failures++
+	if c.state == StateHalfOpen || (c.state == StateClosed && c.failures >= c.cfg.FailureThreshold) {
+		c.logger.Warning(
+			message.NewMessage(
+				message.ECircuitBreakerOpened,
+				"The circuit breaker of %s has opened after %d consecutive failed requests, failing requests for %s",
+				c.name,
+				c.failures,
+				c.cfg.OpenDuration,
+			),
+		)
+		c.openedAt = c.now()
+		c.setState(StateOpen)
+	}
+}
+
+func (c *circuitBreaker) State() State {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state
+}
+
+func (c *circuitBreaker) setState(state State) {
+	c.state = state
+	c.stateMetric.Set(float64(state), metrics.Label("backend", c.name))
+}
+
+type disabledCircuitBreaker struct {
+}
+
+func (d *disabledCircuitBreaker) Allow() error {
+	return nil
+}
+
+func (d *disabledCircuitBreaker) Success() {
+}
+
+func (d *disabledCircuitBreaker) Failure() {
+}
+
+func (d *disabledCircuitBreaker) State() State {
+	return StateClosed
+}
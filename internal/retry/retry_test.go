@@ -0,0 +1,114 @@
+package retry //nolint:testpackage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(
+		config.WebhookRetryConfig{
+			MaxAttempts:    4,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxBackoff:     time.Second,
+			Multiplier:     3,
+		},
+	)
+	assert.Equal(t, 100*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 300*time.Millisecond, b.Delay(2))
+	assert.Equal(t, 900*time.Millisecond, b.Delay(3))
+	assert.Equal(t, time.Second, b.Delay(4))
+	assert.True(t, b.ShouldRetry(3))
+	assert.False(t, b.ShouldRetry(4))
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewBackoff(
+		config.WebhookRetryConfig{
+			InitialBackoff: time.Second,
+			MaxBackoff:     time.Second,
+			Multiplier:     1,
+			Jitter:         0.5,
+		},
+	).(*backoff)
+	b.random = func() float64 { return 0 }
+	assert.Equal(t, 500*time.Millisecond, b.Delay(1))
+	b.random = func() float64 { return 0.999 }
+	assert.InDelta(t, float64(1500*time.Millisecond), float64(b.Delay(1)), float64(time.Millisecond))
+	assert.True(t, b.ShouldRetry(100), "0 attempts should retry until the timeout")
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	b := NewBackoff(config.WebhookRetryConfig{}).(*backoff)
+	b.random = func() float64 { return 0.5 }
+	assert.Equal(t, 500*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 10*time.Second, b.Delay(10))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	collector := metrics.New(dummy.New())
+	breaker := NewCircuitBreaker(
+		"test",
+		config.CircuitBreakerConfig{
+			Enable:           true,
+			FailureThreshold: 2,
+			OpenDuration:     time.Minute,
+		},
+		log.NewTestLogger(t),
+		collector,
+	).(*circuitBreaker)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Failure()
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	breaker.Failure()
+	assert.Equal(t, StateClosed, breaker.State(), "a success should reset the failures")
+	breaker.Failure()
+	assert.Equal(t, StateOpen, breaker.State())
+	assert.Equal(t, []string{"test"}, OpenCircuitBreakers(collector))
+
+	var typedErr message.Message
+	if assert.True(t, errors.As(breaker.Allow(), &typedErr)) {
+		assert.Equal(t, message.ECircuitBreakerOpen, typedErr.Code())
+	}
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow(), "a trial request should be allowed after the open duration")
+	assert.Equal(t, StateHalfOpen, breaker.State())
+	assert.Error(t, breaker.Allow(), "only one trial request should be allowed")
+	breaker.Failure()
+	assert.Equal(t, StateOpen, breaker.State(), "a failed trial request should open the circuit breaker again")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	assert.Equal(t, StateClosed, breaker.State())
+	assert.Empty(t, OpenCircuitBreakers(collector))
+	assert.Equal(t, float64(2), collector.GetMetric(MetricNameCircuitBreakerRejected)[0].Value)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := NewCircuitBreaker(
+		"test",
+		config.CircuitBreakerConfig{},
+		log.NewTestLogger(t),
+		metrics.New(dummy.New()),
+	)
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	assert.NoError(t, breaker.Allow())
+	assert.Equal(t, StateClosed, breaker.State())
+}
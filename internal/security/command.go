@@ -0,0 +1,63 @@
+package security
+
+import (
+	"regexp"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// findCommandRule returns the first rule matching the arguments, or nil if no rule matches.
+func findCommandRule(rules []config.CommandRule, argv []string) *config.CommandRule {
+	for i := range rules {
+		if matchCommandRule(rules[i], argv) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func matchCommandRule(rule config.CommandRule, argv []string) bool {
+	patterns := rule.Args
+	if len(patterns) > 0 && patterns[len(patterns)-1] == "**" {
+		patterns = patterns[:len(patterns)-1]
+		if len(argv) < len(patterns) {
+			return false
+		}
+	} else if len(argv) != len(patterns) {
+		return false
+	}
+	for i, pattern := range patterns {
+		expression, err := config.CompileCommandArgPattern(pattern)
+		// The configuration has been validated, so compiling cannot fail.
+		if err != nil || !expression.MatchString(argv[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var rewriteArgument = regexp.MustCompile(`\$[0-9]`)
+
+// rewriteCommand creates the arguments of the rewritten command from the template of the rule by replacing $@ with
+// all arguments and $0 to $9 with the single arguments of the original command.
+func rewriteCommand(template []string, argv []string) []string {
+	var result []string
+	for _, part := range template {
+		if part == "$@" {
+			result = append(result, argv...)
+			continue
+		}
+		result = append(
+			result, rewriteArgument.ReplaceAllStringFunc(
+				part, func(placeholder string) string {
+					index := int(placeholder[1] - '0')
+					if index < len(argv) {
+						return argv[index]
+					}
+					return ""
+				},
+			),
+		)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package security //nolint:testpackage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+func TestCommandRules(t *testing.T) {
+	backend := &dummyBackend{}
+	session := &sessionHandler{
+		config: config.SecurityConfig{
+			Command: config.CommandConfig{
+				Mode:  config.ExecutionPolicyFilter,
+				Allow: []string{"/usr/bin/uptime"},
+				Rules: []config.CommandRule{
+					{
+						Name:   "no-private-repos",
+						Action: config.CommandActionDeny,
+						Args:   []string{"git-*", "private/*"},
+					},
+					{
+						Name:   "git",
+						Action: config.CommandActionAllow,
+						Args:   []string{"git-upload-pack", `re:[a-z0-9/_-]+\.git`},
+					},
+					{
+						Name:    "rsync",
+						Action:  config.CommandActionRewrite,
+						Args:    []string{"rsync", "--server", "**"},
+						Rewrite: []string{"/usr/local/bin/rsync-wrapper", "$@"},
+					},
+					{
+						Name:    "receive-pack",
+						Action:  config.CommandActionRewrite,
+						Args:    []string{"git-receive-pack", "*"},
+						Rewrite: []string{"/usr/bin/git-receive-pack", "/srv/git/$1"},
+					},
+				},
+			},
+		},
+		backend: backend,
+		sshConnection: &sshConnectionHandler{
+			lock: &sync.Mutex{},
+		},
+		logger: log.NewTestLogger(t),
+	}
+
+	assert.NoError(t, session.OnExecRequest(1, "git-upload-pack 'team/project.git'"))
+	assert.NoError(t, session.OnExecRequest(2, "rsync --server --sender -vlogDtpre.iLsfxC . 'my dir/'"))
+	assert.NoError(t, session.OnExecRequest(3, "git-receive-pack repo.git"))
+	assert.NoError(t, session.OnExecRequest(4, "/usr/bin/uptime"))
+	assert.Equal(
+		t,
+		[]string{
+			"git-upload-pack team/project.git",
+			"/usr/local/bin/rsync-wrapper rsync --server --sender -vlogDtpre.iLsfxC . 'my dir/'",
+			"/usr/bin/git-receive-pack /srv/git/repo.git",
+			"/usr/bin/uptime",
+		},
+		backend.commandsExecuted,
+	)
+
+	backend.commandsExecuted = nil
+	assert.Error(t, session.OnExecRequest(5, "git-upload-pack 'private/project.git'"))
+	assert.Error(t, session.OnExecRequest(6, "git-upload-pack 'project.git'; rm -rf /"))
+	assert.Error(t, session.OnExecRequest(7, "git-upload-pack '$(id).git'"))
+	assert.Error(t, session.OnExecRequest(8, "git-upload-pack a.git b.git"))
+	assert.Error(t, session.OnExecRequest(9, "/bin/bash"))
+	assert.Error(t, session.OnExecRequest(10, "echo 'unterminated"))
+	assert.Empty(t, backend.commandsExecuted)
+
+	session.config.Command.Mode = config.ExecutionPolicyEnable
+	assert.Error(t, session.OnExecRequest(11, "git-upload-pack private/project.git"))
+	assert.NoError(t, session.OnExecRequest(12, "echo 'hello world'"))
+	assert.Equal(t, []string{"echo 'hello world'"}, backend.commandsExecuted)
+}
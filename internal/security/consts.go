@@ -0,0 +1,86 @@
+package security
+
+// ReasonIdle is the reason a session is closed when no data has been sent for longer than the idle timeout.
+const ReasonIdle = "idle"
+
+// ReasonSessionDuration is the reason a session is closed when it exceeds the maximum session duration.
+const ReasonSessionDuration = "session_duration"
+
+// ReasonConnectionDuration is the reason a connection is closed when it exceeds the maximum connection duration.
+const ReasonConnectionDuration = "connection_duration"
+
+// MetricLabelReason is the name of the label holding the reason of a policy-based disconnect.
+const MetricLabelReason = "reason"
+
+// MetricNamePolicyDisconnects is the number of sessions and connections closed because of a timeout policy.
+const MetricNamePolicyDisconnects = "containerssh_security_policy_disconnects_total"
+
+// MetricUnitPolicyDisconnects is the unit of the policy-based disconnects.
+const MetricUnitPolicyDisconnects = "disconnects_total"
+
+// MetricHelpPolicyDisconnects is the help text of the policy-based disconnects.
+const MetricHelpPolicyDisconnects = "The number of sessions and connections closed because of a timeout policy."
+
+// MetricLabelRule is the name of the label holding the rule that would have rejected a request in dry run mode.
+const MetricLabelRule = "rule"
+
+// MetricNameDryRunRejections is the number of requests that would have been rejected in dry run mode.
+const MetricNameDryRunRejections = "containerssh_security_dry_run_rejections_total"
+
+// MetricUnitDryRunRejections is the unit of the dry run rejections.
+const MetricUnitDryRunRejections = "rejections_total"
+
+// MetricHelpDryRunRejections is the help text of the dry run rejections.
+const MetricHelpDryRunRejections = "The number of requests that would have been rejected by the security settings in dry run mode."
+
+// RuleEnv is the rule rejecting environment variables.
+const RuleEnv = "env"
+
+// RuleTTY is the rule rejecting TTY requests.
+const RuleTTY = "tty"
+
+// RuleCommand is the rule rejecting commands. Commands rejected by a command rule are labeled with command/ followed
+// by the name of the rule.
+const RuleCommand = "command"
+
+// RuleShell is the rule rejecting shell requests.
+const RuleShell = "shell"
+
+// RuleSubsystem is the rule rejecting subsystem requests.
+const RuleSubsystem = "subsystem"
+
+// RuleSignal is the rule rejecting signals.
+const RuleSignal = "signal"
+
+// RuleX11Forwarding is the rule rejecting X11 forwarding requests.
+const RuleX11Forwarding = "x11_forwarding"
+
+// RuleAgentForwarding is the rule rejecting agent forwarding requests.
+const RuleAgentForwarding = "agent_forwarding"
+
+// RuleForwarding is the rule rejecting port forwarding channels.
+const RuleForwarding = "forwarding"
+
+// RuleReverseForwarding is the rule rejecting reverse port forwarding requests.
+const RuleReverseForwarding = "reverse_forwarding"
+
+// RuleSocketForwarding is the rule rejecting socket forwarding channels.
+const RuleSocketForwarding = "socket_forwarding"
+
+// RuleSocketListen is the rule rejecting socket listen requests.
+const RuleSocketListen = "socket_listen"
+
+// RuleMaxSessions is the rule rejecting sessions over the maximum number of sessions.
+const RuleMaxSessions = "max_sessions"
+
+// RuleMaxForwardChannels is the rule rejecting forwarding channels over the maximum number of forwarding channels.
+const RuleMaxForwardChannels = "max_forward_channels"
+
+// RuleMaxReverseForwards is the rule rejecting reverse forwards over the maximum number of reverse forwards.
+const RuleMaxReverseForwards = "max_reverse_forwards"
+
+// RuleMaxX11Channels is the rule rejecting X11 channels over the maximum number of X11 channels.
+const RuleMaxX11Channels = "max_x11_channels"
+
+// RuleMaxConnectionsPerUser is the rule rejecting connections over the maximum number of connections of a user.
+const RuleMaxConnectionsPerUser = "max_connections_per_user"
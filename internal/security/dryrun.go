@@ -0,0 +1,38 @@
+package security
+
+import (
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// enforce decides whether a request rejected by rule is actually rejected. Outside dry run mode the rejection is
+// logged and true is returned. In dry run mode the rejection is logged, counted and recorded through the auditor, if
+// it implements sshserver.PolicyAuditor, and false is returned to let the request through.
+func enforce(
+	dryRun bool,
+	logger log.Logger,
+	counter metrics.Counter,
+	auditor interface{},
+	rule string,
+	err message.Message,
+) bool {
+	if !dryRun {
+		logger.Debug(err)
+		return true
+	}
+	logger.Notice(
+		message.NewMessage(
+			message.MSecurityDryRunRejection,
+			"Not enforcing rule %s in dry run mode: %s",
+			rule,
+			err.Explanation(),
+		).Label("rule", rule).Label("code", err.Code()),
+	)
+	counter.Increment(metrics.Label(MetricLabelRule, rule))
+	if policyAuditor, ok := auditor.(sshserver.PolicyAuditor); ok {
+		policyAuditor.AuditPolicyDryRun(rule, err.Explanation())
+	}
+	return false
+}
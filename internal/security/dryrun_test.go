@@ -0,0 +1,164 @@
+package security //nolint:testpackage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+func TestDryRunSession(t *testing.T) {
+	collector, connection := createDryRunConnection(t, config.SecurityConfig{
+		DryRun:    true,
+		Env:       config.SecurityEnvConfig{Deny: []string{"LD_PRELOAD"}},
+		TTY:       config.SecurityTTYConfig{Mode: config.ExecutionPolicyDisable},
+		Subsystem: config.SubsystemConfig{Mode: config.ExecutionPolicyDisable},
+		Command: config.CommandConfig{
+			Rules: []config.CommandRule{
+				{Name: "no-rm", Action: config.CommandActionDeny, Args: []string{"rm", "**"}},
+			},
+		},
+		MaxSessions: 1,
+	})
+	auditor := &dryRunAuditor{policySessionChannel: newPolicySessionChannel()}
+	backend := &dummyBackend{env: map[string]string{}}
+	session := &sessionHandler{
+		config:        connection.config,
+		backend:       backend,
+		session:       auditor,
+		sshConnection: connection,
+		logger:        log.NewTestLogger(t),
+	}
+
+	assert.NoError(t, session.OnEnvRequest(1, "LD_PRELOAD", "/tmp/evil.so"))
+	assert.NoError(t, session.OnPtyRequest(2, "xterm", 80, 25, 800, 600, []byte{}))
+	assert.NoError(t, session.OnExecRequest(3, "rm -rf /tmp/x"))
+	assert.NoError(t, session.OnSubsystem(4, "sftp"))
+	assert.Equal(t, "/tmp/evil.so", backend.env["LD_PRELOAD"])
+	assert.Equal(t, []string{"rm -rf /tmp/x", "sftp"}, backend.commandsExecuted)
+
+	assert.Equal(t, []string{RuleEnv, RuleTTY, RuleCommand + "/no-rm", RuleSubsystem}, auditor.rules)
+	assertDryRunRejections(t, collector, map[string]float64{
+		RuleEnv:                1,
+		RuleTTY:                1,
+		RuleCommand + "/no-rm": 1,
+		RuleSubsystem:          1,
+	})
+
+	// Sessions over the limit are let through as well.
+	for i := 0; i < 2; i++ {
+		_, err := connection.OnSessionChannel(createChannelMetadata(i), []byte{}, auditor)
+		assert.Nil(t, err)
+	}
+	assertDryRunRejections(t, collector, map[string]float64{
+		RuleEnv:                1,
+		RuleTTY:                1,
+		RuleCommand + "/no-rm": 1,
+		RuleSubsystem:          1,
+		RuleMaxSessions:        1,
+	})
+}
+
+func TestDryRunForwarding(t *testing.T) {
+	collector, connection := createDryRunConnection(t, config.SecurityConfig{
+		DryRun:             true,
+		MaxForwardChannels: -1,
+		MaxReverseForwards: -1,
+		Forwarding: config.ForwardingConfig{
+			ForwardingMode:        config.ExecutionPolicyDisable,
+			ReverseForwardingMode: config.ExecutionPolicyDisable,
+		},
+	})
+
+	_, err := connection.OnTCPForwardChannel(1, "example.com", 80, "127.0.0.1", 12345)
+	// The dummy backend rejects the channel itself, so the security layer has let it through.
+	assert.Equal(t, message.ESSHNotImplemented, err.Code())
+	assert.False(t, isSecurityRejection(connection.OnRequestTCPReverseForward("0.0.0.0", 8080, nil)))
+	assertDryRunRejections(t, collector, map[string]float64{
+		RuleForwarding:        1,
+		RuleReverseForwarding: 1,
+	})
+
+	connection.config.DryRun = false
+	_, err = connection.OnTCPForwardChannel(2, "example.com", 80, "127.0.0.1", 12345)
+	assert.Equal(t, message.ESecurityForwardingRejected, err.Code())
+	assertDryRunRejections(t, collector, map[string]float64{
+		RuleForwarding:        1,
+		RuleReverseForwarding: 1,
+	})
+}
+
+func TestDryRunTimeout(t *testing.T) {
+	collector, connection := createDryRunConnection(t, config.SecurityConfig{
+		DryRun:      true,
+		MaxSessions: -1,
+		Timeouts: config.SecurityTimeoutConfig{
+			MaxSessionDuration: 100 * time.Millisecond,
+		},
+	})
+	connection.disconnectCounter = collector.MustCreateCounter(
+		MetricNamePolicyDisconnects,
+		MetricUnitPolicyDisconnects,
+		MetricHelpPolicyDisconnects,
+	)
+	auditor := &dryRunAuditor{policySessionChannel: newPolicySessionChannel()}
+	handler, err := connection.OnSessionChannel(createChannelMetadata(0), []byte{}, auditor)
+	assert.Nil(t, err)
+	assert.NoError(t, handler.OnShell(0))
+
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, auditor.isClosed(), "the session was closed in dry run mode")
+	assert.Equal(t, []string{ReasonSessionDuration}, auditor.getRules())
+	assert.Len(t, collector.GetMetric(MetricNamePolicyDisconnects), 0)
+	assertDryRunRejections(t, collector, map[string]float64{ReasonSessionDuration: 1})
+	handler.OnClose()
+}
+
+func createDryRunConnection(t *testing.T, cfg config.SecurityConfig) (metrics.Collector, *sshConnectionHandler) {
+	collector := metrics.New(dummy.New())
+	return collector, &sshConnectionHandler{
+		config:      cfg,
+		backend:     &dummySSHBackend{},
+		lock:        &sync.Mutex{},
+		logger:      log.NewTestLogger(t),
+		connectedAt: time.Now(),
+		dryRunCounter: collector.MustCreateCounter(
+			MetricNameDryRunRejections,
+			MetricUnitDryRunRejections,
+			MetricHelpDryRunRejections,
+		),
+	}
+}
+
+func assertDryRunRejections(t *testing.T, collector metrics.Collector, expected map[string]float64) {
+	actual := map[string]float64{}
+	for _, value := range collector.GetMetric(MetricNameDryRunRejections) {
+		actual[value.Labels[MetricLabelRule]] = value.Value
+	}
+	assert.Equal(t, expected, actual)
+}
+
+// dryRunAuditor is a session channel that records the rules reported through the sshserver.PolicyAuditor interface.
+type dryRunAuditor struct {
+	*policySessionChannel
+
+	rules []string
+}
+
+func (d *dryRunAuditor) AuditPolicyDryRun(rule string, _ string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.rules = append(d.rules, rule)
+}
+
+func (d *dryRunAuditor) getRules() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.rules
+}
@@ -0,0 +1,97 @@
+package security
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// checkTargetFilter checks a forwarding host and port against the filter for the mode, which must not be disabled. It
+// returns an explanation for the logs if the target is rejected, or an empty string if it is allowed.
+func checkTargetFilter(
+	mode config.SecurityExecutionPolicy,
+	filter config.ForwardingTargetFilter,
+	host string,
+	port uint32,
+) string {
+	if mode == config.ExecutionPolicyFilter && !matchTargetRules(filter.Allow, host, port) {
+		return "it does not match the allow list"
+	}
+	if matchTargetRules(filter.Deny, host, port) {
+		return "it matches the deny list"
+	}
+	return ""
+}
+
+// checkPathFilter checks a unix socket path against the filter for the mode, which must not be disabled. It returns
+// an explanation for the logs if the path is rejected, or an empty string if it is allowed.
+func checkPathFilter(mode config.SecurityExecutionPolicy, filter config.ForwardingPathFilter, socketPath string) string {
+	socketPath = path.Clean(socketPath)
+	if mode == config.ExecutionPolicyFilter && !matchGlobs(filter.Allow, socketPath) {
+		return "it does not match the allow list"
+	}
+	if matchGlobs(filter.Deny, socketPath) {
+		return "it matches the deny list"
+	}
+	return ""
+}
+
+func matchTargetRules(rules []config.ForwardingTargetRule, host string, port uint32) bool {
+	for _, rule := range rules {
+		if matchTargetRule(rule, host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTargetRule(rule config.ForwardingTargetRule, host string, port uint32) bool {
+	return matchHost(rule, host) && matchPort(rule.Ports, port)
+}
+
+func matchHost(rule config.ForwardingTargetRule, host string) bool {
+	if len(rule.Hosts) == 0 && len(rule.Networks) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range rule.Networks {
+			ipNet, err := config.ParseCIDROrIP(network)
+			// The configuration has been validated, so parsing cannot fail.
+			if err == nil && ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, pattern := range rule.Hosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPort(ports []string, port uint32) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, portRange := range ports {
+		from, to, err := config.ParsePortRange(portRange)
+		if err == nil && port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlobs(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
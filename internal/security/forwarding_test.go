@@ -0,0 +1,120 @@
+package security //nolint:testpackage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+func TestTCPForwardingFilter(t *testing.T) {
+	handler := &sshConnectionHandler{
+		config: config.SecurityConfig{
+			MaxForwardChannels: -1,
+			MaxReverseForwards: -1,
+			Forwarding: config.ForwardingConfig{
+				ForwardingMode: config.ExecutionPolicyFilter,
+				TCPTargets: config.ForwardingTargetFilter{
+					Allow: []config.ForwardingTargetRule{
+						{Hosts: []string{"*.example.com"}, Ports: []string{"443", "8000-8999"}},
+						{Networks: []string{"10.0.0.0/8"}},
+					},
+					Deny: []config.ForwardingTargetRule{
+						{Hosts: []string{"admin.example.com"}},
+						{Networks: []string{"10.0.0.1"}, Ports: []string{"22"}},
+					},
+				},
+			},
+		},
+		backend: &dummySSHBackend{},
+		lock:    &sync.Mutex{},
+		logger:  log.NewTestLogger(t),
+	}
+
+	forward := func(host string, port uint32) string {
+		_, err := handler.OnTCPForwardChannel(1, host, port, "127.0.0.1", 12345)
+		if err == nil {
+			return ""
+		}
+		return err.Code()
+	}
+	assert.Equal(t, message.ESSHNotImplemented, forward("www.example.com", 443))
+	assert.Equal(t, message.ESSHNotImplemented, forward("WWW.Example.com", 8080))
+	assert.Equal(t, message.ESSHNotImplemented, forward("10.1.2.3", 5432))
+	assert.Equal(t, message.ESSHNotImplemented, forward("10.0.0.1", 80))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("www.example.com", 22))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("example.org", 443))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("admin.example.com", 443))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("10.0.0.1", 22))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("192.168.0.1", 443))
+
+	handler.config.Forwarding.ForwardingMode = config.ExecutionPolicyEnable
+	assert.Equal(t, message.ESSHNotImplemented, forward("example.org", 443))
+	assert.Equal(t, message.ESecurityForwardingRejected, forward("admin.example.com", 443))
+}
+
+func TestReverseForwardingFilter(t *testing.T) {
+	handler := &sshConnectionHandler{
+		config: config.SecurityConfig{
+			MaxForwardChannels: -1,
+			MaxReverseForwards: -1,
+			Forwarding: config.ForwardingConfig{
+				ReverseForwardingMode: config.ExecutionPolicyFilter,
+				ReverseBindAddresses: config.ForwardingTargetFilter{
+					Allow: []config.ForwardingTargetRule{
+						{Hosts: []string{"localhost"}, Networks: []string{"127.0.0.1"}, Ports: []string{"1024-65535"}},
+					},
+				},
+			},
+		},
+		backend: &dummySSHBackend{},
+		lock:    &sync.Mutex{},
+		logger:  log.NewTestLogger(t),
+	}
+
+	assert.False(t, isSecurityRejection(handler.OnRequestTCPReverseForward("localhost", 8080, nil)))
+	assert.False(t, isSecurityRejection(handler.OnRequestTCPReverseForward("127.0.0.1", 8080, nil)))
+	assert.True(t, isSecurityRejection(handler.OnRequestTCPReverseForward("0.0.0.0", 8080, nil)))
+	assert.True(t, isSecurityRejection(handler.OnRequestTCPReverseForward("localhost", 80, nil)))
+}
+
+func TestSocketForwardingFilter(t *testing.T) {
+	handler := &sshConnectionHandler{
+		config: config.SecurityConfig{
+			MaxForwardChannels: -1,
+			MaxReverseForwards: -1,
+			Forwarding: config.ForwardingConfig{
+				SocketForwardingMode: config.ExecutionPolicyFilter,
+				SocketListenMode:     config.ExecutionPolicyEnable,
+				SocketPaths: config.ForwardingPathFilter{
+					Allow: []string{"/tmp/*.sock"},
+					Deny:  []string{"/var/run/docker.sock", "/tmp/private.sock"},
+				},
+			},
+		},
+		backend: &dummySSHBackend{},
+		lock:    &sync.Mutex{},
+		logger:  log.NewTestLogger(t),
+	}
+
+	_, err := handler.OnDirectStreamLocal(1, "/tmp/app.sock")
+	assert.Equal(t, message.ESSHNotImplemented, err.Code())
+	_, err = handler.OnDirectStreamLocal(1, "/tmp/../tmp/app.sock")
+	assert.Equal(t, message.ESSHNotImplemented, err.Code())
+	_, err = handler.OnDirectStreamLocal(1, "/tmp/private.sock")
+	assert.Equal(t, message.ESecurityForwardingRejected, err.Code())
+	_, err = handler.OnDirectStreamLocal(1, "/var/run/app.sock")
+	assert.Equal(t, message.ESecurityForwardingRejected, err.Code())
+
+	assert.False(t, isSecurityRejection(handler.OnRequestStreamLocal("/var/run/app.sock", nil)))
+	assert.True(t, isSecurityRejection(handler.OnRequestStreamLocal("/var/run/docker.sock", nil)))
+}
+
+func isSecurityRejection(err error) bool {
+	var msg message.Message
+	return errors.As(err, &msg) && msg.Code() == message.ESecurityReverseForwardingRejected
+}
@@ -4,23 +4,33 @@ import (
 	"fmt"
 
     "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/internal/metrics"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/log"
 )
 
-// New creates a new security backend proxy.
+// New creates a new security backend proxy. The disconnectCounter is incremented with the reason label whenever a
+// session or connection is closed because of a timeout policy. The dryRunCounter is incremented with the rule label
+// whenever a request would have been rejected in dry run mode. The userConnections are shared between all connections of
+// the server to enforce the MaxConnectionsPerUser limit. It may be nil if the connections should not be counted.
 //goland:noinspection GoUnusedExportedFunction
 func New(
 	config config.SecurityConfig,
 	backend sshserver.NetworkConnectionHandler,
 	logger log.Logger,
+	disconnectCounter metrics.Counter,
+	dryRunCounter metrics.Counter,
+	userConnections *UserConnections,
 ) (sshserver.NetworkConnectionHandler, error) {
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid security configuration (%w)", err)
 	}
 	return &networkHandler{
-		config:  config,
-		backend: backend,
-		logger:  logger,
+		config:            config,
+		backend:           backend,
+		logger:            logger,
+		disconnectCounter: disconnectCounter,
+		dryRunCounter:     dryRunCounter,
+		userConnections:   userConnections,
 	}, nil
 }
@@ -3,19 +3,28 @@ package security
 import (
 	"context"
 	"sync"
+	"time"
 
     auth2 "go.containerssh.io/libcontainerssh/auth"
     config2 "go.containerssh.io/libcontainerssh/config"
     "go.containerssh.io/libcontainerssh/internal/auth"
+    "go.containerssh.io/libcontainerssh/internal/metrics"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/log"
+    "go.containerssh.io/libcontainerssh/message"
     "go.containerssh.io/libcontainerssh/metadata"
 )
 
 type networkHandler struct {
-	config  config2.SecurityConfig
-	backend sshserver.NetworkConnectionHandler
-	logger  log.Logger
+	config            config2.SecurityConfig
+	backend           sshserver.NetworkConnectionHandler
+	logger            log.Logger
+	disconnectCounter metrics.Counter
+	dryRunCounter     metrics.Counter
+	// userConnections counts the connections of each user across the server. It is nil if they are not counted.
+	userConnections *UserConnections
+	// username is the user this connection is counted for in userConnections, or empty if it is not counted.
+	username string
 }
 
 func (n *networkHandler) OnAuthKeyboardInteractive(
@@ -64,18 +73,62 @@ func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticate
 	metadata metadata.ConnectionAuthenticatedMetadata,
 	failureReason error,
 ) {
+	if err := n.addUserConnection(meta); err != nil {
+		return nil, meta, err
+	}
 	backend, _, failureReason := n.backend.OnHandshakeSuccess(meta)
 	if failureReason != nil {
+		n.removeUserConnection()
 		return nil, meta, failureReason
 	}
 	return &sshConnectionHandler{
-		config:  n.config,
-		backend: backend,
-		lock:    &sync.Mutex{},
-		logger:  n.logger,
+		config:            n.config,
+		backend:           backend,
+		lock:              &sync.Mutex{},
+		logger:            n.logger,
+		connectedAt:       time.Now(),
+		disconnectCounter: n.disconnectCounter,
+		dryRunCounter:     n.dryRunCounter,
 	}, meta, nil
 }
 
+// addUserConnection counts the connection for the authenticated user and returns an error if the user has reached
+// the MaxConnectionsPerUser limit.
+func (n *networkHandler) addUserConnection(meta metadata.ConnectionAuthenticatedMetadata) error {
+	if n.userConnections == nil {
+		return nil
+	}
+	username := meta.AuthenticatedUsername
+	if username == "" {
+		username = meta.Username
+	}
+	connections := n.userConnections.add(username)
+	n.username = username
+	if n.config.MaxConnectionsPerUser > -1 && connections > n.config.MaxConnectionsPerUser {
+		err := message.UserMessage(
+			message.ESecurityMaxUserConnections,
+			"Too many connections.",
+			"The connection is rejected because the user %s has too many open connections.",
+			username,
+		).Label("username", username)
+		if enforce(n.config.DryRun, n.logger, n.dryRunCounter, nil, RuleMaxConnectionsPerUser, err) {
+			n.removeUserConnection()
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUserConnection removes the connection from the count of the user, if it has been counted.
+func (n *networkHandler) removeUserConnection() {
+	if n.username == "" {
+		return
+	}
+	n.userConnections.remove(n.username)
+	n.username = ""
+}
+
 func (n *networkHandler) OnDisconnect() {
+	n.removeUserConnection()
 	n.backend.OnDisconnect()
 }
@@ -5,19 +5,27 @@ import (
 
     config2 "go.containerssh.io/libcontainerssh/config"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
+    "go.containerssh.io/libcontainerssh/internal/unixutils"
     "go.containerssh.io/libcontainerssh/log"
     "go.containerssh.io/libcontainerssh/message"
 )
 
 type sessionHandler struct {
-	config        config2.SecurityConfig
-	backend       sshserver.SessionChannelHandler
+	config  config2.SecurityConfig
+	backend sshserver.SessionChannelHandler
+	// session is the session channel, which records the requests let through in dry run mode if it implements
+	// sshserver.PolicyAuditor.
+	session       sshserver.SessionChannel
 	sshConnection *sshConnectionHandler
 	logger        log.Logger
+	// timeouts closes the session when a timeout policy is reached. It is nil if no timeout is configured.
+	timeouts *sessionTimeouts
 }
 
 func (s *sessionHandler) OnClose() {
+	s.timeouts.stop()
 	s.backend.OnClose()
+	s.sshConnection.releaseSession()
 }
 
 func (s *sessionHandler) OnShutdown(shutdownContext context.Context) {
@@ -47,6 +55,11 @@ func (s *sessionHandler) getPolicy(primary config2.SecurityExecutionPolicy) conf
 	return config2.ExecutionPolicyEnable
 }
 
+// enforce decides whether a request rejected by rule is actually rejected, see the enforce function.
+func (s *sessionHandler) enforce(rule string, err message.Message) bool {
+	return enforce(s.config.DryRun, s.logger, s.sshConnection.dryRunCounter, s.session, rule, err)
+}
+
 func (s *sessionHandler) contains(items []string, item string) bool {
 	for _, searchItem := range items {
 		if searchItem == item {
@@ -58,40 +71,37 @@ func (s *sessionHandler) contains(items []string, item string) bool {
 
 func (s *sessionHandler) OnEnvRequest(requestID uint64, name string, value string) error {
 	mode := s.getPolicy(s.config.Env.Mode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecurityEnvRejected,
 			"Environment variable setting rejected.",
 			"Setting an environment variable is rejected because it is disabled in the security settings.",
 		).Label("name", name)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyFilter:
-		if s.contains(s.config.Env.Allow, name) {
-			return s.backend.OnEnvRequest(requestID, name, value)
+		if !s.contains(s.config.Env.Allow, name) {
+			err = message.UserMessage(
+				message.ESecurityEnvRejected,
+				"Environment variable setting rejected.",
+				"Setting an environment variable is rejected because it does not match the allow list.",
+			).Label("name", name)
 		}
-		err := message.UserMessage(
-			message.ESecurityEnvRejected,
-			"Environment variable setting rejected.",
-			"Setting an environment variable is rejected because it does not match the allow list.",
-		).Label("name", name)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyEnable:
 		fallthrough
 	default:
-		if !s.contains(s.config.Env.Deny, name) {
-			return s.backend.OnEnvRequest(requestID, name, value)
+		if s.contains(s.config.Env.Deny, name) {
+			err = message.UserMessage(
+				message.ESecurityEnvRejected,
+				"Environment variable setting rejected.",
+				"Setting an environment variable is rejected because it matches the deny list.",
+			).Label("name", name)
 		}
-		err := message.UserMessage(
-			message.ESecurityEnvRejected,
-			"Environment variable setting rejected.",
-			"Setting an environment variable is rejected because it matches the deny list.",
-		).Label("name", name)
-		s.logger.Debug(err)
+	}
+	if err != nil && s.enforce(RuleEnv, err) {
 		return err
 	}
+	return s.backend.OnEnvRequest(requestID, name, value)
 }
 
 func (s *sessionHandler) OnPtyRequest(
@@ -113,45 +123,41 @@ func (s *sessionHandler) OnPtyRequest(
 			"TTY allocation disabled.",
 			"TTY allocation is disabled in the security settings.",
 		)
-		s.logger.Debug(err)
-		return err
+		if s.enforce(RuleTTY, err) {
+			return err
+		}
 	case config2.ExecutionPolicyEnable:
 		fallthrough
 	default:
-		return s.backend.OnPtyRequest(requestID, term, columns, rows, width, height, modeList)
 	}
+	return s.backend.OnPtyRequest(requestID, term, columns, rows, width, height, modeList)
 }
 
 func (s *sessionHandler) OnExecRequest(
 	requestID uint64,
 	program string,
 ) error {
+	// Requests only arrive once the channel is open, so the timeouts can now warn the user on the standard error.
+	s.timeouts.start()
 	mode := s.getPolicy(s.config.Command.Mode)
-	switch mode {
-	case config2.ExecutionPolicyDisable:
+	command := program
+	if mode == config2.ExecutionPolicyDisable {
 		err := message.UserMessage(
 			message.ESecurityExecRejected,
 			"Command execution disabled.",
 			"Command execution is disabled in the security settings.",
 		)
-		s.logger.Debug(err)
-		return err
-	case config2.ExecutionPolicyFilter:
-		if !s.contains(s.config.Command.Allow, program) {
-			err := message.UserMessage(
-				message.ESecurityExecRejected,
-				"Command execution disabled.",
-				"The specified command passed from the client does not match the specified allow list.",
-			)
-			s.logger.Debug(err)
+		if s.enforce(RuleCommand, err) {
+			return err
+		}
+	} else {
+		var err error
+		if command, err = s.applyCommandPolicy(mode, program); err != nil {
 			return err
 		}
-	case config2.ExecutionPolicyEnable:
-		fallthrough
-	default:
 	}
 	if s.config.ForceCommand == "" {
-		return s.backend.OnExecRequest(requestID, program)
+		return s.backend.OnExecRequest(requestID, command)
 	}
 	if err := s.backend.OnEnvRequest(requestID, "SSH_ORIGINAL_COMMAND", program); err != nil {
 		err := message.WrapUser(
@@ -172,9 +178,83 @@ func (s *sessionHandler) OnExecRequest(
 	return s.backend.OnExecRequest(requestID, s.config.ForceCommand)
 }
 
+// applyCommandPolicy checks the program against the command rules and the allow list and returns the command to
+// execute. In dry run mode a rejected program is returned unchanged.
+func (s *sessionHandler) applyCommandPolicy(mode config2.SecurityExecutionPolicy, program string) (string, error) {
+	if len(s.config.Command.Rules) == 0 {
+		if mode == config2.ExecutionPolicyFilter && !s.contains(s.config.Command.Allow, program) {
+			err := message.UserMessage(
+				message.ESecurityExecRejected,
+				"Command execution disabled.",
+				"The specified command passed from the client does not match the specified allow list.",
+			)
+			if s.enforce(RuleCommand, err) {
+				return "", err
+			}
+		}
+		return program, nil
+	}
+	argv, err := unixutils.ParseCMDStrict(program)
+	if err != nil || len(argv) == 0 {
+		err := message.UserMessage(
+			message.ESecurityExecRejected,
+			"Command execution disabled.",
+			"The specified command passed from the client cannot be split into arguments safely.",
+		)
+		if s.enforce(RuleCommand, err) {
+			return "", err
+		}
+		return program, nil
+	}
+	rule := findCommandRule(s.config.Command.Rules, argv)
+	if rule == nil {
+		if mode == config2.ExecutionPolicyFilter && !s.contains(s.config.Command.Allow, program) {
+			err := message.UserMessage(
+				message.ESecurityExecRejected,
+				"Command execution disabled.",
+				"The specified command passed from the client does not match any command rule or the allow list.",
+			)
+			if s.enforce(RuleCommand, err) {
+				return "", err
+			}
+		}
+		if mode == config2.ExecutionPolicyFilter {
+			return program, nil
+		}
+		return unixutils.QuoteCMD(argv), nil
+	}
+	switch rule.Action {
+	case config2.CommandActionDeny:
+		err := message.UserMessage(
+			message.ESecurityExecRejected,
+			"Command execution disabled.",
+			"The specified command passed from the client matches the deny rule %s.",
+			rule.Name,
+		).Label("rule", rule.Name)
+		if s.enforce(RuleCommand+"/"+rule.Name, err) {
+			return "", err
+		}
+		return program, nil
+	case config2.CommandActionRewrite:
+		command := unixutils.QuoteCMD(rewriteCommand(rule.Rewrite, argv))
+		s.logger.Debug(
+			message.NewMessage(
+				message.MSecurityCommandRewritten,
+				"Rewriting command to %s as instructed by rule %s",
+				command,
+				rule.Name,
+			).Label("rule", rule.Name),
+		)
+		return command, nil
+	default:
+		return unixutils.QuoteCMD(argv), nil
+	}
+}
+
 func (s *sessionHandler) OnShell(
 	requestID uint64,
 ) error {
+	s.timeouts.start()
 	mode := s.getPolicy(s.config.Shell.Mode)
 	switch mode {
 	case config2.ExecutionPolicyDisable:
@@ -185,8 +265,9 @@ func (s *sessionHandler) OnShell(
 			"Shell execution disabled.",
 			"Shell execution is disabled in the security settings.",
 		)
-		s.logger.Debug(err)
-		return err
+		if s.enforce(RuleShell, err) {
+			return err
+		}
 	case config2.ExecutionPolicyEnable:
 		fallthrough
 	default:
@@ -207,38 +288,37 @@ func (s *sessionHandler) OnSubsystem(
 	requestID uint64,
 	subsystem string,
 ) error {
+	s.timeouts.start()
 	mode := s.getPolicy(s.config.Subsystem.Mode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecuritySubsystemRejected,
 			"Subsystem execution disabled.",
 			"Subsystem execution is disabled in the security settings.",
 		)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyFilter:
 		if !s.contains(s.config.Subsystem.Allow, subsystem) {
-			err := message.UserMessage(
+			err = message.UserMessage(
 				message.ESecuritySubsystemRejected,
 				"Subsystem execution disabled.",
 				"The specified subsystem does not match the allowed subsystems list.",
 			)
-			s.logger.Debug(err)
-			return err
 		}
 	case config2.ExecutionPolicyEnable:
 		if s.contains(s.config.Subsystem.Deny, subsystem) {
-			err := message.UserMessage(
+			err = message.UserMessage(
 				message.ESecuritySubsystemRejected,
 				"Subsystem execution disabled.",
 				"The subsystem execution is rejected because the specified subsystem matches the deny list.",
 			)
-			s.logger.Debug(err)
-			return err
 		}
 	default:
 	}
+	if err != nil && s.enforce(RuleSubsystem, err) {
+		return err
+	}
 	if s.config.ForceCommand == "" {
 		return s.backend.OnSubsystem(requestID, subsystem)
 	}
@@ -263,40 +343,37 @@ func (s *sessionHandler) OnSubsystem(
 
 func (s *sessionHandler) OnSignal(requestID uint64, signal string) error {
 	mode := s.getPolicy(s.config.Shell.Mode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecuritySignalRejected,
 			"Sending signals is rejected.",
 			"Sending the signal is rejected because signal delivery is disabled.",
 		)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyFilter:
-		if s.contains(s.config.Signal.Allow, signal) {
-			return s.backend.OnSignal(requestID, signal)
+		if !s.contains(s.config.Signal.Allow, signal) {
+			err = message.UserMessage(
+				message.ESecuritySignalRejected,
+				"Sending signals is rejected.",
+				"Sending the signal is rejected because the specified signal does not match the allow list.",
+			)
 		}
-		err := message.UserMessage(
-			message.ESecuritySignalRejected,
-			"Sending signals is rejected.",
-			"Sending the signal is rejected because the specified signal does not match the allow list.",
-		)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyEnable:
 		fallthrough
 	default:
-		if !s.contains(s.config.Signal.Deny, signal) {
-			return s.backend.OnSignal(requestID, signal)
+		if s.contains(s.config.Signal.Deny, signal) {
+			err = message.UserMessage(
+				message.ESecuritySignalRejected,
+				"Sending signals is rejected.",
+				"Sending the signal is rejected because the specified signal matches the deny list.",
+			)
 		}
-		err := message.UserMessage(
-			message.ESecuritySignalRejected,
-			"Sending signals is rejected.",
-			"Sending the signal is rejected because the specified signal matches the deny list.",
-		)
-		s.logger.Debug(err)
+	}
+	if err != nil && s.enforce(RuleSignal, err) {
 		return err
 	}
+	return s.backend.OnSignal(requestID, signal)
 }
 
 func (s *sessionHandler) OnWindow(requestID uint64, columns uint32, rows uint32, width uint32, height uint32) error {
@@ -312,26 +389,62 @@ func (s *sessionHandler) OnX11Request(
 	reverseHandler sshserver.ReverseForward,
 ) error {
 	mode := s.getPolicy(s.config.Forwarding.X11ForwardingMode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecurityX11ForwardingRejected,
 			"X11 forwarding is rejected",
 			"X11 forwarding is rejected because it is disabled in the config",
 		)
-		s.logger.Debug(err)
-		return err
 	case config2.ExecutionPolicyFilter:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecurityX11ForwardingRejected,
 			"X11 forwarding is rejected",
 			"X11 forwarding is rejected because it is set to filter and filterint X11 requests is not supported",
 		)
-		s.logger.Debug(err)
+	case config2.ExecutionPolicyEnable:
+		fallthrough
+	default:
+	}
+	if err != nil && s.enforce(RuleX11Forwarding, err) {
 		return err
+	}
+	if s.config.MaxX11Channels > -1 {
+		reverseHandler = &x11LimitHandler{
+			ReverseForward: reverseHandler,
+			connection:     s.sshConnection,
+			session:        s.session,
+		}
+	}
+	return s.backend.OnX11Request(requestID, singleConnection, protocol, cookie, screen, reverseHandler)
+}
+
+func (s *sessionHandler) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	mode := s.getPolicy(s.config.Forwarding.AgentForwardingMode)
+	var err message.Message
+	switch mode {
+	case config2.ExecutionPolicyDisable:
+		err = message.UserMessage(
+			message.ESecurityAgentForwardingRejected,
+			"Agent forwarding is rejected",
+			"Agent forwarding is rejected because it is disabled in the config",
+		)
+	case config2.ExecutionPolicyFilter:
+		err = message.UserMessage(
+			message.ESecurityAgentForwardingRejected,
+			"Agent forwarding is rejected",
+			"Agent forwarding is rejected because it is set to filter and filtering agent forwarding requests is not supported",
+		)
 	case config2.ExecutionPolicyEnable:
 		fallthrough
 	default:
-		return s.backend.OnX11Request(requestID, singleConnection, protocol, cookie, screen, reverseHandler)
 	}
+	if err != nil && s.enforce(RuleAgentForwarding, err) {
+		return err
+	}
+	return s.backend.OnAuthAgentRequest(requestID, reverseHandler)
 }
@@ -63,6 +63,30 @@ func TestPTYRequest(t *testing.T) {
 	assert.Error(t, session.OnPtyRequest(1, "XTERM", 80, 25, 800, 600, []byte{}))
 }
 
+func TestAgentForwardingRequest(t *testing.T) {
+	session := &sessionHandler{
+		config:  config.SecurityConfig{},
+		backend: &dummyBackend{},
+		sshConnection: &sshConnectionHandler{
+			lock: &sync.Mutex{},
+		},
+		logger: log.NewTestLogger(t),
+	}
+
+	session.config.Forwarding.AgentForwardingMode = config.ExecutionPolicyEnable
+	assert.NoError(t, session.OnAuthAgentRequest(1, nil))
+
+	session.config.Forwarding.AgentForwardingMode = config.ExecutionPolicyFilter
+	assert.Error(t, session.OnAuthAgentRequest(2, nil))
+
+	session.config.Forwarding.AgentForwardingMode = config.ExecutionPolicyDisable
+	assert.Error(t, session.OnAuthAgentRequest(3, nil))
+
+	session.config.Forwarding.AgentForwardingMode = config.ExecutionPolicyUnconfigured
+	session.config.DefaultMode = config.ExecutionPolicyDisable
+	assert.Error(t, session.OnAuthAgentRequest(4, nil))
+}
+
 func TestCommand(t *testing.T) {
 	backend := &dummyBackend{}
 	session := &sessionHandler{
@@ -279,4 +303,11 @@ func (s *dummyBackend) OnX11Request(
 	return fmt.Errorf("Unimplemented")
 }
 
+func (s *dummyBackend) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	return nil
+}
+
 // endregion
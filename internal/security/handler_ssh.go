@@ -2,9 +2,13 @@ package security
 
 import (
 	"context"
+	"net"
+	"strconv"
 	"sync"
+	"time"
 
     config2 "go.containerssh.io/libcontainerssh/config"
+    "go.containerssh.io/libcontainerssh/internal/metrics"
     "go.containerssh.io/libcontainerssh/internal/sshserver"
     "go.containerssh.io/libcontainerssh/log"
     "go.containerssh.io/libcontainerssh/message"
@@ -18,6 +22,136 @@ type sshConnectionHandler struct {
 	sessionCount uint
 	lock         *sync.Mutex
 	logger       log.Logger
+
+	// forwardChannels is the number of open port and socket forwarding channels.
+	forwardChannels uint
+	// reverseForwards contains the active reverse forwarding bindings.
+	reverseForwards map[string]struct{}
+	// x11Channels is the number of open X11 channels.
+	x11Channels uint
+
+	// connectedAt is the time the handshake finished, used for the maximum connection duration.
+	connectedAt time.Time
+	// disconnected is set when a session has closed the connection because of the maximum connection duration.
+	disconnected      bool
+	disconnectCounter metrics.Counter
+	// dryRunCounter counts the requests that would have been rejected in dry run mode.
+	dryRunCounter metrics.Counter
+}
+
+// enforce decides whether a request rejected by rule is actually rejected, see the enforce function.
+func (s *sshConnectionHandler) enforce(rule string, err message.Message, auditor interface{}) bool {
+	return enforce(s.config.DryRun, s.logger, s.dryRunCounter, auditor, rule, err)
+}
+
+// markDisconnected records that the connection is being closed because of the maximum connection duration. It
+// returns false if it has already been recorded.
+func (s *sshConnectionHandler) markDisconnected() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.disconnected {
+		return false
+	}
+	s.disconnected = true
+	return true
+}
+
+// releaseSession frees the place of a closed session in the MaxSessions limit.
+func (s *sshConnectionHandler) releaseSession() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.sessionCount > 0 {
+		s.sessionCount--
+	}
+}
+
+// acquireForwardChannel takes a place in the MaxForwardChannels limit for a new forwarding channel. It returns a
+// rejection if the limit has been reached.
+func (s *sshConnectionHandler) acquireForwardChannel() sshserver.ChannelRejection {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.config.MaxForwardChannels > -1 && s.forwardChannels >= uint(s.config.MaxForwardChannels) {
+		err := sshserver.NewChannelRejection(
+			ssh.ResourceShortage,
+			message.ESecurityMaxForwardChannels,
+			"Too many forwarding channels.",
+			"The forwarding channel is rejected because the connection has too many open forwarding channels.",
+		)
+		if s.enforce(RuleMaxForwardChannels, err, nil) {
+			return err
+		}
+	}
+	s.forwardChannels++
+	return nil
+}
+
+// releaseForwardChannel frees the place of a closed forwarding channel in the MaxForwardChannels limit.
+func (s *sshConnectionHandler) releaseForwardChannel() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.forwardChannels > 0 {
+		s.forwardChannels--
+	}
+}
+
+// releaseX11Channel frees the place of a closed X11 channel in the MaxX11Channels limit.
+func (s *sshConnectionHandler) releaseX11Channel() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.x11Channels > 0 {
+		s.x11Channels--
+	}
+}
+
+// acquireReverseForward records a new reverse forwarding binding identified by key. It returns an error if the
+// MaxReverseForwards limit has been reached. A binding that is already active is not counted again.
+func (s *sshConnectionHandler) acquireReverseForward(key string, auditor interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.reverseForwards == nil {
+		s.reverseForwards = map[string]struct{}{}
+	}
+	if _, ok := s.reverseForwards[key]; ok {
+		return nil
+	}
+	if s.config.MaxReverseForwards > -1 && len(s.reverseForwards) >= s.config.MaxReverseForwards {
+		err := message.UserMessage(
+			message.ESecurityMaxReverseForwards,
+			"Too many reverse forwards.",
+			"The reverse forwarding on %s is rejected because the connection has too many active reverse forwards.",
+			key,
+		)
+		if s.enforce(RuleMaxReverseForwards, err, auditor) {
+			return err
+		}
+	}
+	s.reverseForwards[key] = struct{}{}
+	return nil
+}
+
+// releaseReverseForward removes the reverse forwarding binding identified by key.
+func (s *sshConnectionHandler) releaseReverseForward(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.reverseForwards, key)
+}
+
+// checkExpired returns a rejection if the connection has exceeded the maximum connection duration.
+func (s *sshConnectionHandler) checkExpired() sshserver.ChannelRejection {
+	if s.config.Timeouts.MaxConnectionDuration <= 0 ||
+		time.Since(s.connectedAt) < s.config.Timeouts.MaxConnectionDuration {
+		return nil
+	}
+	err := sshserver.NewChannelRejection(
+		ssh.Prohibited,
+		message.ESecurityConnectionExpired,
+		"The connection has reached its maximum duration.",
+		"The channel is rejected because the connection has exceeded the maximum connection duration.",
+	)
+	if !s.enforce(ReasonConnectionDuration, err, nil) {
+		return nil
+	}
+	return err
 }
 
 func (s *sshConnectionHandler) OnShutdown(shutdownContext context.Context) {
@@ -41,16 +175,29 @@ func (s *sshConnectionHandler) OnSessionChannel(
 	extraData []byte,
 	session sshserver.SessionChannel,
 ) (channel sshserver.SessionChannelHandler, failureReason sshserver.ChannelRejection) {
+	if err := s.checkExpired(); err != nil {
+		return nil, err
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	if s.config.MaxSessions > -1 && s.sessionCount >= uint(s.config.MaxSessions) {
 		err := &ErrTooManySessions{
 			labels: message.Labels(map[message.LabelName]message.LabelValue{}),
 		}
-		s.logger.Debug(err)
-		return nil, err
+		if s.enforce(RuleMaxSessions, err, session) {
+			return nil, err
+		}
+	}
+	var activity *activitySession
+	backendSession := session
+	if s.config.Timeouts.IdleTimeout > 0 {
+		activity = &activitySession{
+			SessionChannel: session,
+			lock:           &sync.Mutex{},
+		}
+		backendSession = activity
 	}
-	backend, err := s.backend.OnSessionChannel(meta, extraData, session)
+	backend, err := s.backend.OnSessionChannel(meta, extraData, backendSession)
 	if err != nil {
 		return nil, err
 	}
@@ -58,8 +205,10 @@ func (s *sshConnectionHandler) OnSessionChannel(
 	return &sessionHandler{
 		config:        s.config,
 		backend:       backend,
+		session:       session,
 		sshConnection: s,
 		logger:        s.logger,
+		timeouts:      newSessionTimeouts(s.config.Timeouts, session, activity, s, s.logger),
 	}, nil
 }
 
@@ -80,31 +229,44 @@ func (s *sshConnectionHandler) OnTCPForwardChannel(
 	originatorHost string,
 	originatorPort uint32,
 ) (channel sshserver.ForwardChannel, failureReason sshserver.ChannelRejection) {
+	if err := s.checkExpired(); err != nil {
+		return nil, err
+	}
 	mode := s.getPolicy(s.config.Forwarding.ForwardingMode)
+	var err sshserver.ChannelRejection
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := sshserver.NewChannelRejection(
+		err = sshserver.NewChannelRejection(
 			ssh.Prohibited,
 			message.ESecurityForwardingRejected,
 			"Forwarding is rejected",
 			"Forwarding is rejected because it is disabled",
 		)
-		s.logger.Debug(err)
+	default:
+		if reason := checkTargetFilter(mode, s.config.Forwarding.TCPTargets, hostToConnect, portToConnect); reason != "" {
+			err = sshserver.NewChannelRejection(
+				ssh.Prohibited,
+				message.ESecurityForwardingRejected,
+				"Forwarding is rejected",
+				"Forwarding to %s port %d is rejected because %s",
+				hostToConnect,
+				portToConnect,
+				reason,
+			)
+		}
+	}
+	if err != nil && s.enforce(RuleForwarding, err, nil) {
 		return nil, err
-	case config2.ExecutionPolicyFilter:
-		err := sshserver.NewChannelRejection(
-			ssh.Prohibited,
-			message.ESecurityForwardingRejected,
-			"Forwarding is rejected",
-			"Forwarding is rejected because it is set to filtered and filtering is currently not supported",
-		)
-		s.logger.Debug(err)
+	}
+	if err := s.acquireForwardChannel(); err != nil {
 		return nil, err
-	case config2.ExecutionPolicyEnable:
-		fallthrough
-	default:
-		return s.backend.OnTCPForwardChannel(channelID, hostToConnect, portToConnect, originatorHost, originatorPort)
 	}
+	channel, err = s.backend.OnTCPForwardChannel(channelID, hostToConnect, portToConnect, originatorHost, originatorPort)
+	if err != nil {
+		s.releaseForwardChannel()
+		return nil, err
+	}
+	return newLimitedChannel(channel, s.releaseForwardChannel), nil
 }
 
 func (s *sshConnectionHandler) OnRequestTCPReverseForward(
@@ -113,69 +275,99 @@ func (s *sshConnectionHandler) OnRequestTCPReverseForward(
 	reverseHandler sshserver.ReverseForward,
 ) error {
 	mode := s.getPolicy(s.config.Forwarding.ReverseForwardingMode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecurityReverseForwardingRejected,
 			"Reverse forwarding is rejected",
 			"Reverse forwarding is rejected because it is disabled in the config",
 		)
-		s.logger.Debug(err)
+	default:
+		if reason := checkTargetFilter(mode, s.config.Forwarding.ReverseBindAddresses, bindHost, bindPort); reason != "" {
+			err = message.UserMessage(
+				message.ESecurityReverseForwardingRejected,
+				"Reverse forwarding is rejected",
+				"Reverse forwarding on %s port %d is rejected because %s",
+				bindHost,
+				bindPort,
+				reason,
+			)
+		}
+	}
+	if err != nil && s.enforce(RuleReverseForwarding, err, reverseHandler) {
 		return err
-	case config2.ExecutionPolicyFilter:
-		err := message.UserMessage(
-			message.ESecurityReverseForwardingRejected,
-			"Reverse forwarding is rejected",
-			"Reverse forwarding is rejected because it is set to filter and filtering reverse forwarding requests is not supported",
-		)
-		s.logger.Debug(err)
+	}
+	key := tcpReverseForwardKey(bindHost, bindPort)
+	if err := s.acquireReverseForward(key, reverseHandler); err != nil {
 		return err
-	case config2.ExecutionPolicyEnable:
-		fallthrough
-	default:
-		return s.backend.OnRequestTCPReverseForward(bindHost, bindPort, reverseHandler)
 	}
+	if err := s.backend.OnRequestTCPReverseForward(bindHost, bindPort, reverseHandler); err != nil {
+		s.releaseReverseForward(key)
+		return err
+	}
+	return nil
 }
 
 func (s *sshConnectionHandler) OnRequestCancelTCPReverseForward(
 	bindHost string,
 	bindPort uint32,
 ) error {
-	return s.backend.OnRequestCancelTCPReverseForward(
+	if err := s.backend.OnRequestCancelTCPReverseForward(
 		bindHost,
 		bindPort,
-	)
+	); err != nil {
+		return err
+	}
+	s.releaseReverseForward(tcpReverseForwardKey(bindHost, bindPort))
+	return nil
+}
+
+func tcpReverseForwardKey(bindHost string, bindPort uint32) string {
+	return net.JoinHostPort(bindHost, strconv.FormatUint(uint64(bindPort), 10))
 }
 
 func (s *sshConnectionHandler) OnDirectStreamLocal(
 	channelID uint64,
 	path string,
 ) (channel sshserver.ForwardChannel, failureReason sshserver.ChannelRejection) {
+	if err := s.checkExpired(); err != nil {
+		return nil, err
+	}
 	mode := s.getPolicy(s.config.Forwarding.SocketForwardingMode)
+	var err sshserver.ChannelRejection
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := sshserver.NewChannelRejection(
+		err = sshserver.NewChannelRejection(
 			ssh.Prohibited,
 			message.ESecurityForwardingRejected,
 			"StreamLocal forwarding is rejected",
 			"StreamLocal forwarding is rejected because it is disabled",
 		)
-		s.logger.Debug(err)
+	default:
+		if reason := checkPathFilter(mode, s.config.Forwarding.SocketPaths, path); reason != "" {
+			err = sshserver.NewChannelRejection(
+				ssh.Prohibited,
+				message.ESecurityForwardingRejected,
+				"StreamLocal forwarding is rejected",
+				"StreamLocal forwarding to %s is rejected because %s",
+				path,
+				reason,
+			)
+		}
+	}
+	if err != nil && s.enforce(RuleSocketForwarding, err, nil) {
 		return nil, err
-	case config2.ExecutionPolicyFilter:
-		err := sshserver.NewChannelRejection(
-			ssh.Prohibited,
-			message.ESecurityForwardingRejected,
-			"StreamLocal forwarding is rejected",
-			"StreamLocal forwarding is rejected because it is set to filtered and filtering is currently not supported",
-		)
-		s.logger.Debug(err)
+	}
+	if err := s.acquireForwardChannel(); err != nil {
+		return nil, err
+	}
+	channel, err = s.backend.OnDirectStreamLocal(channelID, path)
+	if err != nil {
+		s.releaseForwardChannel()
 		return nil, err
-	case config2.ExecutionPolicyEnable:
-		fallthrough
-	default:
-		return s.backend.OnDirectStreamLocal(channelID, path)
 	}
+	return newLimitedChannel(channel, s.releaseForwardChannel), nil
 }
 
 func (s *sshConnectionHandler) OnRequestStreamLocal(
@@ -183,34 +375,46 @@ func (s *sshConnectionHandler) OnRequestStreamLocal(
 	reverseHandler sshserver.ReverseForward,
 ) error {
 	mode := s.getPolicy(s.config.Forwarding.SocketListenMode)
+	var err message.Message
 	switch mode {
 	case config2.ExecutionPolicyDisable:
-		err := message.UserMessage(
+		err = message.UserMessage(
 			message.ESecurityReverseForwardingRejected,
 			"Reverse socket forwarding is rejected",
 			"Reverse socket forwarding is rejected because it is disabled in the config",
 		)
-		s.logger.Debug(err)
+	default:
+		if reason := checkPathFilter(mode, s.config.Forwarding.SocketPaths, path); reason != "" {
+			err = message.UserMessage(
+				message.ESecurityReverseForwardingRejected,
+				"Reverse socket forwarding is rejected",
+				"Reverse socket forwarding on %s is rejected because %s",
+				path,
+				reason,
+			)
+		}
+	}
+	if err != nil && s.enforce(RuleSocketListen, err, reverseHandler) {
 		return err
-	case config2.ExecutionPolicyFilter:
-		err := message.UserMessage(
-			message.ESecurityReverseForwardingRejected,
-			"Reverse socket forwarding is rejected",
-			"Reverse socket forwarding is rejected because it is set to filter and filtering reverse forwarding requests is not supported",
-		)
-		s.logger.Debug(err)
+	}
+	if err := s.acquireReverseForward(path, reverseHandler); err != nil {
 		return err
-	case config2.ExecutionPolicyEnable:
-		fallthrough
-	default:
-		return s.backend.OnRequestStreamLocal(path, reverseHandler)
 	}
+	if err := s.backend.OnRequestStreamLocal(path, reverseHandler); err != nil {
+		s.releaseReverseForward(path)
+		return err
+	}
+	return nil
 }
 
 func (s *sshConnectionHandler) OnRequestCancelStreamLocal(
 	path string,
 ) error {
-	return s.backend.OnRequestCancelStreamLocal(path)
+	if err := s.backend.OnRequestCancelStreamLocal(path); err != nil {
+		return err
+	}
+	s.releaseReverseForward(path)
+	return nil
 }
 
 // ErrTooManySessions indicates that too many sessions were opened in the same connection.
@@ -0,0 +1,269 @@
+package security
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// activitySession wraps the session channel passed to the backend and records when data was last sent on the standard
+// input or output.
+type activitySession struct {
+	sshserver.SessionChannel
+
+	lock         *sync.Mutex
+	lastActivity time.Time
+	stdin        io.Reader
+	stdout       io.Writer
+}
+
+func (a *activitySession) Stdin() io.Reader {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stdin == nil {
+		a.stdin = &activityReader{backend: a.SessionChannel.Stdin(), session: a}
+	}
+	return a.stdin
+}
+
+func (a *activitySession) Stdout() io.Writer {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stdout == nil {
+		a.stdout = &activityWriter{backend: a.SessionChannel.Stdout(), session: a}
+	}
+	return a.stdout
+}
+
+func (a *activitySession) touch() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.lastActivity = time.Now()
+}
+
+func (a *activitySession) getLastActivity() time.Time {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.lastActivity
+}
+
+type activityReader struct {
+	backend io.Reader
+	session *activitySession
+}
+
+func (a *activityReader) Read(p []byte) (int, error) {
+	n, err := a.backend.Read(p)
+	if n > 0 {
+		a.session.touch()
+	}
+	return n, err
+}
+
+type activityWriter struct {
+	backend io.Writer
+	session *activitySession
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	n, err := a.backend.Write(p)
+	if n > 0 {
+		a.session.touch()
+	}
+	return n, err
+}
+
+// sessionTimeouts closes a session when it has been idle or open for too long, or when the connection has been open
+// for too long. The user is warned on the standard error before the session is closed.
+type sessionTimeouts struct {
+	config     config.SecurityTimeoutConfig
+	session    sshserver.SessionChannel
+	activity   *activitySession
+	connection *sshConnectionHandler
+	openedAt   time.Time
+	logger     log.Logger
+
+	startOnce *sync.Once
+	stopOnce  *sync.Once
+	done      chan struct{}
+}
+
+// newSessionTimeouts creates the timeouts for a session. It returns nil if no timeout is configured.
+func newSessionTimeouts(
+	cfg config.SecurityTimeoutConfig,
+	session sshserver.SessionChannel,
+	activity *activitySession,
+	connection *sshConnectionHandler,
+	logger log.Logger,
+) *sessionTimeouts {
+	if cfg.IdleTimeout <= 0 && cfg.MaxSessionDuration <= 0 && cfg.MaxConnectionDuration <= 0 {
+		return nil
+	}
+	return &sessionTimeouts{
+		config:     cfg,
+		session:    session,
+		activity:   activity,
+		connection: connection,
+		openedAt:   time.Now(),
+		logger:     logger,
+		startOnce:  &sync.Once{},
+		stopOnce:   &sync.Once{},
+		done:       make(chan struct{}),
+	}
+}
+
+// start starts watching the session. It must only be called once the channel is open, so the warning can be written.
+func (t *sessionTimeouts) start() {
+	if t == nil {
+		return
+	}
+	t.startOnce.Do(func() {
+		go t.run()
+	})
+}
+
+// stop stops watching the session.
+func (t *sessionTimeouts) stop() {
+	if t == nil {
+		return
+	}
+	t.stopOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+// nextDeadline returns the earliest of the configured deadlines and the reason belonging to it.
+func (t *sessionTimeouts) nextDeadline() (string, time.Time) {
+	reason := ""
+	var deadline time.Time
+	if t.config.MaxConnectionDuration > 0 {
+		reason = ReasonConnectionDuration
+		deadline = t.connection.connectedAt.Add(t.config.MaxConnectionDuration)
+	}
+	if t.config.MaxSessionDuration > 0 {
+		sessionDeadline := t.openedAt.Add(t.config.MaxSessionDuration)
+		if reason == "" || sessionDeadline.Before(deadline) {
+			reason = ReasonSessionDuration
+			deadline = sessionDeadline
+		}
+	}
+	if t.config.IdleTimeout > 0 && t.activity != nil {
+		lastActivity := t.activity.getLastActivity()
+		if lastActivity.Before(t.openedAt) {
+			lastActivity = t.openedAt
+		}
+		idleDeadline := lastActivity.Add(t.config.IdleTimeout)
+		if reason == "" || idleDeadline.Before(deadline) {
+			reason = ReasonIdle
+			deadline = idleDeadline
+		}
+	}
+	return reason, deadline
+}
+
+func (t *sessionTimeouts) run() {
+	// warnedDeadline is the deadline the user has last been warned about. The idle deadline moves with each
+	// activity, in which case the user is warned again before the new deadline.
+	var warnedDeadline time.Time
+	for {
+		reason, deadline := t.nextDeadline()
+		now := time.Now()
+		if !now.Before(deadline) {
+			t.expire(reason)
+			return
+		}
+		wakeUp := deadline
+		if t.config.Warning > 0 && !deadline.Equal(warnedDeadline) {
+			warnAt := deadline.Add(-t.config.Warning)
+			if now.Before(warnAt) {
+				wakeUp = warnAt
+			} else {
+				t.warn(reason, deadline.Sub(now))
+				warnedDeadline = deadline
+			}
+		}
+		timer := time.NewTimer(wakeUp.Sub(now))
+		select {
+		case <-t.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *sessionTimeouts) warn(reason string, remaining time.Duration) {
+	var text string
+	remaining = remaining.Round(time.Second)
+	switch reason {
+	case ReasonIdle:
+		text = fmt.Sprintf("Your session has been idle and will be closed in %s.", remaining)
+	case ReasonSessionDuration:
+		text = fmt.Sprintf("Your session has reached its maximum duration and will be closed in %s.", remaining)
+	default:
+		text = fmt.Sprintf("Your connection has reached its maximum duration and will be closed in %s.", remaining)
+	}
+	if _, err := t.session.Stderr().Write([]byte("\r\n" + text + "\r\n")); err != nil {
+		t.logger.Debug(
+			message.Wrap(
+				err,
+				message.ESecurityDisconnectWarningFailed,
+				"Failed to send the disconnect warning to the user",
+			),
+		)
+	}
+}
+
+func (t *sessionTimeouts) expire(reason string) {
+	var msg message.Message
+	switch reason {
+	case ReasonIdle:
+		msg = message.NewMessage(
+			message.MSecurityIdleTimeout,
+			"Closing session after %s without activity",
+			t.config.IdleTimeout,
+		)
+	case ReasonSessionDuration:
+		msg = message.NewMessage(
+			message.MSecuritySessionDurationExceeded,
+			"Closing session after reaching the maximum session duration of %s",
+			t.config.MaxSessionDuration,
+		)
+	default:
+		if !t.connection.markDisconnected() {
+			// Another session of the same connection is already closing the connection.
+			return
+		}
+		msg = message.NewMessage(
+			message.MSecurityConnectionDurationExceeded,
+			"Closing connection after reaching the maximum connection duration of %s",
+			t.config.MaxConnectionDuration,
+		)
+	}
+	if t.connection.config.DryRun {
+		enforce(true, t.logger, t.connection.dryRunCounter, t.session, reason, msg)
+		return
+	}
+	t.logger.Info(msg)
+	t.connection.disconnectCounter.Increment(metrics.Label(MetricLabelReason, reason))
+	closer, ok := t.session.(sshserver.PolicyCloser)
+	var err error
+	switch {
+	case !ok:
+		err = t.session.Close()
+	case reason == ReasonConnectionDuration:
+		err = closer.DisconnectForPolicy(reason)
+	default:
+		err = closer.CloseForPolicy(reason)
+	}
+	if err != nil {
+		t.logger.Debug(message.Wrap(err, message.ESecurityPolicyCloseFailed, "Failed to close the session"))
+	}
+}
@@ -0,0 +1,238 @@
+package security //nolint:testpackage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/geoip/dummy"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	collector, connection := createTimeoutConnection(t, config.SecurityTimeoutConfig{
+		IdleTimeout: 200 * time.Millisecond,
+		Warning:     100 * time.Millisecond,
+	})
+	session := newPolicySessionChannel()
+	handler, err := connection.OnSessionChannel(createChannelMetadata(0), []byte{}, session)
+	assert.Nil(t, err)
+	assert.NoError(t, handler.OnShell(0))
+
+	reason, connectionClosed := session.wait(t)
+	assert.Equal(t, ReasonIdle, reason)
+	assert.False(t, connectionClosed)
+	assert.Contains(t, session.stderrText(), "idle")
+	assertPolicyDisconnects(t, collector, ReasonIdle)
+	handler.OnClose()
+}
+
+func TestIdleTimeoutActivity(t *testing.T) {
+	session := newPolicySessionChannel()
+	activity := &activitySession{
+		SessionChannel: session,
+		lock:           &sync.Mutex{},
+	}
+	collector, connection := createTimeoutConnection(t, config.SecurityTimeoutConfig{})
+	timeouts := newSessionTimeouts(
+		config.SecurityTimeoutConfig{IdleTimeout: 200 * time.Millisecond},
+		session,
+		activity,
+		connection,
+		log.NewTestLogger(t),
+	)
+	timeouts.start()
+	defer timeouts.stop()
+
+	for i := 0; i < 8; i++ {
+		time.Sleep(50 * time.Millisecond)
+		_, err := activity.Stdout().Write([]byte("x"))
+		assert.NoError(t, err)
+	}
+	assert.False(t, session.isClosed(), "the session was closed despite the activity")
+
+	reason, _ := session.wait(t)
+	assert.Equal(t, ReasonIdle, reason)
+	assertPolicyDisconnects(t, collector, ReasonIdle)
+}
+
+func TestMaxSessionDuration(t *testing.T) {
+	collector, connection := createTimeoutConnection(t, config.SecurityTimeoutConfig{
+		IdleTimeout:        time.Hour,
+		MaxSessionDuration: 200 * time.Millisecond,
+		Warning:            100 * time.Millisecond,
+	})
+	session := newPolicySessionChannel()
+	handler, err := connection.OnSessionChannel(createChannelMetadata(0), []byte{}, session)
+	assert.Nil(t, err)
+	assert.NoError(t, handler.OnExecRequest(0, "/bin/bash"))
+
+	reason, connectionClosed := session.wait(t)
+	assert.Equal(t, ReasonSessionDuration, reason)
+	assert.False(t, connectionClosed)
+	assert.Contains(t, session.stderrText(), "maximum duration")
+	assertPolicyDisconnects(t, collector, ReasonSessionDuration)
+	handler.OnClose()
+}
+
+func TestMaxConnectionDuration(t *testing.T) {
+	collector, connection := createTimeoutConnection(t, config.SecurityTimeoutConfig{
+		MaxConnectionDuration: 200 * time.Millisecond,
+	})
+	session1 := newPolicySessionChannel()
+	handler1, err := connection.OnSessionChannel(createChannelMetadata(0), []byte{}, session1)
+	assert.Nil(t, err)
+	assert.NoError(t, handler1.OnShell(0))
+	session2 := newPolicySessionChannel()
+	handler2, err := connection.OnSessionChannel(createChannelMetadata(1), []byte{}, session2)
+	assert.Nil(t, err)
+	assert.NoError(t, handler2.OnShell(0))
+
+	time.Sleep(400 * time.Millisecond)
+	reason1, disconnected1 := session1.result()
+	reason2, disconnected2 := session2.result()
+	assert.True(t, disconnected1 || disconnected2, "the connection was not closed")
+	assert.False(t, disconnected1 && disconnected2, "the connection was closed twice")
+	assert.Equal(t, ReasonConnectionDuration, reason1+reason2)
+	assertPolicyDisconnects(t, collector, ReasonConnectionDuration)
+
+	_, rejection := connection.OnSessionChannel(createChannelMetadata(2), []byte{}, newPolicySessionChannel())
+	assert.NotNil(t, rejection)
+	handler1.OnClose()
+	handler2.OnClose()
+}
+
+func createTimeoutConnection(
+	t *testing.T,
+	timeouts config.SecurityTimeoutConfig,
+) (metrics.Collector, *sshConnectionHandler) {
+	collector := metrics.New(dummy.New())
+	counter := collector.MustCreateCounter(
+		MetricNamePolicyDisconnects,
+		MetricUnitPolicyDisconnects,
+		MetricHelpPolicyDisconnects,
+	)
+	return collector, &sshConnectionHandler{
+		config: config.SecurityConfig{
+			MaxSessions: -1,
+			Timeouts:    timeouts,
+		},
+		backend:           &dummySSHBackend{},
+		lock:              &sync.Mutex{},
+		logger:            log.NewTestLogger(t),
+		connectedAt:       time.Now(),
+		disconnectCounter: counter,
+	}
+}
+
+func assertPolicyDisconnects(t *testing.T, collector metrics.Collector, reason string) {
+	values := collector.GetMetric(MetricNamePolicyDisconnects)
+	if !assert.Len(t, values, 1) {
+		return
+	}
+	assert.Equal(t, float64(1), values[0].Value)
+	assert.Equal(t, reason, values[0].Labels[MetricLabelReason])
+}
+
+// policySessionChannel is a session channel that records the policy-based close.
+type policySessionChannel struct {
+	lock       *sync.Mutex
+	stderr     *bytes.Buffer
+	reason     string
+	connection bool
+	closed     chan struct{}
+}
+
+func newPolicySessionChannel() *policySessionChannel {
+	return &policySessionChannel{
+		lock:   &sync.Mutex{},
+		stderr: &bytes.Buffer{},
+		closed: make(chan struct{}),
+	}
+}
+
+var _ sshserver.PolicyCloser = &policySessionChannel{}
+
+func (p *policySessionChannel) Stdin() io.Reader {
+	return strings.NewReader("")
+}
+
+func (p *policySessionChannel) Stdout() io.Writer {
+	return io.Discard
+}
+
+func (p *policySessionChannel) Stderr() io.Writer {
+	return p
+}
+
+func (p *policySessionChannel) Write(data []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.stderr.Write(data)
+}
+
+func (p *policySessionChannel) ExitStatus(_ uint32) {}
+
+func (p *policySessionChannel) ExitSignal(_ string, _ bool, _ string, _ string) {}
+
+func (p *policySessionChannel) CloseWrite() error {
+	return nil
+}
+
+func (p *policySessionChannel) Close() error {
+	return p.CloseForPolicy("")
+}
+
+func (p *policySessionChannel) CloseForPolicy(reason string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reason = reason
+	close(p.closed)
+	return nil
+}
+
+func (p *policySessionChannel) DisconnectForPolicy(reason string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reason = reason
+	p.connection = true
+	close(p.closed)
+	return nil
+}
+
+func (p *policySessionChannel) stderrText() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.stderr.String()
+}
+
+func (p *policySessionChannel) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *policySessionChannel) result() (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.reason, p.connection
+}
+
+func (p *policySessionChannel) wait(t *testing.T) (string, bool) {
+	select {
+	case <-p.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session was not closed")
+	}
+	return p.result()
+}
@@ -0,0 +1,102 @@
+package security
+
+import (
+	"sync"
+
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/message"
+)
+
+// UserConnections counts the open connections of each user across the whole server. It is shared between the
+// connections to enforce the MaxConnectionsPerUser setting.
+type UserConnections struct {
+	lock        *sync.Mutex
+	connections map[string]int
+}
+
+// NewUserConnections creates an empty connection counter for the users.
+func NewUserConnections() *UserConnections {
+	return &UserConnections{
+		lock:        &sync.Mutex{},
+		connections: map[string]int{},
+	}
+}
+
+// add records a new connection of the user and returns the number of open connections including the new one.
+func (u *UserConnections) add(username string) int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.connections[username]++
+	return u.connections[username]
+}
+
+// remove records that a connection of the user has been closed.
+func (u *UserConnections) remove(username string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.connections[username]--
+	if u.connections[username] <= 0 {
+		delete(u.connections, username)
+	}
+}
+
+// limitedChannel is a forward channel that frees its place in a per-connection limit when it is closed.
+type limitedChannel struct {
+	sshserver.ForwardChannel
+
+	once    *sync.Once
+	release func()
+}
+
+func newLimitedChannel(channel sshserver.ForwardChannel, release func()) sshserver.ForwardChannel {
+	return &limitedChannel{
+		ForwardChannel: channel,
+		once:           &sync.Once{},
+		release:        release,
+	}
+}
+
+func (l *limitedChannel) Close() error {
+	l.once.Do(l.release)
+	return l.ForwardChannel.Close()
+}
+
+// x11LimitHandler wraps the reverse forwarding handler of an X11 request and limits how many X11 channels can be open
+// at the same time on the connection.
+type x11LimitHandler struct {
+	sshserver.ReverseForward
+
+	connection *sshConnectionHandler
+	session    sshserver.SessionChannel
+}
+
+func (x *x11LimitHandler) NewChannelX11(
+	originatorAddress string,
+	originatorPort uint32,
+) (sshserver.ForwardChannel, uint64, error) {
+	s := x.connection
+	s.lock.Lock()
+	if s.config.MaxX11Channels > -1 && s.x11Channels >= uint(s.config.MaxX11Channels) {
+		err := message.UserMessage(
+			message.ESecurityMaxX11Channels,
+			"Too many X11 channels.",
+			"The X11 connection from %s port %d is not forwarded because the connection has too many open X11 channels.",
+			originatorAddress,
+			originatorPort,
+		)
+		// The X11 channel belongs to a session, so the session records the rejection in dry run mode.
+		if s.enforce(RuleMaxX11Channels, err, x.session) {
+			s.lock.Unlock()
+			return nil, 0, err
+		}
+	}
+	s.x11Channels++
+	s.lock.Unlock()
+
+	channel, channelID, err := x.ReverseForward.NewChannelX11(originatorAddress, originatorPort)
+	if err != nil {
+		s.releaseX11Channel()
+		return nil, channelID, err
+	}
+	return newLimitedChannel(channel, s.releaseX11Channel), channelID, nil
+}
@@ -0,0 +1,209 @@
+package security //nolint:testpackage
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestSessionLimitReleased(t *testing.T) {
+	connection := createLimitConnection(t, config.SecurityConfig{MaxSessions: 1})
+
+	handler, err := connection.OnSessionChannel(createChannelMetadata(0), []byte{}, &sessionChannel{})
+	assert.Nil(t, err)
+	_, err = connection.OnSessionChannel(createChannelMetadata(1), []byte{}, &sessionChannel{})
+	assert.Equal(t, message.ESecurityMaxSessions, err.Code())
+
+	handler.OnClose()
+	_, err = connection.OnSessionChannel(createChannelMetadata(2), []byte{}, &sessionChannel{})
+	assert.Nil(t, err)
+}
+
+func TestMaxForwardChannels(t *testing.T) {
+	connection := createLimitConnection(t, config.SecurityConfig{MaxForwardChannels: 2, MaxReverseForwards: -1})
+
+	channel1, err := connection.OnTCPForwardChannel(1, "example.com", 80, "127.0.0.1", 12345)
+	assert.Nil(t, err)
+	channel2, err := connection.OnDirectStreamLocal(2, "/tmp/app.sock")
+	assert.Nil(t, err)
+	_, err = connection.OnTCPForwardChannel(3, "example.com", 80, "127.0.0.1", 12345)
+	assert.Equal(t, message.ESecurityMaxForwardChannels, err.Code())
+	_, err = connection.OnDirectStreamLocal(4, "/tmp/app.sock")
+	assert.Equal(t, message.ESecurityMaxForwardChannels, err.Code())
+
+	assert.NoError(t, channel1.Close())
+	// Closing twice must not free two places.
+	assert.NoError(t, channel1.Close())
+	_, err = connection.OnTCPForwardChannel(5, "example.com", 80, "127.0.0.1", 12345)
+	assert.Nil(t, err)
+	_, err = connection.OnTCPForwardChannel(6, "example.com", 80, "127.0.0.1", 12345)
+	assert.NotNil(t, err)
+	assert.NoError(t, channel2.Close())
+}
+
+func TestMaxReverseForwards(t *testing.T) {
+	connection := createLimitConnection(t, config.SecurityConfig{MaxForwardChannels: -1, MaxReverseForwards: 2})
+
+	assert.NoError(t, connection.OnRequestTCPReverseForward("127.0.0.1", 8080, nil))
+	assert.NoError(t, connection.OnRequestStreamLocal("/tmp/app.sock", nil))
+	// Requesting an active binding again is not counted.
+	assert.NoError(t, connection.OnRequestTCPReverseForward("127.0.0.1", 8080, nil))
+	assert.True(t, isLimitRejection(connection.OnRequestTCPReverseForward("127.0.0.1", 8081, nil)))
+	assert.True(t, isLimitRejection(connection.OnRequestStreamLocal("/tmp/other.sock", nil)))
+
+	assert.NoError(t, connection.OnRequestCancelTCPReverseForward("127.0.0.1", 8080))
+	assert.NoError(t, connection.OnRequestTCPReverseForward("127.0.0.1", 8081, nil))
+	assert.NoError(t, connection.OnRequestCancelStreamLocal("/tmp/app.sock"))
+	assert.NoError(t, connection.OnRequestStreamLocal("/tmp/other.sock", nil))
+}
+
+func TestMaxX11Channels(t *testing.T) {
+	connection := createLimitConnection(t, config.SecurityConfig{MaxX11Channels: 1})
+	handler := &x11LimitHandler{
+		ReverseForward: &pipeReverseForward{},
+		connection:     connection,
+	}
+
+	channel, _, err := handler.NewChannelX11("127.0.0.1", 6010)
+	assert.NoError(t, err)
+	_, _, err = handler.NewChannelX11("127.0.0.1", 6011)
+	var msg message.Message
+	if assert.ErrorAs(t, err, &msg) {
+		assert.Equal(t, message.ESecurityMaxX11Channels, msg.Code())
+	}
+
+	assert.NoError(t, channel.Close())
+	_, _, err = handler.NewChannelX11("127.0.0.1", 6012)
+	assert.NoError(t, err)
+}
+
+func TestMaxConnectionsPerUser(t *testing.T) {
+	userConnections := NewUserConnections()
+	connect := func(username string) (*networkHandler, error) {
+		handler := &networkHandler{
+			config:          config.SecurityConfig{MaxConnectionsPerUser: 2},
+			backend:         &limitNetworkBackend{},
+			logger:          log.NewTestLogger(t),
+			userConnections: userConnections,
+		}
+		_, _, err := handler.OnHandshakeSuccess(createConnectionMetadata(username))
+		return handler, err
+	}
+
+	foo1, err := connect("foo")
+	assert.NoError(t, err)
+	_, err = connect("foo")
+	assert.NoError(t, err)
+	rejected, err := connect("foo")
+	var msg message.Message
+	if assert.ErrorAs(t, err, &msg) {
+		assert.Equal(t, message.ESecurityMaxUserConnections, msg.Code())
+	}
+	// The rejected connection is still disconnected, which must not free a place.
+	rejected.OnDisconnect()
+	_, err = connect("foo")
+	assert.Error(t, err)
+	_, err = connect("bar")
+	assert.NoError(t, err)
+
+	foo1.OnDisconnect()
+	_, err = connect("foo")
+	assert.NoError(t, err)
+}
+
+func createLimitConnection(t *testing.T, cfg config.SecurityConfig) *sshConnectionHandler {
+	return &sshConnectionHandler{
+		config:  cfg,
+		backend: &forwardingSSHBackend{},
+		lock:    &sync.Mutex{},
+		logger:  log.NewTestLogger(t),
+	}
+}
+
+func createConnectionMetadata(username string) metadata.ConnectionAuthenticatedMetadata {
+	return metadata.ConnectionAuthenticatedMetadata{
+		ConnectionAuthPendingMetadata: metadata.ConnectionAuthPendingMetadata{
+			ConnectionMetadata: metadata.ConnectionMetadata{ConnectionID: "asdf"},
+			Username:           username,
+		},
+		AuthenticatedUsername: username,
+	}
+}
+
+func isLimitRejection(err error) bool {
+	var msg message.Message
+	return errors.As(err, &msg) && msg.Code() == message.ESecurityMaxReverseForwards
+}
+
+// forwardingSSHBackend is an SSH connection backend that accepts all forwarding requests.
+type forwardingSSHBackend struct {
+	dummySSHBackend
+}
+
+func (f *forwardingSSHBackend) OnTCPForwardChannel(
+	_ uint64,
+	_ string,
+	_ uint32,
+	_ string,
+	_ uint32,
+) (sshserver.ForwardChannel, sshserver.ChannelRejection) {
+	channel, _ := net.Pipe()
+	return channel, nil
+}
+
+func (f *forwardingSSHBackend) OnDirectStreamLocal(_ uint64, _ string) (
+	sshserver.ForwardChannel,
+	sshserver.ChannelRejection,
+) {
+	channel, _ := net.Pipe()
+	return channel, nil
+}
+
+func (f *forwardingSSHBackend) OnRequestTCPReverseForward(_ string, _ uint32, _ sshserver.ReverseForward) error {
+	return nil
+}
+
+func (f *forwardingSSHBackend) OnRequestCancelTCPReverseForward(_ string, _ uint32) error {
+	return nil
+}
+
+func (f *forwardingSSHBackend) OnRequestStreamLocal(_ string, _ sshserver.ReverseForward) error {
+	return nil
+}
+
+func (f *forwardingSSHBackend) OnRequestCancelStreamLocal(_ string) error {
+	return nil
+}
+
+// pipeReverseForward opens an in-memory channel for each reverse forwarding request.
+type pipeReverseForward struct {
+	sshserver.ReverseForward
+}
+
+func (p *pipeReverseForward) NewChannelX11(_ string, _ uint32) (sshserver.ForwardChannel, uint64, error) {
+	channel, _ := net.Pipe()
+	return channel, 1, nil
+}
+
+// limitNetworkBackend is a network connection backend that accepts the handshake.
+type limitNetworkBackend struct {
+	sshserver.NetworkConnectionHandler
+}
+
+func (l *limitNetworkBackend) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	sshserver.SSHConnectionHandler,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	return &dummySSHBackend{}, meta, nil
+}
+
+func (l *limitNetworkBackend) OnDisconnect() {}
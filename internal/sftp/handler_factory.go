@@ -0,0 +1,32 @@
+package sftp
+
+import (
+	"fmt"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+)
+
+// New creates a backend proxy that serves the sftp subsystem with the built-in SFTP server. The file operations are
+// executed by a shell started through the backend, so it works with every backend. If the built-in SFTP server is not
+// enabled the backend is returned unchanged. The hook is consulted for every operation and may be nil.
+func New(
+	config config.SFTPConfig,
+	backend sshserver.NetworkConnectionHandler,
+	logger log.Logger,
+	hook Hook,
+) (sshserver.NetworkConnectionHandler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid SFTP configuration (%w)", err)
+	}
+	if !config.Enable {
+		return backend, nil
+	}
+	return &networkHandler{
+		NetworkConnectionHandler: backend,
+		config:                   config,
+		logger:                   logger,
+		hook:                     hook,
+	}, nil
+}
@@ -0,0 +1,33 @@
+package sftp
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type networkHandler struct {
+	sshserver.NetworkConnectionHandler
+
+	config config.SFTPConfig
+	logger log.Logger
+	hook   Hook
+}
+
+func (n *networkHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	connection sshserver.SSHConnectionHandler,
+	metadata metadata.ConnectionAuthenticatedMetadata,
+	failureReason error,
+) {
+	backend, meta, failureReason := n.NetworkConnectionHandler.OnHandshakeSuccess(meta)
+	if failureReason != nil {
+		return nil, meta, failureReason
+	}
+	return &sshConnectionHandler{
+		SSHConnectionHandler: backend,
+		config:               n.config,
+		logger:               n.logger,
+		hook:                 n.hook,
+	}, meta, nil
+}
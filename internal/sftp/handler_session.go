@@ -0,0 +1,99 @@
+package sftp
+
+import (
+	"errors"
+	"io"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type sessionHandler struct {
+	sshserver.SessionChannelHandler
+
+	config config.SFTPConfig
+	meta   metadata.ChannelMetadata
+	// client is the session channel of the client the SFTP server talks to.
+	client sshserver.SessionChannel
+	// session is the session channel passed to the backend.
+	session *sessionChannel
+	logger  log.Logger
+	hook    Hook
+	pty     bool
+}
+
+func (s *sessionHandler) OnPtyRequest(
+	requestID uint64,
+	term string,
+	columns uint32,
+	rows uint32,
+	width uint32,
+	height uint32,
+	modeList []byte,
+) error {
+	if err := s.SessionChannelHandler.OnPtyRequest(requestID, term, columns, rows, width, height, modeList); err != nil {
+		return err
+	}
+	s.pty = true
+	return nil
+}
+
+func (s *sessionHandler) OnSubsystem(requestID uint64, subsystem string) error {
+	// A terminal would echo the commands sent to the shell, so the subsystem of the backend is used in that case.
+	if subsystem != "sftp" || s.pty {
+		return s.SessionChannelHandler.OnSubsystem(requestID, subsystem)
+	}
+	shellStdin, shellStdout := s.session.startShell()
+	if err := s.SessionChannelHandler.OnExecRequest(requestID, s.config.Shell); err != nil {
+		s.session.stopShell()
+		return err
+	}
+	s.logger.Debug(message.NewMessage(message.MSFTPStarted, "Starting the built-in SFTP server."))
+	go s.serve(shellStdin, shellStdout)
+	return nil
+}
+
+func (s *sessionHandler) serve(shellStdin io.WriteCloser, shellStdout io.Reader) {
+	exitStatus := uint32(0)
+	fs, err := newShellFileSystem(s.config.Root, shellStdin, shellStdout)
+	if err != nil {
+		s.logger.Error(message.Wrap(
+			err,
+			message.ESFTPShellFailed,
+			"Failed to start the shell of the built-in SFTP server.",
+		))
+		exitStatus = 1
+	} else {
+		err = newServer(s.config, s.meta, fs, s.hook, s.logger).serve(s.client.Stdin(), s.client.Stdout())
+		switch {
+		case err == nil:
+		case errors.Is(err, errShellExited):
+			s.logger.Error(message.Wrap(
+				err,
+				message.ESFTPShellFailed,
+				"The shell of the built-in SFTP server has stopped unexpectedly.",
+			))
+			exitStatus = 1
+		default:
+			s.logger.Debug(message.Wrap(err, message.ESFTPProtocolError, "The SFTP session has failed."))
+			exitStatus = 1
+		}
+	}
+	// Closing the input ends the shell. Its remaining output is discarded so it cannot block on writing.
+	_ = shellStdin.Close()
+	go func() {
+		_, _ = io.Copy(io.Discard, shellStdout)
+	}()
+	s.client.ExitStatus(exitStatus)
+	if err := s.client.Close(); err != nil && !errors.Is(err, io.EOF) {
+		s.logger.Debug(message.Wrap(err, message.ESFTPShellFailed, "Failed to close the SFTP session."))
+	}
+}
+
+func (s *sessionHandler) OnClose() {
+	s.SessionChannelHandler.OnClose()
+	s.session.closeShell()
+}
@@ -0,0 +1,190 @@
+package sftp //nolint:testpackage
+
+import (
+	"encoding/binary"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestSubsystem(t *testing.T) {
+	backend := &shellBackend{}
+	handler, err := New(config.SFTPConfig{Enable: true, Shell: "/bin/sh", Root: "/"}, &shellNetworkBackend{
+		backend: backend,
+	}, log.NewTestLogger(t), nil)
+	assert.NoError(t, err)
+	connection, _, err := handler.OnHandshakeSuccess(metadata.ConnectionAuthenticatedMetadata{})
+	assert.NoError(t, err)
+	client := newClientSession()
+	session, rejection := connection.OnSessionChannel(metadata.ChannelMetadata{}, nil, client)
+	assert.Nil(t, rejection)
+
+	assert.NoError(t, session.OnSubsystem(1, "sftp"))
+	assert.Equal(t, []string{"/bin/sh"}, backend.programs)
+
+	_, err = client.stdinWriter.Write(newPacket(packetInit).uint32(protocolVersion).finish())
+	assert.NoError(t, err)
+	assert.Equal(t, packetVersion, readPacket(t, client).t)
+	_, err = client.stdinWriter.Write(newPacket(packetStat).uint32(1).string("/").finish())
+	assert.NoError(t, err)
+	response := readPacket(t, client)
+	assert.Equal(t, packetAttrs, response.t)
+	assert.Equal(t, uint32(1), response.r.uint32())
+	assert.Equal(t, uint32(0o040000), response.r.attributes().Mode&0o170000)
+
+	// Closing the input ends the SFTP session successfully.
+	assert.NoError(t, client.stdinWriter.Close())
+	select {
+	case <-client.closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the session was not closed")
+	}
+	assert.Equal(t, uint32(0), client.exitStatus)
+	session.OnClose()
+}
+
+func TestOtherSubsystem(t *testing.T) {
+	backend := &shellBackend{}
+	handler, err := New(config.SFTPConfig{Enable: true, Shell: "/bin/sh", Root: "/"}, &shellNetworkBackend{
+		backend: backend,
+	}, log.NewTestLogger(t), nil)
+	assert.NoError(t, err)
+	connection, _, err := handler.OnHandshakeSuccess(metadata.ConnectionAuthenticatedMetadata{})
+	assert.NoError(t, err)
+	session, rejection := connection.OnSessionChannel(metadata.ChannelMetadata{}, nil, newClientSession())
+	assert.Nil(t, rejection)
+
+	assert.NoError(t, session.OnSubsystem(1, "other"))
+	assert.Equal(t, []string{"subsystem other"}, backend.programs)
+}
+
+func TestDisabled(t *testing.T) {
+	backend := &shellNetworkBackend{}
+	handler, err := New(config.SFTPConfig{}, backend, log.NewTestLogger(t), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, backend, handler)
+}
+
+func readPacket(t *testing.T, client *clientSession) response {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(client.stdoutReader, header); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(client.stdoutReader, data); err != nil {
+		t.Fatal(err)
+	}
+	return response{t: packetType(data[0]), r: &packetReader{data: data[1:]}}
+}
+
+// clientSession is the session channel of the client with the standard input and output connected to pipes.
+type clientSession struct {
+	stdinReader  *io.PipeReader
+	stdinWriter  *io.PipeWriter
+	stdoutReader *io.PipeReader
+	stdoutWriter *io.PipeWriter
+	exitStatus   uint32
+	closed       chan struct{}
+}
+
+func newClientSession() *clientSession {
+	c := &clientSession{closed: make(chan struct{})}
+	c.stdinReader, c.stdinWriter = io.Pipe()
+	c.stdoutReader, c.stdoutWriter = io.Pipe()
+	return c
+}
+
+func (c *clientSession) Stdin() io.Reader {
+	return c.stdinReader
+}
+
+func (c *clientSession) Stdout() io.Writer {
+	return c.stdoutWriter
+}
+
+func (c *clientSession) Stderr() io.Writer {
+	return io.Discard
+}
+
+func (c *clientSession) ExitStatus(code uint32) {
+	c.exitStatus = code
+}
+
+func (c *clientSession) ExitSignal(_ string, _ bool, _ string, _ string) {}
+
+func (c *clientSession) CloseWrite() error {
+	return c.stdoutWriter.Close()
+}
+
+func (c *clientSession) Close() error {
+	close(c.closed)
+	return c.stdoutWriter.Close()
+}
+
+// shellNetworkBackend is a network connection backend that accepts the handshake.
+type shellNetworkBackend struct {
+	sshserver.NetworkConnectionHandler
+
+	backend *shellBackend
+}
+
+func (s *shellNetworkBackend) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	sshserver.SSHConnectionHandler,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	return s.backend, meta, nil
+}
+
+// shellBackend runs the programs of the sessions as local processes, similar to how the container backends run them.
+type shellBackend struct {
+	sshserver.SSHConnectionHandler
+
+	programs []string
+}
+
+func (s *shellBackend) OnSessionChannel(
+	_ metadata.ChannelMetadata,
+	_ []byte,
+	session sshserver.SessionChannel,
+) (sshserver.SessionChannelHandler, sshserver.ChannelRejection) {
+	return &shellSession{backend: s, session: session}, nil
+}
+
+type shellSession struct {
+	sshserver.SessionChannelHandler
+
+	backend *shellBackend
+	session sshserver.SessionChannel
+}
+
+func (s *shellSession) OnExecRequest(_ uint64, program string) error {
+	s.backend.programs = append(s.backend.programs, program)
+	cmd := exec.Command(program)
+	cmd.Stdin = s.session.Stdin()
+	cmd.Stdout = s.session.Stdout()
+	cmd.Stderr = s.session.Stderr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		_ = cmd.Wait()
+		s.session.ExitStatus(uint32(cmd.ProcessState.ExitCode()))
+		_ = s.session.Close()
+	}()
+	return nil
+}
+
+func (s *shellSession) OnSubsystem(_ uint64, subsystem string) error {
+	s.backend.programs = append(s.backend.programs, "subsystem "+subsystem)
+	return nil
+}
+
+func (s *shellSession) OnClose() {}
@@ -0,0 +1,37 @@
+package sftp
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+type sshConnectionHandler struct {
+	sshserver.SSHConnectionHandler
+
+	config config.SFTPConfig
+	logger log.Logger
+	hook   Hook
+}
+
+func (s *sshConnectionHandler) OnSessionChannel(
+	channelMetadata metadata.ChannelMetadata,
+	extraData []byte,
+	session sshserver.SessionChannel,
+) (channel sshserver.SessionChannelHandler, failureReason sshserver.ChannelRejection) {
+	proxy := newSessionChannel(session)
+	backend, failureReason := s.SSHConnectionHandler.OnSessionChannel(channelMetadata, extraData, proxy)
+	if failureReason != nil {
+		return nil, failureReason
+	}
+	return &sessionHandler{
+		SessionChannelHandler: backend,
+		config:                s.config,
+		meta:                  channelMetadata,
+		client:                session,
+		session:               proxy,
+		logger:                s.logger,
+		hook:                  s.hook,
+	}, nil
+}
@@ -0,0 +1,225 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// protocolVersion is the SFTP protocol version implemented by the server.
+const protocolVersion = 3
+
+// maxPacketLength is the largest packet accepted from the client. Clients send at most 32 kB of data in a write
+// request by default, so this leaves ample room.
+const maxPacketLength = 256 * 1024
+
+// maxReadLength is the most data returned for a single read request.
+const maxReadLength = 64 * 1024
+
+type packetType byte
+
+const (
+	packetInit     packetType = 1
+	packetVersion  packetType = 2
+	packetOpen     packetType = 3
+	packetClose    packetType = 4
+	packetRead     packetType = 5
+	packetWrite    packetType = 6
+	packetLstat    packetType = 7
+	packetFstat    packetType = 8
+	packetSetstat  packetType = 9
+	packetFsetstat packetType = 10
+	packetOpendir  packetType = 11
+	packetReaddir  packetType = 12
+	packetRemove   packetType = 13
+	packetMkdir    packetType = 14
+	packetRmdir    packetType = 15
+	packetRealpath packetType = 16
+	packetStat     packetType = 17
+	packetRename   packetType = 18
+	packetReadlink packetType = 19
+	packetSymlink  packetType = 20
+	packetStatus   packetType = 101
+	packetHandle   packetType = 102
+	packetData     packetType = 103
+	packetName     packetType = 104
+	packetAttrs    packetType = 105
+)
+
+type statusCode uint32
+
+const (
+	statusOK               statusCode = 0
+	statusEOF              statusCode = 1
+	statusNoSuchFile       statusCode = 2
+	statusPermissionDenied statusCode = 3
+	statusFailure          statusCode = 4
+	statusBadMessage       statusCode = 5
+	statusOpUnsupported    statusCode = 8
+)
+
+// Open flags of the open request.
+const (
+	openRead   uint32 = 0x01
+	openWrite  uint32 = 0x02
+	openAppend uint32 = 0x04
+	openCreate uint32 = 0x08
+	openTrunc  uint32 = 0x10
+	openExcl   uint32 = 0x20
+)
+
+// Flags of the attributes structure.
+const (
+	attrSize        uint32 = 0x01
+	attrUIDGID      uint32 = 0x02
+	attrPermissions uint32 = 0x04
+	attrACModTime   uint32 = 0x08
+	attrExtended    uint32 = 0x80000000
+)
+
+var errShortPacket = errors.New("packet too short")
+
+// packetReader decodes the fields of a received packet.
+type packetReader struct {
+	data []byte
+	err  error
+}
+
+func (r *packetReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errShortPacket
+		return nil
+	}
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+
+func (r *packetReader) byte() byte {
+	data := r.take(1)
+	if data == nil {
+		return 0
+	}
+	return data[0]
+}
+
+func (r *packetReader) uint32() uint32 {
+	data := r.take(4)
+	if data == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(data)
+}
+
+func (r *packetReader) uint64() uint64 {
+	data := r.take(8)
+	if data == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+func (r *packetReader) bytes() []byte {
+	length := r.uint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(length) > uint64(len(r.data)) {
+		r.err = errShortPacket
+		return nil
+	}
+	return r.take(int(length))
+}
+
+func (r *packetReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *packetReader) attributes() Attributes {
+	attrs := Attributes{Flags: r.uint32()}
+	if attrs.Flags&attrSize != 0 {
+		attrs.Size = r.uint64()
+	}
+	if attrs.Flags&attrUIDGID != 0 {
+		attrs.UID = r.uint32()
+		attrs.GID = r.uint32()
+	}
+	if attrs.Flags&attrPermissions != 0 {
+		attrs.Mode = r.uint32()
+	}
+	if attrs.Flags&attrACModTime != 0 {
+		attrs.ATime = r.uint32()
+		attrs.MTime = r.uint32()
+	}
+	if attrs.Flags&attrExtended != 0 {
+		count := r.uint32()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			r.string()
+			r.string()
+		}
+	}
+	// Extended attributes are not supported and are ignored.
+	attrs.Flags &^= attrExtended
+	return attrs
+}
+
+// packetWriter encodes a packet to send to the client.
+type packetWriter struct {
+	data []byte
+}
+
+func newPacket(t packetType) *packetWriter {
+	// The length is filled in by the finish method.
+	return &packetWriter{data: []byte{0, 0, 0, 0, byte(t)}}
+}
+
+func (w *packetWriter) byte(value byte) *packetWriter {
+	w.data = append(w.data, value)
+	return w
+}
+
+func (w *packetWriter) uint32(value uint32) *packetWriter {
+	w.data = binary.BigEndian.AppendUint32(w.data, value)
+	return w
+}
+
+func (w *packetWriter) uint64(value uint64) *packetWriter {
+	w.data = binary.BigEndian.AppendUint64(w.data, value)
+	return w
+}
+
+func (w *packetWriter) bytes(value []byte) *packetWriter {
+	w.uint32(uint32(len(value)))
+	w.data = append(w.data, value...)
+	return w
+}
+
+func (w *packetWriter) string(value string) *packetWriter {
+	return w.bytes([]byte(value))
+}
+
+func (w *packetWriter) attributes(attrs Attributes) *packetWriter {
+	w.uint32(attrs.Flags)
+	if attrs.Flags&attrSize != 0 {
+		w.uint64(attrs.Size)
+	}
+	if attrs.Flags&attrUIDGID != 0 {
+		w.uint32(attrs.UID)
+		w.uint32(attrs.GID)
+	}
+	if attrs.Flags&attrPermissions != 0 {
+		w.uint32(attrs.Mode)
+	}
+	if attrs.Flags&attrACModTime != 0 {
+		w.uint32(attrs.ATime)
+		w.uint32(attrs.MTime)
+	}
+	return w
+}
+
+func (w *packetWriter) finish() []byte {
+	binary.BigEndian.PutUint32(w.data, uint32(len(w.data)-4))
+	return w.data
+}
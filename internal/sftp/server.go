@@ -0,0 +1,484 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// maxNamesPerPacket is the number of directory entries returned in one response to a read directory request.
+const maxNamesPerPacket = 100
+
+// server implements version 3 of the SFTP protocol on top of a shell file system.
+type server struct {
+	config config.SFTPConfig
+	meta   metadata.ChannelMetadata
+	fs     *shellFileSystem
+	hook   Hook
+	logger log.Logger
+
+	handles    map[string]interface{}
+	nextHandle uint64
+}
+
+type directory struct {
+	files []File
+}
+
+func newServer(
+	config config.SFTPConfig,
+	meta metadata.ChannelMetadata,
+	fs *shellFileSystem,
+	hook Hook,
+	logger log.Logger,
+) *server {
+	return &server{
+		config:  config,
+		meta:    meta,
+		fs:      fs,
+		hook:    hook,
+		logger:  logger,
+		handles: map[string]interface{}{},
+	}
+}
+
+// serve processes the requests from the client until the input is closed. It returns nil if the client has closed
+// the input, errShellExited if the shell has stopped, or a protocol error.
+func (s *server) serve(input io.Reader, output io.Writer) error {
+	defer s.closeHandles()
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(input, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length == 0 || length > maxPacketLength {
+			return message.UserMessage(
+				message.ESFTPProtocolError,
+				"Invalid SFTP packet.",
+				"The SFTP client sent a packet with an invalid length of %d bytes.",
+				length,
+			)
+		}
+		packet := make([]byte, length)
+		if _, err := io.ReadFull(input, packet); err != nil {
+			return err
+		}
+		response, err := s.handle(packetType(packet[0]), &packetReader{data: packet[1:]})
+		if err != nil {
+			return err
+		}
+		if _, err := output.Write(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(t packetType, r *packetReader) ([]byte, error) {
+	if t == packetInit {
+		r.uint32()
+		return newPacket(packetVersion).uint32(protocolVersion).finish(), nil
+	}
+	id := r.uint32()
+	if r.err != nil {
+		return nil, s.protocolError(t)
+	}
+	// All fields are decoded before the operation is executed so malformed packets are not executed.
+	var operation func() ([]byte, error)
+	switch t {
+	case packetOpen:
+		p, flags, attrs := r.string(), r.uint32(), r.attributes()
+		operation = func() ([]byte, error) { return s.open(id, p, flags, attrs) }
+	case packetClose:
+		handle := r.string()
+		operation = func() ([]byte, error) { return s.closeHandle(id, handle) }
+	case packetRead:
+		handle, offset, length := r.string(), r.uint64(), r.uint32()
+		operation = func() ([]byte, error) { return s.read(id, handle, offset, length) }
+	case packetWrite:
+		handle, offset, data := r.string(), r.uint64(), r.bytes()
+		operation = func() ([]byte, error) { return s.write(id, handle, offset, data) }
+	case packetStat, packetLstat:
+		p := r.string()
+		operation = func() ([]byte, error) { return s.stat(id, p, t == packetStat) }
+	case packetFstat:
+		handle := r.string()
+		operation = func() ([]byte, error) { return s.fstat(id, handle) }
+	case packetSetstat:
+		p, attrs := r.string(), r.attributes()
+		operation = func() ([]byte, error) { return s.setStat(id, p, attrs) }
+	case packetFsetstat:
+		handle, attrs := r.string(), r.attributes()
+		operation = func() ([]byte, error) { return s.fsetStat(id, handle, attrs) }
+	case packetOpendir:
+		p := r.string()
+		operation = func() ([]byte, error) { return s.openDir(id, p) }
+	case packetReaddir:
+		handle := r.string()
+		operation = func() ([]byte, error) { return s.readDir(id, handle) }
+	case packetRemove:
+		p := r.string()
+		operation = func() ([]byte, error) {
+			return s.simple(id, config.SFTPOperationRemove, p, func() error { return s.fs.remove(p) })
+		}
+	case packetMkdir:
+		p, attrs := r.string(), r.attributes()
+		operation = func() ([]byte, error) {
+			return s.simple(id, config.SFTPOperationMkdir, p, func() error { return s.fs.mkdir(p, attrs) })
+		}
+	case packetRmdir:
+		p := r.string()
+		operation = func() ([]byte, error) {
+			return s.simple(id, config.SFTPOperationRmdir, p, func() error { return s.fs.rmdir(p) })
+		}
+	case packetRealpath:
+		// Paths are resolved without following symbolic links, so this does not touch the file system.
+		p := r.string()
+		operation = func() ([]byte, error) { return s.name(id, []File{{Name: cleanPath(p)}}, false), nil }
+	case packetRename:
+		oldPath, newPath := r.string(), r.string()
+		operation = func() ([]byte, error) { return s.rename(id, oldPath, newPath) }
+	case packetReadlink:
+		p := r.string()
+		operation = func() ([]byte, error) { return s.readLink(id, p) }
+	case packetSymlink:
+		// OpenSSH sends the target before the link path, contrary to the specification. Clients follow OpenSSH.
+		target, linkPath := r.string(), r.string()
+		operation = func() ([]byte, error) {
+			return s.simple(
+				id, config.SFTPOperationSymlink, linkPath, func() error { return s.fs.symlink(target, linkPath) },
+			)
+		}
+	default:
+		return s.status(id, statusOpUnsupported, nil), nil
+	}
+	if r.err != nil {
+		return nil, s.protocolError(t)
+	}
+	response, err := operation()
+	// Failed operations are reported to the client in the response, only a lost shell ends the session.
+	if errors.Is(err, errShellExited) {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *server) protocolError(t packetType) error {
+	return message.UserMessage(
+		message.ESFTPProtocolError,
+		"Invalid SFTP packet.",
+		"The SFTP client sent a malformed packet of type %d.",
+		t,
+	)
+}
+
+// check applies the read only mode, the deny list and the hook to an operation on path.
+func (s *server) check(operation config.SFTPOperation, p string) error {
+	p = cleanPath(p)
+	var reason string
+	if s.config.ReadOnly && operation.Modifies() {
+		reason = "the SFTP server is read only"
+	}
+	for _, denied := range s.config.Deny {
+		if denied == operation {
+			reason = "the operation is denied"
+		}
+	}
+	if reason == "" && s.hook != nil {
+		if err := s.hook.OnOperation(s.meta, operation, p); err != nil {
+			reason = err.Error()
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	s.logger.Debug(message.NewMessage(
+		message.ESFTPOperationRejected,
+		"SFTP %s operation on %s rejected: %s",
+		operation,
+		p,
+		reason,
+	))
+	return statusError{statusPermissionDenied}
+}
+
+func (s *server) open(id uint32, p string, flags uint32, attrs Attributes) ([]byte, error) {
+	if flags&openRead != 0 || flags&(openWrite|openAppend) == 0 {
+		if err := s.check(config.SFTPOperationRead, p); err != nil {
+			return s.status(id, statusOK, err), nil
+		}
+	}
+	if flags&(openWrite|openAppend) != 0 {
+		if err := s.check(config.SFTPOperationWrite, p); err != nil {
+			return s.status(id, statusOK, err), nil
+		}
+	}
+	f, err := s.fs.open(p, flags, attrs)
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return s.newHandle(id, f), nil
+}
+
+func (s *server) openDir(id uint32, p string) ([]byte, error) {
+	if err := s.check(config.SFTPOperationList, p); err != nil {
+		return s.status(id, statusOK, err), nil
+	}
+	files, err := s.fs.list(p)
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return s.newHandle(id, &directory{files: files}), nil
+}
+
+func (s *server) newHandle(id uint32, value interface{}) []byte {
+	s.nextHandle++
+	handle := strconv.FormatUint(s.nextHandle, 10)
+	s.handles[handle] = value
+	return newPacket(packetHandle).uint32(id).string(handle).finish()
+}
+
+func (s *server) closeHandle(id uint32, handle string) ([]byte, error) {
+	value, ok := s.handles[handle]
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	delete(s.handles, handle)
+	if f, ok := value.(*file); ok {
+		if err := f.close(); err != nil {
+			return s.status(id, statusOK, err), err
+		}
+	}
+	return s.status(id, statusOK, nil), nil
+}
+
+func (s *server) closeHandles() {
+	for handle, value := range s.handles {
+		if f, ok := value.(*file); ok {
+			_ = f.close()
+		}
+		delete(s.handles, handle)
+	}
+}
+
+func (s *server) file(handle string) (*file, bool) {
+	f, ok := s.handles[handle].(*file)
+	return f, ok
+}
+
+func (s *server) read(id uint32, handle string, offset uint64, length uint32) ([]byte, error) {
+	f, ok := s.file(handle)
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	if length > maxReadLength {
+		length = maxReadLength
+	}
+	data, err := f.read(offset, length)
+	if errors.Is(err, io.EOF) {
+		return s.status(id, statusEOF, nil), nil
+	}
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return newPacket(packetData).uint32(id).bytes(data).finish(), nil
+}
+
+func (s *server) write(id uint32, handle string, offset uint64, data []byte) ([]byte, error) {
+	f, ok := s.file(handle)
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	err := f.write(offset, data)
+	return s.status(id, statusOK, err), err
+}
+
+func (s *server) stat(id uint32, p string, followLinks bool) ([]byte, error) {
+	if err := s.check(config.SFTPOperationStat, p); err != nil {
+		return s.status(id, statusOK, err), nil
+	}
+	attrs, err := s.fs.stat(p, followLinks)
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return newPacket(packetAttrs).uint32(id).attributes(attrs).finish(), nil
+}
+
+func (s *server) fstat(id uint32, handle string) ([]byte, error) {
+	f, ok := s.file(handle)
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	attrs, err := f.stat()
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return newPacket(packetAttrs).uint32(id).attributes(attrs).finish(), nil
+}
+
+func (s *server) setStat(id uint32, p string, attrs Attributes) ([]byte, error) {
+	return s.simple(id, config.SFTPOperationSetStat, p, func() error { return s.fs.setStat(p, attrs) })
+}
+
+func (s *server) fsetStat(id uint32, handle string, attrs Attributes) ([]byte, error) {
+	f, ok := s.file(handle)
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	if err := f.flush(); err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return s.simple(id, config.SFTPOperationSetStat, f.clientPath, func() error {
+		return s.fs.setStat(f.clientPath, attrs)
+	})
+}
+
+func (s *server) readDir(id uint32, handle string) ([]byte, error) {
+	dir, ok := s.handles[handle].(*directory)
+	if !ok {
+		return s.status(id, statusFailure, nil), nil
+	}
+	if len(dir.files) == 0 {
+		return s.status(id, statusEOF, nil), nil
+	}
+	files := dir.files
+	if len(files) > maxNamesPerPacket {
+		files = files[:maxNamesPerPacket]
+	}
+	dir.files = dir.files[len(files):]
+	return s.name(id, files, true), nil
+}
+
+func (s *server) rename(id uint32, oldPath string, newPath string) ([]byte, error) {
+	if err := s.check(config.SFTPOperationRename, newPath); err != nil {
+		return s.status(id, statusOK, err), nil
+	}
+	return s.simple(id, config.SFTPOperationRename, oldPath, func() error { return s.fs.rename(oldPath, newPath) })
+}
+
+func (s *server) readLink(id uint32, p string) ([]byte, error) {
+	if err := s.check(config.SFTPOperationReadLink, p); err != nil {
+		return s.status(id, statusOK, err), nil
+	}
+	target, err := s.fs.readLink(p)
+	if err != nil {
+		return s.status(id, statusOK, err), err
+	}
+	return s.name(id, []File{{Name: target}}, false), nil
+}
+
+// simple checks and executes an operation that returns only a status.
+func (s *server) simple(id uint32, operation config.SFTPOperation, p string, execute func() error) ([]byte, error) {
+	if err := s.check(operation, p); err != nil {
+		return s.status(id, statusOK, err), nil
+	}
+	err := execute()
+	return s.status(id, statusOK, err), err
+}
+
+// status creates a status response. If err is a failed operation its status code takes precedence over code.
+func (s *server) status(id uint32, code statusCode, err error) []byte {
+	var statusErr statusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.code
+	} else if err != nil {
+		code = statusFailure
+	}
+	text := "Success"
+	switch code {
+	case statusEOF:
+		text = "End of file"
+	case statusNoSuchFile:
+		text = "No such file"
+	case statusPermissionDenied:
+		text = "Permission denied"
+	case statusFailure:
+		text = "Failure"
+	case statusBadMessage:
+		text = "Bad message"
+	case statusOpUnsupported:
+		text = "Operation unsupported"
+	}
+	return newPacket(packetStatus).uint32(id).uint32(uint32(code)).string(text).string("").finish()
+}
+
+func (s *server) name(id uint32, files []File, longNames bool) []byte {
+	packet := newPacket(packetName).uint32(id).uint32(uint32(len(files)))
+	for _, f := range files {
+		longName := f.Name
+		if longNames {
+			longName = formatLongName(f)
+		}
+		packet.string(f.Name).string(longName).attributes(f.Attributes)
+	}
+	return packet.finish()
+}
+
+// formatLongName formats a directory entry like ls -l does, which some clients display as is.
+func formatLongName(f File) string {
+	return fmt.Sprintf(
+		"%s %4d %-8d %-8d %8d %s %s",
+		formatMode(f.Attributes.Mode),
+		1,
+		f.Attributes.UID,
+		f.Attributes.GID,
+		f.Attributes.Size,
+		time.Unix(int64(f.Attributes.MTime), 0).UTC().Format("Jan _2 15:04"),
+		path.Base(f.Name),
+	)
+}
+
+// formatMode formats the file type and permissions in st_mode like ls -l does.
+func formatMode(mode uint32) string {
+	result := []byte("?rwxrwxrwx")
+	switch mode & 0o170000 {
+	case 0o100000:
+		result[0] = '-'
+	case 0o040000:
+		result[0] = 'd'
+	case 0o120000:
+		result[0] = 'l'
+	case 0o020000:
+		result[0] = 'c'
+	case 0o060000:
+		result[0] = 'b'
+	case 0o010000:
+		result[0] = 'p'
+	case 0o140000:
+		result[0] = 's'
+	}
+	for i := 0; i < 9; i++ {
+		if mode&(1<<(8-i)) == 0 {
+			result[i+1] = '-'
+		}
+	}
+	if mode&0o4000 != 0 {
+		result[3] = setBit(result[3], 's')
+	}
+	if mode&0o2000 != 0 {
+		result[6] = setBit(result[6], 's')
+	}
+	if mode&0o1000 != 0 {
+		result[9] = setBit(result[9], 't')
+	}
+	return string(result)
+}
+
+func setBit(current byte, executable byte) byte {
+	if current == '-' {
+		return executable - 'a' + 'A'
+	}
+	return executable
+}
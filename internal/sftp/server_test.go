@@ -0,0 +1,256 @@
+package sftp //nolint:testpackage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestReadWrite(t *testing.T) {
+	_, srv := createServer(t, config.SFTPConfig{Root: "/"})
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.bin")
+
+	// The data contains every byte value and spans several write buffers.
+	data := make([]byte, writeBufferSize*2+1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	handle := openFile(t, srv, file, openWrite|openCreate|openTrunc)
+	for offset := 0; offset < len(data); offset += 32768 {
+		end := offset + 32768
+		if end > len(data) {
+			end = len(data)
+		}
+		assertStatus(t, statusOK, request(t, srv, newPacket(packetWrite).uint32(1).string(handle).
+			uint64(uint64(offset)).bytes(data[offset:end])))
+	}
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetClose).uint32(1).string(handle)))
+	written, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, written), "the written data does not match")
+
+	handle = openFile(t, srv, file, openRead)
+	var read []byte
+	for {
+		response := request(t, srv, newPacket(packetRead).uint32(1).string(handle).
+			uint64(uint64(len(read))).uint32(32768))
+		if response.t == packetStatus {
+			assertStatus(t, statusEOF, response)
+			break
+		}
+		read = append(read, response.r.bytes()...)
+	}
+	assert.True(t, bytes.Equal(data, read), "the read data does not match")
+
+	response := request(t, srv, newPacket(packetFstat).uint32(1).string(handle))
+	assert.Equal(t, packetAttrs, response.t)
+	assert.Equal(t, uint64(len(data)), response.r.attributes().Size)
+}
+
+func TestDirectoryOperations(t *testing.T) {
+	root, srv := createServer(t, config.SFTPConfig{Root: t.TempDir()})
+
+	mkdir := newPacket(packetMkdir).uint32(1).string("/dir").attributes(Attributes{})
+	assertStatus(t, statusOK, request(t, srv, mkdir))
+	assertStatus(t, statusFailure, request(t, srv, mkdir))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "dir", "a b\nc"), []byte("hello"), 0o600))
+
+	handle := openDir(t, srv, "/dir")
+	response := request(t, srv, newPacket(packetReaddir).uint32(1).string(handle))
+	assert.Equal(t, packetName, response.t)
+	assert.Equal(t, uint32(1), response.r.uint32())
+	assert.Equal(t, "a b\nc", response.r.string())
+	response.r.string()
+	assert.Equal(t, uint64(5), response.r.attributes().Size)
+	assertStatus(t, statusEOF, request(t, srv, newPacket(packetReaddir).uint32(1).string(handle)))
+
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetRename).uint32(1).string("/dir/a b\nc").string("/dir/d")))
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetSymlink).uint32(1).string("/dir/d").string("/link")))
+	response = request(t, srv, newPacket(packetReadlink).uint32(1).string("/link"))
+	assert.Equal(t, packetName, response.t)
+	assert.Equal(t, uint32(1), response.r.uint32())
+	assert.Equal(t, "/dir/d", response.r.string())
+	response = request(t, srv, newPacket(packetStat).uint32(1).string("/link"))
+	assert.Equal(t, uint64(5), response.r.attributes().Size)
+
+	assertStatus(t, statusFailure, request(t, srv, newPacket(packetRmdir).uint32(1).string("/dir")))
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetRemove).uint32(1).string("/dir/d")))
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetRmdir).uint32(1).string("/dir")))
+	assertStatus(t, statusNoSuchFile, request(t, srv, newPacket(packetStat).uint32(1).string("/dir")))
+}
+
+func TestSetStat(t *testing.T) {
+	root, srv := createServer(t, config.SFTPConfig{Root: t.TempDir()})
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("hello world"), 0o600))
+
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetSetstat).uint32(1).string("/file").attributes(Attributes{
+		Flags: attrSize | attrPermissions | attrACModTime,
+		Size:  5,
+		Mode:  0o640,
+		ATime: 1000000000,
+		MTime: 1234567890,
+	})))
+	response := request(t, srv, newPacket(packetLstat).uint32(1).string("/file"))
+	attrs := response.r.attributes()
+	assert.Equal(t, uint64(5), attrs.Size)
+	assert.Equal(t, uint32(0o100640), attrs.Mode)
+	assert.Equal(t, uint32(1000000000), attrs.ATime)
+	assert.Equal(t, uint32(1234567890), attrs.MTime)
+}
+
+func TestRootRestriction(t *testing.T) {
+	outside := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o600))
+	root, srv := createServer(t, config.SFTPConfig{Root: t.TempDir()})
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("hello"), 0o600))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "new"), filepath.Join(root, "dangling")))
+
+	// Parent directory references stop at the root.
+	response := request(t, srv, newPacket(packetStat).uint32(1).string("/../../file"))
+	assert.Equal(t, packetAttrs, response.t)
+	response = request(t, srv, newPacket(packetRealpath).uint32(1).string("../dir/./x"))
+	response.r.uint32()
+	assert.Equal(t, "/dir/x", response.r.string())
+
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetStat).uint32(1).string("/escape/secret")))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetOpendir).uint32(1).string("/escape")))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetOpen).uint32(1).string("/escape/secret").
+		uint32(openRead).attributes(Attributes{})))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetOpen).uint32(1).string("/dangling").
+		uint32(openWrite|openCreate).attributes(Attributes{})))
+	_, err := os.Stat(filepath.Join(outside, "new"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// The link itself is inside the root, so it can be removed.
+	assertStatus(t, statusOK, request(t, srv, newPacket(packetRemove).uint32(1).string("/escape")))
+	_, err = os.Stat(filepath.Join(outside, "secret"))
+	assert.NoError(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	hook := &denyHook{path: "/private"}
+	root, srv := createServer(t, config.SFTPConfig{
+		Root:     t.TempDir(),
+		ReadOnly: true,
+		Deny:     []config.SFTPOperation{config.SFTPOperationReadLink},
+	})
+	srv.hook = hook
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("hello"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "private"), []byte("hello"), 0o600))
+
+	openFile(t, srv, "/file", openRead)
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetOpen).uint32(1).string("/file").
+		uint32(openWrite).attributes(Attributes{})))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetRemove).uint32(1).string("/file")))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetReadlink).uint32(1).string("/file")))
+	assertStatus(t, statusPermissionDenied, request(t, srv, newPacket(packetStat).uint32(1).string("/private")))
+	assert.Equal(t, []config.SFTPOperation{config.SFTPOperationRead, config.SFTPOperationStat}, hook.operations)
+	_, err := os.Stat(filepath.Join(root, "file"))
+	assert.NoError(t, err)
+}
+
+func TestMalformedPacket(t *testing.T) {
+	_, srv := createServer(t, config.SFTPConfig{Root: "/"})
+	packet := newPacket(packetOpen).uint32(1).string("/etc/passwd").finish()
+	_, err := srv.handle(packetOpen, &packetReader{data: packet[5:]})
+	assert.Error(t, err)
+
+	response := request(t, srv, newPacket(packetType(200)).uint32(1))
+	assertStatus(t, statusOpUnsupported, response)
+}
+
+func createServer(t *testing.T, cfg config.SFTPConfig) (string, *server) {
+	shell := exec.Command("/bin/sh")
+	stdin, err := shell.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := shell.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shell.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = stdin.Close()
+		_ = shell.Wait()
+	})
+	root, err := filepath.EvalSymlinks(cfg.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Root = root
+	fs, err := newShellFileSystem(cfg.Root, stdin, stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, newServer(cfg, metadata.ChannelMetadata{}, fs, nil, log.NewTestLogger(t))
+}
+
+type response struct {
+	t packetType
+	r *packetReader
+}
+
+func request(t *testing.T, srv *server, packet *packetWriter) response {
+	data := packet.finish()
+	result, err := srv.handle(packetType(data[4]), &packetReader{data: data[5:]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &packetReader{data: result[4:]}
+	responseType := packetType(r.byte())
+	// The request ID is always 1.
+	assert.Equal(t, uint32(1), r.uint32())
+	return response{t: responseType, r: r}
+}
+
+func assertStatus(t *testing.T, expected statusCode, response response) {
+	t.Helper()
+	if assert.Equal(t, packetStatus, response.t) {
+		assert.Equal(t, expected, statusCode(response.r.uint32()))
+	}
+}
+
+func openFile(t *testing.T, srv *server, path string, flags uint32) string {
+	t.Helper()
+	response := request(t, srv, newPacket(packetOpen).uint32(1).string(path).uint32(flags).attributes(Attributes{}))
+	if !assert.Equal(t, packetHandle, response.t) {
+		t.FailNow()
+	}
+	return response.r.string()
+}
+
+func openDir(t *testing.T, srv *server, path string) string {
+	t.Helper()
+	response := request(t, srv, newPacket(packetOpendir).uint32(1).string(path))
+	if !assert.Equal(t, packetHandle, response.t) {
+		t.FailNow()
+	}
+	return response.r.string()
+}
+
+// denyHook rejects all operations on a single path and records the operations it is called for.
+type denyHook struct {
+	path       string
+	operations []config.SFTPOperation
+}
+
+func (d *denyHook) OnOperation(_ metadata.ChannelMetadata, operation config.SFTPOperation, path string) error {
+	d.operations = append(d.operations, operation)
+	if path == d.path {
+		return errors.New("private file")
+	}
+	return nil
+}
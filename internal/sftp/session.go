@@ -0,0 +1,150 @@
+package sftp
+
+import (
+	"io"
+	"sync"
+
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+)
+
+// sessionChannel is the session channel passed to the backend. It passes all calls to the client until the built-in
+// SFTP server starts the shell. From then on the input and output of the program started by the backend are connected
+// to the SFTP server instead of the client, and its exit is not reported to the client.
+type sessionChannel struct {
+	client sshserver.SessionChannel
+
+	lock *sync.Mutex
+	// shell is true when the program of the backend is the shell of the SFTP server.
+	shell        bool
+	stdinReader  *io.PipeReader
+	stdinWriter  *io.PipeWriter
+	stdoutReader *io.PipeReader
+	stdoutWriter *io.PipeWriter
+}
+
+func newSessionChannel(client sshserver.SessionChannel) *sessionChannel {
+	return &sessionChannel{
+		client: client,
+		lock:   &sync.Mutex{},
+	}
+}
+
+// startShell connects the input and output of the program started next by the backend to the returned pipes.
+func (s *sessionChannel) startShell() (io.WriteCloser, io.Reader) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shell = true
+	s.stdinReader, s.stdinWriter = io.Pipe()
+	s.stdoutReader, s.stdoutWriter = io.Pipe()
+	return s.stdinWriter, s.stdoutReader
+}
+
+// stopShell connects the session to the client again after the shell could not be started.
+func (s *sessionChannel) stopShell() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closePipes()
+	s.shell = false
+}
+
+// closeShell closes the pipes of the shell so the SFTP server and the program of the backend stop.
+func (s *sessionChannel) closeShell() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closePipes()
+}
+
+func (s *sessionChannel) closePipes() {
+	if !s.shell {
+		return
+	}
+	_ = s.stdinWriter.Close()
+	_ = s.stdinReader.Close()
+	_ = s.stdoutWriter.Close()
+}
+
+func (s *sessionChannel) isShell() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.shell
+}
+
+func (s *sessionChannel) Stdin() io.Reader {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.shell {
+		return s.stdinReader
+	}
+	return s.client.Stdin()
+}
+
+func (s *sessionChannel) Stdout() io.Writer {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.shell {
+		return s.stdoutWriter
+	}
+	return s.client.Stdout()
+}
+
+func (s *sessionChannel) Stderr() io.Writer {
+	if s.isShell() {
+		// The shell sends the errors of the commands to /dev/null, so there is nothing to pass on.
+		return io.Discard
+	}
+	return s.client.Stderr()
+}
+
+func (s *sessionChannel) ExitStatus(code uint32) {
+	if s.isShell() {
+		// The SFTP server reports the exit status to the client when it stops.
+		return
+	}
+	s.client.ExitStatus(code)
+}
+
+func (s *sessionChannel) ExitSignal(signal string, coreDumped bool, errorMessage string, languageTag string) {
+	if s.isShell() {
+		return
+	}
+	s.client.ExitSignal(signal, coreDumped, errorMessage, languageTag)
+}
+
+func (s *sessionChannel) CloseWrite() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.shell {
+		return s.stdoutWriter.Close()
+	}
+	return s.client.CloseWrite()
+}
+
+func (s *sessionChannel) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.shell {
+		// The SFTP server closes the client channel when it stops.
+		return s.stdoutWriter.Close()
+	}
+	return s.client.Close()
+}
+
+func (s *sessionChannel) CloseForPolicy(reason string) error {
+	if closer, ok := s.client.(sshserver.PolicyCloser); ok {
+		return closer.CloseForPolicy(reason)
+	}
+	return s.client.Close()
+}
+
+func (s *sessionChannel) DisconnectForPolicy(reason string) error {
+	if closer, ok := s.client.(sshserver.PolicyCloser); ok {
+		return closer.DisconnectForPolicy(reason)
+	}
+	return s.client.Close()
+}
+
+func (s *sessionChannel) AuditPolicyDryRun(rule string, reason string) {
+	if auditor, ok := s.client.(sshserver.PolicyAuditor); ok {
+		auditor.AuditPolicyDryRun(rule, reason)
+	}
+}
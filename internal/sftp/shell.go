@@ -0,0 +1,487 @@
+package sftp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shellPrelude defines the shell functions executing the file operations. Each function receives the path in the
+// container as $1 and its parent directory as $2. The functions check that the paths resolve inside the root directory
+// in $_R before touching them. Failures are reported in the exit code: 2 if the file does not exist, 13 if the access
+// is denied and 17 if the file already exists. Binary data and file names are hex encoded so the output can be split
+// into lines safely.
+const shellPrelude = `exec 2>/dev/null
+LC_ALL=C
+export LC_ALL
+_in() { case "$1" in "$_R"|"$_R"/*) return 0;; esac; return 13; }
+_rp() { _t=$(readlink -f "$1" && echo x) || return 2; _t=${_t%?x}; }
+_ck() { if _rp "$1"; then _in "$_t"; return; fi; [ -L "$1" ] && return 13; _rp "$2" || return 2; _in "$_t"; }
+_cp() { _rp "$1" || return 2; _in "$_t"; }
+_ex() { [ -e "$1" ] || [ -L "$1" ] || return 2; }
+_hex() { od -An -v -tx1 | tr -d ' \n'; }
+_stat() { _ck "$1" "$2" || return; _ex "$1" || return; stat -L -c '%f %s %u %g %X %Y' "$1"; }
+_lstat() { _cp "$2" || return; _ex "$1" || return; stat -c '%f %s %u %g %X %Y' "$1"; }
+_ls() {
+  _ck "$1" "$2" || return; _ex "$1" || return; [ -d "$1" ] || return 1; [ -r "$1" ] && [ -x "$1" ] || return 13
+  for _f in "$1"/* "$1"/.[!.]* "$1"/..?*; do
+    [ -e "$_f" ] || [ -L "$_f" ] || continue
+    stat -c '%f %s %u %g %X %Y' "$_f" || continue
+    printf '%s' "${_f##*/}" | _hex; echo
+  done
+}
+_opr() { _ck "$1" "$2" || return; _ex "$1" || return; [ -d "$1" ] && return 1; [ -r "$1" ] || return 13; }
+_opw() {
+  _ck "$1" "$2" || return
+  if [ -e "$1" ]; then
+    [ "$3" = x ] && return 17; [ -d "$1" ] && return 1; [ -w "$1" ] || return 13
+    if [ -n "$4" ]; then : >"$1" || return 13; fi
+  else
+    [ "$3" = x ] && [ -L "$1" ] && return 17; [ -n "$3" ] || return 2; [ -d "$2" ] || return 2
+    : >"$1" || return 13
+    if [ -n "$5" ]; then chmod "$5" "$1" || return 13; fi
+  fi
+}
+_rd() { _ck "$1" "$2" || return; _ex "$1" || return; [ -r "$1" ] || return 13; tail -c +"$3" "$1" | head -c "$4" | _hex; }
+_wr() { _ck "$1" "$2" || return; printf "$3" | dd of="$1" bs="$4" seek="$5" conv=notrunc || return 13; }
+_ap() { _ck "$1" "$2" || return; printf "$3" >>"$1" || return 13; }
+_set() {
+  _ck "$1" "$2" || return; _ex "$1" || return
+  if [ -n "$3" ]; then dd if=/dev/null of="$1" bs=1 seek="$3" || return 13; fi
+  if [ -n "$4" ]; then chmod "$4" "$1" || return 13; fi
+  if [ -n "$5" ]; then chown "$5" "$1" || return 13; fi
+  if [ -n "$6" ]; then TZ=UTC0 touch -c -a -t "$6" "$1" && TZ=UTC0 touch -c -m -t "$7" "$1" || return 13; fi
+}
+_rm() { _cp "$2" || return; _ex "$1" || return; [ -d "$1" ] && [ ! -L "$1" ] && return 1; rm -f "$1" || return 13; }
+_md() { _cp "$2" || return; [ -e "$1" ] || [ -L "$1" ] && return 17; [ -d "$2" ] || return 2; mkdir ${3:+-m $3} "$1" || return 13; }
+_rmd() { _cp "$2" || return; _ex "$1" || return; [ -d "$1" ] || return 1; rmdir "$1" || return 1; }
+_mv() { _cp "$2" || return; _cp "$4" || return; _ex "$1" || return; [ -e "$3" ] || [ -L "$3" ] && return 17; mv "$1" "$3" || return 13; }
+_rl() { _cp "$2" || return; _ex "$1" || return; [ -L "$1" ] || return 1; readlink "$1" | _hex; }
+_ln() { _cp "$2" || return; [ -e "$1" ] || [ -L "$1" ] && return 17; ln -s -- "$3" "$1" || return 13; }
+`
+
+// readAheadSize is the amount of data read from a file at once. Clients read in small chunks, so reading ahead saves
+// a round trip to the shell for most read requests.
+const readAheadSize = 256 * 1024
+
+// writeBufferSize is the amount of contiguous data collected from write requests before it is written to the file.
+const writeBufferSize = 256 * 1024
+
+// errShellExited is returned when the shell executing the file operations is no longer available.
+var errShellExited = errors.New("the shell executing the file operations has exited")
+
+// statusError is a failed file operation with the SFTP status code to return to the client.
+type statusError struct {
+	code statusCode
+}
+
+func (s statusError) Error() string {
+	switch s.code {
+	case statusNoSuchFile:
+		return "no such file"
+	case statusPermissionDenied:
+		return "permission denied"
+	default:
+		return "failure"
+	}
+}
+
+// shellFileSystem executes the file operations by sending commands to a POSIX shell running in the container. The
+// commands and their results are exchanged over the standard input and output of the shell. Paths are the paths as
+// seen by the client and are mapped into the root directory.
+type shellFileSystem struct {
+	root   string
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	marker string
+}
+
+// newShellFileSystem sends the prelude to the shell and checks that the root directory exists.
+func newShellFileSystem(root string, stdin io.WriteCloser, stdout io.Reader) (*shellFileSystem, error) {
+	marker := make([]byte, 16)
+	if _, err := rand.Read(marker); err != nil {
+		return nil, err
+	}
+	fs := &shellFileSystem{
+		root:   path.Clean(root),
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		marker: "containerssh-sftp-" + hex.EncodeToString(marker),
+	}
+	if _, err := io.WriteString(stdin, shellPrelude); err != nil {
+		return nil, errShellExited
+	}
+	_, err := fs.run(
+		"_R=$(readlink -f %s && echo x) || exit 2; _R=${_R%%?x}; [ \"$_R\" = / ] && _R=; [ -d \"$_R/\" ]",
+		quote(fs.root),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("the SFTP root directory %s is not available in the container (%w)", fs.root, err)
+	}
+	return fs, nil
+}
+
+// close ends the shell by closing its input.
+func (f *shellFileSystem) close() error {
+	return f.stdin.Close()
+}
+
+// run executes a command in the shell and returns its output.
+func (f *shellFileSystem) run(format string, args ...interface{}) (string, error) {
+	command := fmt.Sprintf(format, args...)
+	if _, err := fmt.Fprintf(f.stdin, "%s </dev/null\nprintf '\\n%%s %%d\\n' %s $?\n", command, f.marker); err != nil {
+		return "", errShellExited
+	}
+	output := &strings.Builder{}
+	for {
+		line, err := f.stdout.ReadString('\n')
+		if err != nil {
+			return "", errShellExited
+		}
+		if !strings.HasPrefix(line, f.marker+" ") {
+			output.WriteString(line)
+			continue
+		}
+		exitCode, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, f.marker+" ")))
+		if err != nil {
+			return "", errShellExited
+		}
+		// The marker is printed on a new line, so the output ends with an added newline.
+		result := strings.TrimSuffix(output.String(), "\n")
+		switch exitCode {
+		case 0:
+			return result, nil
+		case 2:
+			return result, statusError{statusNoSuchFile}
+		case 13:
+			return result, statusError{statusPermissionDenied}
+		default:
+			return result, statusError{statusFailure}
+		}
+	}
+}
+
+// realPath maps the client path into the root directory and returns it with its parent directory.
+func (f *shellFileSystem) realPath(clientPath string) (string, string) {
+	p := path.Join(f.root, cleanPath(clientPath))
+	return quote(p), quote(path.Dir(p))
+}
+
+func (f *shellFileSystem) stat(clientPath string, followLinks bool) (Attributes, error) {
+	function := "_lstat"
+	if followLinks {
+		function = "_stat"
+	}
+	p, parent := f.realPath(clientPath)
+	output, err := f.run("%s %s %s", function, p, parent)
+	if err != nil {
+		return Attributes{}, err
+	}
+	return parseStat(output)
+}
+
+func (f *shellFileSystem) list(clientPath string) ([]File, error) {
+	p, parent := f.realPath(clientPath)
+	output, err := f.run("_ls %s %s", p, parent)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	lines := strings.Split(output, "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		attrs, err := parseStat(lines[i])
+		if err != nil {
+			return nil, err
+		}
+		name, err := hex.DecodeString(lines[i+1])
+		if err != nil {
+			return nil, statusError{statusFailure}
+		}
+		files = append(files, File{Name: string(name), Attributes: attrs})
+	}
+	return files, nil
+}
+
+func (f *shellFileSystem) open(clientPath string, flags uint32, attrs Attributes) (*file, error) {
+	p, parent := f.realPath(clientPath)
+	var err error
+	if flags&(openWrite|openAppend) == 0 {
+		_, err = f.run("_opr %s %s", p, parent)
+	} else {
+		create := "''"
+		if flags&openExcl != 0 {
+			create = "x"
+		} else if flags&openCreate != 0 {
+			create = "c"
+		}
+		truncate := "''"
+		if flags&openTrunc != 0 {
+			truncate = "t"
+		}
+		mode := "''"
+		if attrs.Flags&attrPermissions != 0 {
+			mode = strconv.FormatUint(uint64(attrs.Mode&0o7777), 8)
+		}
+		_, err = f.run("_opw %s %s %s %s %s", p, parent, create, truncate, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &file{
+		fs:         f,
+		clientPath: cleanPath(clientPath),
+		path:       p,
+		parent:     parent,
+		append:     flags&openAppend != 0,
+	}, nil
+}
+
+func (f *shellFileSystem) setStat(clientPath string, attrs Attributes) error {
+	p, parent := f.realPath(clientPath)
+	size, mode, owner, atime, mtime := "''", "''", "''", "''", "''"
+	if attrs.Flags&attrSize != 0 {
+		size = strconv.FormatUint(attrs.Size, 10)
+	}
+	if attrs.Flags&attrPermissions != 0 {
+		mode = strconv.FormatUint(uint64(attrs.Mode&0o7777), 8)
+	}
+	if attrs.Flags&attrUIDGID != 0 {
+		owner = fmt.Sprintf("%d:%d", attrs.UID, attrs.GID)
+	}
+	if attrs.Flags&attrACModTime != 0 {
+		atime = formatTouchTime(attrs.ATime)
+		mtime = formatTouchTime(attrs.MTime)
+	}
+	_, err := f.run("_set %s %s %s %s %s %s %s", p, parent, size, mode, owner, atime, mtime)
+	return err
+}
+
+func (f *shellFileSystem) remove(clientPath string) error {
+	p, parent := f.realPath(clientPath)
+	_, err := f.run("_rm %s %s", p, parent)
+	return err
+}
+
+func (f *shellFileSystem) mkdir(clientPath string, attrs Attributes) error {
+	p, parent := f.realPath(clientPath)
+	mode := "''"
+	if attrs.Flags&attrPermissions != 0 {
+		mode = strconv.FormatUint(uint64(attrs.Mode&0o7777), 8)
+	}
+	_, err := f.run("_md %s %s %s", p, parent, mode)
+	return err
+}
+
+func (f *shellFileSystem) rmdir(clientPath string) error {
+	p, parent := f.realPath(clientPath)
+	_, err := f.run("_rmd %s %s", p, parent)
+	return err
+}
+
+func (f *shellFileSystem) rename(oldPath string, newPath string) error {
+	p, parent := f.realPath(oldPath)
+	target, targetParent := f.realPath(newPath)
+	_, err := f.run("_mv %s %s %s %s", p, parent, target, targetParent)
+	return err
+}
+
+func (f *shellFileSystem) readLink(clientPath string) (string, error) {
+	p, parent := f.realPath(clientPath)
+	output, err := f.run("_rl %s %s", p, parent)
+	if err != nil {
+		return "", err
+	}
+	target, err := hex.DecodeString(output)
+	if err != nil {
+		return "", statusError{statusFailure}
+	}
+	result := strings.TrimSuffix(string(target), "\n")
+	// Absolute targets inside the root are shown as the client sees them.
+	if f.root != "/" && path.IsAbs(result) {
+		if result == f.root {
+			return "/", nil
+		}
+		if strings.HasPrefix(result, f.root+"/") {
+			return strings.TrimPrefix(result, f.root), nil
+		}
+	}
+	return result, nil
+}
+
+func (f *shellFileSystem) symlink(target string, linkPath string) error {
+	p, parent := f.realPath(linkPath)
+	if path.IsAbs(target) {
+		target = path.Join(f.root, target)
+	}
+	_, err := f.run("_ln %s %s %s", p, parent, quote(target))
+	return err
+}
+
+// file is an open file. Reads are served from a read ahead buffer and contiguous writes are collected in a buffer
+// before they are sent to the shell.
+type file struct {
+	fs         *shellFileSystem
+	clientPath string
+	path       string
+	parent     string
+	append     bool
+
+	readOffset uint64
+	readBuffer []byte
+	readEOF    bool
+
+	writeOffset uint64
+	writeBuffer []byte
+}
+
+// read returns the data at offset, or io.EOF if the offset is at or past the end of the file.
+func (f *file) read(offset uint64, length uint32) ([]byte, error) {
+	if err := f.flush(); err != nil {
+		return nil, err
+	}
+	if offset < f.readOffset || offset >= f.readOffset+uint64(len(f.readBuffer)) {
+		if f.readEOF && offset >= f.readOffset+uint64(len(f.readBuffer)) && offset >= f.readOffset {
+			return nil, io.EOF
+		}
+		output, err := f.fs.run("_rd %s %s %d %d", f.path, f.parent, offset+1, readAheadSize)
+		if err != nil {
+			return nil, err
+		}
+		data, err := hex.DecodeString(output)
+		if err != nil {
+			return nil, statusError{statusFailure}
+		}
+		f.readOffset = offset
+		f.readBuffer = data
+		f.readEOF = len(data) < readAheadSize
+		if len(data) == 0 {
+			return nil, io.EOF
+		}
+	}
+	data := f.readBuffer[offset-f.readOffset:]
+	if uint64(len(data)) > uint64(length) {
+		data = data[:length]
+	}
+	return data, nil
+}
+
+// write stores the data to be written at offset. Errors of buffered data may be reported by a later write or close.
+func (f *file) write(offset uint64, data []byte) error {
+	f.readBuffer = nil
+	f.readEOF = false
+	if len(f.writeBuffer) > 0 && (f.append || offset == f.writeOffset+uint64(len(f.writeBuffer))) &&
+		len(f.writeBuffer)+len(data) <= writeBufferSize {
+		f.writeBuffer = append(f.writeBuffer, data...)
+	} else {
+		if err := f.flush(); err != nil {
+			return err
+		}
+		f.writeOffset = offset
+		f.writeBuffer = append(f.writeBuffer, data...)
+	}
+	if len(f.writeBuffer) >= writeBufferSize {
+		return f.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered data to the file.
+func (f *file) flush() error {
+	if len(f.writeBuffer) == 0 {
+		return nil
+	}
+	data := printfEscape(f.writeBuffer)
+	f.writeBuffer = f.writeBuffer[:0]
+	if f.append {
+		_, err := f.fs.run("_ap %s %s '%s'", f.path, f.parent, data)
+		return err
+	}
+	// dd seeks in blocks, so the block size must divide the offset.
+	blockSize := uint64(65536)
+	for f.writeOffset%blockSize != 0 {
+		blockSize /= 2
+	}
+	_, err := f.fs.run(
+		"_wr %s %s '%s' %d %d", f.path, f.parent, data, blockSize, f.writeOffset/blockSize,
+	)
+	return err
+}
+
+func (f *file) stat() (Attributes, error) {
+	if err := f.flush(); err != nil {
+		return Attributes{}, err
+	}
+	output, err := f.fs.run("_stat %s %s", f.path, f.parent)
+	if err != nil {
+		return Attributes{}, err
+	}
+	return parseStat(output)
+}
+
+func (f *file) close() error {
+	return f.flush()
+}
+
+// cleanPath turns a client path into an absolute path without . and .. elements.
+func cleanPath(clientPath string) string {
+	return path.Clean("/" + clientPath)
+}
+
+// quote quotes a string for the shell.
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// printfEscape encodes data as a printf format string in single quotes. All bytes apart from letters and digits are
+// written as octal escapes.
+func printfEscape(data []byte) string {
+	result := &strings.Builder{}
+	result.Grow(len(data) * 4)
+	for _, b := range data {
+		if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') {
+			result.WriteByte(b)
+		} else {
+			_, _ = fmt.Fprintf(result, "\\%03o", b)
+		}
+	}
+	return result.String()
+}
+
+// formatTouchTime formats a Unix timestamp for touch -t in the UTC time zone.
+func formatTouchTime(timestamp uint32) string {
+	return time.Unix(int64(timestamp), 0).UTC().Format("200601021504.05")
+}
+
+// parseStat parses the output of stat -c '%f %s %u %g %X %Y'.
+func parseStat(line string) (Attributes, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 {
+		return Attributes{}, statusError{statusFailure}
+	}
+	mode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return Attributes{}, statusError{statusFailure}
+	}
+	var numbers [5]uint64
+	for i, field := range fields[1:] {
+		if numbers[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+			return Attributes{}, statusError{statusFailure}
+		}
+	}
+	return Attributes{
+		Flags: attrSize | attrUIDGID | attrPermissions | attrACModTime,
+		Size:  numbers[0],
+		UID:   uint32(numbers[1]),
+		GID:   uint32(numbers[2]),
+		Mode:  uint32(mode),
+		ATime: uint32(numbers[3]),
+		MTime: uint32(numbers[4]),
+	}, nil
+}
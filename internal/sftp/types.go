@@ -0,0 +1,32 @@
+package sftp
+
+import (
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+// Hook is a policy hook deciding whether an SFTP operation is allowed. It is called after the read only and deny
+// settings of the configuration have been checked.
+type Hook interface {
+	// OnOperation is called before the operation is executed on path. The path is the path as seen by the client,
+	// relative to the configured root. Returning an error rejects the operation with a permission denied status.
+	OnOperation(meta metadata.ChannelMetadata, operation config.SFTPOperation, path string) error
+}
+
+// Attributes are the file attributes exchanged in the SFTP protocol. Flags states which of the fields are set.
+type Attributes struct {
+	Flags uint32
+	Size  uint64
+	UID   uint32
+	GID   uint32
+	// Mode contains the file type and the permission bits as in the st_mode field of stat.
+	Mode  uint32
+	ATime uint32
+	MTime uint32
+}
+
+// File is an entry of a directory listing.
+type File struct {
+	Name       string
+	Attributes Attributes
+}
@@ -0,0 +1,85 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HandoffFiles returns duplicates of all open listening sockets and the listen addresses they belong to, so they can
+// be passed to a new process on a graceful restart. Once the new process has taken over, HandedOff must be called so
+// the Unix domain sockets are not removed when they are closed in this process.
+//
+// The caller is responsible for closing the returned files once they have been passed on.
+func HandoffFiles() ([]*os.File, []string, error) {
+	openListeners.lock.Lock()
+	defer openListeners.lock.Unlock()
+	var files []*os.File
+	var names []string
+	for l := range openListeners.listeners {
+		fileListener, ok := l.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("the listener on %s cannot be handed off", l.listen)
+		}
+		file, err := fileListener.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("failed to duplicate the listener on %s (%w)", l.listen, err)
+		}
+		files = append(files, file)
+		names = append(names, l.listen)
+	}
+	return files, names, nil
+}
+
+// HandedOff records that a new process has taken over the listening sockets. Unix domain sockets are no longer removed
+// from the filesystem when they are closed in this process, as the new process continues to use them. If the new
+// process fails to start, this function must not be called so the sockets are cleaned up as usual.
+func HandedOff() {
+	openListeners.lock.Lock()
+	defer openListeners.lock.Unlock()
+	for l := range openListeners.listeners {
+		if unixListener, ok := l.Listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+}
+
+// HandoffEnvironment returns the environment for a new process that receives the files from HandoffFiles as its
+// first extra files, starting at file descriptor 3. The sockets are passed in the same format as systemd socket
+// activation, with the escaped listen address as the name.
+func HandoffEnvironment(environment []string, names []string) []string {
+	result := make([]string, 0, len(environment)+2)
+	for _, env := range environment {
+		if strings.HasPrefix(env, "LISTEN_PID=") ||
+			strings.HasPrefix(env, "LISTEN_FDS=") ||
+			strings.HasPrefix(env, "LISTEN_FDNAMES=") {
+			continue
+		}
+		result = append(result, env)
+	}
+	escapedNames := make([]string, len(names))
+	for i, name := range names {
+		escapedNames[i] = handoffName(name)
+	}
+	return append(
+		result,
+		"LISTEN_FDS="+strconv.Itoa(len(names)),
+		"LISTEN_FDNAMES="+strings.Join(escapedNames, ":"),
+	)
+}
+
+// handoffName escapes the listen address for use as a socket name, as the colon is the separator in LISTEN_FDNAMES.
+func handoffName(listen string) string {
+	return url.QueryEscape(listen)
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
@@ -0,0 +1,134 @@
+package socket
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+// Listen opens a listening socket for the listen address. The address may be a TCP host:port, a Unix domain socket
+// in the unix:/path/to/socket format, or a socket passed by systemd in the systemd:name format. The listenConfig is
+// only used for TCP and Unix sockets, the socketMode only for Unix sockets.
+//
+// If a socket for the same listen address has been inherited from a previous ContainerSSH process during a graceful
+// restart, the inherited socket is used instead of opening a new one.
+func Listen(
+	ctx context.Context,
+	listenConfig *net.ListenConfig,
+	listen string,
+	socketMode config.UnixSocketMode,
+) (net.Listener, error) {
+	network, address, err := config.ParseListenAddress(listen)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := socketMode.FileMode()
+	if err != nil {
+		return nil, err
+	}
+	netListener, inherited, err := inheritedListener(listen)
+	if err != nil {
+		return nil, err
+	}
+	if !inherited {
+		switch network {
+		case config.ListenNetworkUnix:
+			if err := removeStaleSocket(address); err != nil {
+				return nil, err
+			}
+			netListener, err = listenUnix(ctx, listenConfig, address, mode)
+		case config.ListenNetworkSystemd:
+			netListener, err = systemdListener(address)
+		default:
+			netListener, err = listenConfig.Listen(ctx, "tcp", address)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return register(netListener, listen), nil
+}
+
+func listenUnix(ctx context.Context, listenConfig *net.ListenConfig, path string, mode os.FileMode) (
+	net.Listener,
+	error,
+) {
+	netListener, err := listenConfig.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = netListener.Close()
+			return nil, fmt.Errorf("failed to set the mode of socket %s (%w)", path, err)
+		}
+	}
+	return netListener, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous process that did not shut down cleanly. Files
+// that are not sockets are left in place, so the listen call fails instead of deleting unrelated data.
+func removeStaleSocket(path string) error {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if stat.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+// UnixClientIP is the IP address reported for clients connecting over a Unix domain socket. These clients have no
+// IP address, and the unspecified address makes sure they are not mistaken for local TCP clients, for example in
+// source address restrictions.
+var UnixClientIP = net.IPv4zero.To4()
+
+// RemoteTCPAddr returns the remote address of the connection as a TCP address. Connections on Unix domain sockets
+// have no remote IP address, so they are reported as coming from UnixClientIP.
+func RemoteTCPAddr(conn net.Conn) *net.TCPAddr {
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr
+	}
+	return &net.TCPAddr{IP: UnixClientIP}
+}
+
+// openListeners contains all listeners opened by Listen that have not been closed yet. These are the sockets handed
+// over to the new process on a graceful restart.
+var openListeners = struct {
+	lock      sync.Mutex
+	listeners map[*listener]struct{}
+}{
+	listeners: map[*listener]struct{}{},
+}
+
+type listener struct {
+	net.Listener
+
+	listen string
+}
+
+func register(netListener net.Listener, listen string) net.Listener {
+	l := &listener{
+		Listener: netListener,
+		listen:   listen,
+	}
+	openListeners.lock.Lock()
+	openListeners.listeners[l] = struct{}{}
+	openListeners.lock.Unlock()
+	return l
+}
+
+func (l *listener) Close() error {
+	openListeners.lock.Lock()
+	delete(openListeners.listeners, l)
+	openListeners.lock.Unlock()
+	return l.Listener.Close()
+}
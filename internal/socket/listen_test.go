@@ -0,0 +1,106 @@
+package socket_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/internal/socket"
+)
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	for i := 0; i < 2; i++ {
+		// The second listen must succeed even though the socket file of the first listener was left behind.
+		listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, "unix:"+path, "")
+		if !assert.NoError(t, err) {
+			return
+		}
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "0.0.0.0", socket.RemoteTCPAddr(conn).IP.String())
+			_, _ = conn.Write([]byte("hello"))
+			_ = conn.Close()
+		}()
+
+		conn, err := net.Dial("unix", path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		buf := make([]byte, 5)
+		_, err = conn.Read(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(buf))
+		_ = conn.Close()
+		if i == 0 {
+			// Handing off the socket leaves the socket file in place, like a process that did not clean up.
+			files, _, err := socket.HandoffFiles()
+			assert.NoError(t, err)
+			for _, file := range files {
+				_ = file.Close()
+			}
+			socket.HandedOff()
+		}
+		_ = listener.Close()
+	}
+}
+
+func TestUnixSocketRemovedWithoutHandoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, "unix:"+path, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// Duplicating the sockets for a new process that then fails to start must not leave the socket file behind.
+	files, _, err := socket.HandoffFiles()
+	assert.NoError(t, err)
+	for _, file := range files {
+		_ = file.Close()
+	}
+	assert.NoError(t, listener.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUnixSocketMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	listener, err := socket.Listen(context.Background(), &net.ListenConfig{}, "unix:"+path, "0660")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	stat, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), stat.Mode().Perm())
+
+	_, err = socket.Listen(context.Background(), &net.ListenConfig{}, "unix:"+path+"2", "0999")
+	assert.Error(t, err)
+}
+
+func TestUnixSocketRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	assert.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+
+	_, err := socket.Listen(context.Background(), &net.ListenConfig{}, "unix:"+path, "")
+	assert.Error(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestSystemdWithoutSockets(t *testing.T) {
+	_, err := socket.Listen(context.Background(), &net.ListenConfig{}, "systemd:ssh", "")
+	assert.Error(t, err)
+}
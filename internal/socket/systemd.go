@@ -0,0 +1,129 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemdListenFDsStart is the first file descriptor passed by systemd socket activation.
+const systemdListenFDsStart = 3
+
+var systemdSockets struct {
+	once  sync.Once
+	lock  sync.Mutex
+	files []*os.File
+	names []string
+	used  map[int]struct{}
+}
+
+// SystemdFiles returns the sockets passed to this process by systemd socket activation along with their names. The
+// environment variables describing them are read on the first call and then removed, so they are not inherited by
+// child processes.
+func SystemdFiles() ([]*os.File, []string) {
+	systemdSockets.once.Do(func() {
+		systemdSockets.files, systemdSockets.names = readSystemdEnvironment()
+		systemdSockets.used = map[int]struct{}{}
+	})
+	return systemdSockets.files, systemdSockets.names
+}
+
+// systemdFileListener creates a listener from the passed socket with the specified index and marks it as used.
+func systemdFileListener(index int) (net.Listener, error) {
+	files, _ := SystemdFiles()
+	systemdSockets.lock.Lock()
+	defer systemdSockets.lock.Unlock()
+	if files[index] == nil {
+		return nil, fmt.Errorf("the passed socket %d has already been closed", index)
+	}
+	netListener, err := net.FileListener(files[index])
+	if err != nil {
+		return nil, err
+	}
+	systemdSockets.used[index] = struct{}{}
+	return netListener, nil
+}
+
+// CloseUnusedSystemdFiles closes the sockets passed by systemd or inherited from the previous process on a graceful
+// restart that have not been used by Listen. It should be called once all listeners have been opened, so sockets
+// that are no longer in the configuration do not keep accepting connections that are never answered.
+func CloseUnusedSystemdFiles() {
+	files, _ := SystemdFiles()
+	systemdSockets.lock.Lock()
+	defer systemdSockets.lock.Unlock()
+	for i, file := range files {
+		if _, used := systemdSockets.used[i]; used || file == nil {
+			continue
+		}
+		_ = file.Close()
+		files[i] = nil
+	}
+}
+
+func readSystemdEnvironment() ([]*os.File, []string) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	var names []string
+	if fdNames := os.Getenv("LISTEN_FDNAMES"); fdNames != "" {
+		names = strings.Split(fdNames, ":")
+	}
+	files := make([]*os.File, count)
+	resultNames := make([]string, count)
+	for i := 0; i < count; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(systemdListenFDsStart+i)
+		if i < len(names) {
+			name = names[i]
+		}
+		files[i] = os.NewFile(uintptr(systemdListenFDsStart+i), name)
+		resultNames[i] = name
+	}
+	return files, resultNames
+}
+
+// systemdListener creates a listener from a socket passed by systemd. The name is matched against the
+// FileDescriptorName of the sockets first, then it is treated as a zero-based index. The passed file descriptor is
+// kept open so the socket can be used again after the listener is closed, for example when the server is restarted.
+func systemdListener(name string) (net.Listener, error) {
+	files, names := SystemdFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no sockets have been passed by systemd (LISTEN_FDS is not set)")
+	}
+	for i, fileName := range names {
+		if fileName == name {
+			return systemdFileListener(i)
+		}
+	}
+	if index, err := strconv.Atoi(name); err == nil && index >= 0 && index < len(files) {
+		return systemdFileListener(index)
+	}
+	return nil, fmt.Errorf("no socket named %s has been passed by systemd", name)
+}
+
+// inheritedListener returns a listener for a socket inherited from the previous process on a graceful restart.
+func inheritedListener(listen string) (net.Listener, bool, error) {
+	_, names := SystemdFiles()
+	name := handoffName(listen)
+	for i, fileName := range names {
+		if fileName == name {
+			netListener, err := systemdFileListener(i)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to use the inherited socket for %s (%w)", listen, err)
+			}
+			return netListener, true, nil
+		}
+	}
+	return nil, false, nil
+}
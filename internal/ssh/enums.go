@@ -12,6 +12,7 @@ const (
 	RequestTypeWindow       RequestType = "window-change"
 	RequestTypeSignal       RequestType = "signal"
 	RequestTypeX11          RequestType = "x11-req"
+	RequestTypeAuthAgent    RequestType = "auth-agent-req@openssh.com"
 
 	// Global
 	RequestTypeReverseForward           RequestType = "tcpip-forward"
@@ -70,6 +70,10 @@ type X11RequestPayload struct {
 	Screen           uint32
 }
 
+// AuthAgentRequestPayload is the empty payload of the auth-agent-req@openssh.com channel request.
+type AuthAgentRequestPayload struct {
+}
+
 type X11ChanOpenRequestPayload struct {
 	OriginatorAddress string
 	OriginatorPort    uint32
@@ -393,6 +393,30 @@ func (s *sshChannelHandler) OnX11Request(
 	return nil
 }
 
+func (s *sshChannelHandler) OnAuthAgentRequest(
+	_ uint64,
+	reverseHandler sshserver.ReverseForward,
+) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.sendRequest(string(ssh2.RequestTypeAuthAgent), nil); err != nil {
+		err := message.WrapUser(
+			err,
+			message.ESSHProxyAgentForwardingFailed,
+			"Error requesting agent forwarding",
+			"ContainerSSH cannot enable agent forwarding because of an error on the backend connection",
+		)
+		s.logger.Debug(err)
+		return err
+	}
+	s.ssh.forwardMu.Lock()
+	defer s.ssh.forwardMu.Unlock()
+	if s.ssh.authAgentHandler == nil {
+		s.ssh.authAgentHandler = reverseHandler
+	}
+	return nil
+}
+
 func (s *sshChannelHandler) OnClose() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -21,6 +21,8 @@ type sshConnectionHandler struct {
 
 	forwardMu      sync.Mutex
 	reverseHandler sshserver.ReverseForward
+	// authAgentHandler opens the channels to the SSH agent of the client once a session requested agent forwarding.
+	authAgentHandler sshserver.ReverseForward
 }
 
 func (s *sshConnectionHandler) handleRequests(requests <-chan *ssh.Request) {
@@ -51,6 +53,8 @@ func (s *sshConnectionHandler) handleChannels(newChannels <-chan ssh.NewChannel)
 			s.handleStreamLocalChannel(newChannel)
 		case "forwarded-tcpip":
 			s.handleReverseForwardChannel(newChannel)
+		case sshserver.ChannelTypeAuthAgent:
+			s.handleAuthAgentChannel(newChannel)
 		default:
 			_ = newChannel.Reject(ssh.Prohibited, "Unsupported channel type")
 		}
@@ -192,6 +196,42 @@ func (s *sshConnectionHandler) handleX11Channel(newChannel ssh.NewChannel) {
 	go s.handleForward(&once, clientChannel, serverChannel)
 }
 
+func (s *sshConnectionHandler) handleAuthAgentChannel(newChannel ssh.NewChannel) {
+	s.forwardMu.Lock()
+	defer s.forwardMu.Unlock()
+	if s.authAgentHandler == nil {
+		_ = newChannel.Reject(ssh.Prohibited, "Agent forwarding was not requested")
+		return
+	}
+	clientChannel, _, err := s.authAgentHandler.NewChannelAuthAgent()
+	if err != nil {
+		m := message.Wrap(
+			err,
+			message.ESSHProxyBackendForwardFailed,
+			"Failed to open SSH agent channel to the client",
+		)
+		s.logger.Info(m)
+		_ = newChannel.Reject(ssh.ConnectionFailed, "Failed to open SSH agent channel to the client")
+		return
+	}
+	serverChannel, req, err := newChannel.Accept()
+	if err != nil {
+		m := message.Wrap(
+			err,
+			message.ESSHProxyBackendForwardFailed,
+			"Failed to accept SSH agent channel from server",
+		)
+		s.logger.Info(m)
+		_ = clientChannel.Close()
+		return
+	}
+	go s.rejectAllRequests(req)
+	once := sync.Once{}
+
+	go s.handleForward(&once, serverChannel, clientChannel)
+	go s.handleForward(&once, clientChannel, serverChannel)
+}
+
 func (s *sshConnectionHandler) OnUnsupportedGlobalRequest(_ uint64, _ string, _ []byte) {
 }
 
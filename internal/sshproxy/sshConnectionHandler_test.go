@@ -0,0 +1,129 @@
+package sshproxy //nolint:testpackage
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestAuthAgentChannel(t *testing.T) {
+	handler := &sshConnectionHandler{
+		logger: log.NewTestLogger(t),
+	}
+
+	t.Run("rejected without agent forwarding request", func(t *testing.T) {
+		newChannel := newTestNewChannel()
+		handler.handleAuthAgentChannel(newChannel)
+		assert.Equal(t, ssh.Prohibited, newChannel.rejectReason)
+	})
+
+	t.Run("relayed to the client agent", func(t *testing.T) {
+		clientSide, clientAgent := net.Pipe()
+		handler.forwardMu.Lock()
+		handler.authAgentHandler = &testAuthAgentReverseForward{
+			channel: clientSide,
+		}
+		handler.forwardMu.Unlock()
+
+		newChannel := newTestNewChannel()
+		handler.handleAuthAgentChannel(newChannel)
+		assert.Equal(t, ssh.RejectionReason(0), newChannel.rejectReason)
+
+		go func() {
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(clientAgent, buf); err != nil {
+				return
+			}
+			_, _ = clientAgent.Write([]byte("pong"))
+		}()
+
+		_, err := newChannel.backend.Write([]byte("ping"))
+		assert.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(newChannel.backend, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(buf))
+		_ = newChannel.backend.Close()
+	})
+}
+
+// testNewChannel simulates an auth-agent@openssh.com channel opened by the backing SSH server. The backend side of the
+// channel is available in the backend field once accepted.
+type testNewChannel struct {
+	rejectReason ssh.RejectionReason
+	backend      net.Conn
+	proxy        net.Conn
+}
+
+func newTestNewChannel() *testNewChannel {
+	backend, proxy := net.Pipe()
+	return &testNewChannel{
+		backend: backend,
+		proxy:   proxy,
+	}
+}
+
+func (t *testNewChannel) Accept() (ssh.Channel, <-chan *ssh.Request, error) {
+	requests := make(chan *ssh.Request)
+	close(requests)
+	return &testChannel{Conn: t.proxy}, requests, nil
+}
+
+func (t *testNewChannel) Reject(reason ssh.RejectionReason, _ string) error {
+	t.rejectReason = reason
+	return nil
+}
+
+func (t *testNewChannel) ChannelType() string {
+	return sshserver.ChannelTypeAuthAgent
+}
+
+func (t *testNewChannel) ExtraData() []byte {
+	return nil
+}
+
+type testChannel struct {
+	net.Conn
+}
+
+func (t *testChannel) CloseWrite() error {
+	return nil
+}
+
+func (t *testChannel) SendRequest(_ string, _ bool, _ []byte) (bool, error) {
+	return false, nil
+}
+
+func (t *testChannel) Stderr() io.ReadWriter {
+	return nil
+}
+
+type testAuthAgentReverseForward struct {
+	channel sshserver.ForwardChannel
+}
+
+func (t *testAuthAgentReverseForward) NewChannelTCP(_ string, _ uint32, _ string, _ uint32) (
+	sshserver.ForwardChannel,
+	uint64,
+	error,
+) {
+	return nil, 0, fmt.Errorf("not supported")
+}
+
+func (t *testAuthAgentReverseForward) NewChannelUnix(_ string) (sshserver.ForwardChannel, uint64, error) {
+	return nil, 0, fmt.Errorf("not supported")
+}
+
+func (t *testAuthAgentReverseForward) NewChannelX11(_ string, _ uint32) (sshserver.ForwardChannel, uint64, error) {
+	return nil, 0, fmt.Errorf("not supported")
+}
+
+func (t *testAuthAgentReverseForward) NewChannelAuthAgent() (sshserver.ForwardChannel, uint64, error) {
+	return t.channel, 0, nil
+}
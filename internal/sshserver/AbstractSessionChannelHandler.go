@@ -166,3 +166,15 @@ func (s *AbstractSessionChannelHandler) OnX11Request(
 ) error {
 	return fmt.Errorf("not supported")
 }
+
+// OnAuthAgentRequest is called when the client requests the forwarding of its SSH agent into the container. The
+// implementation can return an error to reject the request.
+//
+// requestID is an incrementing number uniquely identifying the request within the channel.
+// reverseHandler is a callback interface to open channels to the agent of the client when new connections are made
+func (s *AbstractSessionChannelHandler) OnAuthAgentRequest(
+	_ uint64,
+	_ ReverseForward,
+) error {
+	return fmt.Errorf("not supported")
+}
@@ -0,0 +1,186 @@
+package sshserver_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestAuthRestrictions(t *testing.T) {
+	handler := newAuthTestHandler()
+	srv := sshserver.NewTestServer(t, handler, log.NewTestLogger(t), nil)
+	srv.Start()
+	defer srv.Stop(10 * time.Second)
+
+	conn, err := dialAuthTest(t, srv, ssh.Password("bar"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = conn.Close()
+
+	meta := <-handler.handshakes
+	assert.Equal(t, "foo", meta.AuthenticatedUsername)
+	assert.Equal(t, "bar", meta.Metadata["password"].Value)
+	assert.True(t, meta.Restrictions.NoPTY, "the restrictions of the authenticator were lost")
+	assert.Equal(t, "true", meta.Restrictions.ForceCommand)
+	assert.Equal(t, "SHA256:test", meta.PublicKeyFingerprint, "the public key fingerprint was lost")
+}
+
+func TestAuthPartialSuccess(t *testing.T) {
+	handler := newAuthTestHandler()
+	handler.partialSuccess = true
+	srv := sshserver.NewTestServer(t, handler, log.NewTestLogger(t), nil)
+	srv.Start()
+	defer srv.Stop(10 * time.Second)
+
+	_, err := dialAuthTest(t, srv, ssh.Password("bar"))
+	assert.Error(t, err, "a partial success must not complete the authentication")
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	assert.NoError(t, err)
+	_, err = dialAuthTest(t, srv, ssh.Password("bar"), ssh.PublicKeys(signer))
+	assert.Error(t, err, "only the methods listed in the partial success should be offered")
+
+	_, err = dialAuthTest(
+		t,
+		srv,
+		ssh.KeyboardInteractive(
+			func(_ string, _ string, questions []string, _ []bool) ([]string, error) {
+				return make([]string, len(questions)), nil
+			},
+		),
+	)
+	assert.Error(t, err, "the keyboard-interactive method alone should not succeed")
+
+	conn, err := dialAuthTest(
+		t,
+		srv,
+		ssh.Password("bar"),
+		ssh.KeyboardInteractive(
+			func(_ string, _ string, questions []string, _ []bool) ([]string, error) {
+				return make([]string, len(questions)), nil
+			},
+		),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = conn.Close()
+
+	meta := <-handler.handshakes
+	assert.Equal(t, []string{"password", "keyboard-interactive"}, meta.AuthenticatedMethods)
+	assert.Equal(t, "bar", meta.Metadata["password"].Value, "the metadata of the first step was lost")
+	assert.Equal(t, "password", meta.Metadata["keyboard-interactive"].Value)
+	assert.True(t, meta.Restrictions.NoPTY, "the restrictions of the first step were lost")
+	assert.Equal(t, "SHA256:test", meta.PublicKeyFingerprint, "the public key fingerprint of the first step was lost")
+}
+
+func dialAuthTest(t *testing.T, srv sshserver.TestServer, auth ...ssh.AuthMethod) (*ssh.Client, error) {
+	hostKey, err := ssh.ParsePrivateKey([]byte(srv.GetHostKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ssh.Dial(
+		"tcp",
+		srv.GetListen(),
+		&ssh.ClientConfig{
+			User:            "foo",
+			Auth:            auth,
+			HostKeyCallback: ssh.FixedHostKey(hostKey.PublicKey()),
+			Timeout:         10 * time.Second,
+		},
+	)
+}
+
+func newAuthTestHandler() *authTestHandler {
+	return &authTestHandler{
+		handshakes: make(chan metadata.ConnectionAuthenticatedMetadata, 10),
+	}
+}
+
+type authTestHandler struct {
+	sshserver.AbstractHandler
+
+	handshakes chan metadata.ConnectionAuthenticatedMetadata
+	// partialSuccess requires a keyboard-interactive authentication after the password.
+	partialSuccess bool
+}
+
+func (a *authTestHandler) OnReady() error {
+	return nil
+}
+
+func (a *authTestHandler) OnShutdown(_ context.Context) {
+}
+
+func (a *authTestHandler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	return &authTestNetworkConnectionHandler{handler: a}, meta, nil
+}
+
+type authTestNetworkConnectionHandler struct {
+	sshserver.AbstractNetworkConnectionHandler
+
+	handler *authTestHandler
+}
+
+func (a *authTestNetworkConnectionHandler) OnAuthPassword(
+	meta metadata.ConnectionAuthPendingMetadata,
+	password []byte,
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if string(password) != "bar" {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), nil
+	}
+	authenticated := meta.Authenticated(meta.Username)
+	authenticated.GetMetadata()["password"] = metadata.Value{Value: "bar"}
+	authenticated.Restrictions = metadata.Restrictions{
+		ForceCommand: "true",
+		NoPTY:        true,
+	}
+	authenticated.PublicKeyFingerprint = "SHA256:test"
+	if a.handler.partialSuccess {
+		return sshserver.AuthResponsePartialSuccess, authenticated, &sshserver.PartialSuccess{
+			Methods: []config.SSHAuthMethod{config.SSHAuthMethodKeyboardInteractive},
+		}
+	}
+	return sshserver.AuthResponseSuccess, authenticated, nil
+}
+
+func (a *authTestNetworkConnectionHandler) OnAuthKeyboardInteractive(
+	meta metadata.ConnectionAuthPendingMetadata,
+	_ func(
+		instruction string,
+		questions sshserver.KeyboardInteractiveQuestions,
+	) (answers sshserver.KeyboardInteractiveAnswers, err error),
+) (sshserver.AuthResponse, metadata.ConnectionAuthenticatedMetadata, error) {
+	if len(meta.AuthenticatedMethods) != 1 {
+		return sshserver.AuthResponseFailure, meta.AuthFailed(), nil
+	}
+	authenticated := meta.Authenticated(meta.Username)
+	authenticated.GetMetadata()["keyboard-interactive"] = metadata.Value{Value: meta.AuthenticatedMethods[0]}
+	return sshserver.AuthResponseSuccess, authenticated, nil
+}
+
+func (a *authTestNetworkConnectionHandler) OnHandshakeSuccess(meta metadata.ConnectionAuthenticatedMetadata) (
+	sshserver.SSHConnectionHandler,
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	a.handler.handshakes <- meta
+	return &fullSSHConnectionHandler{}, meta, nil
+}
@@ -0,0 +1,91 @@
+package sshserver_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/internal/test"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"go.containerssh.io/libcontainerssh/service"
+)
+
+func TestDisconnect(t *testing.T) {
+	cfg := config.SSHConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.GenerateHostKey(config.SSHHostKeyTypeED25519))
+	cfg.Listen = fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "SSH"))
+
+	readyChannel := make(chan struct{}, 1)
+	shutdownChannel := make(chan struct{}, 1)
+	handler := &connectionIDRecordingHandler{
+		Handler:       newFullHandler(readyChannel, shutdownChannel, map[string][]byte{"foo": []byte("bar")}, nil),
+		connectionIDs: make(chan string, 1),
+	}
+	server, err := sshserver.New(cfg, handler, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	lifecycle := service.NewLifecycle(server)
+	go func() {
+		_ = lifecycle.Run()
+	}()
+	<-readyChannel
+	defer func() {
+		shutdownContext, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFunc()
+		lifecycle.Stop(shutdownContext)
+		<-shutdownChannel
+	}()
+
+	clientConfig := &ssh.ClientConfig{
+		User:            "foo",
+		Auth:            []ssh.AuthMethod{ssh.Password("bar")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	sshConnection, err := ssh.Dial("tcp", cfg.Listen, clientConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	connectionID := <-handler.connectionIDs
+
+	assert.Error(t, server.Disconnect("nonexistent"))
+	assert.NoError(t, server.Disconnect(connectionID))
+
+	disconnected := make(chan struct{})
+	go func() {
+		_ = sshConnection.Wait()
+		close(disconnected)
+	}()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "the client was not disconnected")
+	}
+
+	// Once the connection is gone it can no longer be disconnected.
+	assert.Eventually(t, func() bool {
+		return server.Disconnect(connectionID) != nil
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+type connectionIDRecordingHandler struct {
+	sshserver.Handler
+
+	connectionIDs chan string
+}
+
+func (c *connectionIDRecordingHandler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	c.connectionIDs <- meta.ConnectionID
+	return c.Handler.OnNetworkConnection(meta)
+}
@@ -0,0 +1,94 @@
+package sshserver_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/internal/test"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/service"
+)
+
+func TestDrain(t *testing.T) {
+	cfg := config.SSHConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.GenerateHostKey(config.SSHHostKeyTypeED25519))
+	cfg.Listen = fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "SSH"))
+
+	readyChannel := make(chan struct{}, 1)
+	shutdownChannel := make(chan struct{}, 1)
+	handler := &shutdownRecordingHandler{
+		Handler:        newFullHandler(readyChannel, shutdownChannel, map[string][]byte{"foo": []byte("bar")}, nil),
+		shutdownCalled: make(chan struct{}),
+	}
+	server, err := sshserver.New(cfg, handler, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	lifecycle := service.NewLifecycle(server)
+	go func() {
+		_ = lifecycle.Run()
+	}()
+	<-readyChannel
+
+	clientConfig := &ssh.ClientConfig{
+		User:            "foo",
+		Auth:            []ssh.AuthMethod{ssh.Password("bar")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	sshConnection, err := ssh.Dial("tcp", cfg.Listen, clientConfig)
+	if !assert.NoError(t, err) {
+		lifecycle.Stop(context.Background())
+		return
+	}
+
+	server.Drain()
+	shutdownContext, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	go lifecycle.Stop(shutdownContext)
+
+	// New connections are no longer accepted.
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", cfg.Listen)
+		if err != nil {
+			return true
+		}
+		_ = conn.Close()
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The existing connection keeps working and the handlers are not notified while it is open.
+	_, _, err = sshConnection.SendRequest("keepalive@openssh.com", true, nil)
+	assert.NoError(t, err)
+	select {
+	case <-handler.shutdownCalled:
+		assert.Fail(t, "the handler was notified of the shutdown while a client was still connected")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	_ = sshConnection.Close()
+	select {
+	case <-handler.shutdownCalled:
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "the handler was not notified of the shutdown after the last client disconnected")
+	}
+	<-shutdownChannel
+}
+
+type shutdownRecordingHandler struct {
+	sshserver.Handler
+
+	shutdownCalled chan struct{}
+}
+
+func (s *shutdownRecordingHandler) OnShutdown(shutdownContext context.Context) {
+	close(s.shutdownCalled)
+	s.Handler.OnShutdown(shutdownContext)
+}
@@ -0,0 +1,89 @@
+package sshserver_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/internal/test"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/service"
+)
+
+func TestHostCertificate(t *testing.T) {
+	_, caPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	ca, err := ssh.NewSignerFromKey(caPrivateKey)
+	assert.NoError(t, err)
+
+	port := test.GetNextPort(t, "SSH")
+	cfg := config.SSHConfig{}
+	structutils.Defaults(&cfg)
+	cfg.Listen = fmt.Sprintf("127.0.0.1:%d", port)
+	assert.NoError(t, cfg.GenerateHostKey(config.SSHHostKeyTypeED25519, config.SSHHostKeyTypeECDSAP256))
+	assert.Len(t, cfg.HostKeys, 2)
+
+	hostKey, err := ssh.ParsePrivateKey([]byte(cfg.HostKeys[0]))
+	assert.NoError(t, err)
+	cert := &ssh.Certificate{
+		Key:             hostKey.PublicKey(),
+		CertType:        ssh.HostCert,
+		KeyId:           "containerssh",
+		ValidPrincipals: []string{"127.0.0.1"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	assert.NoError(t, cert.SignCert(rand.Reader, ca))
+	cfg.HostCertificates = []string{string(ssh.MarshalAuthorizedKey(cert))}
+
+	readyChannel := make(chan struct{}, 1)
+	shutdownChannel := make(chan struct{}, 1)
+	handler := newFullHandler(readyChannel, shutdownChannel, map[string][]byte{"foo": []byte("bar")}, nil)
+	server, err := sshserver.New(cfg, handler, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	lifecycle := service.NewLifecycle(server)
+	go func() {
+		_ = lifecycle.Run()
+	}()
+	<-readyChannel
+	defer func() {
+		shutdownContext, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFunc()
+		lifecycle.Stop(shutdownContext)
+		<-shutdownChannel
+	}()
+
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, _ string) bool {
+			return string(auth.Marshal()) == string(ca.PublicKey().Marshal())
+		},
+	}
+	sshConfig := &ssh.ClientConfig{
+		User:              "foo",
+		Auth:              []ssh.AuthMethod{ssh.Password("bar")},
+		HostKeyCallback:   checker.CheckHostKey,
+		HostKeyAlgorithms: []string{ssh.CertAlgoED25519v01},
+	}
+	sshConnection, err := ssh.Dial("tcp", cfg.Listen, sshConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = sshConnection.Close()
+
+	// Clients that do not trust the CA can still use the plain host key.
+	sshConfig.HostKeyAlgorithms = []string{ssh.KeyAlgoECDSA256}
+	sshConfig.HostKeyCallback = ssh.InsecureIgnoreHostKey() //nolint:gosec
+	sshConnection, err = ssh.Dial("tcp", cfg.Listen, sshConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = sshConnection.Close()
+}
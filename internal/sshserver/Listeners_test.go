@@ -0,0 +1,172 @@
+package sshserver_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/internal/structutils"
+	"go.containerssh.io/libcontainerssh/internal/test"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"go.containerssh.io/libcontainerssh/service"
+)
+
+func TestMultipleListeners(t *testing.T) {
+	cfg := config.SSHConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.GenerateHostKey(config.SSHHostKeyTypeED25519))
+	internalKeys := config.SSHConfig{}
+	assert.NoError(t, internalKeys.GenerateHostKey(config.SSHHostKeyTypeECDSAP256))
+	cfg.Banner = "Public\n"
+	cfg.Listeners = []config.SSHListenerConfig{
+		{
+			Name:        "public",
+			Listen:      fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "SSH")),
+			AuthMethods: []config.SSHAuthMethod{config.SSHAuthMethodKeyboardInteractive},
+		},
+		{
+			Name:        "internal",
+			Listen:      fmt.Sprintf("127.0.0.1:%d", test.GetNextPort(t, "SSH")),
+			HostKeys:    internalKeys.HostKeys,
+			Banner:      "Internal\n",
+			AuthMethods: []config.SSHAuthMethod{config.SSHAuthMethodPassword},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	readyChannel := make(chan struct{}, 1)
+	shutdownChannel := make(chan struct{}, 1)
+	handler := &listenerRecordingHandler{
+		Handler:   newFullHandler(readyChannel, shutdownChannel, map[string][]byte{"foo": []byte("bar")}, nil),
+		listeners: make(chan metadata.ConnectionMetadata, 10),
+	}
+	server, err := sshserver.New(cfg, handler, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	lifecycle := service.NewLifecycle(server)
+	go func() {
+		_ = lifecycle.Run()
+	}()
+	<-readyChannel
+	defer func() {
+		shutdownContext, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFunc()
+		lifecycle.Stop(shutdownContext)
+		<-shutdownChannel
+	}()
+
+	dial := func(listen string) (string, string, error) {
+		banner := ""
+		hostKeyType := ""
+		sshConnection, err := ssh.Dial("tcp", listen, &ssh.ClientConfig{
+			User: "foo",
+			Auth: []ssh.AuthMethod{ssh.Password("bar")},
+			HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+				hostKeyType = key.Type()
+				return nil
+			},
+			BannerCallback: func(message string) error {
+				banner = message
+				return nil
+			},
+		})
+		if err == nil {
+			_ = sshConnection.Close()
+		}
+		return banner, hostKeyType, err
+	}
+
+	banner, hostKeyType, err := dial(cfg.Listeners[0].Listen)
+	assert.Error(t, err)
+	assert.Equal(t, "Public\n", banner)
+	assert.Equal(t, ssh.KeyAlgoED25519, hostKeyType)
+	meta := <-handler.listeners
+	assert.Equal(t, "public", meta.Listener)
+	assert.Equal(t, cfg.Listeners[0].Listen, meta.ListenAddress)
+
+	banner, hostKeyType, err = dial(cfg.Listeners[1].Listen)
+	assert.NoError(t, err)
+	assert.Equal(t, "Internal\n", banner)
+	assert.Equal(t, ssh.KeyAlgoECDSA256, hostKeyType)
+	meta = <-handler.listeners
+	assert.Equal(t, "internal", meta.Listener)
+	assert.Equal(t, cfg.Listeners[1].Listen, meta.ListenAddress)
+}
+
+type listenerRecordingHandler struct {
+	sshserver.Handler
+
+	listeners chan metadata.ConnectionMetadata
+}
+
+func (l *listenerRecordingHandler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	l.listeners <- meta
+	return l.Handler.OnNetworkConnection(meta)
+}
+
+func TestUnixSocketListener(t *testing.T) {
+	cfg := config.SSHConfig{}
+	structutils.Defaults(&cfg)
+	assert.NoError(t, cfg.GenerateHostKey(config.SSHHostKeyTypeED25519))
+	socketPath := filepath.Join(t.TempDir(), "ssh.sock")
+	cfg.Listen = "unix:" + socketPath
+	cfg.SocketMode = "0600"
+	assert.NoError(t, cfg.Validate())
+
+	readyChannel := make(chan struct{}, 1)
+	shutdownChannel := make(chan struct{}, 1)
+	handler := &listenerRecordingHandler{
+		Handler:   newFullHandler(readyChannel, shutdownChannel, map[string][]byte{"foo": []byte("bar")}, nil),
+		listeners: make(chan metadata.ConnectionMetadata, 10),
+	}
+	server, err := sshserver.New(cfg, handler, log.NewTestLogger(t))
+	assert.NoError(t, err)
+	lifecycle := service.NewLifecycle(server)
+	go func() {
+		_ = lifecycle.Run()
+	}()
+	<-readyChannel
+	defer func() {
+		shutdownContext, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFunc()
+		lifecycle.Stop(shutdownContext)
+		<-shutdownChannel
+	}()
+
+	stat, err := os.Stat(socketPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	conn, err := net.Dial("unix", socketPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sshConn, _, _, err := ssh.NewClientConn(conn, "localhost", &ssh.ClientConfig{
+		User:            "foo",
+		Auth:            []ssh.AuthMethod{ssh.Password("bar")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if !assert.NoError(t, err) {
+		_ = conn.Close()
+		return
+	}
+	_ = sshConn.Close()
+	meta := <-handler.listeners
+	assert.Equal(t, cfg.Listen, meta.ListenAddress)
+	assert.Equal(t, "0.0.0.0", meta.RemoteAddress.IP.String())
+}
@@ -12,18 +12,29 @@ func New(cfg config.SSHConfig, handler Handler, logger log.Logger) (Server, erro
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
-	hostKeys, err := cfg.LoadHostKeys()
-	if err != nil {
-		return nil, err
+	var listeners []*listener
+	for _, listenerConfig := range cfg.EffectiveListeners() {
+		hostKeys, err := listenerConfig.LoadHostKeys()
+		if err != nil {
+			return nil, err
+		}
+		trustedProxies, err := listenerConfig.ProxyProtocol.TrustedNetworks()
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, &listener{
+			cfg:            listenerConfig,
+			hostKeys:       hostKeys,
+			trustedProxies: trustedProxies,
+		})
 	}
 	return &serverImpl{
-		cfg:          cfg,
-		handler:      handler,
-		logger:       logger,
-		wg:           &sync.WaitGroup{},
-		lock:         &sync.Mutex{},
-		listenSocket: nil,
-		hostKeys:     hostKeys,
+		cfg:       cfg,
+		handler:   handler,
+		logger:    logger,
+		wg:        &sync.WaitGroup{},
+		lock:      &sync.Mutex{},
+		listeners: listeners,
 		shutdownHandlers: &shutdownRegistry{
 			lock:      &sync.Mutex{},
 			callbacks: map[string]shutdownHandler{},
@@ -8,4 +8,13 @@ import (
 // with the Lifecycle interface from the service library.
 type Server interface {
 	service.Service
+
+	// Drain makes the next shutdown graceful towards the connected clients. The server stops accepting connections
+	// as usual, but it waits for the existing connections to end until the shutdown context expires before
+	// notifying the handlers of the shutdown and closing the remaining connections.
+	Drain()
+
+	// Disconnect closes the SSH connection with the given connection ID. It returns an error if no connection with
+	// the ID is open.
+	Disconnect(connectionID string) error
 }
@@ -0,0 +1,89 @@
+package sshserver
+
+import (
+	"maps"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"golang.org/x/crypto/ssh"
+)
+
+// authStep is a step of the authentication of a connection. The first step starts from the connection metadata. When
+// the network connection handler requires further authentication methods, the next step continues from the merged
+// metadata of the methods the client has completed so far.
+type authStep struct {
+	listener           *listener
+	connectionMetadata metadata.ConnectionMetadata
+	handler            *networkConnectionWrapper
+	logger             log.Logger
+
+	// previous is the merged metadata of the completed authentication methods. It is nil in the first step.
+	previous *metadata.ConnectionAuthenticatedMetadata
+}
+
+// start creates the metadata for an authentication attempt in this step.
+func (a *authStep) start(conn ssh.ConnMetadata) metadata.ConnectionAuthPendingMetadata {
+	if a.previous == nil {
+		return a.connectionMetadata.StartAuthentication(string(conn.ClientVersion()), conn.User())
+	}
+	connectionMetadata := a.previous.ConnectionMetadata
+	// The maps are copied so attempts that do not complete the step cannot change the metadata of the previous
+	// steps.
+	connectionMetadata.Metadata = maps.Clone(connectionMetadata.Metadata)
+	connectionMetadata.Environment = maps.Clone(connectionMetadata.Environment)
+	connectionMetadata.Files = maps.Clone(connectionMetadata.Files)
+	authenticating := connectionMetadata.StartAuthentication(string(conn.ClientVersion()), conn.User())
+	authenticating.AuthenticatedMethods = a.previous.AuthenticatedMethods
+	return authenticating
+}
+
+// merge adds the metadata of the previous steps to the metadata returned by a successful authentication method in
+// this step. Values set by this step take precedence, the restrictions of all steps apply.
+func (a *authStep) merge(
+	method config.SSHAuthMethod,
+	authenticated metadata.ConnectionAuthenticatedMetadata,
+) metadata.ConnectionAuthenticatedMetadata {
+	var completedMethods []string
+	if a.previous != nil {
+		completedMethods = a.previous.AuthenticatedMethods
+		mergeMissing(authenticated.GetMetadata(), a.previous.Metadata)
+		mergeMissing(authenticated.GetEnvironment(), a.previous.Environment)
+		mergeMissing(authenticated.GetFiles(), a.previous.Files)
+		if authenticated.AuthenticatedUsername == "" {
+			authenticated.AuthenticatedUsername = a.previous.AuthenticatedUsername
+		}
+		authenticated.Restrictions = authenticated.Restrictions.Merge(a.previous.Restrictions)
+		if authenticated.PublicKeyFingerprint == "" {
+			authenticated.PublicKeyFingerprint = a.previous.PublicKeyFingerprint
+		}
+	}
+	authenticated.AuthenticatedMethods = append(
+		append([]string(nil), completedMethods...),
+		string(method),
+	)
+	return authenticated
+}
+
+// next returns the step following a partial success of the authentication method.
+func (a *authStep) next(
+	method config.SSHAuthMethod,
+	authenticated metadata.ConnectionAuthenticatedMetadata,
+) *authStep {
+	merged := a.merge(method, authenticated)
+	return &authStep{
+		listener:           a.listener,
+		connectionMetadata: a.connectionMetadata,
+		handler:            a.handler,
+		logger:             a.logger,
+		previous:           &merged,
+	}
+}
+
+func mergeMissing[T any](target map[string]T, source map[string]T) {
+	for key, value := range source {
+		if _, ok := target[key]; !ok {
+			target[key] = value
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 type channelWrapper struct {
 	channel        ssh.Channel
+	disconnect     func() error
 	logger         log.Logger
 	lock           *sync.Mutex
 	exitSent       bool
@@ -136,6 +137,17 @@ func (c *channelWrapper) Close() error {
 	return c.channel.Close()
 }
 
+func (c *channelWrapper) CloseForPolicy(_ string) error {
+	return c.Close()
+}
+
+func (c *channelWrapper) DisconnectForPolicy(_ string) error {
+	if c.disconnect == nil {
+		return c.Close()
+	}
+	return c.disconnect()
+}
+
 func (c *channelWrapper) onClose() {
 	c.lock.Lock()
 	defer c.lock.Unlock()
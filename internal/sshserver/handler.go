@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"go.containerssh.io/libcontainerssh/auth"
+	"go.containerssh.io/libcontainerssh/config"
 	auth2 "go.containerssh.io/libcontainerssh/internal/auth"
 	message2 "go.containerssh.io/libcontainerssh/message"
 	"go.containerssh.io/libcontainerssh/metadata"
@@ -44,8 +45,24 @@ const (
 	// AuthResponseUnavailable indicates that the authentication could not be performed because a backend system failed
 	//                         to respond.
 	AuthResponseUnavailable AuthResponse = 3
+
+	// AuthResponsePartialSuccess indicates that the authentication was successful, but the client must complete
+	//                            further authentication methods. The reason should be a *PartialSuccess listing the
+	//                            methods the client can continue with.
+	AuthResponsePartialSuccess AuthResponse = 4
 )
 
+// PartialSuccess is returned as the reason together with AuthResponsePartialSuccess. It lists the authentication
+// methods the client can continue with. If Methods is empty, all methods of the listener except GSSAPI are offered.
+type PartialSuccess struct {
+	Methods []config.SSHAuthMethod
+}
+
+// Error returns the description of the partial success.
+func (p *PartialSuccess) Error() string {
+	return "further authentication required"
+}
+
 // KeyboardInteractiveQuestion contains a question issued to a user as part of the keyboard-interactive exchange.
 type KeyboardInteractiveQuestion struct {
 	// ID is an optional opaque ID that can be used to identify a question in an answer. Can be left empty.
@@ -175,6 +192,24 @@ type SessionChannel interface {
 	Close() error
 }
 
+// PolicyCloser is an optional interface of SessionChannel. It lets a handler close the channel or the whole
+// connection because of a policy, for example an idle timeout. Handlers that wrap the SessionChannel should implement
+// it and pass the call on so the reason can be recorded on the way.
+type PolicyCloser interface {
+	// CloseForPolicy closes the channel because of the policy named in reason.
+	CloseForPolicy(reason string) error
+	// DisconnectForPolicy closes the whole connection because of the policy named in reason.
+	DisconnectForPolicy(reason string) error
+}
+
+// PolicyAuditor is an optional interface of SessionChannel and ReverseForward. It lets a handler record that a request
+// would have been rejected by a policy that is not enforced, for example in the security dry run mode. Handlers that
+// wrap these objects should implement it and pass the call on.
+type PolicyAuditor interface {
+	// AuditPolicyDryRun records that a request would have been rejected by rule for the stated reason.
+	AuditPolicyDryRun(rule string, reason string)
+}
+
 const (
 	ChannelTypeSession              string = "session"
 	ChannelTypeDirectTCPIP          string = "direct-tcpip"
@@ -182,6 +217,7 @@ const (
 	ChannelTypeX11                  string = "x11"
 	ChannelTypeDirectStreamLocal    string = "direct-streamlocal@openssh.com"
 	ChannelTypeForwardedStreamLocal string = "forwarded-streamlocal@openssh.com"
+	ChannelTypeAuthAgent            string = "auth-agent@openssh.com"
 )
 
 // ReverseForward contains a set of callbacks for backends to request the opening of a new channel
@@ -202,6 +238,8 @@ type ReverseForward interface {
 	// originatorAddress is the address that initiated the X11 request
 	// originatorPort is the port that originated the X11 request
 	NewChannelX11(originatorAddress string, originatorPort uint32) (ForwardChannel, uint64, error)
+	// NewChannelAuthAgent requests the opening of a channel to the SSH agent of the client
+	NewChannelAuthAgent() (ForwardChannel, uint64, error)
 }
 
 // ForwardChannel represents a network forwarding channel
@@ -393,6 +431,16 @@ type SessionChannelHandler interface {
 		reverseHandler ReverseForward,
 	) error
 
+	// OnAuthAgentRequest is called when the client requests the forwarding of its SSH agent into the container. The
+	// implementation can return an error to reject the request.
+	//
+	// requestID is an incrementing number uniquely identifying the request within the channel.
+	// reverseHandler is a callback interface to open channels to the agent of the client when new connections are made
+	OnAuthAgentRequest(
+		requestID uint64,
+		reverseHandler ReverseForward,
+	) error
+
 	// endregion
 
 	// region Program execution
@@ -0,0 +1,178 @@
+package sshserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/internal/socket"
+)
+
+// See https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt for the PROXY protocol specification.
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolV1Prefix    = "PROXY "
+	proxyProtocolV1MaxLength = 107
+
+	proxyProtocolV2CommandLocal = 0x0
+	proxyProtocolV2CommandProxy = 0x1
+	proxyProtocolV2FamilyTCP4   = 0x11
+	proxyProtocolV2FamilyTCP6   = 0x21
+)
+
+// proxyProtocolConn is a connection that has had its PROXY protocol header consumed. It reports the client address
+// from the header as the remote address.
+type proxyProtocolConn struct {
+	net.Conn
+	reader     *bufio.Reader
+	remoteAddr *net.TCPAddr
+}
+
+func (p *proxyProtocolConn) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+func (p *proxyProtocolConn) RemoteAddr() net.Addr {
+	return p.remoteAddr
+}
+
+// readProxyProtocolHeader reads a PROXY protocol v1 or v2 header from the connection and returns a connection that
+// reports the client address as its remote address. If the header indicates a local connection (e.g. a health check)
+// the address of the proxy is kept.
+func readProxyProtocolHeader(conn net.Conn, timeout time.Duration) (*proxyProtocolConn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	remoteAddr, err := parseProxyProtocolHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	if remoteAddr == nil {
+		remoteAddr = socket.RemoteTCPAddr(conn)
+	}
+	return &proxyProtocolConn{
+		Conn:       conn,
+		reader:     reader,
+		remoteAddr: remoteAddr,
+	}, nil
+}
+
+func parseProxyProtocolHeader(reader *bufio.Reader) (*net.TCPAddr, error) {
+	prefix, err := reader.Peek(len(proxyProtocolV1Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol header (%w)", err)
+	}
+	if string(prefix) == proxyProtocolV1Prefix {
+		return parseProxyProtocolV1(reader)
+	}
+	signature, err := reader.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol header (%w)", err)
+	}
+	if bytes.Equal(signature, proxyProtocolV2Signature) {
+		return parseProxyProtocolV2(reader)
+	}
+	return nil, fmt.Errorf("no PROXY protocol header found")
+}
+
+func parseProxyProtocolV1(reader *bufio.Reader) (*net.TCPAddr, error) {
+	var line []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PROXY protocol v1 header (%w)", err)
+		}
+		line = append(line, b)
+		if len(line) > proxyProtocolV1MaxLength {
+			return nil, fmt.Errorf("PROXY protocol v1 header too long")
+		}
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			break
+		}
+	}
+	parts := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(parts) >= 2 && parts[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header: %q", line)
+	}
+	ip := net.ParseIP(parts[2])
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source address in PROXY protocol v1 header: %s", parts[2])
+	}
+	switch parts[1] {
+	case "TCP4":
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("invalid IPv4 source address in PROXY protocol v1 header: %s", parts[2])
+		}
+	case "TCP6":
+		if ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 source address in PROXY protocol v1 header: %s", parts[2])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported protocol in PROXY protocol v1 header: %s", parts[1])
+	}
+	port, err := strconv.ParseUint(parts[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source port in PROXY protocol v1 header: %s", parts[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func parseProxyProtocolV2(reader *bufio.Reader) (*net.TCPAddr, error) {
+	header := make([]byte, len(proxyProtocolV2Signature)+4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol v2 header (%w)", err)
+	}
+	versionCommand := header[12]
+	family := header[13]
+	length := binary.BigEndian.Uint16(header[14:16])
+	if versionCommand>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version: %d", versionCommand>>4)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol v2 addresses (%w)", err)
+	}
+	switch versionCommand & 0x0F {
+	case proxyProtocolV2CommandLocal:
+		return nil, nil
+	case proxyProtocolV2CommandProxy:
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol v2 command: %d", versionCommand&0x0F)
+	}
+	switch family {
+	case proxyProtocolV2FamilyTCP4:
+		if len(payload) < 12 {
+			return nil, fmt.Errorf("PROXY protocol v2 address block too short")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:4]),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+	case proxyProtocolV2FamilyTCP6:
+		if len(payload) < 36 {
+			return nil, fmt.Errorf("PROXY protocol v2 address block too short")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	default:
+		// Unsupported address families (e.g. UDP or UNIX) are treated like LOCAL connections as per the
+		// specification.
+		return nil, nil
+	}
+}
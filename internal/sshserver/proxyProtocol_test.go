@@ -0,0 +1,160 @@
+package sshserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+)
+
+func TestProxyProtocolV1(t *testing.T) {
+	addr, err := parseProxyProtocolHeader(
+		bufio.NewReader(bytes.NewReader([]byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 2222\r\nSSH-2.0"))),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1:56324", addr.String())
+
+	addr, err = parseProxyProtocolHeader(
+		bufio.NewReader(bytes.NewReader([]byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 2222\r\n"))),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:56324", addr.String())
+
+	addr, err = parseProxyProtocolHeader(bufio.NewReader(bytes.NewReader([]byte("PROXY UNKNOWN\r\n"))))
+	assert.NoError(t, err)
+	assert.Nil(t, addr)
+
+	_, err = parseProxyProtocolHeader(
+		bufio.NewReader(bytes.NewReader([]byte("PROXY TCP4 2001:db8::1 198.51.100.1 56324 2222\r\n"))),
+	)
+	assert.Error(t, err)
+
+	_, err = parseProxyProtocolHeader(
+		bufio.NewReader(bytes.NewReader([]byte("PROXY TCP6 192.0.2.1 2001:db8::2 56324 2222\r\n"))),
+	)
+	assert.Error(t, err)
+
+	_, err = parseProxyProtocolHeader(bufio.NewReader(bytes.NewReader([]byte("SSH-2.0-OpenSSH\r\n"))))
+	assert.Error(t, err)
+}
+
+func TestProxyProtocolV2(t *testing.T) {
+	addr, err := parseProxyProtocolHeader(bufio.NewReader(bytes.NewReader(
+		buildProxyProtocolV2Header(proxyProtocolV2CommandProxy, net.ParseIP("192.0.2.1").To4(), 56324),
+	)))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1:56324", addr.String())
+
+	addr, err = parseProxyProtocolHeader(bufio.NewReader(bytes.NewReader(
+		buildProxyProtocolV2Header(proxyProtocolV2CommandProxy, net.ParseIP("2001:db8::1"), 56324),
+	)))
+	assert.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:56324", addr.String())
+
+	addr, err = parseProxyProtocolHeader(bufio.NewReader(bytes.NewReader(
+		buildProxyProtocolV2Header(proxyProtocolV2CommandLocal, net.ParseIP("192.0.2.1").To4(), 56324),
+	)))
+	assert.NoError(t, err)
+	assert.Nil(t, addr)
+}
+
+func TestProxyProtocolTrust(t *testing.T) {
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() {
+		_ = netListener.Close()
+	}()
+
+	accept := func(trustedProxies []string, header []byte) (net.Conn, error) {
+		cfg := config.SSHListenerConfig{
+			ProxyProtocol: config.SSHProxyProtocolConfig{
+				TrustedProxies: trustedProxies,
+				HeaderTimeout:  time.Second,
+			},
+		}
+		networks, err := cfg.ProxyProtocol.TrustedNetworks()
+		assert.NoError(t, err)
+		l := &listener{cfg: cfg, trustedProxies: networks}
+		s := &serverImpl{}
+
+		client, err := net.Dial("tcp", netListener.Addr().String())
+		assert.NoError(t, err)
+		defer func() {
+			_ = client.Close()
+		}()
+		_, err = client.Write(append(header, []byte("SSH-2.0-Test\r\n")...))
+		assert.NoError(t, err)
+		conn, err := netListener.Accept()
+		assert.NoError(t, err)
+		return s.acceptProxyProtocol(l, conn)
+	}
+
+	conn, err := accept([]string{"127.0.0.0/8"}, []byte("PROXY TCP4 192.0.2.1 127.0.0.1 56324 2222\r\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1:56324", conn.RemoteAddr().String())
+	line := make([]byte, 14)
+	_, err = io.ReadFull(conn, line)
+	assert.NoError(t, err)
+	assert.Equal(t, "SSH-2.0-Test\r\n", string(line))
+	_ = conn.Close()
+
+	_, err = accept([]string{"192.0.2.0/24"}, []byte("PROXY TCP4 192.0.2.1 127.0.0.1 56324 2222\r\n"))
+	assert.Error(t, err)
+
+	_, err = accept([]string{"127.0.0.1"}, nil)
+	assert.Error(t, err)
+}
+
+func TestProxyProtocolPerListener(t *testing.T) {
+	cfg := config.SSHConfig{
+		Listen: "0.0.0.0:2222",
+		ProxyProtocol: config.SSHProxyProtocolConfig{
+			TrustedProxies: []string{"10.0.0.0/8"},
+			HeaderTimeout:  5 * time.Second,
+		},
+		Listeners: []config.SSHListenerConfig{
+			{
+				Name:   "inherited",
+				Listen: "0.0.0.0:2222",
+			},
+			{
+				Name:   "own",
+				Listen: "0.0.0.0:2223",
+				ProxyProtocol: config.SSHProxyProtocolConfig{
+					TrustedProxies: []string{"192.0.2.1"},
+				},
+			},
+		},
+	}
+	inherited, ok := cfg.Listener("inherited")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"10.0.0.0/8"}, inherited.ProxyProtocol.TrustedProxies)
+
+	own, ok := cfg.Listener("own")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"192.0.2.1"}, own.ProxyProtocol.TrustedProxies)
+	assert.Equal(t, 5*time.Second, own.ProxyProtocol.HeaderTimeout)
+}
+
+func buildProxyProtocolV2Header(command byte, ip net.IP, port uint16) []byte {
+	header := append([]byte{}, proxyProtocolV2Signature...)
+	var addresses []byte
+	family := byte(proxyProtocolV2FamilyTCP4)
+	if ip.To4() == nil {
+		family = proxyProtocolV2FamilyTCP6
+	}
+	addresses = append(addresses, ip...)
+	addresses = append(addresses, ip...)
+	addresses = binary.BigEndian.AppendUint16(addresses, port)
+	addresses = binary.BigEndian.AppendUint16(addresses, 2222)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
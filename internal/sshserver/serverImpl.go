@@ -14,6 +14,8 @@ import (
 	protocol "go.containerssh.io/libcontainerssh/agentprotocol"
 	"go.containerssh.io/libcontainerssh/auth"
 	"go.containerssh.io/libcontainerssh/config"
+	auth2 "go.containerssh.io/libcontainerssh/internal/auth"
+	"go.containerssh.io/libcontainerssh/internal/socket"
 	ssh2 "go.containerssh.io/libcontainerssh/internal/ssh"
 	"go.containerssh.io/libcontainerssh/log"
 	messageCodes "go.containerssh.io/libcontainerssh/message"
@@ -27,20 +29,29 @@ type connection struct {
 	reverseForwards map[string]*protocol.ForwardCtx
 }
 
+// listener is a listening socket of the SSH server with its own configuration.
+type listener struct {
+	cfg            config.SSHListenerConfig
+	hostKeys       []ssh.Signer
+	trustedProxies []*net.IPNet
+	socket         net.Listener
+}
+
 type serverImpl struct {
 	cfg                 config.SSHConfig
 	logger              log.Logger
 	handler             Handler
-	listenSocket        net.Listener
+	listeners           []*listener
+	running             bool
 	wg                  *sync.WaitGroup
 	lock                *sync.Mutex
 	clientSockets       map[*ssh.ServerConn]bool
 	connMap             map[string]connection
 	nextGlobalRequestID uint64
 	nextChannelID       uint64
-	hostKeys            []ssh.Signer
 	shutdownHandlers    *shutdownRegistry
 	shuttingDown        bool
+	draining            bool
 }
 
 func (s *serverImpl) String() string {
@@ -49,80 +60,163 @@ func (s *serverImpl) String() string {
 
 func (s *serverImpl) RunWithLifecycle(lifecycle service.Lifecycle) error {
 	s.lock.Lock()
-	alreadyRunning := false
-	if s.listenSocket != nil {
-		alreadyRunning = true
-	} else {
-		s.clientSockets = make(map[*ssh.ServerConn]bool)
-		s.connMap = make(map[string]connection)
-	}
-	s.shuttingDown = false
-	if alreadyRunning {
+	if s.running {
 		s.lock.Unlock()
 		return messageCodes.NewMessage(messageCodes.ESSHAlreadyRunning, "SSH server is already running")
 	}
+	s.running = true
+	s.clientSockets = make(map[*ssh.ServerConn]bool)
+	s.connMap = make(map[string]connection)
+	s.shuttingDown = false
+	s.draining = false
 
 	listenConfig := net.ListenConfig{
 		Control: s.socketControl,
 	}
 
-	netListener, err := listenConfig.Listen(lifecycle.Context(), "tcp", s.cfg.Listen)
-	if err != nil {
-		s.lock.Unlock()
-		return messageCodes.Wrap(err, messageCodes.ESSHStartFailed, "failed to start SSH server on %s", s.cfg.Listen)
+	for _, l := range s.listeners {
+		netListener, err := socket.Listen(lifecycle.Context(), &listenConfig, l.cfg.Listen, l.cfg.SocketMode)
+		if err != nil {
+			s.closeListenSockets()
+			s.running = false
+			s.lock.Unlock()
+			return messageCodes.Wrap(err, messageCodes.ESSHStartFailed, "failed to start SSH server on %s", l.cfg.Listen)
+		}
+		l.socket = netListener
 	}
-	s.listenSocket = netListener
 	s.lock.Unlock()
 	if err := s.handler.OnReady(); err != nil {
-		if err := netListener.Close(); err != nil {
-			s.logger.Warning(
-				messageCodes.Wrap(
-					err,
-					messageCodes.ESSHListenCloseFailed,
-					"failed to close listen socket after failed startup",
-				),
-			)
-		}
+		s.lock.Lock()
+		s.closeListenSockets()
+		s.running = false
+		s.lock.Unlock()
 		return err
 	}
 	lifecycle.Running()
-	s.logger.Info(messageCodes.NewMessage(messageCodes.MSSHServiceAvailable, "SSH server running on %s", s.cfg.Listen))
+	for _, l := range s.listeners {
+		s.logger.Info(
+			messageCodes.NewMessage(messageCodes.MSSHServiceAvailable, "SSH server running on %s", l.cfg.Listen),
+		)
+	}
 
 	go s.handleListenSocketOnShutdown(lifecycle)
-	for {
-		tcpConn, err := netListener.Accept()
-		if err != nil {
-			// Assume listen socket closed
-			break
+	acceptWg := &sync.WaitGroup{}
+	s.lock.Lock()
+	for _, l := range s.listeners {
+		if l.socket == nil {
+			// The server is already shutting down.
+			continue
 		}
-		s.wg.Add(1)
-		go s.handleConnection(tcpConn)
+		acceptWg.Add(1)
+		go func(l *listener, socket net.Listener) {
+			defer acceptWg.Done()
+			s.acceptConnections(l, socket)
+		}(l, l.socket)
 	}
+	s.lock.Unlock()
+	acceptWg.Wait()
 	lifecycle.Stopping()
 	s.shuttingDown = true
 	allClientsExited := make(chan struct{})
 	shutdownHandlerExited := make(chan struct{}, 1)
-	go s.shutdownHandlers.Shutdown(lifecycle.ShutdownContext())
+	go s.notifyShutdown(lifecycle, allClientsExited, shutdownHandlerExited)
 	go s.disconnectClients(lifecycle, allClientsExited)
-	go s.shutdownHandler(lifecycle, shutdownHandlerExited)
 
 	s.wg.Wait()
 	close(allClientsExited)
 	<-shutdownHandlerExited
+	s.lock.Lock()
+	s.running = false
+	s.lock.Unlock()
 	// This is an expected nil return
 	return nil //nolint:nilerr
 }
 
+// acceptConnections accepts connections on the listener until its socket is closed. When the socket of one listener
+// fails all other listeners are closed too so the server shuts down as a whole.
+func (s *serverImpl) acceptConnections(l *listener, socket net.Listener) {
+	for {
+		tcpConn, err := socket.Accept()
+		if err != nil {
+			// Assume listen socket closed
+			break
+		}
+		s.wg.Add(1)
+		go s.handleConnection(l, tcpConn)
+	}
+	s.lock.Lock()
+	s.closeListenSockets()
+	s.lock.Unlock()
+}
+
+func (s *serverImpl) Drain() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.draining = true
+}
+
+func (s *serverImpl) Disconnect(connectionID string) error {
+	s.lock.Lock()
+	conn, ok := s.connMap[connectionID]
+	s.lock.Unlock()
+	if !ok {
+		return messageCodes.NewMessage(
+			messageCodes.ESSHConnectionNotFound,
+			"No open connection with the ID %s",
+			connectionID,
+		).Label("connectionId", connectionID)
+	}
+	s.logger.Debug(
+		messageCodes.NewMessage(
+			messageCodes.MSSHDisconnectRequested,
+			"Closing connection %s on request",
+			connectionID,
+		).Label("connectionId", connectionID),
+	)
+	return conn.sshConn.Close()
+}
+
+// notifyShutdown notifies the handlers of the shutdown. When draining, the handlers are only notified once all clients
+// have disconnected or the shutdown context has expired, so the backends do not terminate the running sessions early.
+func (s *serverImpl) notifyShutdown(
+	lifecycle service.Lifecycle,
+	allClientsExited chan struct{},
+	shutdownHandlerExited chan struct{},
+) {
+	s.lock.Lock()
+	draining := s.draining
+	s.lock.Unlock()
+	if draining {
+		select {
+		case <-allClientsExited:
+		case <-lifecycle.ShutdownContext().Done():
+		}
+	}
+	go s.shutdownHandlers.Shutdown(lifecycle.ShutdownContext())
+	s.shutdownHandler(lifecycle, shutdownHandlerExited)
+}
+
 func (s *serverImpl) handleListenSocketOnShutdown(lifecycle service.Lifecycle) {
 	<-lifecycle.Context().Done()
 	s.lock.Lock()
-	if err := s.listenSocket.Close(); err != nil {
-		s.logger.Warning(messageCodes.Wrap(err, messageCodes.ESSHListenCloseFailed, "failed to close listen socket"))
-	}
-	s.listenSocket = nil
+	s.closeListenSockets()
 	s.lock.Unlock()
 }
 
+// closeListenSockets closes the sockets of all listeners. It must be called with the server lock held.
+func (s *serverImpl) closeListenSockets() {
+	for _, l := range s.listeners {
+		if l.socket != nil {
+			if err := l.socket.Close(); err != nil {
+				s.logger.Warning(
+					messageCodes.Wrap(err, messageCodes.ESSHListenCloseFailed, "failed to close listen socket"),
+				)
+			}
+			l.socket = nil
+		}
+	}
+}
+
 func (s *serverImpl) disconnectClients(lifecycle service.Lifecycle, allClientsExited chan struct{}) {
 	select {
 	case <-allClientsExited:
@@ -139,30 +233,32 @@ func (s *serverImpl) disconnectClients(lifecycle service.Lifecycle, allClientsEx
 }
 
 func (s *serverImpl) createPasswordAuthenticator(
-	connectionMetadata metadata.ConnectionMetadata,
-	handlerNetworkConnection NetworkConnectionHandler,
-	logger log.Logger,
+	step *authStep,
 ) func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, metadata.ConnectionAuthenticatedMetadata, error) {
 	return func(conn ssh.ConnMetadata, password []byte) (
 		*ssh.Permissions,
 		metadata.ConnectionAuthenticatedMetadata,
 		error,
 	) {
-		authenticatingMetadata := connectionMetadata.StartAuthentication(string(conn.ClientVersion()), conn.User())
-		authResponse, authenticatedMetadata, err := handlerNetworkConnection.OnAuthPassword(
+		authenticatingMetadata := step.start(conn)
+		authResponse, authenticatedMetadata, err := step.handler.OnAuthPassword(
 			authenticatingMetadata,
 			password,
 		)
 		//goland:noinspection GoNilness
 		switch authResponse {
 		case AuthResponseSuccess:
-			s.logAuthSuccessful(logger, authenticatedMetadata, "Password")
+			authenticatedMetadata = step.merge(config.SSHAuthMethodPassword, authenticatedMetadata)
+			s.logAuthSuccessful(step.logger, authenticatedMetadata, "Password")
 			return &ssh.Permissions{}, authenticatedMetadata, nil
+		case AuthResponsePartialSuccess:
+			err = s.partialSuccess(step, config.SSHAuthMethodPassword, "Password", authenticatedMetadata, err)
+			return nil, authenticatedMetadata, err
 		case AuthResponseFailure:
-			err = s.wrapAndLogAuthFailure(logger, authenticatingMetadata, "Password", err)
+			err = s.wrapAndLogAuthFailure(step.logger, authenticatingMetadata, "Password", err)
 			return nil, authenticatedMetadata, err
 		case AuthResponseUnavailable:
-			err = s.wrapAndLogAuthUnavailable(logger, authenticatingMetadata, "Password", err)
+			err = s.wrapAndLogAuthUnavailable(step.logger, authenticatingMetadata, "Password", err)
 			return nil, authenticatedMetadata, err
 		}
 		return nil, authenticatedMetadata, fmt.Errorf("authentication currently unavailable")
@@ -239,10 +335,44 @@ func (s *serverImpl) logAuthSuccessful(
 	logger.Info(err)
 }
 
-func (s *serverImpl) createPubKeyAuthenticator(
-	connectionMetadata metadata.ConnectionMetadata,
-	handlerNetworkConnection NetworkConnectionHandler,
+func (s *serverImpl) logAuthPartialSuccess(
 	logger log.Logger,
+	conn metadata.ConnectionAuthenticatedMetadata,
+	authMethod string,
+) {
+	err := messageCodes.UserMessage(
+		messageCodes.ESSHAuthPartialSuccess,
+		"Authentication successful, further authentication required.",
+		"%s authentication for user %s successful, further authentication methods required.",
+		authMethod,
+		conn.Username,
+	).Label("username", conn.Username).Label("method", strings.ToLower(authMethod))
+	logger.Info(err)
+}
+
+// partialSuccess returns the error telling the SSH library that the client has completed an authentication method,
+// but must continue with the methods listed in the reason.
+func (s *serverImpl) partialSuccess(
+	step *authStep,
+	method config.SSHAuthMethod,
+	authMethod string,
+	authenticatedMetadata metadata.ConnectionAuthenticatedMetadata,
+	reason error,
+) error {
+	var methods []config.SSHAuthMethod
+	var partialSuccess *PartialSuccess
+	if errors.As(reason, &partialSuccess) {
+		methods = partialSuccess.Methods
+	}
+	nextStep := step.next(method, authenticatedMetadata)
+	s.logAuthPartialSuccess(step.logger, *nextStep.previous, authMethod)
+	return &ssh.PartialSuccessError{
+		Next: s.createAuthCallbacks(nextStep, methods),
+	}
+}
+
+func (s *serverImpl) createPubKeyAuthenticator(
+	step *authStep,
 ) func(conn ssh.ConnMetadata, pubKey ssh.PublicKey) (
 	*ssh.Permissions,
 	metadata.ConnectionAuthenticatedMetadata,
@@ -253,22 +383,31 @@ func (s *serverImpl) createPubKeyAuthenticator(
 		metadata.ConnectionAuthenticatedMetadata,
 		error,
 	) {
-		authenticatingMetadata := connectionMetadata.StartAuthentication(string(conn.ClientVersion()), conn.User())
-		authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pubKey)))
-		authResponse, authenticatedMetadata, err := handlerNetworkConnection.OnAuthPubKey(
+		authenticatingMetadata := step.start(conn)
+		authorizedKey := auth.PublicKey{
+			PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pubKey))),
+		}
+		if cert, ok := pubKey.(*ssh.Certificate); ok {
+			authorizedKey.Certificate = auth2.DecodeCertificate(cert)
+		}
+		authResponse, authenticatedMetadata, err := step.handler.OnAuthPubKey(
 			authenticatingMetadata,
-			auth.PublicKey{PublicKey: authorizedKey},
+			authorizedKey,
 		)
 		//goland:noinspection GoNilness
 		switch authResponse {
 		case AuthResponseSuccess:
-			s.logAuthSuccessful(logger, authenticatedMetadata, "Public key")
+			authenticatedMetadata = step.merge(config.SSHAuthMethodPublicKey, authenticatedMetadata)
+			s.logAuthSuccessful(step.logger, authenticatedMetadata, "Public key")
 			return &ssh.Permissions{}, authenticatedMetadata, nil
+		case AuthResponsePartialSuccess:
+			err = s.partialSuccess(step, config.SSHAuthMethodPublicKey, "Public key", authenticatedMetadata, err)
+			return nil, authenticatedMetadata, err
 		case AuthResponseFailure:
-			err = s.wrapAndLogAuthFailure(logger, authenticatingMetadata, "Public key", err)
+			err = s.wrapAndLogAuthFailure(step.logger, authenticatingMetadata, "Public key", err)
 			return nil, authenticatedMetadata, err
 		case AuthResponseUnavailable:
-			err = s.wrapAndLogAuthUnavailable(logger, authenticatingMetadata, "Public key", err)
+			err = s.wrapAndLogAuthUnavailable(step.logger, authenticatingMetadata, "Public key", err)
 			return nil, authenticatedMetadata, err
 		}
 		// This should never happen
@@ -277,9 +416,7 @@ func (s *serverImpl) createPubKeyAuthenticator(
 }
 
 func (s *serverImpl) createKeyboardInteractiveHandler(
-	connectionMetadata metadata.ConnectionMetadata,
-	handlerNetworkConnection *networkConnectionWrapper,
-	logger log.Logger,
+	step *authStep,
 ) func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (
 	*ssh.Permissions,
 	metadata.ConnectionAuthenticatedMetadata,
@@ -289,7 +426,7 @@ func (s *serverImpl) createKeyboardInteractiveHandler(
 		conn ssh.ConnMetadata,
 		challenge ssh.KeyboardInteractiveChallenge,
 	) (*ssh.Permissions, metadata.ConnectionAuthenticatedMetadata, error) {
-		authenticatingMetadata := connectionMetadata.StartAuthentication(string(conn.ClientVersion()), conn.User())
+		authenticatingMetadata := step.start(conn)
 		challengeWrapper := func(
 			instruction string,
 			questions KeyboardInteractiveQuestions,
@@ -312,20 +449,30 @@ func (s *serverImpl) createKeyboardInteractiveHandler(
 			}
 			return answers, err
 		}
-		authResponse, authenticatedMetadata, err := handlerNetworkConnection.OnAuthKeyboardInteractive(
+		authResponse, authenticatedMetadata, err := step.handler.OnAuthKeyboardInteractive(
 			authenticatingMetadata,
 			challengeWrapper,
 		)
 		//goland:noinspection GoNilness
 		switch authResponse {
 		case AuthResponseSuccess:
-			s.logAuthSuccessful(logger, authenticatedMetadata, "Keyboard-interactive")
+			authenticatedMetadata = step.merge(config.SSHAuthMethodKeyboardInteractive, authenticatedMetadata)
+			s.logAuthSuccessful(step.logger, authenticatedMetadata, "Keyboard-interactive")
 			return &ssh.Permissions{}, authenticatedMetadata, nil
+		case AuthResponsePartialSuccess:
+			err = s.partialSuccess(
+				step,
+				config.SSHAuthMethodKeyboardInteractive,
+				"Keyboard-interactive",
+				authenticatedMetadata,
+				err,
+			)
+			return nil, authenticatedMetadata, err
 		case AuthResponseFailure:
-			err = s.wrapAndLogAuthFailure(logger, authenticatingMetadata, "Keyboard-interactive", err)
+			err = s.wrapAndLogAuthFailure(step.logger, authenticatingMetadata, "Keyboard-interactive", err)
 			return nil, authenticatedMetadata, err
 		case AuthResponseUnavailable:
-			err = s.wrapAndLogAuthUnavailable(logger, authenticatingMetadata, "Keyboard-interactive", err)
+			err = s.wrapAndLogAuthUnavailable(step.logger, authenticatingMetadata, "Keyboard-interactive", err)
 			return nil, authenticatedMetadata, err
 		}
 		return nil, authenticatedMetadata, fmt.Errorf("authentication currently unavailable")
@@ -333,14 +480,19 @@ func (s *serverImpl) createKeyboardInteractiveHandler(
 }
 
 func (s *serverImpl) createConfiguration(
+	l *listener,
 	meta metadata.ConnectionMetadata,
 	handlerNetworkConnection *networkConnectionWrapper,
 	logger log.Logger,
 ) *ssh.ServerConfig {
-	passwordCallback, pubkeyCallback, keyboardInteractiveCallback, gssConfig := s.createAuthenticators(
-		meta,
-		handlerNetworkConnection,
-		logger,
+	callbacks := s.createAuthCallbacks(
+		&authStep{
+			listener:           l,
+			connectionMetadata: meta,
+			handler:            handlerNetworkConnection,
+			logger:             logger,
+		},
+		nil,
 	)
 
 	serverConfig := &ssh.ServerConfig{
@@ -351,34 +503,51 @@ func (s *serverImpl) createConfiguration(
 		},
 		NoClientAuth:                false,
 		MaxAuthTries:                6,
-		PasswordCallback:            passwordCallback,
-		PublicKeyCallback:           pubkeyCallback,
-		KeyboardInteractiveCallback: keyboardInteractiveCallback,
-		GSSAPIWithMICConfig:         gssConfig,
+		PasswordCallback:            callbacks.PasswordCallback,
+		PublicKeyCallback:           callbacks.PublicKeyCallback,
+		KeyboardInteractiveCallback: callbacks.KeyboardInteractiveCallback,
+		GSSAPIWithMICConfig:         callbacks.GSSAPIWithMICConfig,
 		ServerVersion:               s.cfg.ServerVersion.String(),
-		BannerCallback:              func(conn ssh.ConnMetadata) string { return s.cfg.Banner },
+		BannerCallback:              func(conn ssh.ConnMetadata) string { return l.cfg.Banner },
 	}
-	for _, key := range s.hostKeys {
+	for _, key := range l.hostKeys {
 		serverConfig.AddHostKey(key)
 	}
 	return serverConfig
 }
 
-func (s *serverImpl) createAuthenticators(
-	meta metadata.ConnectionMetadata,
-	handlerNetworkConnection *networkConnectionWrapper,
-	logger log.Logger,
-) (
-	func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error),
-	func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error),
-	func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error),
-	*ssh.GSSAPIWithMICConfig,
-) {
-	passwordCallback := s.createPasswordCallback(meta, handlerNetworkConnection, logger)
-	pubkeyCallback := s.createPubKeyCallback(meta, handlerNetworkConnection, logger)
-	keyboardInteractiveCallback := s.createKeyboardInteractiveCallback(meta, handlerNetworkConnection, logger)
-	gssConfig := s.createGSSAPIConfig(meta, handlerNetworkConnection, logger)
-	return passwordCallback, pubkeyCallback, keyboardInteractiveCallback, gssConfig
+// createAuthCallbacks creates the authentication callbacks of a step for the methods allowed on the listener. If
+// methods is not empty, only the listed methods are offered. GSSAPI is only offered in the first step.
+func (s *serverImpl) createAuthCallbacks(step *authStep, methods []config.SSHAuthMethod) ssh.ServerAuthCallbacks {
+	offered := func(method config.SSHAuthMethod) bool {
+		if !step.listener.cfg.AllowsAuthMethod(method) {
+			return false
+		}
+		if len(methods) == 0 {
+			return true
+		}
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+		return false
+	}
+
+	callbacks := ssh.ServerAuthCallbacks{}
+	if offered(config.SSHAuthMethodPassword) {
+		callbacks.PasswordCallback = s.createPasswordCallback(step)
+	}
+	if offered(config.SSHAuthMethodPublicKey) {
+		callbacks.PublicKeyCallback = s.createPubKeyCallback(step)
+	}
+	if offered(config.SSHAuthMethodKeyboardInteractive) {
+		callbacks.KeyboardInteractiveCallback = s.createKeyboardInteractiveCallback(step)
+	}
+	if step.previous == nil && offered(config.SSHAuthMethodGSSAPI) {
+		callbacks.GSSAPIWithMICConfig = s.createGSSAPIConfig(step.connectionMetadata, step.handler, step.logger)
+	}
+	return callbacks
 }
 
 func (s *serverImpl) createGSSAPIConfig(
@@ -407,18 +576,11 @@ func (s *serverImpl) createGSSAPIConfig(
 				if err != nil {
 					return nil, s.wrapAndLogAuthFailure(logger, authenticating, "GSSAPI", err)
 				}
+				authenticated.AuthenticatedMethods = []string{string(config.SSHAuthMethodGSSAPI)}
 				handlerNetworkConnection.authenticatedMetadata = authenticated
 				s.logAuthSuccessful(logger, authenticated, "GSSAPI")
 
-				marshaledMetadata, err := json.Marshal(authenticated)
-				if err != nil {
-					return nil, err
-				}
-				return &ssh.Permissions{
-					Extensions: map[string]string{
-						"containerssh-metadata": string(marshaledMetadata),
-					},
-				}, err
+				return authPermissions(authenticated)
 			},
 			Server: gssServer,
 		}
@@ -427,15 +589,9 @@ func (s *serverImpl) createGSSAPIConfig(
 }
 
 func (s *serverImpl) createKeyboardInteractiveCallback(
-	connectionMetadata metadata.ConnectionMetadata,
-	handlerNetworkConnection *networkConnectionWrapper,
-	logger log.Logger,
+	step *authStep,
 ) func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
-	keyboardInteractiveHandler := s.createKeyboardInteractiveHandler(
-		connectionMetadata,
-		handlerNetworkConnection,
-		logger,
-	)
+	keyboardInteractiveHandler := s.createKeyboardInteractiveHandler(step)
 	keyboardInteractiveCallback := func(
 		conn ssh.ConnMetadata,
 		challenge ssh.KeyboardInteractiveChallenge,
@@ -444,76 +600,143 @@ func (s *serverImpl) createKeyboardInteractiveCallback(
 		if err != nil {
 			return nil, err
 		}
-		marshaledMetadata, err := json.Marshal(authenticatedMetadata)
-		if err != nil {
-			return nil, err
-		}
-		return &ssh.Permissions{
-			Extensions: map[string]string{
-				"containerssh-metadata": string(marshaledMetadata),
-			},
-		}, err
+		return authPermissions(authenticatedMetadata)
 	}
 	return keyboardInteractiveCallback
 }
 
 func (s *serverImpl) createPubKeyCallback(
-	meta metadata.ConnectionMetadata,
-	handlerNetworkConnection *networkConnectionWrapper,
-	logger log.Logger,
+	step *authStep,
 ) func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
-	pubKeyHandler := s.createPubKeyAuthenticator(meta, handlerNetworkConnection, logger)
+	pubKeyHandler := s.createPubKeyAuthenticator(step)
 	pubkeyCallback := func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
 		_, authenticatedMetadata, err := pubKeyHandler(conn, key)
 		if err != nil {
 			return nil, err
 		}
-		marshaledMetadata, err := json.Marshal(authenticatedMetadata)
-		if err != nil {
-			return nil, err
-		}
-		return &ssh.Permissions{
-			Extensions: map[string]string{
-				"containerssh-metadata": string(marshaledMetadata),
-			},
-		}, err
+		return authPermissions(authenticatedMetadata)
 	}
 	return pubkeyCallback
 }
 
 func (s *serverImpl) createPasswordCallback(
-	meta metadata.ConnectionMetadata,
-	handlerNetworkConnection *networkConnectionWrapper,
-	logger log.Logger,
+	step *authStep,
 ) func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
-	passwordHandler := s.createPasswordAuthenticator(meta, handlerNetworkConnection, logger)
+	passwordHandler := s.createPasswordAuthenticator(step)
 	passwordCallback := func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
 		_, authenticatedMetadata, err := passwordHandler(conn, password)
 		if err != nil {
 			return nil, err
 		}
-		marshaledMetadata, err := json.Marshal(authenticatedMetadata)
-		if err != nil {
-			return nil, err
-		}
-		return &ssh.Permissions{
-			Extensions: map[string]string{
-				"containerssh-metadata": string(marshaledMetadata),
-			},
-		}, err
+		return authPermissions(authenticatedMetadata)
 	}
 	return passwordCallback
 }
 
-func (s *serverImpl) handleConnection(conn net.Conn) {
-	addr := conn.RemoteAddr().(*net.TCPAddr)
+const (
+	permissionsMetadataKey     = "containerssh-metadata"
+	permissionsRestrictionsKey = "containerssh-restrictions"
+	permissionsFingerprintKey  = "containerssh-public-key-fingerprint"
+)
+
+// authPermissions stores the authenticated metadata in the SSH permissions of a successful authentication. The
+// restrictions and the public key fingerprint are not part of the JSON form of the metadata, so they are stored
+// separately.
+func authPermissions(meta metadata.ConnectionAuthenticatedMetadata) (*ssh.Permissions, error) {
+	marshaledMetadata, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	marshaledRestrictions, err := json.Marshal(meta.Restrictions)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Permissions{
+		Extensions: map[string]string{
+			permissionsMetadataKey:     string(marshaledMetadata),
+			permissionsRestrictionsKey: string(marshaledRestrictions),
+			permissionsFingerprintKey:  meta.PublicKeyFingerprint,
+		},
+	}, nil
+}
+
+// authenticatedMetadataFromPermissions restores the authenticated metadata stored by authPermissions.
+func authenticatedMetadataFromPermissions(permissions *ssh.Permissions) (
+	metadata.ConnectionAuthenticatedMetadata,
+	error,
+) {
+	var authenticatedMetadata metadata.ConnectionAuthenticatedMetadata
+	if permissions == nil {
+		return authenticatedMetadata, fmt.Errorf("no authenticated metadata in the SSH permissions")
+	}
+	marshaledMetadata, ok := permissions.Extensions[permissionsMetadataKey]
+	if !ok {
+		return authenticatedMetadata, fmt.Errorf("no authenticated metadata in the SSH permissions")
+	}
+	if err := json.Unmarshal([]byte(marshaledMetadata), &authenticatedMetadata); err != nil {
+		return authenticatedMetadata, err
+	}
+	if marshaledRestrictions, ok := permissions.Extensions[permissionsRestrictionsKey]; ok {
+		if err := json.Unmarshal([]byte(marshaledRestrictions), &authenticatedMetadata.Restrictions); err != nil {
+			return authenticatedMetadata, err
+		}
+	}
+	authenticatedMetadata.PublicKeyFingerprint = permissions.Extensions[permissionsFingerprintKey]
+	return authenticatedMetadata, nil
+}
+
+// acceptProxyProtocol checks if the connection is coming from a trusted proxy of the listener and reads the PROXY
+// protocol header.
+func (s *serverImpl) acceptProxyProtocol(l *listener, conn net.Conn) (net.Conn, error) {
+	proxyAddr := socket.RemoteTCPAddr(conn)
+	trusted := false
+	for _, network := range l.trustedProxies {
+		if network.Contains(proxyAddr.IP) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return nil, messageCodes.NewMessage(
+			messageCodes.ESSHProxyProtocolUntrusted,
+			"Rejecting connection from %s because it is not a trusted proxy",
+			proxyAddr.IP.String(),
+		).Label("remoteAddr", proxyAddr.IP.String())
+	}
+	proxiedConn, err := readProxyProtocolHeader(conn, l.cfg.ProxyProtocol.HeaderTimeout)
+	if err != nil {
+		return nil, messageCodes.Wrap(
+			err,
+			messageCodes.ESSHProxyProtocolInvalidHeader,
+			"Rejecting connection from proxy %s because of an invalid PROXY protocol header",
+			proxyAddr.IP.String(),
+		).Label("remoteAddr", proxyAddr.IP.String())
+	}
+	return proxiedConn, nil
+}
+
+func (s *serverImpl) handleConnection(l *listener, conn net.Conn) {
+	if l.cfg.ProxyProtocol.Enabled() {
+		proxiedConn, err := s.acceptProxyProtocol(l, conn)
+		if err != nil {
+			s.logger.Info(err)
+			_ = conn.Close()
+			s.wg.Done()
+			return
+		}
+		conn = proxiedConn
+	}
+	addr := socket.RemoteTCPAddr(conn)
 	connectionID := GenerateConnectionID()
 	logger := s.logger.
 		WithLabel("remoteAddr", addr.IP.String()).
-		WithLabel("connectionId", connectionID)
+		WithLabel("connectionId", connectionID).
+		WithLabel("listener", l.cfg.Name)
 	connectionMeta := metadata.ConnectionMetadata{
 		RemoteAddress: metadata.RemoteAddress(*addr),
 		ConnectionID:  connectionID,
+		Listener:      l.cfg.Name,
+		ListenAddress: l.cfg.Listen,
 		Metadata:      map[string]metadata.Value{},
 		Environment:   map[string]metadata.Value{},
 		Files:         map[string]metadata.BinaryValue{},
@@ -542,7 +765,7 @@ func (s *serverImpl) handleConnection(conn net.Conn) {
 
 	sshConn, channels, globalRequests, err := ssh.NewServerConn(
 		conn,
-		s.createConfiguration(connectionMeta, &wrapper, logger),
+		s.createConfiguration(l, connectionMeta, &wrapper, logger),
 	)
 	abortCleanup := func() {
 		logger.Info(messageCodes.Wrap(err, messageCodes.ESSHHandshakeFailed, "SSH handshake failed"))
@@ -557,13 +780,7 @@ func (s *serverImpl) handleConnection(conn net.Conn) {
 		abortCleanup()
 		return
 	}
-	var authenticatedMetadata metadata.ConnectionAuthenticatedMetadata
-	marshaledMetadata, ok := sshConn.Permissions.Extensions["containerssh-metadata"]
-	if !ok {
-		abortCleanup()
-		return
-	}
-	err = json.Unmarshal([]byte(marshaledMetadata), &authenticatedMetadata)
+	authenticatedMetadata, err := authenticatedMetadataFromPermissions(sshConn.Permissions)
 	if err != nil {
 		abortCleanup()
 		return
@@ -628,6 +845,10 @@ func (s *serverImpl) handleConnection(conn net.Conn) {
 	go func() {
 		_ = sshConn.Wait()
 		logger.Debug(messageCodes.NewMessage(messageCodes.MSSHDisconnected, "Client disconnected"))
+		s.lock.Lock()
+		delete(s.clientSockets, sshConn)
+		delete(s.connMap, connectionID)
+		s.lock.Unlock()
 		s.shutdownHandlers.Unregister(shutdownHandlerID)
 		s.shutdownHandlers.Unregister(sshShutdownHandlerID)
 		handlerNetworkConnection.OnDisconnect()
@@ -827,6 +1048,9 @@ func (s *serverImpl) handleSessionChannel(
 	logger log.Logger,
 ) {
 	channelCallbacks := &channelWrapper{
+		disconnect: func() error {
+			return s.Disconnect(channelMetadata.Connection.ConnectionID)
+		},
 		logger: logger,
 		lock:   &sync.Mutex{},
 	}
@@ -1047,6 +1271,10 @@ func (s *serverImpl) unmarshalX11(request *ssh.Request) (payload ssh2.X11Request
 	return payload, ssh.Unmarshal(request.Payload, &payload)
 }
 
+func (s *serverImpl) unmarshalAuthAgent(request *ssh.Request) (payload ssh2.AuthAgentRequestPayload, err error) {
+	return payload, ssh.Unmarshal(request.Payload, &payload)
+}
+
 func (s *serverImpl) unmarshalChannelRequestPayload(request *ssh.Request) (payload interface{}, err error) {
 	switch ssh2.RequestType(request.Type) {
 	case ssh2.RequestTypeEnv:
@@ -1065,6 +1293,8 @@ func (s *serverImpl) unmarshalChannelRequestPayload(request *ssh.Request) (paylo
 		return s.unmarshalSignal(request)
 	case ssh2.RequestTypeX11:
 		return s.unmarshalX11(request)
+	case ssh2.RequestTypeAuthAgent:
+		return s.unmarshalAuthAgent(request)
 	default:
 		return nil, nil
 	}
@@ -1195,6 +1425,8 @@ func (s *serverImpl) handleDecodedChannelRequest(
 		return s.onSignal(requestID, sessionChannel, payload)
 	case ssh2.RequestTypeX11:
 		return s.onX11(channelMetadata.Connection.ConnectionID, requestID, sessionChannel, payload)
+	case ssh2.RequestTypeAuthAgent:
+		return s.onAuthAgent(channelMetadata.Connection.ConnectionID, requestID, sessionChannel)
 	}
 	return nil
 }
@@ -1325,6 +1557,26 @@ func (s *serverImpl) onX11(connectionID string, requestID uint64, sessionChannel
 	return err
 }
 
+func (s *serverImpl) onAuthAgent(connectionID string, requestID uint64, sessionChannel SessionChannelHandler) error {
+	s.lock.Lock()
+	conn, ok := s.connMap[connectionID]
+	s.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("Couldn't find connection in map, something terrible happened")
+	}
+
+	reverseForwardHandler := ReverseForwardHandler{
+		sshConn: conn.sshConn,
+		server:  s,
+		logger:  s.logger,
+	}
+	err := sessionChannel.OnAuthAgentRequest(requestID, &reverseForwardHandler)
+	if err != nil {
+		s.logger.Debug(messageCodes.Wrap(err, messageCodes.ESSHAgentForwardingFailed, "Failed to start agent forwarding"))
+	}
+	return err
+}
+
 func (s *serverImpl) onSubsystem(
 	requestID uint64,
 	sessionChannel SessionChannelHandler,
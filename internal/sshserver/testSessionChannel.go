@@ -250,6 +250,13 @@ func (s *testSessionChannel) OnX11Request(
 	return fmt.Errorf("Unimplemented")
 }
 
+func (s *testSessionChannel) OnAuthAgentRequest(
+	requestID uint64,
+	reverseHandler ReverseForward,
+) error {
+	return fmt.Errorf("Unimplemented")
+}
+
 func (t *testSessionChannel) OnShutdown(_ context.Context) {
 	if t.running {
 		_ = t.session.Close()
@@ -0,0 +1,394 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.containerssh.io/libcontainerssh/internal/ldap"
+)
+
+// LDAPEntry is an entry in the directory of the LDAP test server.
+type LDAPEntry struct {
+	// DN is the distinguished name of the entry.
+	DN string
+	// Password is the password used to bind as this entry. If empty, binding as this entry is not possible.
+	Password string
+	// Attributes contains the attributes of the entry.
+	Attributes map[string][]string
+}
+
+// LDAPServerInstance is an in-process LDAP server for testing. It supports simple binds, subtree searches, LDAPS and
+// StartTLS.
+type LDAPServerInstance interface {
+	// URL returns the ldap:// URL of the server. The server supports the StartTLS operation on this URL.
+	URL() string
+	// TLSURL returns the ldaps:// URL of the server.
+	TLSURL() string
+	// CACert returns the PEM-encoded CA certificate of the server.
+	CACert() string
+	// Binds returns the DNs of the successful binds in order.
+	Binds() []string
+	// TLSBinds returns true if all successful binds happened over TLS.
+	TLSBinds() bool
+}
+
+// LDAPServer starts an in-process LDAP server with the given entries. The server is stopped when the test ends.
+func LDAPServer(t *testing.T, entries []LDAPEntry) LDAPServerInstance {
+	t.Helper()
+
+	cert, caPEM := ldapCertificate(t)
+	srv := &ldapServer{
+		entries:  entries,
+		tlsBinds: true,
+		caCert:   caPEM,
+		conns:    map[net.Conn]struct{}{},
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+
+	plainListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start LDAP test server (%v)", err)
+	}
+	tlsListener, err := tls.Listen("tcp", "127.0.0.1:0", srv.tlsConfig)
+	if err != nil {
+		_ = plainListener.Close()
+		t.Fatalf("failed to start LDAP test server (%v)", err)
+	}
+	srv.url = fmt.Sprintf("ldap://%s", plainListener.Addr().String())
+	srv.tlsURL = fmt.Sprintf("ldaps://%s", tlsListener.Addr().String())
+	go srv.serve(plainListener, false)
+	go srv.serve(tlsListener, true)
+	t.Cleanup(func() {
+		_ = plainListener.Close()
+		_ = tlsListener.Close()
+		srv.lock.Lock()
+		for conn := range srv.conns {
+			_ = conn.Close()
+		}
+		srv.lock.Unlock()
+		srv.wg.Wait()
+	})
+	return srv
+}
+
+type ldapServer struct {
+	entries   []LDAPEntry
+	url       string
+	tlsURL    string
+	caCert    string
+	tlsConfig *tls.Config
+	wg        sync.WaitGroup
+
+	lock     sync.Mutex
+	conns    map[net.Conn]struct{}
+	binds    []string
+	tlsBinds bool
+}
+
+func (s *ldapServer) URL() string {
+	return s.url
+}
+
+func (s *ldapServer) TLSURL() string {
+	return s.tlsURL
+}
+
+func (s *ldapServer) CACert() string {
+	return s.caCert
+}
+
+func (s *ldapServer) Binds() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *ldapServer) TLSBinds() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.tlsBinds
+}
+
+func (s *ldapServer) serve(listener net.Listener, isTLS bool) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn, isTLS)
+			s.lock.Lock()
+			delete(s.conns, conn)
+			s.lock.Unlock()
+		}()
+	}
+}
+
+func (s *ldapServer) handle(conn net.Conn, isTLS bool) {
+	defer func() { _ = conn.Close() }()
+	reader := ldap.NewPacketReader(conn)
+	for {
+		message, err := reader.Read()
+		if err != nil {
+			return
+		}
+		if len(message.Children) < 2 {
+			return
+		}
+		id, _ := message.Children[0].Int()
+		op := message.Children[1]
+		switch op.Tag {
+		case ldap.TagBindRequest:
+			s.reply(conn, id, s.bind(op, isTLS))
+		case ldap.TagSearchRequest:
+			for _, response := range s.search(op) {
+				s.reply(conn, id, response)
+			}
+		case ldap.TagExtendedRequest:
+			if isTLS {
+				s.reply(conn, id, ldapResult(ldap.TagExtendedResponse, 1, "TLS already established"))
+				continue
+			}
+			s.reply(conn, id, ldapResult(ldap.TagExtendedResponse, ldap.ResultSuccess, ""))
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = ldap.NewPacketReader(conn)
+			isTLS = true
+		case ldap.TagUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *ldapServer) reply(conn net.Conn, id int64, op *ldap.Packet) {
+	message := ldap.NewConstructed(ldap.TagSequence, ldap.NewInteger(ldap.TagInteger, id), op)
+	_, _ = conn.Write(message.Bytes())
+}
+
+func (s *ldapServer) bind(op *ldap.Packet, isTLS bool) *ldap.Packet {
+	if len(op.Children) < 3 {
+		return ldapResult(ldap.TagBindResponse, 2, "invalid bind request")
+	}
+	dn := op.Children[1].String()
+	password := op.Children[2].String()
+	if dn == "" {
+		return ldapResult(ldap.TagBindResponse, ldap.ResultSuccess, "")
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			s.lock.Lock()
+			s.binds = append(s.binds, entry.DN)
+			s.tlsBinds = s.tlsBinds && isTLS
+			s.lock.Unlock()
+			return ldapResult(ldap.TagBindResponse, ldap.ResultSuccess, "")
+		}
+	}
+	return ldapResult(ldap.TagBindResponse, ldap.ResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *ldapServer) search(op *ldap.Packet) []*ldap.Packet {
+	if len(op.Children) < 8 {
+		return []*ldap.Packet{ldapResult(ldap.TagSearchResultDone, 2, "invalid search request")}
+	}
+	baseDN := strings.ToLower(op.Children[0].String())
+	sizeLimit, _ := op.Children[3].Int()
+	filter := op.Children[6]
+	var requested []string
+	for _, attribute := range op.Children[7].Children {
+		requested = append(requested, attribute.String())
+	}
+
+	var result []*ldap.Packet
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), baseDN) || !ldapMatch(filter, entry) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(result)) >= sizeLimit {
+			return append(result, ldapResult(ldap.TagSearchResultDone, ldap.ResultSizeLimitExceeded, ""))
+		}
+		attributes := ldap.NewConstructed(ldap.TagSequence)
+		for name, values := range entry.Attributes {
+			if len(requested) > 0 && !containsFold(requested, name) {
+				continue
+			}
+			set := ldap.NewConstructed(ldap.TagSet)
+			for _, value := range values {
+				set.Children = append(set.Children, ldap.NewString(ldap.TagOctetString, value))
+			}
+			attributes.Children = append(
+				attributes.Children,
+				ldap.NewConstructed(ldap.TagSequence, ldap.NewString(ldap.TagOctetString, name), set),
+			)
+		}
+		result = append(
+			result,
+			ldap.NewConstructed(ldap.TagSearchResultEntry, ldap.NewString(ldap.TagOctetString, entry.DN), attributes),
+		)
+	}
+	return append(result, ldapResult(ldap.TagSearchResultDone, ldap.ResultSuccess, ""))
+}
+
+// ldapMatch evaluates a BER-encoded search filter against an entry. Ordering and approximate matches are compared as
+// case-insensitive strings.
+func ldapMatch(filter *ldap.Packet, entry LDAPEntry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !ldapMatch(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if ldapMatch(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !ldapMatch(filter.Children[0], entry)
+	case ldap.FilterPresent:
+		return len(ldapValues(entry, filter.String())) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		expected := strings.ToLower(filter.Children[1].String())
+		for _, value := range ldapValues(entry, filter.Children[0].String()) {
+			value = strings.ToLower(value)
+			switch filter.Tag {
+			case ldap.FilterGreaterOrEqual:
+				if value >= expected {
+					return true
+				}
+			case ldap.FilterLessOrEqual:
+				if value <= expected {
+					return true
+				}
+			default:
+				if value == expected {
+					return true
+				}
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, value := range ldapValues(entry, filter.Children[0].String()) {
+			if ldapMatchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func ldapMatchSubstrings(value string, parts []*ldap.Packet) bool {
+	for _, part := range parts {
+		substring := strings.ToLower(part.String())
+		switch part.Tag {
+		case ldap.FilterSubstringsInit:
+			if !strings.HasPrefix(value, substring) {
+				return false
+			}
+			value = value[len(substring):]
+		case ldap.FilterSubstringsAny:
+			index := strings.Index(value, substring)
+			if index < 0 {
+				return false
+			}
+			value = value[index+len(substring):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, substring) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func ldapValues(entry LDAPEntry, attribute string) []string {
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func ldapResult(tag byte, code int64, diagnosticMessage string) *ldap.Packet {
+	return ldap.NewConstructed(
+		tag,
+		ldap.NewInteger(ldap.TagEnumerated, code),
+		ldap.NewString(ldap.TagOctetString, ""),
+		ldap.NewString(ldap.TagOctetString, diagnosticMessage),
+	)
+}
+
+// ldapCertificate creates a self-signed certificate for 127.0.0.1 and returns it with its PEM encoding.
+func ldapCertificate(t *testing.T) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate LDAP test server key (%v)", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ContainerSSH LDAP test server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create LDAP test server certificate (%v)", err)
+	}
+	certPEM := &bytes.Buffer{}
+	if err := pem.Encode(certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("failed to encode LDAP test server certificate (%v)", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, certPEM.String()
+}
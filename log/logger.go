@@ -5,6 +5,14 @@ import (
     "go.containerssh.io/libcontainerssh/message"
 )
 
+// LevelSetter is implemented by loggers whose log level can be changed after they have been created, such as the
+// loggers created by the logger factory.
+type LevelSetter interface {
+	// SetLevel changes the log level of this logger and of all loggers derived from it with WithLabel. Loggers derived
+	// with WithLevel keep their own level.
+	SetLevel(level config.LogLevel)
+}
+
 // Logger The logger interface provides logging facilities on various levels.
 type Logger interface {
 	// WithLevel returns a copy of the logger for a specified log level. Panics if the log level provided is invalid.
@@ -81,7 +81,7 @@ func (f *loggerFactory) Make(cfg config.LogConfig) (Logger, error) {
 	}
 
 	return &logger{
-		level:  cfg.Level,
+		level:  newLevel(cfg.Level),
 		labels: map[message.LabelName]message.LabelValue{},
 		writer: writer,
 		helper: helper,
@@ -1,12 +1,16 @@
 package log
 
 import (
+	"sync/atomic"
+
     "go.containerssh.io/libcontainerssh/config"
     messageCodes "go.containerssh.io/libcontainerssh/message"
 )
 
 type logger struct {
-	level  config.LogLevel
+	// level is shared between the logger and the loggers derived from it with WithLabel so SetLevel affects all of
+	// them.
+	level  *atomic.Int32
 	labels messageCodes.Labels
 	writer Writer
 	helper func()
@@ -22,13 +26,23 @@ func (pipeline *logger) Rotate() error {
 
 func (pipeline *logger) WithLevel(level config.LogLevel) Logger {
 	return &logger{
-		level:  level,
+		level:  newLevel(level),
 		labels: pipeline.labels,
 		writer: pipeline.writer,
 		helper: pipeline.helper,
 	}
 }
 
+func (pipeline *logger) SetLevel(level config.LogLevel) {
+	pipeline.level.Store(int32(level))
+}
+
+func newLevel(level config.LogLevel) *atomic.Int32 {
+	result := &atomic.Int32{}
+	result.Store(int32(level))
+	return result
+}
+
 func (pipeline *logger) WithLabel(labelName messageCodes.LabelName, labelValue messageCodes.LabelValue) Logger {
 	newLabels := make(messageCodes.Labels, len(pipeline.labels))
 	for k, v := range pipeline.labels {
@@ -47,7 +61,7 @@ func (pipeline *logger) WithLabel(labelName messageCodes.LabelName, labelValue m
 
 func (pipeline *logger) write(level config.LogLevel, message ...interface{}) {
 	pipeline.helper()
-	if pipeline.level >= level {
+	if config.LogLevel(pipeline.level.Load()) >= level {
 		if len(message) == 0 {
 			return
 		}
@@ -82,7 +96,7 @@ func (pipeline *logger) write(level config.LogLevel, message ...interface{}) {
 
 func (pipeline *logger) writef(level config.LogLevel, format string, args ...interface{}) {
 	pipeline.helper()
-	if pipeline.level >= level {
+	if config.LogLevel(pipeline.level.Load()) >= level {
 		var msg messageCodes.Message
 
 		msg = messageCodes.NewMessage(messageCodes.EUnknownError, format, args...)
@@ -30,6 +30,30 @@ func TestLogLevelFiltering(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	p := log.MustNewLogger(config.LogConfig{
+		Level:       config.LogLevelInfo,
+		Format:      config.LogFormatLJSON,
+		Destination: config.LogDestinationStdout,
+		Stdout:      &buf,
+	})
+	labeled := p.WithLabel("module", "test")
+	pinned := p.WithLevel(config.LogLevelInfo)
+	msg := message.UserMessage("E_TEST", "test", "test")
+
+	labeled.Debug(msg)
+	assert.Equal(t, 0, buf.Len())
+
+	p.(log.LevelSetter).SetLevel(config.LogLevelDebug)
+	labeled.Debug(msg)
+	assert.NotEqual(t, 0, buf.Len())
+
+	buf.Reset()
+	pinned.Debug(msg)
+	assert.Equal(t, 0, buf.Len())
+}
+
 func testLevel(t *testing.T, logLevel config.LogLevel, writeLogLevel config.LogLevel) {
 	var buf bytes.Buffer
 	p := log.MustNewLogger(config.LogConfig{
@@ -135,7 +135,7 @@ func runContainerSSH(
 		}
 	}
 
-	if err := startServices(cfg, loggerFactory); err != nil {
+	if err := startServices(cfg, loggerFactory, logger, configFile); err != nil {
 		logger.Critical(err)
 		os.Exit(1)
 	}
@@ -175,16 +175,41 @@ func getArguments() (string, bool, bool, bool) {
 	return configFile, actionDumpConfig, actionLicenses, healthCheck
 }
 
-func startServices(cfg config.AppConfig, loggerFactory log.LoggerFactory) error {
+func startServices(
+	cfg config.AppConfig,
+	loggerFactory log.LoggerFactory,
+	logger log.Logger,
+	configFile string,
+) error {
 	pool, lifecycle, err := New(cfg, loggerFactory)
 	if err != nil {
 		return err
 	}
 
-	return startPool(pool, lifecycle)
+	return startPool(pool, lifecycle, func() {
+		reloadConfigFile(pool, configFile, loggerFactory, logger)
+	})
 }
 
-func startPool(pool Service, lifecycle service.Lifecycle) error {
+// reloadConfigFile reads the configuration file again and applies it to the running pool. Errors are logged and the
+// current configuration stays in place.
+func reloadConfigFile(pool Service, configFile string, loggerFactory log.LoggerFactory, logger log.Logger) {
+	cfg := config.AppConfig{}
+	cfg.Default()
+	if err := readConfigFile(configFile, loggerFactory, &cfg); err != nil {
+		logger.Error(
+			message.Wrap(
+				err,
+				message.ECoreConfigReloadFailed,
+				"Failed to read configuration file %s, keeping the current configuration",
+				configFile,
+			))
+		return
+	}
+	_ = pool.Reload(cfg)
+}
+
+func startPool(pool Service, lifecycle service.Lifecycle, reloadConfig func()) error {
 	starting := make(chan struct{})
 	lifecycle.OnStarting(
 		func(s service.Service, l service.Lifecycle) {
@@ -223,20 +248,36 @@ func startPool(pool Service, lifecycle service.Lifecycle) error {
 				if err != nil {
 					panic(err)
 				}
+				reloadConfig()
 			} else {
 				break
 			}
 		}
 	}()
+	restartSignals := make(chan os.Signal, 1)
+	if len(restartSignalList) > 0 {
+		signal.Notify(restartSignals, restartSignalList...)
+	}
+	go func() {
+		for range restartSignals {
+			// Errors are logged by the pool, the current process keeps running if the restart fails.
+			_ = pool.Restart(lifecycle)
+		}
+	}()
 	err := lifecycle.Wait()
 	signal.Ignore(rotateSignalList...)
 	signal.Ignore(exitSignalList...)
+	if len(restartSignalList) > 0 {
+		signal.Ignore(restartSignalList...)
+	}
 	close(exitSignals)
+	close(restartSignals)
 	return err
 }
 
 func generateHostKeys(configFile string, cfg *config.AppConfig, logger log.Logger) error {
-	if err := cfg.SSH.GenerateHostKey(); err != nil {
+	// ed25519 keys are fast to generate and supported by all current SSH clients.
+	if err := cfg.SSH.GenerateHostKey(config.SSHHostKeyTypeED25519); err != nil {
 		return err
 	}
 
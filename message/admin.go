@@ -0,0 +1,21 @@
+package message
+
+// MAdminServiceAvailable indicates that the admin API is now online and ready for service.
+const MAdminServiceAvailable = "ADMIN_AVAILABLE"
+
+// EAdminConnectionNotFound indicates that the admin API was asked about a connection that is not open. The connection
+// may have already ended.
+const EAdminConnectionNotFound = "ADMIN_CONNECTION_NOT_FOUND"
+
+// EAdminChannelNotFound indicates that the admin API was asked about a session channel that is not open. The channel
+// may have already been closed.
+const EAdminChannelNotFound = "ADMIN_CHANNEL_NOT_FOUND"
+
+// MAdminDisconnect indicates that a connection is being closed on request from the admin API.
+const MAdminDisconnect = "ADMIN_DISCONNECT"
+
+// MAdminCloseChannel indicates that a session channel is being closed on request from the admin API.
+const MAdminCloseChannel = "ADMIN_CLOSE_CHANNEL"
+
+// MAdminSignal indicates that a signal is being sent to a session on request from the admin API.
+const MAdminSignal = "ADMIN_SIGNAL"
@@ -6,6 +6,10 @@ const EAuthInvalidStatus = "AUTH_INVALID_STATUS"
 // MAuth indicates that ContainerSSH is trying to contact the authentication backend to verify the user credentials.
 const MAuth = "AUTH"
 
+// MAuthCached indicates that the response of the authentication server has been served from the cache instead of
+// sending a request.
+const MAuthCached = "AUTH_CACHED"
+
 // EAuthConfigError indicates that the authentication configuration is invalid.
 const EAuthConfigError = "AUTH_CONFIG_ERROR"
 
@@ -161,3 +165,87 @@ const EAuthKerberosBackendError = "KRB_BACKEND_ERROR"
 
 // EAuthzFailed indicates that the authorization server rejected the user
 const EAuthzFailed = "AUTHZ_FAILED"
+
+// EAuthAuthorizedKeysReadFailed indicates that ContainerSSH failed to read or parse an authorized_keys file for a user.
+// Check the permissions and the contents of the file.
+const EAuthAuthorizedKeysReadFailed = "AUTH_AUTHORIZED_KEYS_READ_FAILED"
+
+// MAuthAuthorizedKeysReloaded indicates that ContainerSSH detected a change in an authorized_keys file and reloaded it.
+const MAuthAuthorizedKeysReloaded = "AUTH_AUTHORIZED_KEYS_RELOADED"
+
+// EAuthAuthorizedKeysInvalidUsername indicates that the user supplied a username that cannot be used to look up an
+// authorized_keys file, for example because it contains a path separator.
+const EAuthAuthorizedKeysInvalidUsername = "AUTH_AUTHORIZED_KEYS_INVALID_USERNAME"
+
+// EAuthAuthorizedKeysSourceMismatch indicates that the public key matched an authorized_keys entry, but the from=
+// option of the entry does not permit the connecting IP address.
+const EAuthAuthorizedKeysSourceMismatch = "AUTH_AUTHORIZED_KEYS_SOURCE_MISMATCH"
+
+// EAuthCertificateRejected indicates that the user presented an OpenSSH certificate signed by a trusted CA, but the
+// certificate is not valid for this connection. This can be because it has expired, is not valid for the username,
+// has an unsupported critical option, or the source-address option does not match.
+const EAuthCertificateRejected = "AUTH_CERTIFICATE_REJECTED"
+
+// EAuthKeyRevoked indicates that the user presented a key or certificate that is listed in the revoked keys file.
+const EAuthKeyRevoked = "AUTH_KEY_REVOKED"
+
+// EAuthRevokedKeysReadFailed indicates that ContainerSSH failed to read or parse the revoked keys file. All public key
+// authentication attempts are rejected until this is fixed.
+const EAuthRevokedKeysReadFailed = "AUTH_REVOKED_KEYS_READ_FAILED"
+
+// EAuthLDAPConnectionFailed indicates that ContainerSSH failed to connect to the LDAP server or to bind with the
+// configured service account. Check the URL, the TLS settings and the bind credentials.
+const EAuthLDAPConnectionFailed = "AUTH_LDAP_CONNECTION_FAILED"
+
+// EAuthLDAPSearchFailed indicates that the LDAP server returned an error when searching for the user. Check the base
+// DN and the user filter.
+const EAuthLDAPSearchFailed = "AUTH_LDAP_SEARCH_FAILED"
+
+// EAuthLDAPUserNotFound indicates that the user search did not return any entry for the username.
+const EAuthLDAPUserNotFound = "AUTH_LDAP_USER_NOT_FOUND"
+
+// EAuthLDAPAmbiguousUser indicates that the user search returned more than one entry for the username, so the user
+// has been rejected. Make the user filter more specific.
+const EAuthLDAPAmbiguousUser = "AUTH_LDAP_AMBIGUOUS_USER"
+
+// EAuthLDAPBindFailed indicates that the LDAP server returned an error other than invalid credentials when verifying
+// the password of the user.
+const EAuthLDAPBindFailed = "AUTH_LDAP_BIND_FAILED"
+
+// EAuthLDAPInvalidPublicKey indicates that the public key attribute of the user contains a value that is not a valid
+// OpenSSH public key. The value is ignored.
+const EAuthLDAPInvalidPublicKey = "AUTH_LDAP_INVALID_PUBLIC_KEY"
+
+// EAuthTOTPSecretsReadFailed indicates that ContainerSSH failed to read the TOTP secrets file. Users without a secret
+// in the connection metadata cannot authenticate until this is fixed.
+const EAuthTOTPSecretsReadFailed = "AUTH_TOTP_SECRETS_READ_FAILED"
+
+// EAuthTOTPNoSecret indicates that no TOTP secret was found for the user in the connection metadata or in the secrets
+// file, so the user cannot authenticate with a one-time password.
+const EAuthTOTPNoSecret = "AUTH_TOTP_NO_SECRET"
+
+// EAuthTOTPInvalidSecret indicates that the TOTP secret of the user is not a valid base32-encoded value.
+const EAuthTOTPInvalidSecret = "AUTH_TOTP_INVALID_SECRET"
+
+// EAuthTOTPInvalidCode indicates that the user entered a one-time password that is not valid in the current time
+// window.
+const EAuthTOTPInvalidCode = "AUTH_TOTP_INVALID_CODE"
+
+// EAuthTOTPReplay indicates that the user entered a one-time password that has already been used. Each one-time
+// password can only be used once, even if it is still within the accepted time window.
+const EAuthTOTPReplay = "AUTH_TOTP_REPLAY"
+
+// EAuthKeyboardInteractiveTooManyRounds indicates that the auth server sent more rounds of keyboard-interactive
+// questions than the configured maximum, so the authentication has been rejected.
+const EAuthKeyboardInteractiveTooManyRounds = "AUTH_KEYBOARD_INTERACTIVE_TOO_MANY_ROUNDS"
+
+// MAuthzRuleAllowed indicates that an authorization rule, or the default action if no rule matched, allowed the user to
+// log in.
+const MAuthzRuleAllowed = "AUTHZ_RULE_ALLOWED"
+
+// EAuthzRuleDenied indicates that an authorization rule, or the default action if no rule matched, rejected the user.
+const EAuthzRuleDenied = "AUTHZ_RULE_DENIED"
+
+// EAuthMethodNotAllowed indicates that the user attempted an authentication method that the configured authentication
+// method sequences do not allow at this point, for example a password when a public key is required first.
+const EAuthMethodNotAllowed = "AUTH_METHOD_NOT_ALLOWED"
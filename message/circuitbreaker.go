@@ -0,0 +1,13 @@
+package message
+
+// ECircuitBreakerOpen indicates that a request to a webhook server has not been sent because the circuit breaker is
+// open after too many consecutive failures. The request fails immediately until the open duration has passed.
+const ECircuitBreakerOpen = "CIRCUIT_BREAKER_OPEN"
+
+// ECircuitBreakerOpened indicates that the circuit breaker of a webhook server has opened after too many consecutive
+// failed requests.
+const ECircuitBreakerOpened = "CIRCUIT_BREAKER_OPENED"
+
+// MCircuitBreakerClosed indicates that the circuit breaker of a webhook server has closed again after a successful
+// request.
+const MCircuitBreakerClosed = "CIRCUIT_BREAKER_CLOSED"
@@ -14,6 +14,15 @@ const EConfigBackendError = "CONFIG_BACKEND_ERROR"
 // server.
 const MConfigSuccess = "CONFIG_RESPONSE"
 
+// MConfigCached indicates that the per-user backend configuration has been served from the cache instead of sending a
+// request to the configuration server.
+const MConfigCached = "CONFIG_CACHED"
+
+// WConfigStale indicates that the configuration server is unavailable and ContainerSSH is using an expired per-user
+// backend configuration from the cache, as allowed by the staleIfError option. Check the connectivity to the
+// configuration server.
+const WConfigStale = "CONFIG_STALE"
+
 // EConfigInvalidStatus indicates that ContainerSSH has received a non-200 response code when calling a per-user backend
 // configuration from the configuration server.
 const EConfigInvalidStatus = "CONFIG_INVALID_STATUS_CODE"
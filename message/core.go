@@ -20,3 +20,21 @@ const ECoreHealthCheckFailed = "CORE_HEALTH_CHECK_FAILED"
 
 // MCoreHealthCheckSuccessful indicates that The health check was successful.
 const MCoreHealthCheckSuccessful = "CORE_HEALTH_CHECK_SUCCESSFUL"
+
+// MCoreRestarting indicates that ContainerSSH received a graceful restart request and is starting a new process that takes over the listening sockets.
+const MCoreRestarting = "CORE_RESTARTING"
+
+// ECoreRestartFailed indicates that the graceful restart failed, for example because the new process did not start up in time. The current process continues to run.
+const ECoreRestartFailed = "CORE_RESTART_FAILED"
+
+// MCoreDraining indicates that the new process has taken over after a graceful restart and the current process is waiting for the existing connections to end before exiting.
+const MCoreDraining = "CORE_DRAINING"
+
+// MCoreConfigReloaded indicates that ContainerSSH has reloaded its configuration. The new configuration applies to new connections, existing connections keep their original configuration.
+const MCoreConfigReloaded = "CORE_CONFIG_RELOADED"
+
+// ECoreConfigReloadFailed indicates that ContainerSSH could not reload its configuration, for example because the new configuration is invalid. ContainerSSH keeps running with the current configuration.
+const ECoreConfigReloadFailed = "CORE_CONFIG_RELOAD_FAILED"
+
+// ECoreConfigReloadRestartRequired indicates that the reloaded configuration contains changes that can only be applied by restarting ContainerSSH. These changes are ignored until the next restart.
+const ECoreConfigReloadRestartRequired = "CORE_CONFIG_RELOAD_RESTART_REQUIRED"
@@ -32,3 +32,20 @@ const MHTTPServerResponseWriteFailed = "HTTP_SERVER_RESPONSE_WRITE_FAILED"
 // MHTTPServerEncodeFailed indicates that the HTTP server failed to encode the response object. This can happen
 // if the incorrect response object was returned in a webhook.
 const MHTTPServerEncodeFailed = "HTTP_SERVER_ENCODE_FAILED"
+
+// EHTTPServerSignatureMissing indicates that the webhook server received a request without a signature while
+// signatures are required. Check if the client is configured with the same shared secret.
+const EHTTPServerSignatureMissing = "HTTP_SERVER_SIGNATURE_MISSING"
+
+// EHTTPServerSignatureInvalid indicates that the signature of a request received by the webhook server does not match
+// the request. This happens if the client and the server are configured with different shared secrets, or if the
+// request has been tampered with.
+const EHTTPServerSignatureInvalid = "HTTP_SERVER_SIGNATURE_INVALID"
+
+// EHTTPServerSignatureExpired indicates that the timestamp of a signed request is too far from the clock of the
+// webhook server. Check if the clocks of the client and the server are synchronized.
+const EHTTPServerSignatureExpired = "HTTP_SERVER_SIGNATURE_EXPIRED"
+
+// EHTTPServerSignatureReplay indicates that the webhook server received a signed request with a nonce it has already
+// seen. This means that a previous request has been sent again, possibly by an attacker.
+const EHTTPServerSignatureReplay = "HTTP_SERVER_SIGNATURE_REPLAY"
@@ -0,0 +1,18 @@
+package message
+
+// ERateLimitConnectionRejected indicates that a connection was rejected because the source IP address is banned or
+// the connection rate limit has been exceeded.
+const ERateLimitConnectionRejected = "RATELIMIT_CONNECTION_REJECTED"
+
+// ERateLimitConnectionBanned indicates that a connection was rejected because the source IP address is temporarily
+// banned due to too many failed authentication attempts.
+const ERateLimitConnectionBanned = "RATELIMIT_CONNECTION_BANNED"
+
+// ERateLimitAuthRejected indicates that an authentication attempt was rejected without consulting the authentication
+// backend because the source IP address is banned or the authentication rate limit for the username has been
+// exceeded.
+const ERateLimitAuthRejected = "RATELIMIT_AUTH_REJECTED"
+
+// ERateLimitBanned indicates that a source IP address has been temporarily banned due to too many failed
+// authentication attempts.
+const ERateLimitBanned = "RATELIMIT_BANNED"
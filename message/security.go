@@ -33,10 +33,61 @@ const ESecuritySignalRejected = "SECURITY_SIGNAL_REJECTED"
 
 const ESecurityX11ForwardingRejected = "SECURITY_X11_FORWARDING_REJECTED"
 
+// ESecurityAgentForwardingRejected indicates that the client requested the forwarding of its SSH agent, but the
+// request was rejected by the security settings.
+const ESecurityAgentForwardingRejected = "SECURITY_AGENT_FORWARDING_REJECTED"
+
 // ESecurityMaxSessions indicates that the client has reached the maximum number of configured sessions, the new session
 // request is therefore rejected.
 const ESecurityMaxSessions = "SECURITY_MAX_SESSIONS"
 
+// ESecurityMaxForwardChannels indicates that the client has reached the maximum number of port and socket forwarding
+// channels, the new channel is therefore rejected.
+const ESecurityMaxForwardChannels = "SECURITY_MAX_FORWARD_CHANNELS"
+
+// ESecurityMaxReverseForwards indicates that the client has reached the maximum number of reverse forwarding bindings,
+// the new reverse forwarding request is therefore rejected.
+const ESecurityMaxReverseForwards = "SECURITY_MAX_REVERSE_FORWARDS"
+
+// ESecurityMaxX11Channels indicates that the maximum number of X11 channels has been reached, the new X11 connection
+// is therefore not forwarded to the client.
+const ESecurityMaxX11Channels = "SECURITY_MAX_X11_CHANNELS"
+
+// ESecurityMaxUserConnections indicates that the user has reached the maximum number of concurrent connections on the
+// server, the new connection is therefore rejected.
+const ESecurityMaxUserConnections = "SECURITY_MAX_USER_CONNECTIONS"
+
 const ESecurityForwardingRejected = "SECURITY_FORWARDING_REJECTED"
 
-const ESecurityReverseForwardingRejected = "SECURITY_REVERSE_FORWARDING_REJECTED"
\ No newline at end of file
+const ESecurityReverseForwardingRejected = "SECURITY_REVERSE_FORWARDING_REJECTED"
+// MSecurityIdleTimeout indicates that ContainerSSH closed a session because no data has been sent on its standard
+// input or output for longer than the configured idle timeout.
+const MSecurityIdleTimeout = "SECURITY_IDLE_TIMEOUT"
+
+// MSecuritySessionDurationExceeded indicates that ContainerSSH closed a session because it has been open for longer
+// than the configured maximum session duration.
+const MSecuritySessionDurationExceeded = "SECURITY_SESSION_DURATION_EXCEEDED"
+
+// MSecurityConnectionDurationExceeded indicates that ContainerSSH closed a connection because it has been open for
+// longer than the configured maximum connection duration.
+const MSecurityConnectionDurationExceeded = "SECURITY_CONNECTION_DURATION_EXCEEDED"
+
+// ESecurityConnectionExpired indicates that ContainerSSH rejected a new channel or request because the connection
+// has exceeded the configured maximum connection duration.
+const ESecurityConnectionExpired = "SECURITY_CONNECTION_EXPIRED"
+
+// ESecurityDisconnectWarningFailed indicates that ContainerSSH could not send the warning about an upcoming
+// policy-based disconnect to the user.
+const ESecurityDisconnectWarningFailed = "SECURITY_DISCONNECT_WARNING_FAILED"
+
+// ESecurityPolicyCloseFailed indicates that ContainerSSH could not close a session or connection after a timeout
+// policy has been reached.
+const ESecurityPolicyCloseFailed = "SECURITY_POLICY_CLOSE_FAILED"
+
+// MSecurityCommandRewritten indicates that ContainerSSH replaced the command requested by the client because it matched
+// a rewrite rule of the command policy.
+const MSecurityCommandRewritten = "SECURITY_EXEC_REWRITTEN"
+
+// MSecurityDryRunRejection indicates that a request would have been rejected by the security settings, but was let
+// through because the security settings are in dry run mode.
+const MSecurityDryRunRejection = "SECURITY_DRY_RUN_REJECTION"
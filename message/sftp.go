@@ -0,0 +1,15 @@
+package message
+
+// MSFTPStarted indicates that the built-in SFTP server has been started instead of the sftp subsystem of the backend.
+const MSFTPStarted = "SFTP_STARTED"
+
+// ESFTPShellFailed indicates that the shell executing the file operations of the built-in SFTP server could not be
+// started or has stopped unexpectedly.
+const ESFTPShellFailed = "SFTP_SHELL_FAILED"
+
+// ESFTPOperationRejected indicates that an SFTP operation has been rejected because of the read only mode, the deny
+// list or a policy hook.
+const ESFTPOperationRejected = "SFTP_OPERATION_REJECTED"
+
+// ESFTPProtocolError indicates that the SFTP client has sent a malformed packet and the SFTP session is closed.
+const ESFTPProtocolError = "SFTP_PROTOCOL_ERROR"
@@ -57,6 +57,10 @@ const ESSHAuthFailed = "SSH_AUTH_FAILED"
 // ESSHAuthSuccessful indicates that the user has provided valid credentials and is now authenticated.
 const ESSHAuthSuccessful = "SSH_AUTH_SUCCESSFUL"
 
+// ESSHAuthPartialSuccess indicates that the user has completed an authentication method, but the configuration
+// requires further authentication methods before the user is authenticated.
+const ESSHAuthPartialSuccess = "SSH_AUTH_PARTIAL_SUCCESS"
+
 // ESSHExitCodeFailed indicates that ContainerSSH failed to obtain and send the exit code of the program to the user.
 const ESSHExitCodeFailed = "SSH_EXIT_CODE_FAILED"
 
@@ -94,3 +98,22 @@ const ESSHSOReuseFailed = "SSH_SOCKET_REUSE_FAILED"
 
 // ESSHNotImplemented indicates that a feature is not implemented in the backend.
 const ESSHNotImplemented = "SSH_NOT_IMPLEMENTED"
+
+// ESSHProxyProtocolUntrusted indicates that PROXY protocol support is enabled and a connection was received from an
+// address that is not in the list of trusted proxies. The connection is rejected.
+const ESSHProxyProtocolUntrusted = "SSH_PROXY_PROTOCOL_UNTRUSTED"
+
+// ESSHProxyProtocolInvalidHeader indicates that a trusted proxy connected, but did not send a valid PROXY protocol
+// header in time. Check the configuration of your load balancer.
+const ESSHProxyProtocolInvalidHeader = "SSH_PROXY_PROTOCOL_INVALID_HEADER"
+
+// ESSHConnectionNotFound indicates that a connection was requested to be closed, but no open connection with the
+// given ID exists. The connection may have already ended.
+const ESSHConnectionNotFound = "SSH_CONNECTION_NOT_FOUND"
+
+// MSSHDisconnectRequested indicates that a connection is being closed on request, for example from the admin API.
+const MSSHDisconnectRequested = "SSH_DISCONNECT_REQUESTED"
+
+// ESSHAgentForwardingFailed indicates that the client requested the forwarding of its SSH agent, but the backend
+// rejected the request or failed to set up the forwarding.
+const ESSHAgentForwardingFailed = "SSH_AGENT_FORWARDING_FAILED"
@@ -49,6 +49,10 @@ const ESSHProxyWindowChangeFailed = "SSHPROXY_BACKEND_WINDOW_CHANGE_FAILED"
 
 const ESSHProxyX11RequestFailed = "SSHPROXY_X11_FAILED"
 
+// ESSHProxyAgentForwardingFailed indicates that ContainerSSH failed to request the forwarding of the SSH agent of the
+// client on the backend connection. The backing server may have agent forwarding disabled.
+const ESSHProxyAgentForwardingFailed = "SSHPROXY_AGENT_FORWARDING_FAILED"
+
 // MSSHProxyShutdown indicates that ContainerSSH is shutting down and is sending TERM and KILL signals on the backend
 // connection.
 const MSSHProxyShutdown = "SSHPROXY_SHUTDOWN"
@@ -0,0 +1,7 @@
+package metadata
+
+// BackendContainerKey is the metadata key the backends set after a successful handshake when they have launched a
+// container or pod for the connection. The value is the ID of the container, or the namespace and name of the pod
+// separated by a slash. The key is not set in the session execution mode, where the containers and pods are launched
+// per session channel after the handshake.
+const BackendContainerKey = "CONTAINERSSH_BACKEND_CONTAINER"
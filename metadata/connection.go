@@ -96,6 +96,18 @@ type ConnectionMetadata struct {
 	// in: body
 	ConnectionID string `json:"connectionId"`
 
+	// Listener is the name of the SSH server listener the user connected to.
+	//
+	// required: false
+	// in: body
+	Listener string `json:"listener,omitempty"`
+
+	// ListenAddress is the configured listen address of the SSH server listener the user connected to.
+	//
+	// required: false
+	// in: body
+	ListenAddress string `json:"listenAddress,omitempty"`
+
 	// Metadata is a set of key-value pairs that carry additional information from the authentication and configuration
 	// system to the backends. Backends can expose this information as container labels, environment variables, or
 	// other places.
@@ -124,10 +136,9 @@ func (meta ConnectionMetadata) StartAuthentication(
 	username string,
 ) ConnectionAuthPendingMetadata {
 	return ConnectionAuthPendingMetadata{
-		meta,
-
-		clientVersion,
-		username,
+		ConnectionMetadata: meta,
+		ClientVersion:      clientVersion,
+		Username:           username,
 	}
 }
 
@@ -167,29 +178,36 @@ type ConnectionAuthPendingMetadata struct {
 	// required: true
 	// in: body
 	Username string `json:"username"`
+
+	// AuthenticatedMethods contains the SSH authentication methods the client has already completed in the order
+	// they were completed, if the configuration requires more than one authentication method. For example, the
+	// keyboard-interactive step after a public key contains "publickey".
+	//
+	// required: false
+	// in: body
+	AuthenticatedMethods []string `json:"authenticatedMethods,omitempty"`
 }
 
 func NewTestAuthenticatingMetadata(username string) ConnectionAuthPendingMetadata {
 	return ConnectionAuthPendingMetadata{
-		NewTestMetadata(),
-		"SSH-2.0-FooSSH",
-		username,
+		ConnectionMetadata: NewTestMetadata(),
+		ClientVersion:      "SSH-2.0-FooSSH",
+		Username:           username,
 	}
 }
 
 // Authenticated creates a copy after authentication.
 func (c ConnectionAuthPendingMetadata) Authenticated(username string) ConnectionAuthenticatedMetadata {
 	return ConnectionAuthenticatedMetadata{
-		c,
-		username,
+		ConnectionAuthPendingMetadata: c,
+		AuthenticatedUsername:         username,
 	}
 }
 
 // AuthFailed creates a copy after a failed authentication to be passed along with an authentication failure.
 func (c ConnectionAuthPendingMetadata) AuthFailed() ConnectionAuthenticatedMetadata {
 	return ConnectionAuthenticatedMetadata{
-		c,
-		"",
+		ConnectionAuthPendingMetadata: c,
 	}
 }
 
@@ -205,6 +223,56 @@ type ConnectionAuthenticatedMetadata struct {
 	// required: false
 	// in: body
 	AuthenticatedUsername string `json:"authenticatedUsername,omitempty"`
+
+	// Restrictions contains the restrictions the authenticator placed on the connection, for example from the options
+	// of an authorized_keys entry or the critical options of a user certificate. Restrictions are deliberately not
+	// serialized so webhooks and configuration servers cannot set or lift them.
+	Restrictions Restrictions `json:"-"`
+
+	// Authorization contains the decision of the authorization provider if one is configured. It is recorded in the
+	// audit log and is not serialized.
+	Authorization *Authorization `json:"-"`
+
+	// PublicKeyFingerprint contains the SHA256 fingerprint of the public key the user authenticated with, if any. It
+	// is used as part of the cache key of configuration responses and is not serialized.
+	PublicKeyFingerprint string `json:"-"`
+}
+
+// Authorization is the decision of the authorization provider about a connection.
+type Authorization struct {
+	// Allowed indicates that the user is allowed to log in.
+	Allowed bool
+	// Rule is the name of the rule that made the decision, if the authorization provider uses rules. It is empty
+	// if the default action applied.
+	Rule string
+}
+
+// Restrictions mirror the per-key restrictions of OpenSSH, such as command= or no-port-forwarding. They can only make
+// the security configuration of a connection stricter.
+type Restrictions struct {
+	// ForceCommand contains the command that should be executed instead of the one requested by the client.
+	ForceCommand string
+	// NoPortForwarding disables all port and socket forwarding.
+	NoPortForwarding bool
+	// NoPTY disables TTY allocation.
+	NoPTY bool
+	// NoX11Forwarding disables X11 forwarding.
+	NoX11Forwarding bool
+	// NoAgentForwarding disables SSH agent forwarding.
+	NoAgentForwarding bool
+}
+
+// Merge combines two sets of restrictions, keeping the stricter value of each. If both contain a forced command, the
+// existing one is kept.
+func (r Restrictions) Merge(other Restrictions) Restrictions {
+	if r.ForceCommand == "" {
+		r.ForceCommand = other.ForceCommand
+	}
+	r.NoPortForwarding = r.NoPortForwarding || other.NoPortForwarding
+	r.NoPTY = r.NoPTY || other.NoPTY
+	r.NoX11Forwarding = r.NoX11Forwarding || other.NoX11Forwarding
+	r.NoAgentForwarding = r.NoAgentForwarding || other.NoAgentForwarding
+	return r
 }
 
 // Merge merges the newMeta into the current metadata structure. If environment, files, or metadata are set, these
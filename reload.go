@@ -0,0 +1,328 @@
+package libcontainerssh
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/authintegration"
+	"go.containerssh.io/libcontainerssh/internal/geoip/geoipprovider"
+	"go.containerssh.io/libcontainerssh/internal/metrics"
+	"go.containerssh.io/libcontainerssh/internal/ratelimit"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/message"
+	"go.containerssh.io/libcontainerssh/metadata"
+	"go.containerssh.io/libcontainerssh/service"
+)
+
+// handlerChainFactory creates the part of the handler chain that can be replaced on a configuration reload: the audit
+// log, rate limiting, authentication and backend handlers. The dependencies that live for the whole run, such as the
+// metrics collector and the rate limiter state, are shared between the chains.
+type handlerChainFactory struct {
+	metricsCollector    metrics.Collector
+	geoIPLookupProvider geoipprovider.LookupProvider
+	rateLimitConfig     config.RateLimitConfig
+	rateLimiter         ratelimit.Limiter
+
+	// authConfig and authHandler are the authentication configuration and handler of the active chain. The
+	// authentication handler is reused as long as the configuration does not change, so the services it depends on,
+	// such as the OAuth2 server, keep running across reloads.
+	authConfig  config.AuthConfig
+	authHandler sshserver.Handler
+}
+
+// handlerChain is a handler chain created by handlerChainFactory.
+type handlerChain struct {
+	handler     sshserver.Handler
+	authConfig  config.AuthConfig
+	authHandler sshserver.Handler
+	// services are the services the authentication handler needs. They are empty if the authentication handler of the
+	// active chain has been reused.
+	services []service.Service
+}
+
+func (f *handlerChainFactory) create(cfg config.AppConfig, logger log.Logger) (*handlerChain, error) {
+	containerBackend, err := createBackend(cfg, logger, f.metricsCollector)
+	if err != nil {
+		return nil, err
+	}
+
+	var authHandler sshserver.Handler
+	var services []service.Service
+	if f.authHandler != nil && reflect.DeepEqual(f.authConfig, cfg.Auth) {
+		authHandler, err = authintegration.WithBackend(f.authHandler, containerBackend)
+	} else {
+		authHandler, services, err = createAuthHandler(
+			cfg,
+			logger,
+			containerBackend,
+			f.metricsCollector,
+			f.geoIPLookupProvider,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitHandler := createRateLimitHandler(
+		f.rateLimitConfig,
+		f.rateLimiter,
+		logger,
+		authHandler,
+		f.metricsCollector,
+	)
+
+	auditLogHandler, err := createAuditLogHandler(cfg, logger, rateLimitHandler, f.geoIPLookupProvider)
+	if err != nil {
+		return nil, err
+	}
+	return &handlerChain{
+		handler:     auditLogHandler,
+		authConfig:  cfg.Auth,
+		authHandler: authHandler,
+		services:    services,
+	}, nil
+}
+
+// activate records the chain as the active one, so later chains can reuse its authentication handler.
+func (f *handlerChainFactory) activate(chain *handlerChain) {
+	f.authConfig = chain.authConfig
+	f.authHandler = chain.authHandler
+}
+
+// newReloadableHandler creates a handler that passes new connections to the current handler chain. Connections that
+// are already open stay with the chain they were opened with, so they keep their original configuration. A chain that
+// has been replaced is shut down once its last connection is closed.
+func newReloadableHandler(handler sshserver.Handler) *reloadableHandler {
+	current := &reloadableChain{handler: handler}
+	return &reloadableHandler{
+		current: current,
+		chains:  []*reloadableChain{current},
+	}
+}
+
+type reloadableHandler struct {
+	lock    sync.Mutex
+	current *reloadableChain
+	chains  []*reloadableChain
+}
+
+// reloadableChain is a handler chain with the number of connections that are open on it.
+type reloadableChain struct {
+	handler     sshserver.Handler
+	connections int
+}
+
+// replace makes the handler the one new connections are passed to.
+func (r *reloadableHandler) replace(handler sshserver.Handler) {
+	r.lock.Lock()
+	previous := r.current
+	r.current = &reloadableChain{handler: handler}
+	r.chains = append(r.chains, r.current)
+	unused := r.removeIfUnused(previous)
+	r.lock.Unlock()
+
+	if unused {
+		previous.handler.OnShutdown(context.Background())
+	}
+}
+
+// removeIfUnused removes the chain if it has been replaced and has no open connections. It returns true if the chain
+// has been removed and should be shut down. The caller must hold the lock.
+func (r *reloadableHandler) removeIfUnused(chain *reloadableChain) bool {
+	if chain == r.current || chain.connections > 0 {
+		return false
+	}
+	for i, c := range r.chains {
+		if c == chain {
+			r.chains = append(r.chains[:i], r.chains[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reloadableHandler) OnReady() error {
+	r.lock.Lock()
+	current := r.current
+	r.lock.Unlock()
+	return current.handler.OnReady()
+}
+
+func (r *reloadableHandler) OnShutdown(shutdownContext context.Context) {
+	r.lock.Lock()
+	chains := make([]*reloadableChain, len(r.chains))
+	copy(chains, r.chains)
+	r.lock.Unlock()
+	wg := &sync.WaitGroup{}
+	wg.Add(len(chains))
+	for _, chain := range chains {
+		go func(handler sshserver.Handler) {
+			defer wg.Done()
+			handler.OnShutdown(shutdownContext)
+		}(chain.handler)
+	}
+	wg.Wait()
+}
+
+func (r *reloadableHandler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	r.lock.Lock()
+	current := r.current
+	current.connections++
+	r.lock.Unlock()
+
+	networkHandler, meta, err := current.handler.OnNetworkConnection(meta)
+	if err != nil {
+		r.onDisconnect(current)
+		return nil, meta, err
+	}
+	return &reloadableNetworkConnectionHandler{
+		NetworkConnectionHandler: networkHandler,
+		disconnect: func() {
+			r.onDisconnect(current)
+		},
+	}, meta, nil
+}
+
+func (r *reloadableHandler) onDisconnect(chain *reloadableChain) {
+	r.lock.Lock()
+	chain.connections--
+	unused := r.removeIfUnused(chain)
+	r.lock.Unlock()
+
+	if unused {
+		chain.handler.OnShutdown(context.Background())
+	}
+}
+
+// reloadableNetworkConnectionHandler notifies the reloadableHandler when a connection is closed.
+type reloadableNetworkConnectionHandler struct {
+	sshserver.NetworkConnectionHandler
+
+	disconnect func()
+}
+
+func (r *reloadableNetworkConnectionHandler) OnDisconnect() {
+	r.NetworkConnectionHandler.OnDisconnect()
+	r.disconnect()
+}
+
+func (s *servicePool) Reload(cfg config.AppConfig) error {
+	if err := cfg.Validate(false); err != nil {
+		err = message.Wrap(
+			err,
+			message.ECoreConfigReloadFailed,
+			"The new configuration is invalid, keeping the current configuration",
+		)
+		s.logger.Error(err)
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, section := range restartRequiredSections(s.config, cfg) {
+		s.logger.Warning(
+			message.NewMessage(
+				message.ECoreConfigReloadRestartRequired,
+				"Changes to the %s configuration require a restart and have not been applied",
+				section,
+			).Label("section", section),
+		)
+	}
+	cfg = keepRestartRequiredSections(s.config, cfg)
+
+	chain, err := s.chainFactory.create(cfg, s.logger)
+	if err != nil {
+		err = message.Wrap(
+			err,
+			message.ECoreConfigReloadFailed,
+			"Failed to apply the new configuration, keeping the current configuration",
+		)
+		s.logger.Error(err)
+		return err
+	}
+	if len(chain.services) > 0 {
+		chain.handler.OnShutdown(context.Background())
+		err := message.NewMessage(
+			message.ECoreConfigReloadFailed,
+			"The new authentication configuration requires starting additional services, which is only possible on a restart, keeping the current configuration",
+		)
+		s.logger.Error(err)
+		return err
+	}
+	if err := chain.handler.OnReady(); err != nil {
+		chain.handler.OnShutdown(context.Background())
+		err = message.Wrap(
+			err,
+			message.ECoreConfigReloadFailed,
+			"Failed to apply the new configuration, keeping the current configuration",
+		)
+		s.logger.Error(err)
+		return err
+	}
+
+	s.chainFactory.activate(chain)
+	s.reloadableHandler.replace(chain.handler)
+	if levelSetter, ok := s.logger.(log.LevelSetter); ok {
+		levelSetter.SetLevel(cfg.Log.Level)
+	}
+	s.config = cfg
+	s.logger.Info(
+		message.NewMessage(
+			message.MCoreConfigReloaded,
+			"Configuration reloaded, the new configuration applies to new connections",
+		),
+	)
+	return nil
+}
+
+// restartRequiredSections returns the configuration sections that differ between the running and the new
+// configuration but cannot be changed without a restart.
+func restartRequiredSections(running config.AppConfig, cfg config.AppConfig) []string {
+	runningLog := running.Log
+	runningLog.Level = cfg.Log.Level
+	sections := []struct {
+		name    string
+		running interface{}
+		new     interface{}
+	}{
+		{"ssh", running.SSH, cfg.SSH},
+		{"log", runningLog, cfg.Log},
+		{"metrics", running.Metrics, cfg.Metrics},
+		{"geoip", running.GeoIP, cfg.GeoIP},
+		{"health", running.Health, cfg.Health},
+		{"ratelimit", running.RateLimit, cfg.RateLimit},
+		{"restart", running.Restart, cfg.Restart},
+		{"admin", running.Admin, cfg.Admin},
+	}
+	var result []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.running, section.new) {
+			result = append(result, section.name)
+		}
+	}
+	return result
+}
+
+// keepRestartRequiredSections returns the new configuration with the sections that cannot be changed without a
+// restart taken from the running configuration. The log level is the only log option that is applied.
+func keepRestartRequiredSections(running config.AppConfig, cfg config.AppConfig) config.AppConfig {
+	level := cfg.Log.Level
+	cfg.SSH = running.SSH
+	cfg.Log = running.Log
+	cfg.Log.Level = level
+	cfg.Metrics = running.Metrics
+	cfg.GeoIP = running.GeoIP
+	cfg.Health = running.Health
+	cfg.RateLimit = running.RateLimit
+	cfg.Restart = running.Restart
+	cfg.Admin = running.Admin
+	return cfg
+}
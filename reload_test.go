@@ -0,0 +1,129 @@
+package libcontainerssh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.containerssh.io/libcontainerssh/config"
+	"go.containerssh.io/libcontainerssh/internal/sshserver"
+	"go.containerssh.io/libcontainerssh/log"
+	"go.containerssh.io/libcontainerssh/metadata"
+)
+
+func TestReload(t *testing.T) {
+	cfg := newReloadTestConfig(t)
+	srv, _, err := New(cfg, log.NewLoggerFactory())
+	if !assert.NoError(t, err) {
+		return
+	}
+	pool := srv.(*servicePool)
+
+	invalidCfg := cfg
+	invalidCfg.Backend = "invalid"
+	assert.Error(t, pool.Reload(invalidCfg))
+	assert.Equal(t, config.BackendDocker, pool.config.Backend)
+	assert.Len(t, pool.reloadableHandler.chains, 1)
+
+	newCfg := cfg
+	newCfg.Security.ForceCommand = "/bin/true"
+	newCfg.Log.Level = config.LogLevelDebug
+	newCfg.SSH.Listen = "127.0.0.1:2223"
+	assert.NoError(t, pool.Reload(newCfg))
+	assert.Equal(t, "/bin/true", pool.config.Security.ForceCommand)
+	assert.Equal(t, config.LogLevelDebug, pool.config.Log.Level)
+	// The SSH listeners cannot be changed without a restart.
+	assert.Equal(t, cfg.SSH.Listen, pool.config.SSH.Listen)
+	// The previous chain had no connections, so it has been shut down and removed.
+	assert.Len(t, pool.reloadableHandler.chains, 1)
+}
+
+func TestReloadKeepsAuthServices(t *testing.T) {
+	cfg := newReloadTestConfig(t)
+	cfg.Auth.KeyboardInteractiveAuth.Method = config.KeyboardInteractiveAuthMethodOAuth2
+	cfg.Auth.KeyboardInteractiveAuth.OAuth2.ClientID = "foo"
+	cfg.Auth.KeyboardInteractiveAuth.OAuth2.ClientSecret = "bar"
+	cfg.Auth.KeyboardInteractiveAuth.OAuth2.Provider = config.AuthOAuth2GitHubProvider
+	srv, _, err := New(cfg, log.NewLoggerFactory())
+	if !assert.NoError(t, err) {
+		return
+	}
+	pool := srv.(*servicePool)
+
+	newCfg := cfg
+	newCfg.Security.ForceCommand = "/bin/true"
+	assert.NoError(t, pool.Reload(newCfg), "reloading without changing the authentication should reuse the OAuth2 server")
+	assert.Equal(t, "/bin/true", pool.config.Security.ForceCommand)
+
+	newCfg.Auth.KeyboardInteractiveAuth.OAuth2.ClientSecret = "baz"
+	assert.Error(t, pool.Reload(newCfg), "changing the OAuth2 configuration requires a restart")
+	assert.Equal(t, "bar", pool.config.Auth.KeyboardInteractiveAuth.OAuth2.ClientSecret)
+}
+
+func TestReloadableHandlerShutdown(t *testing.T) {
+	first := &reloadTestHandler{}
+	second := &reloadTestHandler{}
+	third := &reloadTestHandler{}
+	handler := newReloadableHandler(first)
+
+	networkHandler, _, err := handler.OnNetworkConnection(metadata.ConnectionMetadata{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	handler.replace(second)
+	assert.False(t, first.shutdown, "a replaced chain with open connections should not be shut down")
+	assert.Len(t, handler.chains, 2)
+
+	handler.replace(third)
+	assert.True(t, second.shutdown, "a replaced chain without connections should be shut down")
+	assert.Len(t, handler.chains, 2)
+
+	networkHandler.OnDisconnect()
+	assert.True(t, first.shutdown, "a replaced chain should be shut down when its last connection is closed")
+	assert.Len(t, handler.chains, 1)
+
+	handler.OnShutdown(context.Background())
+	assert.True(t, third.shutdown)
+}
+
+type reloadTestHandler struct {
+	sshserver.AbstractHandler
+
+	shutdown bool
+}
+
+func (r *reloadTestHandler) OnNetworkConnection(meta metadata.ConnectionMetadata) (
+	sshserver.NetworkConnectionHandler,
+	metadata.ConnectionMetadata,
+	error,
+) {
+	return &sshserver.AbstractNetworkConnectionHandler{}, meta, nil
+}
+
+func (r *reloadTestHandler) OnShutdown(_ context.Context) {
+	r.shutdown = true
+}
+
+func TestRestartRequiredSections(t *testing.T) {
+	cfg := newReloadTestConfig(t)
+
+	newCfg := cfg
+	newCfg.Log.Level = config.LogLevelDebug
+	newCfg.Security.ForceCommand = "/bin/true"
+	assert.Empty(t, restartRequiredSections(cfg, newCfg))
+
+	newCfg.SSH.Banner = "Hello world!"
+	newCfg.Health.Enable = !cfg.Health.Enable
+	assert.Equal(t, []string{"ssh", "health"}, restartRequiredSections(cfg, newCfg))
+}
+
+func newReloadTestConfig(t *testing.T) config.AppConfig {
+	cfg := config.AppConfig{}
+	cfg.Default()
+	cfg.Log.Destination = config.LogDestinationTest
+	cfg.Log.T = t
+	assert.NoError(t, cfg.SSH.GenerateHostKey())
+	return cfg
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package libcontainerssh
+
+import (
+	"os"
+)
+
+// restartSignalList contains the signals that trigger a graceful restart. Graceful restarts are not supported on this
+// platform.
+var restartSignalList []os.Signal
@@ -0,0 +1,12 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package libcontainerssh
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignalList contains the signals that trigger a graceful restart.
+var restartSignalList = []os.Signal{syscall.SIGUSR2}
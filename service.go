@@ -1,6 +1,7 @@
 package libcontainerssh
 
 import (
+    "go.containerssh.io/libcontainerssh/config"
     "go.containerssh.io/libcontainerssh/service"
 )
 
@@ -10,4 +11,15 @@ type Service interface {
 
 	// RotateLogs closes the currently open logs and reopens them to allow for log rotation.
 	RotateLogs() error
+
+	// Reload applies a new configuration to new connections. Existing connections keep their original configuration.
+	// Changes to options that require a restart, such as the SSH listeners, are logged and ignored. If the new
+	// configuration is invalid an error is returned and the current configuration stays in place.
+	Reload(cfg config.AppConfig) error
+
+	// Restart performs a graceful restart. It starts a new ContainerSSH process that takes over the listening sockets,
+	// then stops the current instance running in lifecycle once the existing connections have ended or the drain
+	// timeout has passed. If the new process fails to start the current instance keeps running and an error is
+	// returned.
+	Restart(lifecycle service.Lifecycle) error
 }
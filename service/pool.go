@@ -5,6 +5,7 @@ package service
 type Pool interface {
 	Service
 
-	// Add adds a service to the service pool.
+	// Add adds a service to the service pool. Services are stopped one after the other in the reverse order they
+	// were added in, so a service should be added after the services it depends on.
 	Add(s Service) Lifecycle
 }
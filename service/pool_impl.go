@@ -47,6 +47,7 @@ func (p *pool) RunWithLifecycle(lifecycle Lifecycle) error {
 		panic("bug: pool already running, cannot run again")
 	}
 	p.logger.Info(message.NewMessage(message.MServicesStarting, "Services are starting..."))
+	p.startupComplete = make(chan struct{}, len(p.services))
 	p.stopComplete = make(chan struct{}, len(p.services))
 	p.running = true
 	p.stopping = false
@@ -165,15 +166,7 @@ func (p *pool) triggerStop(shutdownContext context.Context) {
 	svc := p.services
 	p.mutex.Unlock()
 
-	wg := &sync.WaitGroup{}
-	wg.Add(len(svc))
-	for _, s := range svc {
-		service := s
-		l := p.lifecycles[service]
-		go func() {
-			defer wg.Done()
-			l.Stop(shutdownContext)
-		}()
+	for i := len(svc) - 1; i >= 0; i-- {
+		p.lifecycles[svc[i]].Stop(shutdownContext)
 	}
-	wg.Wait()
 }
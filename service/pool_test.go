@@ -397,3 +397,35 @@ func TestTwoServicesOneTerminatedDuringStartup(t *testing.T) {
 		service.StateCrashed,
 	}, poolStates)
 }
+
+func TestStopOrder(t *testing.T) {
+	pool := service.NewPool(service.NewLifecycleFactory(), log.NewTestLogger(t))
+	poolLifecycle := service.NewLifecycle(pool)
+	poolStarted := make(chan bool)
+	poolStopped := make(chan bool)
+	poolLifecycle.OnRunning(func(s service.Service, l service.Lifecycle) {
+		poolStarted <- true
+	})
+
+	lock := &sync.Mutex{}
+	var stopped []string
+	for _, name := range []string{"Test service 1", "Test service 2", "Test service 3"} {
+		pool.Add(newTestService(name)).OnStopped(func(s service.Service, l service.Lifecycle) {
+			lock.Lock()
+			defer lock.Unlock()
+			stopped = append(stopped, s.String())
+		})
+	}
+
+	go func() {
+		_ = poolLifecycle.Run()
+		poolStopped <- true
+	}()
+	<-poolStarted
+	poolLifecycle.Stop(context.Background())
+	<-poolStopped
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"Test service 3", "Test service 2", "Test service 1"}, stopped)
+}